@@ -2,389 +2,12997 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"embed"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/gob"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"html/template"
+	"io"
+	"log/slog"
+	"math"
+	"math/bits"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 const PAGE_SIZE = 4096 // 4KB
 
+const maxFrameStatsRows = 32
+
+// Acessos recentes incluídos na tabela de páginas do modo didático
+const livePageTableRecent = 8
+
+// Traces de exemplo distribuídos junto com o binário
+//
+//go:embed examples/*.txt
+var exampleTraces embed.FS
+
+// Painel do modo servidor (serve -ui); usa apenas a API JSON
+//
+//go:embed web/index.html
+var dashboardHTML []byte
+
+// Página que folheia os quadros gravados por -animate
+//
+//go:embed web/flipbook.html
+var flipbookHTML string
+
+var flipbookTemplate = template.Must(template.New("flipbook").Parse(flipbookHTML))
+
 type PageAccess struct {
 	PageID string
 	Type   string // "I" = instrução, "D" = dados
+	Write  bool   // acesso de escrita (coluna opcional W/R no trace)
+	Zero   bool   // escrita em página de demanda-zero (coluna Z no trace)
+	Offset uint32 // deslocamento no segmento (traces S<n>:<deslocamento>)
+	Delay  int64  // ns até o próximo acesso (última coluna +N no trace); 0 sem a coluna
 }
 
 type PageFrame struct {
 	PageID     string
 	Referenced bool
+	Dirty      bool // bit M: a página foi escrita desde que foi carregada
 	LoadCount  int
 }
 
 type Simulator struct {
-	memorySize    int
-	totalFrames   int
-	accesses      []PageAccess
-	distinctPages map[string]bool
-	pageLoadCount map[string]int
-	didacticMode  bool
-	showLoadCount bool
-	showPageTable bool
-	skipOptimal   bool
+	memorySize          int
+	totalFrames         int
+	accesses            []PageAccess
+	distinctPages       map[string]bool
+	pageLoadCount       map[string]int
+	writeCount          int
+	fileRanges          []pageRange
+	classStats          [2]ClassStats
+	colorStats          ColorStats
+	swapWriteCost       int // µs por página gravada no swap
+	fileWriteCost       int // µs por página gravada no arquivo
+	faultReadCost       int // µs para ler uma página do disco na falta
+	zeroFillCost        int // µs para alocar e zerar um frame
+	tlbHitCost          int // ns da tradução que acerta na TLB (-cost)
+	walkCost            int // ns do percurso na tabela de páginas (-cost)
+	costReport          bool
+	costJSON            string
+	faultDist           string
+	faultSpread         float64
+	faultSigma          float64
+	seriesInterval      int
+	observers           []Observer // AddObserver
+	rssThreshold        int
+	rssCSV              string
+	finalStateFile      string
+	traceFormat         string
+	traceReader         string // -reader: auto, buffered ou slice
+	parseWorkers        int    // -parse-workers; 0: automático
+	fromAccess          int    // -from-access: primeiro acesso simulado, a partir de 1
+	truncatePhase       int    // -truncate-at-phase: fase em cujo fim o trace é cortado
+	phaseWindow         int    // -phase-window: janela da detecção de fases, em acessos
+	runs                []accessRun
+	useRuns             bool
+	protoOut            string
+	frameStats          []FrameStats
+	didacticMode        bool
+	showPageTableLive   bool
+	showLoadCount       bool
+	showPageTable       bool
+	showFrameStats      bool
+	skipOptimal         bool
+	noEstimate          bool
+	bootstrapBlocks     int
+	warmup              int
+	warmupAuto          bool
+	bootstrapWarm       bool
+	bootstrapTests      []pairTest
+	convergeEpsilon     float64
+	vaddrBits           int
+	pageRadix           int    // -page-radix: base dos números de página sem 0x
+	ptFrames            string // -pt-frames: "", pinned ou evictable
+	showFrameTableFlag  bool
+	tlbEntries          int
+	tlbWindow           int
+	clockVariant        string
+	refClearInterval    int
+	refClearOnlyTimer   bool
+	refClearSweep       []int
+	sweepTau            []int   // -sweep-tau: janelas do Working Set
+	sweepTauTime        bool    // τ em ns sobre o tempo dos acessos, não em acessos
+	accessTime          int64   // -access-time: ns entre acessos sem a coluna +N; 0 desliga
+	traceTimes          []int64 // início de cada acesso e o fim do trace, quando há tempo
+	sweepTauCSV         string
+	targetFaults        int     // -target-faults; -1 desliga
+	targetRate          float64 // -target-rate como fração dos acessos; -1 desliga
+	targetJSON          string
+	showMRC             bool
+	timelinePages       []string
+	topFaulted          int
+	timelineOut         string
+	heatmapFile         string
+	animateDir          string
+	tuiRate             float64
+	playback            float64 // -playback: fator sobre o tempo do trace; 0 desliga
+	pace                *pacer  // ritmo do modo didático num terminal
+	keys                <-chan byte
+	didacticFrom        int
+	didacticTo          int // 0: até o fim
+	lessonFile          string
+	traceName           string       // nome do arquivo carregado, sem diretório
+	diagnostics         *Diagnostics // leitura do trace, para a resposta do serve
+	animate             animateFilter
+	mrcCSV              string
+	algorithms          []string
+	seed                int64
+	weights             FaultWeights // -weight
+	weighted            bool
+	trials              int    // -trials: execuções de cada algoritmo aleatório
+	trialsJSON          string // -trials-json
+	resultsJSON         string // -json: resultados no formato da resposta do serve
+	victimsFile         string // -victims: base dos arquivos com as vítimas de cada algoritmo
+	eventsCSV           string // -events-csv: uma linha por acesso de cada algoritmo
+	eventsParquet       string // -events-parquet: os mesmos eventos em Parquet
+	parquetRowGroup     int    // -parquet-row-group: linhas por grupo (0: parquetDefaultRowGroup)
+	parquetDictionary   bool   // -parquet-dict: dicionário para page_id e victim
+	patterns            bool   // -patterns: faltas por padrão de acesso das páginas
+	patternThresholds   PatternThresholds
+	patternsCSV         string                 // -patterns-csv
+	pagePatterns        map[string]PagePattern // padrão de cada página do trace carregado
+	eventSinks          []eventSink            // saídas do log de eventos abertas pelo Run
+	segmentLimits       map[uint64]uint64      // -segment-limits: limite em bytes de cada segmento
+	segmentViolations   map[uint64]int         // acessos além do limite, por segmento
+	hyperbolicSamples   int
+	nruInterval         int      // -nru-interval: acessos entre as limpezas do bit R no NRU
+	wsClockTau          int      // -wsclock-tau: idade, em acessos, que tira a página do working set
+	wsWindow            int      // -ws-window: janela Δ, em acessos, do algoritmo Working Set
+	policyExpr          exprFunc // -policy-expr; nil usa defaultPolicyExpr
+	policyExprText      string   // texto de -policy-expr, para a chave do cache
+	cacheDir            string   // -cache: resultados guardados por trace e configuração
+	noCache             bool
+	cacheClear          bool
+	cacheTrace          string // SHA-256 dos acessos, calculado na primeira consulta
+	checkpointDir       string // -checkpoint-dir
+	checkpointEvery     int    // -checkpoint-every: acessos entre checkpoints; 0 não grava
+	resume              bool   // -resume: continua do último checkpoint
+	checkpointAlgorithm string // algoritmo em execução por Run; "" nas execuções auxiliares
+	ctx                 context.Context
+	stopped             error // motivo da interrupção da última Run (Ctrl-C ou -timeout)
+	optimalPreferClean  bool
+	numaNodes           int
+	sets                int
+	associativity       int
+	cacheSize           int
+	cacheAssoc          int
+	cacheLine           int
+	colorAware          bool
+	numaLatency         []int
+	numaPlacement       string
+	numaLocal           bool
+	clockAdaptive       bool
+	clockSweepMin       int
+	clockSweepMax       int
+	clockPressureWindow int
+	nextUse             *nextUseCache
 }
 
 func NewSimulator(memorySize int) *Simulator {
 	return &Simulator{
-		memorySize:    memorySize,
-		totalFrames:   memorySize / PAGE_SIZE,
-		distinctPages: make(map[string]bool),
-		pageLoadCount: make(map[string]int),
-		didacticMode:  false,
-		showLoadCount: false,
-		showPageTable: false,
+		memorySize:          memorySize,
+		totalFrames:         memorySize / PAGE_SIZE,
+		distinctPages:       make(map[string]bool),
+		targetFaults:        -1,
+		targetRate:          -1,
+		pageLoadCount:       make(map[string]int),
+		didacticMode:        false,
+		showLoadCount:       false,
+		showPageTable:       false,
+		vaddrBits:           32,
+		pageRadix:           defaultPageRadix,
+		algorithms:          []string{"optimal", "clock", "lru", "fifo"},
+		seed:                1,
+		swapWriteCost:       8000,
+		numaNodes:           2,
+		sets:                2,
+		cacheAssoc:          8,
+		faultReadCost:       8000,
+		zeroFillCost:        50,
+		tlbHitCost:          1,
+		walkCost:            100,
+		faultSpread:         0.5,
+		faultSigma:          1,
+		cacheLine:           64,
+		numaLatency:         []int{100, 160},
+		numaPlacement:       "type",
+		fileWriteCost:       8000,
+		hyperbolicSamples:   8,
+		nruInterval:         100,
+		wsClockTau:          1000,
+		wsWindow:            1000,
+		clockVariant:        "classic",
+		traceReader:         "auto",
+		clockSweepMin:       1,
+		clockPressureWindow: 100,
+		tlbWindow:           1000,
+		animate:             animateFilter{Every: 1, From: 1, Max: 500},
+		tuiRate:             5,
+		phaseWindow:         defaultPhaseWindow,
+		patternThresholds:   defaultPatternThresholds,
+		didacticFrom:        1,
+		nextUse:             &nextUseCache{},
 	}
 }
 
-func (s *Simulator) LoadAccessFile(filename string) error {
+// Carrega o trace do arquivo no formato de s.traceFormat; o resumo da
+// leitura vem junto com o erro, sem avisos no log (veja Diagnostics.log)
+func (s *Simulator) LoadAccessFile(filename string) (Diagnostics, error) {
 	file, err := os.Open(filename)
 	if err != nil {
-		return fmt.Errorf("erro ao abrir arquivo %s: %v", filename, err)
+		return Diagnostics{File: filepath.Base(filename)}, fmt.Errorf("erro ao abrir arquivo %s: %v", filename, err)
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	lineCount := 0
-	invalidLines := 0
+	s.traceName = filepath.Base(filename)
+	load := s.LoadAccesses
+	if s.traceFormat == "proto" {
+		load = s.LoadProtoAccesses
+	} else if info, err := file.Stat(); err == nil && info.Mode().IsRegular() &&
+		(s.traceReader == "slice" || (s.traceReader == "auto" && (info.Size() >= sliceReaderMinSize || s.parseWorkers > 1))) {
+		// Pipes e dispositivos não têm tamanho conhecido e ficam com o bufio
+		load = func(r io.Reader) (Diagnostics, error) {
+			data := make([]byte, info.Size())
+			if _, err := io.ReadFull(r, data); err != nil {
+				return Diagnostics{File: s.traceName}, fmt.Errorf("erro ao ler arquivo: %v", err)
+			}
+			return s.LoadAccessBytes(data)
+		}
+	}
+	if s.fromAccess > 1 && s.traceFormat != "proto" {
+		load = func(io.Reader) (Diagnostics, error) {
+			return s.loadFrom(file, filename)
+		}
+	}
+	d, err := load(file)
+	if err != nil {
+		return d, err
+	}
+	if s.fromAccess > 1 && s.traceFormat == "proto" {
+		s.dropAccesses(s.fromAccess - 1) // sem índice para o formato binário
+	}
+	total := len(s.accesses)
+	if err := s.applyPhaseCut(&d); err != nil {
+		return d, err
+	}
+	if d.TruncatedAt > 0 {
+		fmt.Printf("Trace cortado no fim da fase %d: acessos 1 a %d de %d\n", d.Phase, d.TruncatedAt, total)
+	}
+	d.Accesses = len(s.accesses)
+	if s.useRuns {
+		s.runs = buildRuns(s.accesses)
+		fmt.Printf("Sequências de acessos repetidos: %d acessos em %d sequências (razão %.2f)\n",
+			len(s.accesses), len(s.runs), float64(len(s.accesses))/float64(len(s.runs)))
+	}
+	return d, nil
+}
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		lineCount++
+// Índice de um trace texto (sim index): a posição em bytes e o número da
+// linha de um a cada Every acessos, com as páginas distintas vistas antes
+// dele. Com -from M a leitura começa no ponto do índice anterior a M, sem
+// interpretar o começo do arquivo. O índice vale enquanto o trace tiver o
+// mesmo tamanho e o mesmo SHA-256.
+const (
+	traceIndexVersion = 1
+	traceIndexEvery   = 4096
+)
 
-		if line == "" {
-			continue
-		}
+type traceIndexEntry struct {
+	Access   int   `json:"access"` // acessos antes deste ponto
+	Offset   int64 `json:"offset"`
+	Line     int   `json:"line"`     // linha do arquivo nesta posição, a partir de 1
+	Distinct int   `json:"distinct"` // páginas distintas antes deste ponto
+}
 
-		parts := strings.Fields(line)
-		var pageID string
+type traceIndex struct {
+	Version  int               `json:"version"`
+	Size     int64             `json:"size"`
+	SHA256   string            `json:"sha256"`
+	Every    int               `json:"every"`
+	Accesses int               `json:"accesses"`
+	Entries  []traceIndexEntry `json:"entries"`
+}
 
-		if len(parts) >= 2 {
-			pageID = parts[1]
-		} else if len(parts) == 1 {
-			pageID = parts[0]
-		} else {
-			invalidLines++
-			if invalidLines <= 10 {
-				fmt.Printf("Aviso: Linha %d ignorada (formato inválido): %s\n", lineCount, line)
-			}
+func traceIndexName(trace string) string {
+	return trace + ".idx"
+}
+
+// Lê o trace uma vez, como LoadAccesses, guardando um ponto a cada every
+// acessos; o SHA-256 é calculado na mesma passada
+func buildTraceIndex(trace string, every int) (*traceIndex, error) {
+	file, err := os.Open(trace)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir arquivo %s: %v", trace, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size := &countingWriter{}
+	scanner := newTraceScanner(io.TeeReader(file, io.MultiWriter(hash, size)))
+	index := &traceIndex{Version: traceIndexVersion, Every: every}
+	seen := make(map[string]bool)
+	for scanner.Scan() {
+		access, err := scanner.parse()
+		if err != nil {
 			continue
 		}
-
-		if len(pageID) >= 2 && (pageID[0] == 'I' || pageID[0] == 'D') {
-			pageAccess := PageAccess{
-				PageID: pageID,
-				Type:   string(pageID[0]), // (I ou D)
-			}
-			s.accesses = append(s.accesses, pageAccess)
-			s.distinctPages[pageAccess.PageID] = true
-		} else {
-			invalidLines++
-			if invalidLines <= 10 {
-				fmt.Printf("Aviso: Linha %d ignorada (formato de página inválido): %s\n", lineCount, line)
-			}
+		if index.Accesses%every == 0 {
+			index.Entries = append(index.Entries, traceIndexEntry{
+				Access: index.Accesses, Offset: scanner.start, Line: scanner.lines, Distinct: len(seen),
+			})
 		}
+		seen[access.PageID] = true
+		index.Accesses++
 	}
-
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("erro ao ler arquivo: %v", err)
+		return nil, fmt.Errorf("erro ao ler %s: %v", trace, err)
+	}
+	io.Copy(io.Discard, io.TeeReader(file, io.MultiWriter(hash, size))) // depois de um NUL
+	index.Size = size.n
+	index.SHA256 = fmt.Sprintf("%x", hash.Sum(nil))
+	return index, nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+func writeTraceIndex(filename string, index *traceIndex) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("erro ao gravar %s: %v", filename, err)
+	}
+	return nil
+}
+
+// Índice do trace, se existir e ainda corresponder ao arquivo; nil quando
+// não há índice ou ele está desatualizado
+func loadTraceIndex(trace string) (*traceIndex, error) {
+	data, err := os.ReadFile(traceIndexName(trace))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var index traceIndex
+	if err := json.Unmarshal(data, &index); err != nil || index.Version != traceIndexVersion {
+		return nil, nil
+	}
+	info, err := os.Stat(trace)
+	if err != nil || info.Size() != index.Size {
+		return nil, nil
+	}
+	file, err := os.Open(trace)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, err
 	}
+	if fmt.Sprintf("%x", hash.Sum(nil)) != index.SHA256 {
+		return nil, nil
+	}
+	return &index, nil
+}
+
+// Ponto do índice mais próximo antes do acesso skip (acessos a pular)
+func (index *traceIndex) seek(skip int) traceIndexEntry {
+	k := sort.Search(len(index.Entries), func(k int) bool { return index.Entries[k].Access > skip }) - 1
+	if k < 0 {
+		return traceIndexEntry{Line: 1}
+	}
+	return index.Entries[k]
+}
 
-	if invalidLines > 10 {
-		fmt.Printf("... e mais %d linhas inválidas (não mostradas)\n", invalidLines-10)
+// Lê o trace a partir do acesso s.fromAccess (-from), usando o índice para
+// pular o começo do arquivo quando ele existe e está atualizado
+func (s *Simulator) loadFrom(file *os.File, filename string) (Diagnostics, error) {
+	skip := s.fromAccess - 1
+	entry := traceIndexEntry{Line: 1}
+	index, err := loadTraceIndex(filename)
+	if err != nil {
+		return Diagnostics{File: s.traceName}, err
+	}
+	if index != nil {
+		entry = index.seek(skip)
+		if _, err := file.Seek(entry.Offset, io.SeekStart); err != nil {
+			return Diagnostics{File: s.traceName}, fmt.Errorf("erro ao posicionar em %s: %v", filename, err)
+		}
+		logger.Info("leitura pelo índice", "index", traceIndexName(filename), "line", entry.Line,
+			"access", entry.Access+1, "distinct", entry.Distinct)
+	} else {
+		logger.Info("sem índice atualizado; o trace é lido desde o início", "index", traceIndexName(filename))
 	}
 
+	scanner := newTraceScanner(file)
+	scanner.lines = entry.Line - 1
+	// As linhas puladas contam na numeração dos avisos
+	skipped := &loadedLines{lines: entry.Line - 1}
+	d, err := s.addLoaded([]*loadedLines{skipped, readLines(scanner)}, scanner.normalizations())
+	if err != nil {
+		return d, err
+	}
+	s.dropAccesses(skip - entry.Access)
 	if len(s.accesses) == 0 {
-		return fmt.Errorf("nenhum acesso válido encontrado no arquivo")
+		return d, fmt.Errorf("o trace tem menos de %d acessos", s.fromAccess)
 	}
+	return d, nil
+}
 
-	fmt.Printf("Arquivo processado: %d linhas lidas, %d acessos válidos, %d linhas inválidas\n",
-		lineCount, len(s.accesses), invalidLines)
+// Descarta os n primeiros acessos carregados
+func (s *Simulator) dropAccesses(n int) {
+	if n <= 0 {
+		return
+	}
+	s.accesses = s.accesses[min(n, len(s.accesses)):]
+	s.recount()
+}
 
-	return nil
+// Recalcula as páginas distintas e as escritas depois de cortar o trace
+func (s *Simulator) recount() {
+	s.distinctPages = make(map[string]bool)
+	s.writeCount = 0
+	for _, access := range s.accesses {
+		s.distinctPages[access.PageID] = true
+		if access.Write {
+			s.writeCount++
+		}
+	}
 }
 
-func (s *Simulator) OptimalAlgorithm() int {
-	frames := make([]string, 0, s.totalFrames)
-	frameMap := make(map[string]int) // page : frame index
-	pageFaults := 0
-	s.pageLoadCount = make(map[string]int)
+// Fases do trace (-truncate-at-phase): para cada ponto j, compara as
+// frequências das páginas em [j-W, j) e [j, j+W); a sobreposição (soma dos
+// mínimos, de 0 a W) cai abaixo de phaseOverlap*W perto de uma mudança de
+// fase. Em cada trecho abaixo do limite, a fronteira é o primeiro ponto de
+// menor sobreposição. As janelas deslizam um acesso por vez, então a
+// detecção é uma passada de O(n); fases mais curtas que a janela não são
+// separadas.
+const (
+	defaultPhaseWindow = 1000
+	phaseOverlap       = 0.5
+)
 
-	nextUse := make(map[string][]int)
-	for i, access := range s.accesses {
-		pageID := access.PageID
-		nextUse[pageID] = append(nextUse[pageID], i)
+// Aplica -truncate-at-phase ao trace carregado, anotando o corte na leitura
+func (s *Simulator) applyPhaseCut(d *Diagnostics) error {
+	if s.truncatePhase == 0 {
+		return nil
+	}
+	cut, err := s.truncateAtPhase(s.truncatePhase)
+	if err != nil {
+		return err
 	}
+	d.Accesses, d.TruncatedAt, d.Phase = cut, cut, s.truncatePhase
+	return nil
+}
 
-	for i, access := range s.accesses {
-		pageID := access.PageID
+// Índice do primeiro acesso de cada fase depois da primeira
+func detectPhases(accesses []PageAccess, window int) []int {
+	if 2*window > len(accesses) {
+		return nil
+	}
+	left, right := make(map[string]int), make(map[string]int)
+	for _, access := range accesses[:window] {
+		left[access.PageID]++
+	}
+	for _, access := range accesses[window : 2*window] {
+		right[access.PageID]++
+	}
+	shared := 0
+	for page, n := range left {
+		shared += min(n, right[page])
+	}
+	move := func(page string, toLeft, toRight int) {
+		shared -= min(left[page], right[page])
+		left[page] += toLeft
+		right[page] += toRight
+		shared += min(left[page], right[page])
+	}
+	var boundaries []int
+	limit := int(math.Ceil(phaseOverlap * float64(window)))
+	best, bestAt := 0, -1
+	for j := window; ; j++ {
+		switch {
+		case shared >= limit && bestAt >= 0:
+			boundaries = append(boundaries, bestAt)
+			bestAt = -1
+		case shared < limit && (bestAt < 0 || shared < best):
+			best, bestAt = shared, j
+		}
+		if j+window == len(accesses) {
+			break
+		}
+		// Desliza: um acesso sai da esquerda, um passa da direita para a
+		// esquerda e um entra na direita
+		move(accesses[j-window].PageID, -1, 0)
+		move(accesses[j].PageID, 1, -1)
+		move(accesses[j+window].PageID, 0, 1)
+	}
+	if bestAt >= 0 {
+		boundaries = append(boundaries, bestAt)
+	}
+	return boundaries
+}
 
-		if _, found := frameMap[pageID]; found {
-			// Hit
+// Corta o trace no fim da fase n e devolve os acessos mantidos; o trace
+// inteiro fica quando ele tem exatamente n fases
+func (s *Simulator) truncateAtPhase(n int) (int, error) {
+	boundaries := detectPhases(s.accesses, s.phaseWindow)
+	cut := len(s.accesses)
+	switch {
+	case n <= len(boundaries):
+		cut = boundaries[n-1]
+	case n > len(boundaries)+1:
+		return 0, fmt.Errorf("o trace tem %d fases detectadas, menos que %d (janela de %d acessos)",
+			len(boundaries)+1, n, s.phaseWindow)
+	}
+	s.accesses = s.accesses[:cut]
+	s.recount()
+	return cut, nil
+}
+
+// sim index [-every K] trace
+func runIndex(args []string) {
+	every := traceIndexEvery
+	var files []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-every" && i+1 < len(args) {
+			value, err := strconv.Atoi(args[i+1])
+			if err != nil || value < 1 {
+				logger.Error("intervalo inválido", "value", args[i+1])
+				return
+			}
+			every = value
+			i++
 			continue
 		}
+		files = append(files, args[i])
+	}
+	if len(files) != 1 {
+		fmt.Println("Uso: go run main.go index [-every K] <trace>")
+		return
+	}
 
-		// Page fault
-		pageFaults++
-		s.pageLoadCount[pageID]++
+	index, err := buildTraceIndex(files[0], every)
+	if err != nil {
+		logger.Error("erro ao indexar o trace", "file", files[0], "err", err)
+		return
+	}
+	name := traceIndexName(files[0])
+	if err := writeTraceIndex(name, index); err != nil {
+		logger.Error("erro ao gravar o índice", "file", name, "err", err)
+		return
+	}
+	fmt.Printf("Índice gravado em %s: %d acessos, %d pontos (um a cada %d acessos)\n",
+		name, index.Accesses, len(index.Entries), every)
+}
 
-		if len(frames) < s.totalFrames {
-			frames = append(frames, pageID)
-			frameMap[pageID] = len(frames) - 1
-		} else {
-			farthestNextUse := -1
-			victimFrame := -1
+// Trace em protobuf (proto/trace.proto): mensagens Access precedidas pelo
+// tamanho em varint. A codificação é feita à mão, pois o formato é pequeno;
+// os tipos gerados ficam só para os testes.
+//
+//go:generate protoc --go_out=. --go_opt=paths=source_relative proto/trace.proto
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
 
-			for frameIdx, pageInFrame := range frames {
-				positions := nextUse[pageInFrame]
+	maxProtoMessage = 1 << 20
+)
 
-				searchIndex := sort.SearchInts(positions, i+1)
+func appendProtoVarint(b []byte, field int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3|protoVarint))
+	return binary.AppendUvarint(b, v)
+}
 
-				var nextPos int
-				if searchIndex == len(positions) {
-					// vitima
-					nextPos = len(s.accesses)
-				} else {
-					nextPos = positions[searchIndex]
-				}
+// Varint omitido quando zero, o valor padrão
+func appendProtoUint(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	return appendProtoVarint(b, field, v)
+}
 
-				if nextPos > farthestNextUse {
-					farthestNextUse = nextPos
-					victimFrame = frameIdx
-				}
+func appendProtoBytes(b []byte, field int, data []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3|protoBytes))
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
 
-				if nextPos == len(s.accesses) {
-					break
-				}
-			}
+func protoBool(v bool) uint64 {
+	if v {
+		return 1
+	}
+	return 0
+}
 
-			//remove vitima
-			victimPage := frames[victimFrame]
-			delete(frameMap, victimPage)
+// Mensagem Access; campos com o valor padrão são omitidos, como no proto3
+func (a PageAccess) MarshalProto() []byte {
+	var b []byte
+	if a.Type == "D" {
+		b = appendProtoVarint(b, 1, 1)
+	}
+	b = appendProtoBytes(b, 2, []byte(a.PageID))
+	if a.Write {
+		b = appendProtoVarint(b, 3, 1)
+	}
+	if a.Zero {
+		b = appendProtoVarint(b, 6, 1)
+	}
+	if a.Delay != 0 {
+		b = appendProtoVarint(b, 7, uint64(a.Delay))
+	}
+	return b
+}
 
-			// add pagina
-			frames[victimFrame] = pageID
-			frameMap[pageID] = victimFrame
+// Percorre os campos de uma mensagem, chamando visit para os varints e
+// para os campos de tamanho variável; os demais tipos são pulados
+func walkProto(msg []byte, visit func(field int, v uint64, data []byte) error) error {
+	for len(msg) > 0 {
+		tag, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return errors.New("tag inválida")
+		}
+		msg = msg[n:]
+		field := int(tag >> 3)
+		switch tag & 7 {
+		case protoVarint:
+			v, n := binary.Uvarint(msg)
+			if n <= 0 {
+				return errors.New("varint inválido")
+			}
+			msg = msg[n:]
+			if err := visit(field, v, nil); err != nil {
+				return err
+			}
+		case protoBytes:
+			size, n := binary.Uvarint(msg)
+			if n <= 0 || size > uint64(len(msg)-n) {
+				return errors.New("campo truncado")
+			}
+			data := msg[n : n+int(size)]
+			msg = msg[n+int(size):]
+			if err := visit(field, 0, data); err != nil {
+				return err
+			}
+		case protoFixed64, protoFixed32:
+			size := 8
+			if tag&7 == protoFixed32 {
+				size = 4
+			}
+			if len(msg) < size {
+				return errors.New("campo truncado")
+			}
+			msg = msg[size:]
+		default:
+			return fmt.Errorf("tipo de campo desconhecido: %d", tag&7)
 		}
 	}
-	return pageFaults
+	return nil
 }
 
-// Algoritmo do Relógio (Clock)
-func (s *Simulator) ClockAlgorithm() int {
-	frames := make([]*PageFrame, s.totalFrames)
-	pageToFrame := make(map[string]int)
-	clockPointer := 0
-	pageFaults := 0
+func unmarshalAccessProto(msg []byte) (PageAccess, error) {
+	var access PageAccess
+	isData := false
+	err := walkProto(msg, func(field int, v uint64, data []byte) error {
+		switch field {
+		case 1:
+			isData = v == 1
+		case 2:
+			access.PageID = string(data)
+		case 3:
+			access.Write = v != 0
+		case 6:
+			access.Zero = v != 0
+		case 7:
+			if v > math.MaxInt64 {
+				return errDelay
+			}
+			access.Delay = int64(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return PageAccess{}, err
+	}
 
-	s.pageLoadCount = make(map[string]int)
+	// A página passa pela mesma validação das linhas do trace em texto
+	parsed, err := parseLine(access.PageID)
+	if err == errSkipLine || strings.ContainsAny(access.PageID, " \t") {
+		return PageAccess{}, errPageFormat
+	}
+	if err != nil {
+		return PageAccess{}, err
+	}
+	if (parsed.Type == "D") != isData {
+		return PageAccess{}, errKindMismatch
+	}
+	parsed.Write, parsed.Zero, parsed.Delay = access.Write || access.Zero, access.Zero, access.Delay
+	return parsed, nil
+}
 
-	for i, access := range s.accesses {
-		pageID := access.PageID
+// Lê a próxima mensagem delimitada; io.EOF ao final do arquivo
+func readProtoMessage(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errors.New("tamanho de mensagem truncado")
+		}
+		return nil, err
+	}
+	if size > maxProtoMessage {
+		return nil, fmt.Errorf("mensagem de %d bytes", size)
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, errors.New("mensagem truncada")
+	}
+	return msg, nil
+}
 
-		// Verifica se a página já está na memória
-		if frameIndex, exists := pageToFrame[pageID]; exists {
-			// Hit - marca como referenciada
-			frames[frameIndex].Referenced = true
-			if s.didacticMode {
-				fmt.Printf("Acesso %d - Página %s: Hit\n", i+1, pageID)
-			}
+// Lê os acessos de um trace protobuf, uma mensagem por vez; no resumo as
+// linhas são as mensagens
+func (s *Simulator) LoadProtoAccesses(r io.Reader) (Diagnostics, error) {
+	reader := bufio.NewReader(r)
+	d := Diagnostics{File: s.traceName, Format: "proto"}
+	limit := rejectedLimit()
+	for {
+		msg, err := readProtoMessage(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return d, fmt.Errorf("mensagem %d: %v", d.Lines+1, err)
+		}
+		d.Lines++
+
+		access, err := unmarshalAccessProto(msg)
+		if err != nil {
+			d.reject(d.Lines, err, fmt.Sprintf("%x", msg), limit)
 			continue
 		}
+		s.accesses = append(s.accesses, access)
+		s.distinctPages[access.PageID] = true
+		if access.Write {
+			s.writeCount++
+		}
+		d.Accesses++
+	}
+
+	if len(s.accesses) == 0 {
+		return d, fmt.Errorf("nenhum acesso válido encontrado no arquivo")
+	}
+	return d, nil
+}
+
+// Mensagem Results com o resumo de cada algoritmo; como no proto3, os
+// campos com valor zero são omitidos
+func marshalResultsProto(accesses, distinct, frames int, results []Result) []byte {
+	var b []byte
+	b = appendProtoUint(b, 1, uint64(accesses))
+	b = appendProtoUint(b, 2, uint64(distinct))
+	b = appendProtoUint(b, 3, uint64(frames))
+	for _, r := range results {
+		var item []byte
+		item = appendProtoBytes(item, 1, []byte(r.Algorithm))
+		item = appendProtoUint(item, 2, uint64(r.Faults))
+		item = appendProtoUint(item, 3, uint64(r.WriteBacks))
+		item = appendProtoUint(item, 4, uint64(r.ZeroFills))
+		if r.Partial {
+			item = appendProtoUint(item, 5, uint64(r.Accesses))
+			item = appendProtoVarint(item, 6, 1)
+		}
+		b = appendProtoBytes(b, 4, item)
+	}
+	return appendProtoUint(b, 5, resultsSchemaVersion)
+}
+
+// Converte um trace entre texto e protobuf, lendo e gravando aos poucos;
+// transform, se não for nil, é aplicada a cada acesso antes da gravação
+func convertTrace(from, to, input, output string, transform func(PageAccess) (PageAccess, error)) error {
+	in, err := os.Open(input)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir arquivo %s: %v", input, err)
+	}
+	defer in.Close()
+	out, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("erro ao criar %s: %v", output, err)
+	}
+	defer out.Close()
+	w := bufio.NewWriter(out)
 
-		// Falta de página
-		pageFaults++
-		s.pageLoadCount[pageID]++
+	var transformErr error
+	write := func(access PageAccess) {
+		if transform != nil && transformErr == nil {
+			access, transformErr = transform(access)
+		}
+		if transformErr != nil {
+			return
+		}
+		if to == "proto" {
+			msg := access.MarshalProto()
+			w.Write(binary.AppendUvarint(nil, uint64(len(msg))))
+			w.Write(msg)
+		} else {
+			fmt.Fprintln(w, access)
+		}
+	}
 
-		// Procura por um frame vazio primeiro
-		emptyFrame := -1
-		for j := 0; j < s.totalFrames; j++ {
-			if frames[j] == nil {
-				emptyFrame = j
+	count, invalid := 0, 0
+	if from == "proto" {
+		reader := bufio.NewReader(in)
+		for {
+			msg, err := readProtoMessage(reader)
+			if err == io.EOF {
 				break
 			}
+			if err != nil {
+				return fmt.Errorf("mensagem %d: %v", count+invalid+1, err)
+			}
+			access, err := unmarshalAccessProto(msg)
+			if err != nil {
+				invalid++
+				continue
+			}
+			write(access)
+			count++
 		}
-
-		if emptyFrame != -1 {
-			// Usa frame vazio
-			frames[emptyFrame] = &PageFrame{
-				PageID:     pageID,
-				Referenced: true,
-				LoadCount:  1,
+	} else {
+		scanner := newTraceScanner(in)
+		for scanner.Scan() {
+			access, err := scanner.parse()
+			if err == errSkipLine {
+				continue
 			}
-			pageToFrame[pageID] = emptyFrame
-		} else {
-			// Usa algoritmo do relógio para encontrar vítima
-			for {
-				if !frames[clockPointer].Referenced {
-					// Encontrou vítima
-					oldPageID := frames[clockPointer].PageID
-					delete(pageToFrame, oldPageID)
-
-					frames[clockPointer] = &PageFrame{
-						PageID:     pageID,
-						Referenced: true,
-						LoadCount:  1,
-					}
-					pageToFrame[pageID] = clockPointer
-					clockPointer = (clockPointer + 1) % s.totalFrames
-					break
-				} else {
-					// Dá segunda chance
-					frames[clockPointer].Referenced = false
-					clockPointer = (clockPointer + 1) % s.totalFrames
-				}
+			if err != nil {
+				invalid++
+				continue
 			}
+			write(access)
+			count++
 		}
-
-		if s.didacticMode {
-			fmt.Printf("Acesso %d - Página %s: Falta de página\n", i+1, pageID)
-			s.printMemoryState(frames)
-			fmt.Println("---")
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("erro ao ler arquivo: %v", err)
 		}
 	}
+	if transformErr != nil {
+		return transformErr
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("erro ao gravar %s: %v", output, err)
+	}
 
-	return pageFaults
+	inInfo, _ := in.Stat()
+	outInfo, _ := out.Stat()
+	fmt.Printf("%d acessos convertidos (%d inválidos ignorados)\n", count, invalid)
+	fmt.Printf("%s: %s -> %s: %s\n", input, formatBytes(float64(inInfo.Size())),
+		output, formatBytes(float64(outInfo.Size())))
+	return nil
 }
 
-func (s *Simulator) printMemoryState(frames []*PageFrame) {
-	fmt.Print("Estado da memória: [")
-	for i, frame := range frames {
-		if frame != nil {
-			refChar := "R"
-			if !frame.Referenced {
-				refChar = "NR"
-			}
-			fmt.Printf("%s(%s)", frame.PageID, refChar)
-		} else {
-			fmt.Print("vazio")
+// Lê as linhas de um trace tolerando arquivos gerados no Windows ou em
+// planilhas: fim de linha CRLF, marca BOM no início e preenchimento com
+// bytes NUL no final, que encerra a leitura. Espaços e tabulações ao redor
+// dos campos são tratados por parseLine.
+type traceScanner struct {
+	scanner  *bufio.Scanner
+	line     string
+	lines    int
+	start    int64 // posição em bytes da linha atual (sim index)
+	consumed int64
+	crlf     bool
+	bom      bool
+	nul      bool
+	skipping bool // descartando o resto de uma linha longa demais
+	long     bool // a linha atual passou de maxLineLength e foi descartada
+}
+
+func newTraceScanner(r io.Reader) *traceScanner {
+	t := &traceScanner{scanner: bufio.NewScanner(r)}
+	t.scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	t.scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := t.split(data, atEOF)
+		t.consumed += int64(advance)
+		return advance, token, err
+	})
+	return t
+}
+
+// Divide as linhas como bufio.ScanLines. Uma linha que não cabe no buffer
+// é descartada até a quebra e entregue vazia, marcada em long, para ser
+// rejeitada como as outras linhas inválidas sem interromper a leitura.
+func (t *traceScanner) split(data []byte, atEOF bool) (int, []byte, error) {
+	if t.skipping {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			t.skipping = false
+			return i + 1, []byte{}, nil
 		}
-		if i < len(frames)-1 {
-			fmt.Print(", ")
+		if atEOF {
+			t.skipping = false
+			return len(data), []byte{}, nil
 		}
+		return len(data), nil, nil
 	}
-	fmt.Println("]")
+	advance, token, err := bufio.ScanLines(data, atEOF)
+	if advance == 0 && token == nil && len(data) >= maxLineLength {
+		t.skipping, t.long = true, true
+		return len(data), nil, nil
+	}
+	if advance >= 2 && data[advance-1] == '\n' && data[advance-2] == '\r' {
+		t.crlf = true
+	}
+	return advance, token, err
 }
 
-func (s *Simulator) ShowLoadCount() {
-	if !s.showLoadCount {
-		return
+func (t *traceScanner) Scan() bool {
+	t.start = t.consumed
+	t.long = false
+	if t.nul || !t.scanner.Scan() {
+		return false
 	}
+	t.lines++
+	t.line = t.scanner.Text()
+	if t.long {
+		return true
+	}
+	if t.lines == 1 && strings.HasPrefix(t.line, "\ufeff") {
+		t.line = strings.TrimPrefix(t.line, "\ufeff")
+		t.bom = true
+	}
+	if i := strings.IndexByte(t.line, 0); i >= 0 {
+		t.line = t.line[:i]
+		t.nul = true
+		return strings.TrimSpace(t.line) != "" // o que vem antes do NUL ainda vale
+	}
+	return true
+}
 
-	fmt.Println("\n=== NÚMERO DE CARREGAMENTOS POR PÁGINA ===")
+func (t *traceScanner) Text() string {
+	return t.line
+}
 
-	// Ordena as páginas para exibição organizada
-	var pages []string
-	for page := range s.pageLoadCount {
-		pages = append(pages, page)
-	}
-	sort.Strings(pages)
+func (t *traceScanner) Err() error {
+	return t.scanner.Err()
+}
 
-	for _, page := range pages {
-		fmt.Printf("Página %s: %d carregamentos\n", page, s.pageLoadCount[page])
+// Ajustes feitos no arquivo, para o resumo da leitura
+func (t *traceScanner) normalizations() []string {
+	var applied []string
+	if t.crlf {
+		applied = append(applied, "fim de linha CRLF")
+	}
+	if t.bom {
+		applied = append(applied, "marca BOM removida")
+	}
+	if t.nul {
+		applied = append(applied, fmt.Sprintf("leitura encerrada no byte NUL da linha %d", t.lines))
 	}
+	return applied
 }
 
-func (s *Simulator) EstimatePageTableSize() {
-	if !s.showPageTable {
-		return
+// Linhas do trace para LoadAccesses: traceScanner lê com bufio, linha a
+// linha; sliceScanner percorre o arquivo inteiro já na memória (-reader)
+type traceLines interface {
+	Scan() bool
+	Text() string
+	Err() error
+	parse() (PageAccess, error)
+	normalizations() []string
+}
+
+func (t *traceScanner) parse() (PageAccess, error) {
+	if t.long {
+		return PageAccess{}, errLineTooLong
 	}
+	return parseLine(t.line)
+}
 
-	fmt.Println("\n=== ESTIMATIVA DO TAMANHO DA TABELA DE PÁGINAS ===")
+// Com -reader auto, arquivos a partir deste tamanho são lidos de uma vez
+const sliceReaderMinSize = 4 << 20
 
-	entrySize := 8
-	numDistinctPages := len(s.distinctPages)
+// Percorre os bytes do trace procurando as quebras de linha, com as mesmas
+// normalizações, números de linha e limite de tamanho do traceScanner.
+// As linhas comuns são interpretadas direto dos bytes, sem criar uma
+// string por linha; o identificador da página é compartilhado entre os
+// acessos à mesma página. O resto vai para parseLine.
+type sliceScanner struct {
+	data   []byte
+	line   []byte
+	lines  int
+	crlf   bool
+	bom    bool
+	nul    bool
+	long   bool // a linha atual passou de maxLineLength e foi descartada
+	err    error
+	inner  bool // trecho que não começa no início do arquivo (sem BOM)
+	intern map[string]string
+}
 
-	tableSize := numDistinctPages * entrySize
+func newSliceScanner(data []byte) *sliceScanner {
+	return &sliceScanner{data: data, intern: make(map[string]string)}
+}
 
-	fmt.Printf("Páginas distintas acessadas: %d\n", numDistinctPages)
+func (t *sliceScanner) Scan() bool {
+	if t.nul || len(t.data) == 0 {
+		return false
+	}
+	line, rest := t.data, []byte(nil)
+	if i := bytes.IndexByte(t.data, '\n'); i >= 0 {
+		line, rest = t.data[:i], t.data[i+1:]
+	}
+	// O bufio.Scanner precisa de espaço para a linha e a quebra no buffer
+	t.long = len(line) >= maxLineLength
+	if t.long {
+		t.data, t.line = rest, nil
+		t.lines++
+		return true
+	}
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		if len(rest) < len(t.data)-n { // havia quebra de linha depois do \r
+			t.crlf = true
+		}
+		line = line[:n-1]
+	}
+	t.data = rest
+	t.lines++
+	if t.lines == 1 && !t.inner && bytes.HasPrefix(line, []byte("\ufeff")) {
+		line = line[len("\ufeff"):]
+		t.bom = true
+	}
+	t.line = line
+	if i := bytes.IndexByte(line, 0); i >= 0 {
+		t.line = line[:i]
+		t.nul = true
+		return len(bytes.TrimSpace(t.line)) > 0
+	}
+	return true
+}
+
+func (t *sliceScanner) Text() string {
+	return string(t.line)
+}
+
+func (t *sliceScanner) Err() error {
+	return t.err
+}
+
+// Maior número de campos interpretados sem passar por parseLine
+const sliceMaxFields = 8
+
+func (t *sliceScanner) parse() (PageAccess, error) {
+	if t.long {
+		return PageAccess{}, errLineTooLong
+	}
+	var fields [sliceMaxFields][]byte
+	n := 0
+	for i := 0; i < len(t.line); {
+		c := t.line[i]
+		if c >= 0x80 || c == '\v' || c == '\f' {
+			return parseLine(t.Text()) // espaços Unicode e afins: caminho geral
+		}
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(t.line) && t.line[j] > ' ' && t.line[j] < 0x80 {
+			j++
+		}
+		if n == sliceMaxFields || (j < len(t.line) && t.line[j] >= 0x80) {
+			return parseLine(t.Text())
+		}
+		fields[n] = t.line[i:j]
+		n++
+		i = j
+	}
+	if n == 0 || fields[0][0] == '#' {
+		return PageAccess{}, errSkipLine
+	}
+
+	var delay int64
+	if n >= 2 && fields[n-1][0] == '+' {
+		d, ok := parseDelay(fields[n-1][1:])
+		if !ok {
+			return parseLine(t.Text())
+		}
+		delay, n = d, n-1
+	}
+	write, zero := false, false
+	if n >= 2 && len(fields[n-1]) == 1 {
+		switch fields[n-1][0] {
+		case 'W', 'w':
+			write, n = true, n-1
+		case 'Z', 'z':
+			write, zero, n = true, true, n-1
+		case 'R', 'r':
+			n--
+		}
+	}
+	page := fields[0]
+	if n >= 2 {
+		page = fields[1]
+	}
+	if len(page) < 2 || (page[0] != 'I' && page[0] != 'D') {
+		return parseLine(t.Text()) // mesmo motivo de rejeição
+	}
+	id, ok := t.intern[string(page)]
+	if !ok {
+		id = string(page)
+		t.intern[id] = id
+	}
+	kind := "D"
+	if page[0] == 'I' {
+		kind = "I"
+	}
+	return PageAccess{PageID: id, Type: kind, Write: write, Zero: zero, Delay: delay}, nil
+}
+
+func (t *sliceScanner) normalizations() []string {
+	scanner := traceScanner{lines: t.lines, crlf: t.crlf, bom: t.bom, nul: t.nul}
+	return scanner.normalizations()
+}
+
+// Com -parse-workers automático, traces a partir deste tamanho são
+// interpretados em paralelo, um trecho por processador
+const parallelParseMinSize = 1 << 20
+
+// Carrega um trace já lido para a memória (-reader slice). O texto é
+// dividido em trechos que terminam em quebras de linha, interpretados em
+// paralelo e juntados na ordem: o resultado é o da leitura sequencial.
+func (s *Simulator) LoadAccessBytes(data []byte) (Diagnostics, error) {
+	workers := s.parseWorkers
+	if workers == 0 {
+		workers = 1
+		if len(data) >= parallelParseMinSize {
+			workers = runtime.NumCPU()
+		}
+	}
+	parts := splitLines(data, workers)
+	scanners := make([]*sliceScanner, len(parts))
+	chunks := make([]*loadedLines, len(parts))
+	var wg sync.WaitGroup
+	for i, part := range parts {
+		scanners[i] = newSliceScanner(part)
+		scanners[i].inner = i > 0
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			chunks[i] = readLines(scanners[i])
+		}()
+	}
+	wg.Wait()
+
+	// Normalizações do arquivo inteiro, até onde a leitura foi
+	var whole traceScanner
+	for i, scanner := range scanners {
+		whole.bom = whole.bom || scanner.bom
+		whole.crlf = whole.crlf || scanner.crlf
+		whole.lines += scanner.lines
+		if scanner.nul || chunks[i].err != nil {
+			whole.nul = scanner.nul
+			chunks[i].stop = true
+			break
+		}
+	}
+	return s.addLoaded(chunks, whole.normalizations())
+}
+
+// Divide data em até n trechos de tamanhos parecidos, cada um terminando
+// logo depois de uma quebra de linha (o último, no fim do arquivo). Uma
+// linha nunca fica dividida entre dois trechos.
+func splitLines(data []byte, n int) [][]byte {
+	var parts [][]byte
+	for n = max(n, 1); len(data) > 0; n-- {
+		end := len(data)
+		if n > 1 {
+			if i := bytes.IndexByte(data[max(len(data)/n, 1)-1:], '\n'); i >= 0 {
+				end = max(len(data)/n, 1) + i
+			}
+		}
+		parts = append(parts, data[:end])
+		data = data[end:]
+	}
+	return parts
+}
+
+// Linha vazia ou comentário: não é um acesso nem um erro
+var errSkipLine = errors.New("linha ignorada")
+
+// Motivos de rejeição de uma linha do trace (ou mensagem do proto), que
+// são as chaves de Diagnostics.Reasons
+var (
+	errPageKind     = errors.New("página sem o tipo I ou D")
+	errPageNumber   = errors.New("página só com o tipo, sem identificador")
+	errPageFormat   = errors.New("formato de página inválido")
+	errKindMismatch = errors.New("tipo não confere com a página")
+	errDelay        = errors.New("atraso inválido (use +N, em ns)")
+	errSegment      = errors.New("endereço segmentado inválido (use S<segmento>:<deslocamento>)")
+	errLineTooLong  = fmt.Errorf("linha maior que %d bytes", maxLineLength)
+)
+
+// Atraso da coluna +N (sem o +): só dígitos, cabendo em int64
+func parseDelay[T string | []byte](text T) (int64, bool) {
+	if len(text) == 0 {
+		return 0, false
+	}
+	var delay int64
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c < '0' || c > '9' || delay > (math.MaxInt64-int64(c-'0'))/10 {
+			return 0, false
+		}
+		delay = delay*10 + int64(c-'0')
+	}
+	return delay, true
+}
+
+// Maior linha aceita no trace
+const maxLineLength = 1 << 20
+
+// Interpreta uma linha do trace: "PAGINA" ou "TIPO PAGINA" (a página pode
+// ser um endereço segmentado S<segmento>:<deslocamento>), com uma coluna
+// opcional R (leitura), W (escrita) ou Z (escrita que cria a página,
+// preenchida com zeros sem ler o disco) e, por último, o atraso opcional
+// +N até o próximo acesso
+func parseLine(line string) (PageAccess, error) {
+	if len(line) >= maxLineLength {
+		return PageAccess{}, errLineTooLong
+	}
+	line = strings.TrimSpace(line)
+
+	// Linhas vazias e comentários são ignorados
+	if line == "" || strings.HasPrefix(line, "#") {
+		return PageAccess{}, errSkipLine
+	}
+
+	parts := strings.Fields(line)
+	var delay int64
+	if n := len(parts); n >= 2 && strings.HasPrefix(parts[n-1], "+") {
+		d, ok := parseDelay(parts[n-1][1:])
+		if !ok {
+			return PageAccess{}, errDelay
+		}
+		delay, parts = d, parts[:n-1]
+	}
+	write, zero := false, false
+	if n := len(parts); n >= 2 {
+		switch strings.ToUpper(parts[n-1]) {
+		case "W":
+			write = true
+			parts = parts[:n-1]
+		case "Z":
+			write, zero = true, true
+			parts = parts[:n-1]
+		case "R":
+			parts = parts[:n-1]
+		}
+	}
+
+	var pageID string
+	if len(parts) >= 2 {
+		pageID = parts[1]
+	} else {
+		pageID = parts[0]
+	}
+
+	if pageID[0] == 'S' {
+		access, err := parseSegmentAddress(pageID)
+		if err != nil {
+			return PageAccess{}, err
+		}
+		access.Write, access.Zero, access.Delay = write, zero, delay
+		return access, nil
+	}
+	if pageID[0] != 'I' && pageID[0] != 'D' {
+		return PageAccess{}, errPageKind
+	}
+	if len(pageID) < 2 {
+		return PageAccess{}, errPageNumber
+	}
+	return PageAccess{
+		PageID: pageID,
+		Type:   string(pageID[0]), // (I ou D)
+		Write:  write,
+		Zero:   zero,
+		Delay:  delay,
+	}, nil
+}
+
+// Segmentação com paginação: o endereço S<segmento>:<deslocamento>, com o
+// deslocamento em decimal ou 0x..., cai na página deslocamento/PAGE_SIZE
+// da tabela de páginas do segmento. A página é identificada pelo endereço
+// base dela no segmento (S3:0x1f40 -> S3:0x1000); o deslocamento fica em
+// Offset para conferir o limite do segmento. O tipo é sempre D.
+func parseSegmentAddress(token string) (PageAccess, error) {
+	segment, offset, ok := splitSegmentAddress(token)
+	if !ok {
+		return PageAccess{}, errSegment
+	}
+	return PageAccess{
+		PageID: segmentPageID(segment, offset/PAGE_SIZE),
+		Type:   "D",
+		Offset: uint32(offset),
+	}, nil
+}
+
+func splitSegmentAddress(token string) (segment, offset uint64, ok bool) {
+	number, position, found := strings.Cut(token[1:], ":")
+	segment, err := strconv.ParseUint(number, 10, 32)
+	if !found || err != nil {
+		return 0, 0, false
+	}
+	base := 10
+	if lower := strings.ToLower(position); strings.HasPrefix(lower, "0x") {
+		position, base = lower[2:], 16
+	}
+	offset, err = strconv.ParseUint(position, base, 32)
+	return segment, offset, err == nil
+}
+
+func segmentPageID(segment, page uint64) string {
+	return fmt.Sprintf("S%d:0x%x", segment, page*PAGE_SIZE)
+}
+
+// Segmento e página de um ID S<n>:0x<base>; ok false nos outros IDs
+func segmentPage(pageID string) (segment, page uint64, ok bool) {
+	if pageID == "" || pageID[0] != 'S' {
+		return 0, 0, false
+	}
+	segment, offset, ok := splitSegmentAddress(pageID)
+	return segment, offset / PAGE_SIZE, ok
+}
+
+// Tabela de limites de -segment-limits: entradas S0=0x4000 ou "0 16384"
+// separadas por vírgulas ou linhas, com # iniciando comentários
+func parseSegmentLimits(text string) (map[uint64]uint64, error) {
+	limits := make(map[uint64]uint64)
+	for _, line := range strings.Split(text, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		for _, entry := range strings.Split(line, ",") {
+			fields := strings.Fields(strings.ReplaceAll(entry, "=", " "))
+			if len(fields) == 0 {
+				continue
+			}
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("entrada inválida %q (use S<segmento>=<limite>)", strings.TrimSpace(entry))
+			}
+			segment, err := strconv.ParseUint(strings.TrimPrefix(fields[0], "S"), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("segmento inválido %q", fields[0])
+			}
+			limit, err := strconv.ParseUint(fields[1], 0, 64)
+			if err != nil {
+				return nil, fmt.Errorf("limite inválido %q", fields[1])
+			}
+			limits[segment] = limit
+		}
+	}
+	if len(limits) == 0 {
+		return nil, fmt.Errorf("nenhum segmento na tabela")
+	}
+	return limits, nil
+}
+
+// Forma normalizada da linha do acesso, aceita por parseLine
+func (a PageAccess) String() string {
+	line := a.PageID
+	if segment, _, ok := segmentPage(a.PageID); ok {
+		line = fmt.Sprintf("S%d:0x%x", segment, a.Offset)
+	}
+	switch {
+	case a.Zero:
+		line += " Z"
+	case a.Write:
+		line += " W"
+	}
+	if a.Delay != 0 {
+		line += " +" + strconv.FormatInt(a.Delay, 10)
+	}
+	return line
+}
+
+// Lê os acessos do trace texto em r; devolve o resumo da leitura
+func (s *Simulator) LoadAccesses(r io.Reader) (Diagnostics, error) {
+	return s.loadLines(newTraceScanner(r))
+}
+
+func (s *Simulator) loadLines(scanner traceLines) (Diagnostics, error) {
+	return s.addLoaded([]*loadedLines{readLines(scanner)}, scanner.normalizations())
+}
+
+// Acessos lidos de um trecho do trace, antes de entrarem no simulador.
+// Os números de linha dos avisos contam a partir do início do trecho.
+type loadedLines struct {
+	accesses []PageAccess
+	pages    map[string]bool
+	lines    int
+	invalid  int
+	reasons  map[string]int
+	warnings []lineWarning // só os primeiros rejectedLimit()
+	writes   int
+	err      error
+	stop     bool // a leitura do arquivo termina neste trecho (NUL ou erro)
+}
+
+type lineWarning struct {
+	line int
+	err  error
+	text string
+}
+
+const maxLoadWarnings = 10
+
+// Linhas rejeitadas guardadas em Diagnostics.Rejected: todas com -v
+func rejectedLimit() int {
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		return math.MaxInt
+	}
+	return maxLoadWarnings
+}
+
+func readLines(scanner traceLines) *loadedLines {
+	l := &loadedLines{pages: make(map[string]bool), reasons: make(map[string]int)}
+	limit := rejectedLimit()
+	for scanner.Scan() {
+		l.lines++
+		access, err := scanner.parse()
+		if err == errSkipLine {
+			continue
+		}
+		if err != nil {
+			l.invalid++
+			l.reasons[err.Error()]++
+			if len(l.warnings) < limit {
+				l.warnings = append(l.warnings, lineWarning{l.lines, err, strings.TrimSpace(scanner.Text())})
+			}
+			continue
+		}
+
+		l.accesses = append(l.accesses, access)
+		l.pages[access.PageID] = true
+		if access.Write {
+			l.writes++
+		}
+	}
+	l.err = scanner.Err()
+	return l
+}
+
+// Junta os trechos na ordem do arquivo, renumerando as linhas rejeitadas
+func (s *Simulator) addLoaded(chunks []*loadedLines, normalizations []string) (Diagnostics, error) {
+	d := Diagnostics{File: s.traceName, Format: "text", Normalizations: normalizations}
+	limit := rejectedLimit()
+	hexWarned := false
+
+	for _, l := range chunks {
+		for _, w := range l.warnings {
+			if len(d.Rejected) < limit {
+				d.Rejected = append(d.Rejected, RejectedLine{d.Lines + w.line, w.err.Error(), w.text})
+			}
+		}
+		for reason, n := range l.reasons {
+			if d.Reasons == nil {
+				d.Reasons = make(map[string]int)
+			}
+			d.Reasons[reason] += n
+		}
+		d.Invalid += l.invalid
+		d.Lines += l.lines
+		if s.segmentLimits != nil {
+			s.dropViolations(l)
+		}
+		d.Accesses += len(l.accesses)
+		s.accesses = append(s.accesses, l.accesses...)
+		for page := range l.pages {
+			s.distinctPages[page] = true
+		}
+		if page := hexLookingPage(l.pages, s.pageRadix); page != "" && !hexWarned {
+			logger.Warn("páginas com dígitos hexadecimais ficam sem número em decimal (use -page-radix 16)", "file", s.traceName, "page", page)
+			hexWarned = true
+		}
+		s.writeCount += l.writes
+
+		if l.err != nil {
+			return d, fmt.Errorf("erro ao ler arquivo: %v", l.err)
+		}
+		if l.stop {
+			break
+		}
+	}
+
+	if len(s.accesses) == 0 {
+		return d, fmt.Errorf("nenhum acesso válido encontrado no arquivo")
+	}
+
+	return d, nil
+}
+
+// Tira do trecho os acessos S<n>:<deslocamento> além do limite do
+// segmento (ou de segmento sem limite na tabela), contando-os como
+// violações de segmentação: o hardware os recusa antes da paginação.
+func (s *Simulator) dropViolations(l *loadedLines) {
+	kept := l.accesses[:0]
+	for _, access := range l.accesses {
+		segment, _, ok := segmentPage(access.PageID)
+		if limit, known := s.segmentLimits[segment]; ok && (!known || uint64(access.Offset) >= limit) {
+			if s.segmentViolations == nil {
+				s.segmentViolations = make(map[uint64]int)
+			}
+			s.segmentViolations[segment]++
+			continue
+		}
+		kept = append(kept, access)
+	}
+	if len(kept) == len(l.accesses) {
+		return
+	}
+	l.accesses = kept
+	l.pages, l.writes = make(map[string]bool), 0
+	for _, access := range kept {
+		l.pages[access.PageID] = true
+		if access.Write {
+			l.writes++
+		}
+	}
+}
+
+// Resumo da leitura de um trace, para quem usa o simulador como
+// biblioteca: contagens, linhas inválidas por motivo e as primeiras
+// rejeitadas. Os avisos da linha de comando saem dele (log).
+type Diagnostics struct {
+	File           string         `json:"file,omitempty"`
+	Format         string         `json:"format"` // text ou proto (as linhas são as mensagens)
+	Lines          int            `json:"lines"`
+	Accesses       int            `json:"accesses"`
+	Invalid        int            `json:"invalid"`
+	Reasons        map[string]int `json:"reasons,omitempty"`
+	Rejected       []RejectedLine `json:"rejected,omitempty"` // as primeiras maxLoadWarnings (todas com -v)
+	Normalizations []string       `json:"normalizations,omitempty"`
+	// -truncate-at-phase: o trace termina no acesso TruncatedAt, fim da fase Phase
+	TruncatedAt int `json:"truncated_at,omitempty"`
+	Phase       int `json:"truncated_phase,omitempty"`
+}
+
+type RejectedLine struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+	Text   string `json:"text"`
+}
+
+func (d *Diagnostics) reject(line int, err error, text string, limit int) {
+	d.Invalid++
+	if d.Reasons == nil {
+		d.Reasons = make(map[string]int)
+	}
+	d.Reasons[err.Error()]++
+	if len(d.Rejected) < limit {
+		d.Rejected = append(d.Rejected, RejectedLine{line, err.Error(), text})
+	}
+}
+
+// Linhas ou mensagens, conforme o formato
+func (d Diagnostics) unit() string {
+	if d.Format == "proto" {
+		return "mensagem"
+	}
+	return "linha"
+}
+
+func (d Diagnostics) String() string {
+	var b strings.Builder
+	if d.File != "" {
+		fmt.Fprintf(&b, "%s: ", d.File)
+	}
+	fmt.Fprintf(&b, "%d %ss lidas, %d acessos válidos, %d %ss inválidas", d.Lines, d.unit(), d.Accesses, d.Invalid, d.unit())
+	reasons := make([]string, 0, len(d.Reasons))
+	for reason := range d.Reasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Fprintf(&b, "\n  %s: %d", reason, d.Reasons[reason])
+	}
+	for _, r := range d.Rejected {
+		fmt.Fprintf(&b, "\n  %s %d (%s): %s", d.unit(), r.Line, r.Reason, r.Text)
+	}
+	if hidden := d.Invalid - len(d.Rejected); hidden > 0 {
+		fmt.Fprintf(&b, "\n  ... e mais %d %ss inválidas", hidden, d.unit())
+	}
+	if len(d.Normalizations) > 0 {
+		fmt.Fprintf(&b, "\n  normalizado: %s", strings.Join(d.Normalizations, "; "))
+	}
+	return b.String()
+}
+
+// Avisos da linha de comando sobre a leitura: as linhas rejeitadas (além
+// das maxLoadWarnings primeiras, só com -v), as normalizações e o resumo
+func (d Diagnostics) log() {
+	key := "line"
+	if d.Format == "proto" {
+		key = "message"
+	}
+	for k, r := range d.Rejected {
+		level := slog.LevelWarn
+		if k >= maxLoadWarnings {
+			level = slog.LevelDebug
+		}
+		logger.Log(context.Background(), level, d.unit()+" ignorada", "file", d.File,
+			key, r.Line, "reason", r.Reason, "text", r.Text)
+	}
+	if hidden := d.Invalid - len(d.Rejected); hidden > 0 {
+		logger.Warn("outras "+d.unit()+"s inválidas não mostradas (use -v)", "file", d.File, "count", hidden)
+	}
+	if len(d.Normalizations) > 0 {
+		logger.Info("arquivo normalizado", "file", d.File, "normalizations", strings.Join(d.Normalizations, "; "))
+	}
+	attrs := []any{"file", d.File, "lines", d.Lines, "accesses", d.Accesses, "invalid", d.Invalid}
+	if d.Invalid > 0 {
+		attrs = append(attrs, "reasons", d.Reasons)
+	}
+	logger.Info("arquivo processado", attrs...)
+}
+
+// Classes de página: as anônimas vão para a área de swap ao serem
+// substituídas; as mapeadas de arquivo só são gravadas se modificadas
+const (
+	classAnon = iota
+	classFile
+)
+
+var pageClassNames = [...]string{"anônima", "arquivo"}
+
+type ClassStats struct {
+	Faults    int
+	Evictions int
+	Writes    int // gravações no arquivo ou no swap
+}
+
+// Acessos por cor de página na última execução (-cache-size)
+type ColorStats struct {
+	Accesses   []int // acessos servidos pelos frames de cada cor
+	HotLoads   []int // carregamentos de páginas quentes em cada cor
+	Collisions int   // página quente carregada em cor que já tinha outra
+}
+
+// Número de cores de página da cache: páginas cujos frames têm a mesma cor
+// disputam os mesmos conjuntos da cache (0 sem -cache-size)
+func (s *Simulator) pageColors() int {
+	if s.cacheSize == 0 {
+		return 0
+	}
+	cacheSets := s.cacheSize / (s.cacheAssoc * s.cacheLine)
+	return max(1, cacheSets*s.cacheLine/PAGE_SIZE)
+}
+
+// Páginas quentes: acessadas mais vezes que a média das páginas do trace
+func (s *Simulator) hotPages() map[string]bool {
+	counts := make(map[string]int)
+	for _, access := range s.accesses {
+		counts[access.PageID]++
+	}
+	hot := make(map[string]bool)
+	for pageID, count := range counts {
+		if count*len(counts) > len(s.accesses) {
+			hot[pageID] = true
+		}
+	}
+	return hot
+}
+
+// Lê o arquivo de classes: cada linha tem uma página (D42) ou um intervalo
+// (D100-D199) seguido de "file" ou "anon". Páginas não listadas são anônimas.
+// Os números são lidos na base de s.pageRadix.
+func (s *Simulator) LoadPageClasses(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir arquivo %s: %v", filename, err)
+	}
+	defer file.Close()
+
+	s.fileRanges = nil
+	scanner := newTraceScanner(file)
+	lineCount := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		lineCount++
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Fields(line)
+		if len(parts) != 2 || (parts[1] != "file" && parts[1] != "anon") {
+			return fmt.Errorf("linha %d: formato inválido (use <página|início-fim> file|anon): %s", lineCount, line)
+		}
+
+		first, last, found := strings.Cut(parts[0], "-")
+		if !found {
+			last = first
+		}
+		start, okStart := pageNumber(first, s.pageRadix)
+		end, okEnd := pageNumber(last, s.pageRadix)
+		if !okStart || !okEnd || first[0] != last[0] || start > end {
+			return fmt.Errorf("linha %d: intervalo de páginas inválido: %s", lineCount, parts[0])
+		}
+
+		class := classAnon
+		if parts[1] == "file" {
+			class = classFile
+		}
+		s.fileRanges = append(s.fileRanges, pageRange{first[0], start, end, class})
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("erro ao ler arquivo: %v", err)
+	}
+	return nil
+}
+
+type pageRange struct {
+	prefix     byte
+	start, end uint64
+	class      int
+}
+
+// Classe da página; a última linha do arquivo de classes que a cobre vale
+func (s *Simulator) pageClass(pageID string) int {
+	number, ok := pageNumber(pageID, s.pageRadix)
+	class := classAnon
+	if !ok {
+		return class
+	}
+	for _, r := range s.fileRanges {
+		if pageID[0] == r.prefix && number >= r.start && number <= r.end {
+			class = r.class
+		}
+	}
+	return class
+}
+
+// Posições em que cada página é acessada no trace. Só depende do trace,
+// então é construído uma vez e compartilhado, sem alterações, por todas
+// as execuções do Ótimo (vários tamanhos de memória, desempate etc.).
+type NextUseIndex struct {
+	positions map[string][]int // page : posições em que é acessada
+	total     int
+}
+
+func newNextUseIndex(accesses []PageAccess) *NextUseIndex {
+	positions := make(map[string][]int)
+	for i, access := range accesses {
+		pageID := access.PageID
+		positions[pageID] = append(positions[pageID], i)
+	}
+	return &NextUseIndex{positions: positions, total: len(accesses)}
+}
+
+// Índice do trace carregado, guardado para as próximas execuções. Um
+// trace recarregado (ou um trecho dele) tem outro primeiro acesso ou outro
+// tamanho, e o índice é refeito.
+type nextUseCache struct {
+	mu    sync.Mutex
+	first *PageAccess
+	index *NextUseIndex
+}
+
+// Devolve o índice do Ótimo para os acessos carregados, construindo-o só
+// na primeira chamada; seguro para várias goroutines
+func (s *Simulator) BuildNextUseIndex() *NextUseIndex {
+	if s.nextUse == nil || len(s.accesses) == 0 {
+		return newNextUseIndex(s.accesses)
+	}
+	s.nextUse.mu.Lock()
+	defer s.nextUse.mu.Unlock()
+	cache := s.nextUse
+	if cache.index == nil || cache.first != &s.accesses[0] || cache.index.total != len(s.accesses) {
+		cache.first, cache.index = &s.accesses[0], newNextUseIndex(s.accesses)
+	}
+	return cache.index
+}
+
+// Algoritmo Ótimo: precisa conhecer toda a sequência de acessos
+type optimalPolicy struct {
+	frames   []*PageFrame
+	frameMap map[string]int // page : frame index
+	nextUse  *NextUseIndex
+	used     int
+	position int // índice do acesso atual
+
+	// Entre as páginas empatadas (nenhuma é usada de novo), substitui uma
+	// limpa para evitar a gravação em disco
+	preferClean bool
+}
+
+func newOptimalPolicy(totalFrames int, index *NextUseIndex) *optimalPolicy {
+	return &optimalPolicy{
+		frames:   make([]*PageFrame, totalFrames),
+		frameMap: make(map[string]int),
+		nextUse:  index,
+	}
+}
+
+func (o *optimalPolicy) Frames() []*PageFrame {
+	return o.frames
+}
+
+func (o *optimalPolicy) checkpointState() policyState {
+	return policyState{Frames: saveFrames(o.frames), Hand: o.position}
+}
+
+func (o *optimalPolicy) restoreState(st policyState) {
+	o.frameMap, o.used = restoreFrames(o.frames, st.Frames)
+	o.position = st.Hand
+}
+
+func (o *optimalPolicy) Repeat(pageID string, n int) bool {
+	o.position += n
+	return true
+}
+
+func (o *optimalPolicy) Access(pageID string) StepResult {
+	i := o.position
+	o.position++
+
+	if frameIdx, found := o.frameMap[pageID]; found {
+		// Hit
+		return StepResult{PageID: pageID, Hit: true, Frame: frameIdx}
+	}
+
+	// Page fault
+	if o.used < len(o.frames) {
+		o.frames[o.used] = &PageFrame{PageID: pageID, LoadCount: 1}
+		o.frameMap[pageID] = o.used
+		o.used++
+		return StepResult{PageID: pageID, Frame: o.used - 1}
+	}
+
+	farthestNextUse := -1
+	victimFrame := -1
+
+	for frameIdx, frame := range o.frames {
+		positions := o.nextUse.positions[frame.PageID]
+
+		searchIndex := sort.SearchInts(positions, i+1)
+
+		var nextPos int
+		if searchIndex == len(positions) {
+			// vitima
+			nextPos = o.nextUse.total
+		} else {
+			nextPos = positions[searchIndex]
+		}
+
+		// Só há empate quando nenhuma das páginas será usada de novo
+		tieOnClean := o.preferClean && nextPos == farthestNextUse &&
+			o.frames[victimFrame].Dirty && !frame.Dirty
+		if nextPos > farthestNextUse || tieOnClean {
+			farthestNextUse = nextPos
+			victimFrame = frameIdx
+		}
+
+		if nextPos == o.nextUse.total && !(o.preferClean && o.frames[victimFrame].Dirty) {
+			break
+		}
+	}
+
+	//remove vitima
+	frame := o.frames[victimFrame]
+	victimPage := frame.PageID
+	delete(o.frameMap, victimPage)
+
+	// add pagina
+	frame.PageID = pageID
+	frame.LoadCount++
+	o.frameMap[pageID] = victimFrame
+	return StepResult{PageID: pageID, Frame: victimFrame, Victim: victimPage}
+}
+
+func (s *Simulator) OptimalAlgorithm() int {
+	result := s.runOptimal()
+	s.keepStats(result)
+	return result.Faults
+}
+
+// Executa o algoritmo ótimo medindo à parte a construção do índice nextUse,
+// que em traces grandes pode custar tanto quanto a simulação. Os
+// observadores extra se somam aos registrados.
+func (s *Simulator) runOptimal(extra ...Observer) Result {
+	start := time.Now()
+	policy := newOptimalPolicy(s.totalFrames, s.BuildNextUseIndex())
+	indexTime := time.Since(start)
+	result := s.runPolicy(policy, append(s.observers[:len(s.observers):len(s.observers)], extra...)...)
+	result.Algorithm = "Ótimo"
+	result.IndexTime = indexTime
+	result.Elapsed += indexTime
+	return result
+}
+
+// Políticas que aplicam de uma vez n acertos seguidos na página que acabou
+// de ser acessada, com o mesmo efeito de n chamadas a Access. Devolve false
+// quando a configuração exige o caminho acesso a acesso.
+type repeatPolicy interface {
+	Repeat(pageID string, n int) bool
+}
+
+// Sequência de acessos seguidos à mesma página (-rle)
+type accessRun struct {
+	Start int
+	Count int
+	Write bool // algum acesso da sequência é escrita
+}
+
+// Agrupa os acessos consecutivos à mesma página
+func buildRuns(accesses []PageAccess) []accessRun {
+	var runs []accessRun
+	for i, access := range accesses {
+		if n := len(runs); n > 0 && accesses[i-1].PageID == access.PageID {
+			runs[n-1].Count++
+			runs[n-1].Write = runs[n-1].Write || access.Write
+			continue
+		}
+		runs = append(runs, accessRun{Start: i, Count: 1, Write: access.Write})
+	}
+	return runs
+}
+
+// Resultado de um único acesso processado por uma política
+type StepResult struct {
+	PageID string
+	Hit    bool
+	Frame  int      // frame que contém a página após o acesso
+	Victim string   // página substituída ("" se não houve substituição)
+	Spared []string // páginas que receberam segunda chance neste acesso
+	// A página substituída estava modificada e precisou ser gravada
+	WriteBack bool
+	// Páginas entre as quais a vítima foi sorteada (políticas aleatórias)
+	Candidates []string
+}
+
+// Explica em uma frase o que aconteceu no acesso
+func (r StepResult) Explain() string {
+	if r.Hit {
+		return fmt.Sprintf("A página %s já está no frame %d: hit, e o bit de referência é marcado.",
+			r.PageID, r.Frame)
+	}
+	if r.Victim == "" {
+		return fmt.Sprintf("A página %s não está na memória e o frame %d está vazio: falta de página sem substituição.",
+			r.PageID, r.Frame)
+	}
+	if len(r.Spared) == 0 {
+		return fmt.Sprintf("A página %s não está na memória; o ponteiro encontrou %s com R=0 no frame %d e a substituiu.",
+			r.PageID, r.Victim, r.Frame)
+	}
+	return fmt.Sprintf("A página %s não está na memória; %s recebeu(ram) segunda chance (R=1 -> 0) e %s, com R=0, foi substituída no frame %d.",
+		r.PageID, strings.Join(r.Spared, ", "), r.Victim, r.Frame)
+}
+
+// Política de substituição executada acesso a acesso, sem conhecer o futuro
+type ReplacementPolicy interface {
+	Access(pageID string) StepResult
+	Frames() []*PageFrame
+}
+
+type policyInfo struct {
+	Name  string                               // nome usado na linha de comando e no REPL
+	Label string                               // nome exibido nos relatórios
+	Title string                               // título da seção no relatório
+	New   func(s *Simulator) ReplacementPolicy // usa a configuração do simulador
+}
+
+// Implementada por políticas com estatísticas próprias para o relatório
+type policyReporter interface {
+	Report()
+}
+
+// Implementada por políticas que liberam frames sem pôr outra página no
+// lugar (Working Set): os frames ocupados também diminuem
+type residentPolicy interface {
+	Resident() int
+}
+
+// Implementada por políticas que possuem um ponteiro (relógio)
+type handPolicy interface {
+	Hand() int
+}
+
+// Políticas disponíveis para execução passo a passo
+var streamingPolicies = []policyInfo{
+	{"clock", "Relógio", "DO RELÓGIO", func(s *Simulator) ReplacementPolicy { return s.newClock() }},
+	{"secondchance", "FIFO 2ª chance", "FIFO COM SEGUNDA CHANCE", func(s *Simulator) ReplacementPolicy {
+		return newSecondChancePolicy(s.totalFrames)
+	}},
+	{"enhanced", "Relógio R/M", "DO RELÓGIO COM BIT M (SEGUNDA CHANCE MELHORADA)", func(s *Simulator) ReplacementPolicy {
+		return newEnhancedClockPolicy(s.totalFrames)
+	}},
+	{"wsclock", "WSClock", "WSCLOCK", func(s *Simulator) ReplacementPolicy {
+		return newWSClockPolicy(s.totalFrames, s.wsClockTau)
+	}},
+	{"workingset", "Working Set", "WORKING SET", func(s *Simulator) ReplacementPolicy {
+		return newWorkingSetPolicy(s.totalFrames, s.wsWindow)
+	}},
+	{"fifo", "FIFO", "FIFO", func(s *Simulator) ReplacementPolicy {
+		return newFIFOPolicy(s.totalFrames)
+	}},
+	{"lru", "LRU", "LRU (MENOS RECENTEMENTE USADA)", func(s *Simulator) ReplacementPolicy {
+		return newLRUPolicy(s.totalFrames)
+	}},
+	{"mfu", "MFU", "MFU (MAIS FREQUENTEMENTE USADA)", func(s *Simulator) ReplacementPolicy {
+		return newMFUPolicy(s.totalFrames)
+	}},
+	{"random", "Aleatório", "ALEATÓRIO", func(s *Simulator) ReplacementPolicy {
+		return newRandomPolicy(s.totalFrames, s.seed)
+	}},
+	{"randunref", "Aleatório NR", "ALEATÓRIO ENTRE NÃO REFERENCIADAS", func(s *Simulator) ReplacementPolicy {
+		return newRandomUnreferencedPolicy(s.totalFrames, s.seed)
+	}},
+	{"nru", "NRU", "NRU (NÃO USADA RECENTEMENTE)", func(s *Simulator) ReplacementPolicy {
+		return newNRUPolicy(s.totalFrames, s.nruInterval, s.seed)
+	}},
+	{"numa", "Relógio NUMA", "DO RELÓGIO COM NÓS NUMA", func(s *Simulator) ReplacementPolicy {
+		return newNUMAPolicy(s)
+	}},
+	{"setassoc", "Relógio por conjunto", "DO RELÓGIO ASSOCIATIVO POR CONJUNTO", func(s *Simulator) ReplacementPolicy {
+		return newSetAssociativePolicy(s)
+	}},
+	{"hyperbolic", "Hiperbólico", "HIPERBÓLICO", func(s *Simulator) ReplacementPolicy {
+		return newHyperbolicPolicy(s.totalFrames, s.hyperbolicSamples, s.seed)
+	}},
+	{"expr", "Expressão", "POR EXPRESSÃO (-policy-expr)", func(s *Simulator) ReplacementPolicy {
+		return newExprPolicy(s.totalFrames, s.policyExpr)
+	}},
+}
+
+// Verifica se o algoritmo foi selecionado com -algorithms
+func (s *Simulator) algorithmSelected(name string) bool {
+	for _, selected := range s.algorithms {
+		if selected == name {
+			return true
+		}
+	}
+	return false
+}
+
+func findPolicy(name string) (policyInfo, bool) {
+	for _, p := range streamingPolicies {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return policyInfo{}, false
+}
+
+func policyNames() []string {
+	names := make([]string, len(streamingPolicies))
+	for i, p := range streamingPolicies {
+		names[i] = p.Name
+	}
+	return names
+}
+
+type clockPolicy struct {
+	frames       []*PageFrame
+	pageToFrame  map[string]int
+	clockPointer int
+
+	// Variantes encontradas nos livros-texto (-clock-variant). O classic
+	// segue o relógio de Tanenbaum (Sistemas Operacionais Modernos): o
+	// ponteiro só anda ao passar por um frame na busca por vítima.
+	//
+	// advance segue a fila circular da segunda chance de Silberschatz
+	// (Fundamentos de Sistemas Operacionais), em que o ponteiro marca a
+	// página mais antiga e avança também ao ocupar um frame vazio. Como os
+	// frames só ficam vazios no início e são ocupados em ordem, o ponteiro
+	// volta ao frame 0 quando a memória enche e as faltas são sempre as do
+	// classic; muda apenas a posição exibida durante o preenchimento, que é
+	// onde as simulações à mão divergiam.
+	advanceOnFill bool
+	// restart não vem de um livro-texto: reproduz as listas de exercícios
+	// que recomeçam cada busca no frame 0, e muda as faltas
+	restartSweep bool
+
+	// Limpeza periódica dos bits R, como feita por uma interrupção de timer
+	clearInterval int
+	handClears    bool // false: apenas a interrupção limpa os bits
+	accessCount   int
+
+	// Relógio adaptativo: quanto maior a taxa de faltas recente, mais
+	// frames o ponteiro inspeciona por falta (como o daemon de paginação)
+	adaptive     bool
+	sweepMin     int
+	sweepMax     int
+	window       []bool // faltas nos últimos acessos (buffer circular)
+	windowFaults int
+
+	swept  int // frames inspecionados nas substituições
+	sweeps int
+
+	// Colocação ciente de cores: entre os frames vazios, ocupa um da cor
+	// com menos acessos até agora
+	colorLoad []int
+}
+
+func newClockPolicy(totalFrames int) *clockPolicy {
+	return &clockPolicy{
+		frames:      make([]*PageFrame, totalFrames),
+		pageToFrame: make(map[string]int),
+		handClears:  true,
+	}
+}
+
+// Relógio com as opções de linha de comando aplicadas
+func (s *Simulator) newClock() *clockPolicy {
+	c := newClockPolicy(s.totalFrames)
+	c.clearInterval = s.refClearInterval
+	c.handClears = !s.refClearOnlyTimer
+	if s.clockAdaptive {
+		c.adaptive = true
+		c.sweepMin = s.clockSweepMin
+		c.sweepMax = s.clockSweepMax
+		if c.sweepMax == 0 {
+			c.sweepMax = s.totalFrames
+		}
+		c.window = make([]bool, s.clockPressureWindow)
+	}
+	if colors := s.pageColors(); s.colorAware && colors > 0 {
+		c.colorLoad = make([]int, colors)
+	}
+	switch s.clockVariant {
+	case "advance":
+		c.advanceOnFill = true
+	case "restart":
+		c.restartSweep = true
+	}
+	return c
+}
+
+func (c *clockPolicy) clearReferenceBits() {
+	for _, frame := range c.frames {
+		if frame != nil {
+			frame.Referenced = false
+		}
+	}
+}
+
+func (c *clockPolicy) Frames() []*PageFrame {
+	return c.frames
+}
+
+func (c *clockPolicy) Hand() int {
+	return c.clockPointer
+}
+
+func (c *clockPolicy) checkpointState() policyState {
+	return policyState{Frames: saveFrames(c.frames), Hand: c.clockPointer, Count: c.accessCount,
+		Window: c.window, WindowFaults: c.windowFaults, Swept: c.swept, Sweeps: c.sweeps, ColorLoad: c.colorLoad}
+}
+
+func (c *clockPolicy) restoreState(st policyState) {
+	c.pageToFrame, _ = restoreFrames(c.frames, st.Frames)
+	c.clockPointer, c.accessCount = st.Hand, st.Count
+	copy(c.window, st.Window)
+	c.windowFaults, c.swept, c.sweeps = st.WindowFaults, st.Swept, st.Sweeps
+	copy(c.colorLoad, st.ColorLoad)
+}
+
+func (c *clockPolicy) Repeat(pageID string, n int) bool {
+	if c.adaptive {
+		return false // a janela de pressão registra cada acesso
+	}
+	frameIdx := c.pageToFrame[pageID]
+	if c.clearInterval > 0 {
+		// Uma limpeza acontece antes de cada acesso cujo contador é múltiplo
+		// do intervalo; o próprio acesso marca a página de novo
+		first := max(c.accessCount, 1)
+		boundary := (first + c.clearInterval - 1) / c.clearInterval * c.clearInterval
+		if boundary < c.accessCount+n {
+			c.clearReferenceBits()
+		}
+	}
+	c.accessCount += n
+	c.frames[frameIdx].Referenced = true
+	if c.colorLoad != nil {
+		c.colorLoad[frameIdx%len(c.colorLoad)] += n
+	}
+	return true
+}
+
+func (c *clockPolicy) Access(pageID string) StepResult {
+	result := c.access(pageID)
+	if c.adaptive {
+		c.recordPressure(!result.Hit)
+	}
+	return result
+}
+
+// Registra o acesso na janela usada para medir a pressão de memória
+func (c *clockPolicy) recordPressure(fault bool) {
+	idx := (c.accessCount - 1) % len(c.window)
+	if c.window[idx] {
+		c.windowFaults--
+	}
+	c.window[idx] = fault
+	if fault {
+		c.windowFaults++
+	}
+}
+
+// Frames que o ponteiro deve inspecionar nesta falta
+func (c *clockPolicy) targetSweep() int {
+	if !c.adaptive {
+		return 0
+	}
+	seen := min(c.accessCount-1, len(c.window))
+	if seen == 0 {
+		return c.sweepMin
+	}
+	pressure := float64(c.windowFaults) / float64(seen)
+	return c.sweepMin + int(math.Round(float64(c.sweepMax-c.sweepMin)*pressure))
+}
+
+// Média de frames inspecionados por substituição
+func (c *clockPolicy) AverageSweep() float64 {
+	if c.sweeps == 0 {
+		return 0
+	}
+	return float64(c.swept) / float64(c.sweeps)
+}
+
+func (c *clockPolicy) access(pageID string) StepResult {
+	if c.clearInterval > 0 && c.accessCount > 0 && c.accessCount%c.clearInterval == 0 {
+		c.clearReferenceBits()
+	}
+	c.accessCount++
+
+	// Verifica se a página já está na memória
+	if frameIndex, exists := c.pageToFrame[pageID]; exists {
+		// Hit - marca como referenciada
+		c.frames[frameIndex].Referenced = true
+		c.chargeColor(frameIndex)
+		return StepResult{PageID: pageID, Hit: true, Frame: frameIndex}
+	}
+
+	// Procura por um frame vazio primeiro
+	if c.colorLoad != nil {
+		if j := c.coolestFreeFrame(); j >= 0 {
+			c.frames[j] = &PageFrame{PageID: pageID, Referenced: true, LoadCount: 1}
+			c.pageToFrame[pageID] = j
+			c.chargeColor(j)
+			return StepResult{PageID: pageID, Frame: j}
+		}
+	}
+	for j := range c.frames {
+		if c.frames[j] == nil {
+			c.frames[j] = &PageFrame{
+				PageID:     pageID,
+				Referenced: true,
+				LoadCount:  1,
+			}
+			c.pageToFrame[pageID] = j
+			if c.advanceOnFill {
+				c.clockPointer = (j + 1) % len(c.frames)
+			}
+			return StepResult{PageID: pageID, Frame: j}
+		}
+	}
+
+	// Usa algoritmo do relógio para encontrar vítima
+	if c.restartSweep {
+		c.clockPointer = 0
+	}
+
+	target := c.targetSweep()
+	var spared []string
+	for scanned := 0; ; scanned++ {
+		frame := c.frames[c.clockPointer]
+		// Sem limpeza pelo ponteiro, uma volta completa com todos os bits
+		// marcados substitui o frame sob o ponteiro (como no FIFO)
+		if !frame.Referenced || (!c.handClears && scanned == len(c.frames)) {
+			// Encontrou vítima: o frame é reaproveitado para a nova página
+			victim := c.clockPointer
+			victimPage := frame.PageID
+			delete(c.pageToFrame, victimPage)
+
+			frame.PageID = pageID
+			frame.Referenced = true
+			frame.LoadCount++
+			c.pageToFrame[pageID] = victim
+			c.clockPointer = (c.clockPointer + 1) % len(c.frames)
+
+			// Sob pressão o ponteiro continua, envelhecendo mais frames
+			inspected := scanned + 1
+			for ; inspected < target; inspected++ {
+				c.frames[c.clockPointer].Referenced = false
+				c.clockPointer = (c.clockPointer + 1) % len(c.frames)
+			}
+			c.swept += inspected
+			c.sweeps++
+			c.chargeColor(victim)
+			return StepResult{PageID: pageID, Frame: victim, Victim: victimPage, Spared: spared}
+		}
+		// Dá segunda chance
+		if c.handClears {
+			spared = append(spared, frame.PageID)
+			frame.Referenced = false
+		}
+		c.clockPointer = (c.clockPointer + 1) % len(c.frames)
+	}
+}
+
+func (c *clockPolicy) chargeColor(frameIdx int) {
+	if c.colorLoad != nil {
+		c.colorLoad[frameIdx%len(c.colorLoad)]++
+	}
+}
+
+// Frame vazio da cor com menos acessos (-1 se a memória está cheia)
+func (c *clockPolicy) coolestFreeFrame() int {
+	best := -1
+	for j, frame := range c.frames {
+		if frame != nil {
+			continue
+		}
+		if best < 0 || c.colorLoad[j%len(c.colorLoad)] < c.colorLoad[best%len(c.colorLoad)] {
+			best = j
+		}
+	}
+	return best
+}
+
+// Segunda chance melhorada: o ponteiro classifica os frames pelo par
+// (R, M) e prefere (0, 0), que sai sem gravação. A primeira volta procura
+// um (0, 0) sem mexer nos bits; a segunda procura um (0, 1) desmarcando R
+// dos frames por onde passa. Se nenhuma das duas achar a vítima, todos os
+// bits R estão desmarcados e as voltas se repetem.
+type enhancedClockPolicy struct {
+	frames       []*PageFrame
+	pageToFrame  map[string]int
+	clockPointer int
+
+	cleanVictims int
+	dirtyVictims int
+}
+
+func newEnhancedClockPolicy(totalFrames int) *enhancedClockPolicy {
+	return &enhancedClockPolicy{
+		frames:      make([]*PageFrame, totalFrames),
+		pageToFrame: make(map[string]int),
+	}
+}
+
+func (e *enhancedClockPolicy) Frames() []*PageFrame {
+	return e.frames
+}
+
+func (e *enhancedClockPolicy) Hand() int {
+	return e.clockPointer
+}
+
+func (e *enhancedClockPolicy) Repeat(pageID string, n int) bool {
+	return true // o bit R já foi marcado pelo primeiro acesso
+}
+
+func (e *enhancedClockPolicy) Access(pageID string) StepResult {
+	if frameIdx, exists := e.pageToFrame[pageID]; exists {
+		e.frames[frameIdx].Referenced = true
+		return StepResult{PageID: pageID, Hit: true, Frame: frameIdx}
+	}
+
+	for j, frame := range e.frames {
+		if frame == nil {
+			e.frames[j] = &PageFrame{PageID: pageID, Referenced: true, LoadCount: 1}
+			e.pageToFrame[pageID] = j
+			return StepResult{PageID: pageID, Frame: j}
+		}
+	}
+
+	var spared []string
+	victim := -1
+	for victim == -1 {
+		for scanned := 0; scanned < len(e.frames) && victim == -1; scanned++ {
+			if frame := e.frames[e.clockPointer]; !frame.Referenced && !frame.Dirty {
+				victim = e.clockPointer
+			}
+			e.clockPointer = (e.clockPointer + 1) % len(e.frames)
+		}
+		for scanned := 0; scanned < len(e.frames) && victim == -1; scanned++ {
+			frame := e.frames[e.clockPointer]
+			if !frame.Referenced {
+				victim = e.clockPointer
+			} else {
+				spared = append(spared, frame.PageID)
+				frame.Referenced = false
+			}
+			e.clockPointer = (e.clockPointer + 1) % len(e.frames)
+		}
+	}
+
+	frame := e.frames[victim]
+	if frame.Dirty {
+		e.dirtyVictims++
+	} else {
+		e.cleanVictims++
+	}
+	victimPage := frame.PageID
+	delete(e.pageToFrame, victimPage)
+	frame.PageID = pageID
+	frame.Referenced = true
+	frame.LoadCount++
+	e.pageToFrame[pageID] = victim
+	return StepResult{PageID: pageID, Frame: victim, Victim: victimPage, Spared: spared}
+}
+
+func (e *enhancedClockPolicy) Report() {
+	fmt.Printf("Vítimas limpas (R=0 M=0): %d | modificadas (R=0 M=1): %d\n", e.cleanVictims, e.dirtyVictims)
+}
+
+// WSClock: o ponteiro do Relógio com o limite τ do Working Set, em
+// acessos. Um frame com R=1 recebe segunda chance; com R=0 e idade acima
+// de τ, sai se estiver limpo ou, se modificado, tem a gravação agendada e
+// o ponteiro segue. A gravação termina antes da volta seguinte, então
+// depois de uma volta com gravações agendadas sai a primeira página
+// gravada. Uma volta sem nenhuma agendada quer dizer que todas estão no
+// working set: sai a primeira limpa, ou a do ponteiro, com gravação.
+type wsClockPolicy struct {
+	frames       []*PageFrame
+	pageToFrame  map[string]int
+	clockPointer int
+	tau          int
+	now          int
+	lastUse      []int
+
+	scheduledWrites int
+}
+
+func newWSClockPolicy(totalFrames, tau int) *wsClockPolicy {
+	return &wsClockPolicy{
+		frames:      make([]*PageFrame, totalFrames),
+		pageToFrame: make(map[string]int),
+		tau:         tau,
+		lastUse:     make([]int, totalFrames),
+	}
+}
+
+func (w *wsClockPolicy) Frames() []*PageFrame {
+	return w.frames
+}
+
+func (w *wsClockPolicy) Hand() int {
+	return w.clockPointer
+}
+
+func (w *wsClockPolicy) Repeat(pageID string, n int) bool {
+	w.now += n
+	w.lastUse[w.pageToFrame[pageID]] = w.now
+	return true
+}
+
+func (w *wsClockPolicy) Access(pageID string) StepResult {
+	w.now++
+
+	if frameIdx, exists := w.pageToFrame[pageID]; exists {
+		w.frames[frameIdx].Referenced = true
+		w.lastUse[frameIdx] = w.now
+		return StepResult{PageID: pageID, Hit: true, Frame: frameIdx}
+	}
+
+	for j, frame := range w.frames {
+		if frame == nil {
+			w.frames[j] = &PageFrame{PageID: pageID, Referenced: true, LoadCount: 1}
+			w.pageToFrame[pageID] = j
+			w.lastUse[j] = w.now
+			return StepResult{PageID: pageID, Frame: j}
+		}
+	}
+
+	var spared []string
+	victim, clean, scheduled := -1, -1, 0
+	for scanned := 0; victim == -1; scanned++ {
+		if scanned == len(w.frames) && scheduled == 0 {
+			victim = w.clockPointer
+			if clean != -1 {
+				victim = clean
+			}
+			break
+		}
+		frame := w.frames[w.clockPointer]
+		switch {
+		case frame.Referenced:
+			spared = append(spared, frame.PageID)
+			frame.Referenced = false
+		case w.now-w.lastUse[w.clockPointer] <= w.tau:
+			// ainda no working set
+		case frame.Dirty:
+			frame.Dirty = false
+			scheduled++
+			w.scheduledWrites++
+		default:
+			victim = w.clockPointer
+		}
+		if clean == -1 && !frame.Dirty {
+			clean = w.clockPointer
+		}
+		w.clockPointer = (w.clockPointer + 1) % len(w.frames)
+	}
+
+	frame := w.frames[victim]
+	victimPage := frame.PageID
+	delete(w.pageToFrame, victimPage)
+	frame.PageID = pageID
+	frame.Referenced = true
+	frame.LoadCount++
+	w.pageToFrame[pageID] = victim
+	w.lastUse[victim] = w.now
+	w.clockPointer = (victim + 1) % len(w.frames)
+	return StepResult{PageID: pageID, Frame: victim, Victim: victimPage, Spared: spared}
+}
+
+func (w *wsClockPolicy) Report() {
+	fmt.Printf("τ: %d acessos | Gravações agendadas pelo ponteiro: %d\n", w.tau, w.scheduledWrites)
+}
+
+// Working Set: a página fica na memória enquanto foi usada nos últimos
+// window acessos e, ao sair da janela, seu frame é liberado, então os
+// frames ocupados acompanham o tamanho do working set. Com mais páginas
+// na janela que frames, a falta substitui a usada há mais tempo (LRU).
+// recent guarda o frame de cada acesso da janela: o que sai dela libera o
+// frame se a página não foi usada depois. O anel cresce com os acessos até
+// window+1 posições, então uma janela maior que o trace não ocupa mais
+// memória que ele.
+type workingSetPolicy struct {
+	frames      []*PageFrame
+	pageToFrame map[string]int
+	window      int
+	now         int
+	lastUse     []int
+	loads       []int // cargas dos frames liberados, para LoadCount
+	recent      []int // frame do acesso t em recent[t % (window+1)]
+	used        int
+
+	sizeSum  int // frames ocupados somados a cada acesso
+	peak     int
+	forced   int // substituições com a memória cheia
+	released int
+	writes   int // páginas modificadas gravadas ao serem liberadas
+}
+
+// Maior -ws-window aceita, bem acima do tamanho de qualquer trace
+const maxWSWindow = 1 << 30
+
+func newWorkingSetPolicy(totalFrames, window int) *workingSetPolicy {
+	return &workingSetPolicy{
+		frames:      make([]*PageFrame, totalFrames),
+		pageToFrame: make(map[string]int),
+		window:      window,
+		lastUse:     make([]int, totalFrames),
+		loads:       make([]int, totalFrames),
+		recent:      make([]int, 1),
+	}
+}
+
+func (w *workingSetPolicy) Frames() []*PageFrame {
+	return w.frames
+}
+
+func (w *workingSetPolicy) Resident() int {
+	return w.used
+}
+
+func (w *workingSetPolicy) Access(pageID string) StepResult {
+	w.now++
+	if w.now <= w.window {
+		w.recent = append(w.recent, 0)
+	}
+	slot := w.now % (w.window + 1)
+	if leaving := w.now - w.window - 1; leaving > 0 {
+		frameIdx := w.recent[slot]
+		if frame := w.frames[frameIdx]; frame != nil && w.lastUse[frameIdx] == leaving {
+			if frame.Dirty {
+				w.writes++
+			}
+			delete(w.pageToFrame, frame.PageID)
+			w.loads[frameIdx] = frame.LoadCount
+			w.frames[frameIdx] = nil
+			w.used--
+			w.released++
+		}
+	}
+
+	result := w.load(pageID)
+	w.lastUse[result.Frame] = w.now
+	w.recent[slot] = result.Frame
+	w.sizeSum += w.used
+	w.peak = max(w.peak, w.used)
+	return result
+}
+
+func (w *workingSetPolicy) load(pageID string) StepResult {
+	if frameIdx, exists := w.pageToFrame[pageID]; exists {
+		w.frames[frameIdx].Referenced = true
+		return StepResult{PageID: pageID, Hit: true, Frame: frameIdx}
+	}
+
+	for i, frame := range w.frames {
+		if frame == nil {
+			w.frames[i] = &PageFrame{PageID: pageID, Referenced: true, LoadCount: w.loads[i] + 1}
+			w.pageToFrame[pageID] = i
+			w.used++
+			return StepResult{PageID: pageID, Frame: i}
+		}
+	}
+
+	frameIdx := 0
+	for i := range w.frames {
+		if w.lastUse[i] < w.lastUse[frameIdx] {
+			frameIdx = i
+		}
+	}
+	w.forced++
+	frame := w.frames[frameIdx]
+	victimPage := frame.PageID
+	delete(w.pageToFrame, victimPage)
+	frame.PageID = pageID
+	frame.Referenced = true
+	frame.LoadCount++
+	w.pageToFrame[pageID] = frameIdx
+	return StepResult{PageID: pageID, Frame: frameIdx, Victim: victimPage}
+}
+
+func (w *workingSetPolicy) Report() {
+	average := 0.0
+	if w.now > 0 {
+		average = float64(w.sizeSum) / float64(w.now)
+	}
+	fmt.Printf("Janela Δ: %d acessos | Working set médio: %.1f páginas, pico %d de %d frames\n",
+		w.window, average, w.peak, len(w.frames))
+	fmt.Printf("Páginas liberadas ao sair da janela: %d (%d modificadas, gravadas) | Substituições com a memória cheia: %d\n",
+		w.released, w.writes, w.forced)
+}
+
+// FIFO com segunda chance usando uma fila explícita: a página mais antiga
+// com o bit R marcado volta para o fim da fila em vez de ser substituída.
+// Faz as mesmas escolhas do Relógio, cujo ponteiro percorre os frames na
+// mesma ordem da fila.
+type secondChancePolicy struct {
+	frames      []*PageFrame
+	pageToFrame map[string]int
+	queue       []int // índices dos frames, da página mais antiga à mais nova
+}
+
+// Implementada por políticas que mantêm as páginas em uma fila
+type queuePolicy interface {
+	Queue() []*PageFrame
+}
+
+func newSecondChancePolicy(totalFrames int) *secondChancePolicy {
+	return &secondChancePolicy{
+		frames:      make([]*PageFrame, totalFrames),
+		pageToFrame: make(map[string]int),
+	}
+}
+
+func (q *secondChancePolicy) Frames() []*PageFrame {
+	return q.frames
+}
+
+func (q *secondChancePolicy) Queue() []*PageFrame {
+	queue := make([]*PageFrame, len(q.queue))
+	for i, frameIdx := range q.queue {
+		queue[i] = q.frames[frameIdx]
+	}
+	return queue
+}
+
+func (q *secondChancePolicy) Repeat(pageID string, n int) bool {
+	return true // o bit R já foi marcado pelo primeiro acesso
+}
+
+func (q *secondChancePolicy) Access(pageID string) StepResult {
+	if frameIdx, exists := q.pageToFrame[pageID]; exists {
+		q.frames[frameIdx].Referenced = true
+		return StepResult{PageID: pageID, Hit: true, Frame: frameIdx}
+	}
+
+	if len(q.queue) < len(q.frames) {
+		frameIdx := len(q.queue)
+		q.frames[frameIdx] = &PageFrame{PageID: pageID, Referenced: true, LoadCount: 1}
+		q.pageToFrame[pageID] = frameIdx
+		q.queue = append(q.queue, frameIdx)
+		return StepResult{PageID: pageID, Frame: frameIdx}
+	}
+
+	var spared []string
+	for {
+		frameIdx := q.queue[0]
+		q.queue = q.queue[1:]
+		frame := q.frames[frameIdx]
+
+		if frame.Referenced {
+			// Segunda chance: volta para o fim da fila
+			frame.Referenced = false
+			spared = append(spared, frame.PageID)
+			q.queue = append(q.queue, frameIdx)
+			continue
+		}
+
+		victimPage := frame.PageID
+		delete(q.pageToFrame, victimPage)
+		frame.PageID = pageID
+		frame.Referenced = true
+		frame.LoadCount++
+		q.pageToFrame[pageID] = frameIdx
+		q.queue = append(q.queue, frameIdx)
+		return StepResult{PageID: pageID, Frame: frameIdx, Victim: victimPage, Spared: spared}
+	}
+}
+
+// FIFO: substitui a página carregada há mais tempo, sem olhar o bit R.
+// Os frames são preenchidos em ordem e, depois, cada falta substitui o
+// frame seguinte ao da anterior, então a fila é um índice circular.
+type fifoPolicy struct {
+	frames      []*PageFrame
+	pageToFrame map[string]int
+	next        int // frame da página mais antiga (ou o próximo vazio)
+	used        int
+}
+
+func newFIFOPolicy(totalFrames int) *fifoPolicy {
+	return &fifoPolicy{
+		frames:      make([]*PageFrame, totalFrames),
+		pageToFrame: make(map[string]int),
+	}
+}
+
+func (f *fifoPolicy) Frames() []*PageFrame {
+	return f.frames
+}
+
+func (f *fifoPolicy) checkpointState() policyState {
+	return policyState{Frames: saveFrames(f.frames), Hand: f.next}
+}
+
+func (f *fifoPolicy) restoreState(st policyState) {
+	f.pageToFrame, f.used = restoreFrames(f.frames, st.Frames)
+	f.next = st.Hand
+}
+
+func (f *fifoPolicy) Queue() []*PageFrame {
+	queue := make([]*PageFrame, 0, f.used)
+	for k := range f.used {
+		queue = append(queue, f.frames[(f.next+k)%f.used])
+	}
+	return queue
+}
+
+func (f *fifoPolicy) Repeat(pageID string, n int) bool {
+	return true // acertos não mudam a fila
+}
+
+func (f *fifoPolicy) Access(pageID string) StepResult {
+	if frameIdx, exists := f.pageToFrame[pageID]; exists {
+		f.frames[frameIdx].Referenced = true
+		return StepResult{PageID: pageID, Hit: true, Frame: frameIdx}
+	}
+
+	frameIdx := f.next
+	f.next = (f.next + 1) % len(f.frames)
+	f.pageToFrame[pageID] = frameIdx
+	if f.used < len(f.frames) {
+		f.used++
+		f.frames[frameIdx] = &PageFrame{PageID: pageID, Referenced: true, LoadCount: 1}
+		return StepResult{PageID: pageID, Frame: frameIdx}
+	}
+
+	frame := f.frames[frameIdx]
+	victimPage := frame.PageID
+	delete(f.pageToFrame, victimPage)
+	frame.PageID = pageID
+	frame.Referenced = true
+	frame.LoadCount++
+	return StepResult{PageID: pageID, Frame: frameIdx, Victim: victimPage}
+}
+
+// LRU exato: os frames formam uma lista duplamente encadeada, da página
+// usada mais recentemente à menos recente. Um acerto leva o frame para o
+// início e a vítima é sempre o fim, ambos em O(1).
+type lruPolicy struct {
+	frames      []*PageFrame
+	pageToFrame map[string]int
+	newer       []int // vizinho mais recente de cada frame; -1 no início
+	older       []int // vizinho menos recente; -1 no fim
+	head, tail  int   // frames usados mais e menos recentemente; -1 sem páginas
+	used        int   // frames ocupados, preenchidos em ordem
+}
+
+func newLRUPolicy(totalFrames int) *lruPolicy {
+	return &lruPolicy{
+		frames:      make([]*PageFrame, totalFrames),
+		pageToFrame: make(map[string]int),
+		newer:       make([]int, totalFrames),
+		older:       make([]int, totalFrames),
+		head:        -1,
+		tail:        -1,
+	}
+}
+
+func (l *lruPolicy) Frames() []*PageFrame {
+	return l.frames
+}
+
+func (l *lruPolicy) checkpointState() policyState {
+	return policyState{Frames: saveFrames(l.frames), Newer: l.newer, Older: l.older, Head: l.head, Tail: l.tail}
+}
+
+func (l *lruPolicy) restoreState(st policyState) {
+	l.pageToFrame, l.used = restoreFrames(l.frames, st.Frames)
+	copy(l.newer, st.Newer)
+	copy(l.older, st.Older)
+	l.head, l.tail = st.Head, st.Tail
+}
+
+// Da página usada há mais tempo à mais recente, como a fila do FIFO
+func (l *lruPolicy) Queue() []*PageFrame {
+	var queue []*PageFrame
+	for frameIdx := l.tail; frameIdx >= 0; frameIdx = l.newer[frameIdx] {
+		queue = append(queue, l.frames[frameIdx])
+	}
+	return queue
+}
+
+func (l *lruPolicy) Repeat(pageID string, n int) bool {
+	return true // a página já está no início da lista
+}
+
+func (l *lruPolicy) unlink(frameIdx int) {
+	if l.newer[frameIdx] >= 0 {
+		l.older[l.newer[frameIdx]] = l.older[frameIdx]
+	} else {
+		l.head = l.older[frameIdx]
+	}
+	if l.older[frameIdx] >= 0 {
+		l.newer[l.older[frameIdx]] = l.newer[frameIdx]
+	} else {
+		l.tail = l.newer[frameIdx]
+	}
+}
+
+func (l *lruPolicy) pushFront(frameIdx int) {
+	l.newer[frameIdx], l.older[frameIdx] = -1, l.head
+	if l.head >= 0 {
+		l.newer[l.head] = frameIdx
+	} else {
+		l.tail = frameIdx
+	}
+	l.head = frameIdx
+}
+
+func (l *lruPolicy) Access(pageID string) StepResult {
+	if frameIdx, exists := l.pageToFrame[pageID]; exists {
+		l.frames[frameIdx].Referenced = true
+		if frameIdx != l.head {
+			l.unlink(frameIdx)
+			l.pushFront(frameIdx)
+		}
+		return StepResult{PageID: pageID, Hit: true, Frame: frameIdx}
+	}
+
+	if l.used < len(l.frames) {
+		frameIdx := l.used
+		l.used++
+		l.frames[frameIdx] = &PageFrame{PageID: pageID, Referenced: true, LoadCount: 1}
+		l.pageToFrame[pageID] = frameIdx
+		l.pushFront(frameIdx)
+		return StepResult{PageID: pageID, Frame: frameIdx}
+	}
+
+	frameIdx := l.tail
+	frame := l.frames[frameIdx]
+	victimPage := frame.PageID
+	delete(l.pageToFrame, victimPage)
+	frame.PageID = pageID
+	frame.Referenced = true
+	frame.LoadCount++
+	l.pageToFrame[pageID] = frameIdx
+	l.unlink(frameIdx)
+	l.pushFront(frameIdx)
+	return StepResult{PageID: pageID, Frame: frameIdx, Victim: victimPage}
+}
+
+// MFU: substitui a página mais usada desde a carga, supondo que as pouco
+// usadas acabaram de chegar e ainda serão necessárias; no empate sai a
+// usada há mais tempo. Serve de contraste com o LFU (-policy-expr
+// "idle - accesses*1000000"): as páginas quentes são justamente as que o
+// MFU tira da memória.
+type mfuPolicy struct {
+	frames      []*PageFrame
+	pageToFrame map[string]int
+	now         int
+	uses        []int // acessos desde a carga
+	lastUse     []int
+}
+
+func newMFUPolicy(totalFrames int) *mfuPolicy {
+	return &mfuPolicy{
+		frames:      make([]*PageFrame, totalFrames),
+		pageToFrame: make(map[string]int),
+		uses:        make([]int, totalFrames),
+		lastUse:     make([]int, totalFrames),
+	}
+}
+
+func (m *mfuPolicy) Frames() []*PageFrame {
+	return m.frames
+}
+
+func (m *mfuPolicy) Repeat(pageID string, n int) bool {
+	frameIdx := m.pageToFrame[pageID]
+	m.now += n
+	m.uses[frameIdx] += n
+	m.lastUse[frameIdx] = m.now
+	return true
+}
+
+func (m *mfuPolicy) Access(pageID string) StepResult {
+	m.now++
+
+	if frameIdx, exists := m.pageToFrame[pageID]; exists {
+		m.frames[frameIdx].Referenced = true
+		m.uses[frameIdx]++
+		m.lastUse[frameIdx] = m.now
+		return StepResult{PageID: pageID, Hit: true, Frame: frameIdx}
+	}
+
+	frameIdx := -1
+	var victimPage string
+	for i, frame := range m.frames {
+		if frame == nil {
+			frameIdx = i
+			m.frames[i] = &PageFrame{PageID: pageID}
+			break
+		}
+	}
+	if frameIdx == -1 {
+		frameIdx = 0
+		for i := range m.frames {
+			if m.uses[i] > m.uses[frameIdx] || m.uses[i] == m.uses[frameIdx] && m.lastUse[i] < m.lastUse[frameIdx] {
+				frameIdx = i
+			}
+		}
+		victimPage = m.frames[frameIdx].PageID
+		delete(m.pageToFrame, victimPage)
+		m.frames[frameIdx].PageID = pageID
+	}
+
+	m.frames[frameIdx].Referenced = true
+	m.frames[frameIdx].LoadCount++
+	m.pageToFrame[pageID] = frameIdx
+	m.uses[frameIdx], m.lastUse[frameIdx] = 1, m.now
+	return StepResult{PageID: pageID, Frame: frameIdx, Victim: victimPage}
+}
+
+// Aleatório: sorteia a vítima entre todos os frames, sem olhar bits nem
+// histórico. Serve de base de comparação; com a mesma -seed as escolhas
+// se repetem e -trials mostra a variação entre sementes.
+type randomPolicy struct {
+	frames      []*PageFrame
+	pageToFrame map[string]int
+	rng         *rand.Rand
+}
+
+func newRandomPolicy(totalFrames int, seed int64) *randomPolicy {
+	return &randomPolicy{
+		frames:      make([]*PageFrame, totalFrames),
+		pageToFrame: make(map[string]int),
+		rng:         rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (r *randomPolicy) Frames() []*PageFrame {
+	return r.frames
+}
+
+func (r *randomPolicy) Repeat(pageID string, n int) bool {
+	return true // acertos não mudam o sorteio
+}
+
+func (r *randomPolicy) Access(pageID string) StepResult {
+	if frameIdx, exists := r.pageToFrame[pageID]; exists {
+		r.frames[frameIdx].Referenced = true
+		return StepResult{PageID: pageID, Hit: true, Frame: frameIdx}
+	}
+
+	for i, frame := range r.frames {
+		if frame == nil {
+			r.frames[i] = &PageFrame{PageID: pageID, Referenced: true, LoadCount: 1}
+			r.pageToFrame[pageID] = i
+			return StepResult{PageID: pageID, Frame: i}
+		}
+	}
+
+	frameIdx := r.rng.Intn(len(r.frames))
+	frame := r.frames[frameIdx]
+	victimPage := frame.PageID
+	delete(r.pageToFrame, victimPage)
+	frame.PageID = pageID
+	frame.Referenced = true
+	frame.LoadCount++
+	r.pageToFrame[pageID] = frameIdx
+	return StepResult{PageID: pageID, Frame: frameIdx, Victim: victimPage}
+}
+
+// Sorteia a vítima entre os frames com o bit R desmarcado. Se todos
+// estiverem marcados, desmarca todos e sorteia entre todos os frames.
+type randomUnreferencedPolicy struct {
+	frames      []*PageFrame
+	pageToFrame map[string]int
+	rng         *rand.Rand
+}
+
+func newRandomUnreferencedPolicy(totalFrames int, seed int64) *randomUnreferencedPolicy {
+	return &randomUnreferencedPolicy{
+		frames:      make([]*PageFrame, totalFrames),
+		pageToFrame: make(map[string]int),
+		rng:         rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (r *randomUnreferencedPolicy) Frames() []*PageFrame {
+	return r.frames
+}
+
+func (r *randomUnreferencedPolicy) Repeat(pageID string, n int) bool {
+	return true // o bit R já foi marcado pelo primeiro acesso
+}
+
+func (r *randomUnreferencedPolicy) Access(pageID string) StepResult {
+	if frameIdx, exists := r.pageToFrame[pageID]; exists {
+		r.frames[frameIdx].Referenced = true
+		return StepResult{PageID: pageID, Hit: true, Frame: frameIdx}
+	}
+
+	for i, frame := range r.frames {
+		if frame == nil {
+			r.frames[i] = &PageFrame{PageID: pageID, Referenced: true, LoadCount: 1}
+			r.pageToFrame[pageID] = i
+			return StepResult{PageID: pageID, Frame: i}
+		}
+	}
+
+	var candidates []int
+	for i, frame := range r.frames {
+		if !frame.Referenced {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		for i, frame := range r.frames {
+			frame.Referenced = false
+			candidates = append(candidates, i)
+		}
+	}
+
+	names := make([]string, len(candidates))
+	for i, frameIdx := range candidates {
+		names[i] = r.frames[frameIdx].PageID
+	}
+
+	frameIdx := candidates[r.rng.Intn(len(candidates))]
+	frame := r.frames[frameIdx]
+	victimPage := frame.PageID
+	delete(r.pageToFrame, victimPage)
+	frame.PageID = pageID
+	frame.Referenced = true
+	frame.LoadCount++
+	r.pageToFrame[pageID] = frameIdx
+	return StepResult{PageID: pageID, Frame: frameIdx, Victim: victimPage, Candidates: names}
+}
+
+// NRU: as páginas caem em quatro classes pelos bits R e M (0: R=0 M=0,
+// 1: R=0 M=1, 2: R=1 M=0, 3: R=1 M=1) e a vítima é sorteada na classe mais
+// baixa com alguma página. A cada interval acessos o bit R de todas é
+// desmarcado, como na interrupção do relógio; o M fica até a gravação.
+type nruPolicy struct {
+	frames      []*PageFrame
+	pageToFrame map[string]int
+	interval    int
+	now         int
+	rng         *rand.Rand
+
+	victims [4]int // vítimas tiradas de cada classe
+	resets  int
+}
+
+func newNRUPolicy(totalFrames, interval int, seed int64) *nruPolicy {
+	return &nruPolicy{
+		frames:      make([]*PageFrame, totalFrames),
+		pageToFrame: make(map[string]int),
+		interval:    interval,
+		rng:         rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (n *nruPolicy) Frames() []*PageFrame {
+	return n.frames
+}
+
+func nruClass(frame *PageFrame) int {
+	class := 0
+	if frame.Referenced {
+		class += 2
+	}
+	if frame.Dirty {
+		class++
+	}
+	return class
+}
+
+func (n *nruPolicy) Repeat(pageID string, count int) bool {
+	// Uma limpeza do bit R no meio da sequência vai pelo caminho normal
+	if n.interval > 0 && (n.now+count-1)/n.interval > (n.now-1)/n.interval {
+		return false
+	}
+	n.now += count
+	return true
+}
+
+func (n *nruPolicy) Access(pageID string) StepResult {
+	if n.interval > 0 && n.now > 0 && n.now%n.interval == 0 {
+		for _, frame := range n.frames {
+			if frame != nil {
+				frame.Referenced = false
+			}
+		}
+		n.resets++
+	}
+	n.now++
+
+	if frameIdx, exists := n.pageToFrame[pageID]; exists {
+		n.frames[frameIdx].Referenced = true
+		return StepResult{PageID: pageID, Hit: true, Frame: frameIdx}
+	}
+
+	for i, frame := range n.frames {
+		if frame == nil {
+			n.frames[i] = &PageFrame{PageID: pageID, Referenced: true, LoadCount: 1}
+			n.pageToFrame[pageID] = i
+			return StepResult{PageID: pageID, Frame: i}
+		}
+	}
+
+	lowest := 4
+	var candidates []int
+	for i, frame := range n.frames {
+		switch class := nruClass(frame); {
+		case class < lowest:
+			lowest, candidates = class, append(candidates[:0], i)
+		case class == lowest:
+			candidates = append(candidates, i)
+		}
+	}
+	n.victims[lowest]++
+
+	names := make([]string, len(candidates))
+	for i, frameIdx := range candidates {
+		names[i] = n.frames[frameIdx].PageID
+	}
+
+	frameIdx := candidates[n.rng.Intn(len(candidates))]
+	frame := n.frames[frameIdx]
+	victimPage := frame.PageID
+	delete(n.pageToFrame, victimPage)
+	frame.PageID = pageID
+	frame.Referenced = true
+	frame.LoadCount++
+	n.pageToFrame[pageID] = frameIdx
+	return StepResult{PageID: pageID, Frame: frameIdx, Victim: victimPage, Candidates: names}
+}
+
+func (n *nruPolicy) Report() {
+	fmt.Printf("Bit R desmarcado a cada %d acessos (%d vezes)\n", n.interval, n.resets)
+	fmt.Printf("Vítimas por classe: 0 (R=0 M=0) %d | 1 (R=0 M=1) %d | 2 (R=1 M=0) %d | 3 (R=1 M=1) %d\n",
+		n.victims[0], n.victims[1], n.victims[2], n.victims[3])
+}
+
+// Relógio com os frames divididos entre nós NUMA. A colocação decide em
+// que nó uma página que faltou deve ficar; se o nó estiver cheio e outro
+// tiver frames livres, a página transborda para ele. Páginas nunca migram
+// entre nós depois de carregadas (migração está fora do escopo).
+type numaPolicy struct {
+	frames      []*PageFrame
+	pageToFrame map[string]int
+	bounds      []int // frames do nó n: [bounds[n], bounds[n+1])
+	hands       []int // ponteiro de cada nó (substituição local)
+	globalHand  int
+	latency     []int
+	placement   string // type, rr ou first
+	local       bool   // substituição restrita ao nó escolhido
+	nextNode    int
+	homeNode    map[string]int // nó da primeira carga (placement first)
+
+	served []int // acessos atendidos por nó
+	spills int
+}
+
+func newNUMAPolicy(s *Simulator) *numaPolicy {
+	nodes := min(s.numaNodes, s.totalFrames)
+	bounds := make([]int, nodes+1)
+	for n := 0; n <= nodes; n++ {
+		bounds[n] = n * s.totalFrames / nodes
+	}
+	hands := make([]int, nodes)
+	copy(hands, bounds)
+
+	latency := make([]int, nodes)
+	for n := range latency {
+		if n < len(s.numaLatency) {
+			latency[n] = s.numaLatency[n]
+		} else {
+			latency[n] = s.numaLatency[len(s.numaLatency)-1]
+		}
+	}
+
+	return &numaPolicy{
+		frames:      make([]*PageFrame, s.totalFrames),
+		pageToFrame: make(map[string]int),
+		bounds:      bounds,
+		hands:       hands,
+		latency:     latency,
+		placement:   s.numaPlacement,
+		local:       s.numaLocal,
+		homeNode:    make(map[string]int),
+		served:      make([]int, nodes),
+	}
+}
+
+func (n *numaPolicy) Frames() []*PageFrame {
+	return n.frames
+}
+
+func (n *numaPolicy) nodeOf(frameIdx int) int {
+	return sort.SearchInts(n.bounds, frameIdx+1) - 1
+}
+
+// Nó preferido para a página que faltou
+func (n *numaPolicy) preferredNode(pageID string) int {
+	nodes := len(n.served)
+	switch n.placement {
+	case "rr":
+		node := n.nextNode
+		n.nextNode = (n.nextNode + 1) % nodes
+		return node
+	case "first":
+		if node, ok := n.homeNode[pageID]; ok {
+			return node
+		}
+		node := n.nextNode
+		n.nextNode = (n.nextNode + 1) % nodes
+		n.homeNode[pageID] = node
+		return node
+	default:
+		// Instruções no nó 0 e dados no nó 1
+		if pageID[0] == 'I' {
+			return 0
+		}
+		return 1 % nodes
+	}
+}
+
+func (n *numaPolicy) freeFrame(node int) int {
+	for i := n.bounds[node]; i < n.bounds[node+1]; i++ {
+		if n.frames[i] == nil {
+			return i
+		}
+	}
+	return -1
+}
+
+// Segunda chance sobre os frames [lo, hi) a partir de *hand; devolve o
+// frame da vítima e as páginas poupadas
+func clockSweep(frames []*PageFrame, lo, hi int, hand *int) (int, []string) {
+	var spared []string
+	for {
+		frame := frames[*hand]
+		victim := *hand
+		*hand++
+		if *hand == hi {
+			*hand = lo
+		}
+		if !frame.Referenced {
+			return victim, spared
+		}
+		spared = append(spared, frame.PageID)
+		frame.Referenced = false
+	}
+}
+
+func (n *numaPolicy) Repeat(pageID string, count int) bool {
+	n.served[n.nodeOf(n.pageToFrame[pageID])] += count
+	return true
+}
+
+func (n *numaPolicy) Access(pageID string) StepResult {
+	if frameIdx, exists := n.pageToFrame[pageID]; exists {
+		n.frames[frameIdx].Referenced = true
+		n.served[n.nodeOf(frameIdx)]++
+		return StepResult{PageID: pageID, Hit: true, Frame: frameIdx}
+	}
+
+	node := n.preferredNode(pageID)
+	frameIdx := n.freeFrame(node)
+	if frameIdx == -1 {
+		for other := range n.served {
+			if frameIdx = n.freeFrame(other); frameIdx != -1 {
+				n.spills++
+				break
+			}
+		}
+	}
+
+	result := StepResult{PageID: pageID}
+	if frameIdx != -1 {
+		n.frames[frameIdx] = &PageFrame{PageID: pageID}
+	} else {
+		if n.local {
+			frameIdx, result.Spared = clockSweep(n.frames, n.bounds[node], n.bounds[node+1], &n.hands[node])
+		} else {
+			frameIdx, result.Spared = clockSweep(n.frames, 0, len(n.frames), &n.globalHand)
+		}
+		result.Victim = n.frames[frameIdx].PageID
+		delete(n.pageToFrame, result.Victim)
+		n.frames[frameIdx].PageID = pageID
+	}
+
+	frame := n.frames[frameIdx]
+	frame.Referenced = true
+	frame.LoadCount++
+	n.pageToFrame[pageID] = frameIdx
+	n.served[n.nodeOf(frameIdx)]++
+	result.Frame = frameIdx
+	return result
+}
+
+func (n *numaPolicy) Report() {
+	replacement := "global"
+	if n.local {
+		replacement = "local ao nó"
+	}
+	fmt.Printf("Colocação: %s | Substituição: %s | Transbordos para outro nó: %d\n",
+		n.placement, replacement, n.spills)
+
+	total, cost := 0, 0
+	for node, served := range n.served {
+		total += served
+		cost += served * n.latency[node]
+	}
+	fmt.Printf("%4s %8s %10s %12s %10s\n", "Nó", "Frames", "Ocupados", "Acessos", "Latência")
+	for node, served := range n.served {
+		occupied := 0
+		for i := n.bounds[node]; i < n.bounds[node+1]; i++ {
+			if n.frames[i] != nil {
+				occupied++
+			}
+		}
+		share := 0.0
+		if total > 0 {
+			share = float64(served) / float64(total) * 100
+		}
+		fmt.Printf("%4d %8d %10d %11.2f%% %8dns\n", node, n.bounds[node+1]-n.bounds[node],
+			occupied, share, n.latency[node])
+	}
+	if total > 0 {
+		fmt.Printf("Custo médio por acesso: %.2f ns\n", float64(cost)/float64(total))
+	}
+}
+
+// Colocação associativa por conjunto, como em uma cache: a página só pode
+// ocupar os frames do conjunto (número da página mod conjuntos) e a
+// substituição é feita pelo relógio dentro do conjunto
+type setAssociativePolicy struct {
+	frames      []*PageFrame
+	pageToFrame map[string]int
+	bounds      []int // frames do conjunto c: [bounds[c], bounds[c+1])
+	hands       []int
+	used        []bool
+	hashed      map[string]bool // páginas sem número, distribuídas por hash
+	faults      int
+	sim         *Simulator
+}
+
+func newSetAssociativePolicy(s *Simulator) *setAssociativePolicy {
+	sets := s.sets
+	if s.associativity > 0 {
+		sets = (s.totalFrames + s.associativity - 1) / s.associativity
+	}
+	sets = min(sets, s.totalFrames)
+	bounds := make([]int, sets+1)
+	for c := 0; c <= sets; c++ {
+		bounds[c] = c * s.totalFrames / sets
+	}
+	hands := make([]int, sets)
+	copy(hands, bounds)
+
+	return &setAssociativePolicy{
+		frames:      make([]*PageFrame, s.totalFrames),
+		pageToFrame: make(map[string]int),
+		bounds:      bounds,
+		hands:       hands,
+		used:        make([]bool, sets),
+		hashed:      make(map[string]bool),
+		sim:         s,
+	}
+}
+
+func (a *setAssociativePolicy) Frames() []*PageFrame {
+	return a.frames
+}
+
+func (a *setAssociativePolicy) setOf(pageID string) int {
+	sets := uint64(len(a.hands))
+	if number, ok := pageNumber(pageID, a.sim.pageRadix); ok {
+		return int(number % sets)
+	}
+	a.hashed[pageID] = true
+	h := fnv.New64a()
+	h.Write([]byte(pageID))
+	return int(h.Sum64() % sets)
+}
+
+func (a *setAssociativePolicy) Repeat(pageID string, n int) bool {
+	return true // o bit R já foi marcado pelo primeiro acesso
+}
+
+func (a *setAssociativePolicy) Access(pageID string) StepResult {
+	if frameIdx, exists := a.pageToFrame[pageID]; exists {
+		a.frames[frameIdx].Referenced = true
+		return StepResult{PageID: pageID, Hit: true, Frame: frameIdx}
+	}
+
+	a.faults++
+	set := a.setOf(pageID)
+	a.used[set] = true
+	result := StepResult{PageID: pageID, Frame: -1}
+	for i := a.bounds[set]; i < a.bounds[set+1]; i++ {
+		if a.frames[i] == nil {
+			a.frames[i] = &PageFrame{PageID: pageID}
+			result.Frame = i
+			break
+		}
+	}
+	if result.Frame == -1 {
+		result.Frame, result.Spared = clockSweep(a.frames, a.bounds[set], a.bounds[set+1], &a.hands[set])
+		result.Victim = a.frames[result.Frame].PageID
+		delete(a.pageToFrame, result.Victim)
+		a.frames[result.Frame].PageID = pageID
+	}
+
+	frame := a.frames[result.Frame]
+	frame.Referenced = true
+	frame.LoadCount++
+	a.pageToFrame[pageID] = result.Frame
+	return result
+}
+
+func (a *setAssociativePolicy) Report() {
+	sets := len(a.hands)
+	unused := 0
+	for _, used := range a.used {
+		if !used {
+			unused++
+		}
+	}
+	fmt.Printf("Conjuntos: %d | Frames por conjunto: %d a %d | Conjuntos sem páginas: %d\n",
+		sets, a.totalFramesIn(0), a.totalFramesIn(sets-1), unused)
+	if len(a.hashed) > 0 {
+		fmt.Printf("Páginas sem número (conjunto escolhido por hash): %d\n", len(a.hashed))
+	}
+
+	// Faltas que o relógio totalmente associativo do mesmo tamanho evitaria
+	fully := a.sim.runPolicy(a.sim.newClock()).Faults
+	fmt.Printf("Faltas do relógio totalmente associativo: %d\n", fully)
+	if conflicts := a.faults - fully; conflicts >= 0 {
+		fmt.Printf("Faltas por conflito: %d\n", conflicts)
+	} else {
+		fmt.Printf("Faltas por conflito: 0 (a restrição por conjunto evitou %d faltas do relógio)\n", -conflicts)
+	}
+}
+
+func (a *setAssociativePolicy) totalFramesIn(set int) int {
+	return a.bounds[set+1] - a.bounds[set]
+}
+
+// Cache hiperbólico: substitui a página com a menor taxa de acessos desde
+// que foi carregada, (acessos) / (tempo desde a carga), avaliando apenas
+// uma amostra aleatória de frames a cada substituição
+type hyperbolicPolicy struct {
+	frames      []*PageFrame
+	pageToFrame map[string]int
+	loadTime    []int // acesso em que a página do frame foi carregada
+	hits        []int // acessos à página desde a carga (incluindo a carga)
+	now         int
+	samples     int
+	rng         *rand.Rand
+	candidates  []int
+
+	spreadSum float64 // soma de (maior - menor taxa) entre os amostrados
+	evictions int
+}
+
+func newHyperbolicPolicy(totalFrames, samples int, seed int64) *hyperbolicPolicy {
+	candidates := make([]int, totalFrames)
+	for i := range candidates {
+		candidates[i] = i
+	}
+	return &hyperbolicPolicy{
+		frames:      make([]*PageFrame, totalFrames),
+		pageToFrame: make(map[string]int),
+		loadTime:    make([]int, totalFrames),
+		hits:        make([]int, totalFrames),
+		samples:     samples,
+		rng:         rand.New(rand.NewSource(seed)),
+		candidates:  candidates,
+	}
+}
+
+func (h *hyperbolicPolicy) Frames() []*PageFrame {
+	return h.frames
+}
+
+func (h *hyperbolicPolicy) rate(frameIdx int) float64 {
+	return float64(h.hits[frameIdx]) / float64(h.now-h.loadTime[frameIdx]+1)
+}
+
+func (h *hyperbolicPolicy) Repeat(pageID string, n int) bool {
+	h.now += n
+	h.hits[h.pageToFrame[pageID]] += n
+	return true
+}
+
+func (h *hyperbolicPolicy) Access(pageID string) StepResult {
+	h.now++
+
+	if frameIdx, exists := h.pageToFrame[pageID]; exists {
+		h.hits[frameIdx]++
+		return StepResult{PageID: pageID, Hit: true, Frame: frameIdx}
+	}
+
+	frameIdx := -1
+	var victimPage string
+	for i, frame := range h.frames {
+		if frame == nil {
+			frameIdx = i
+			h.frames[i] = &PageFrame{PageID: pageID}
+			break
+		}
+	}
+
+	if frameIdx == -1 {
+		// Amostra sem repetição (Fisher-Yates parcial)
+		n := min(h.samples, len(h.frames))
+		lowest, highest := math.Inf(1), math.Inf(-1)
+		for i := 0; i < n; i++ {
+			j := i + h.rng.Intn(len(h.candidates)-i)
+			h.candidates[i], h.candidates[j] = h.candidates[j], h.candidates[i]
+
+			candidate := h.candidates[i]
+			rate := h.rate(candidate)
+			if rate < lowest {
+				lowest = rate
+				frameIdx = candidate
+			}
+			highest = math.Max(highest, rate)
+		}
+		h.spreadSum += highest - lowest
+		h.evictions++
+
+		victimPage = h.frames[frameIdx].PageID
+		delete(h.pageToFrame, victimPage)
+		h.frames[frameIdx].PageID = pageID
+	}
+
+	h.frames[frameIdx].LoadCount++
+	h.pageToFrame[pageID] = frameIdx
+	h.loadTime[frameIdx] = h.now
+	h.hits[frameIdx] = 1
+	return StepResult{PageID: pageID, Frame: frameIdx, Victim: victimPage}
+}
+
+func (h *hyperbolicPolicy) Report() {
+	spread := 0.0
+	if h.evictions > 0 {
+		spread = h.spreadSum / float64(h.evictions)
+	}
+	fmt.Printf("Frames amostrados por substituição: %d\n", min(h.samples, len(h.frames)))
+	fmt.Printf("Dispersão média das taxas amostradas (maior - menor): %.4f\n", spread)
+}
+
+// Substituição por expressão (-policy-expr): na falta com a memória cheia,
+// a expressão é avaliada para cada frame e a página com a maior pontuação
+// é substituída (empate: o frame de menor índice). Variáveis, medidas em
+// acessos à política:
+//
+//	age      acessos desde que a página foi carregada
+//	idle     acessos desde o último uso da página
+//	refbit   1 se a página foi usada desde a última substituição, senão 0
+//	dirty    1 se a página foi escrita desde a carga (bit M), senão 0
+//	accesses acessos à página desde a carga, a carga inclusive
+//	loads    vezes que a página foi carregada na execução, esta inclusive
+//
+// Com números, + - * / e parênteses: "age" é o FIFO, "idle" é o LRU e
+// "idle - accesses*1000000" é o LFU com desempate pelo LRU.
+const defaultPolicyExpr = "age"
+
+type exprVars struct {
+	age, idle, refbit, dirty, accesses, loads float64
+}
+
+type exprFunc func(v *exprVars) float64
+
+var exprVariables = map[string]func(v *exprVars) float64{
+	"age":      func(v *exprVars) float64 { return v.age },
+	"idle":     func(v *exprVars) float64 { return v.idle },
+	"refbit":   func(v *exprVars) float64 { return v.refbit },
+	"dirty":    func(v *exprVars) float64 { return v.dirty },
+	"accesses": func(v *exprVars) float64 { return v.accesses },
+	"loads":    func(v *exprVars) float64 { return v.loads },
+}
+
+// Erro de sintaxe com a posição (a partir de 0) no texto da expressão
+type exprError struct {
+	Pos int
+	Msg string
+}
+
+func (e *exprError) Error() string {
+	return fmt.Sprintf("%s (posição %d)", e.Msg, e.Pos+1)
+}
+
+// A expressão com um ^ embaixo da posição do erro
+func (e *exprError) Caret(text string) string {
+	return fmt.Sprintf("  %s\n  %s^", text, strings.Repeat(" ", e.Pos))
+}
+
+// Analisador descendente recursivo que monta uma closure por nó:
+//
+//	expr  = termo {("+" | "-") termo}
+//	termo = unário {("*" | "/") unário}
+//	unário = "-" unário | número | variável | "(" expr ")"
+const exprWordChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_."
+
+type exprParser struct {
+	text string
+	pos  int
+}
+
+func compileExpr(text string) (exprFunc, error) {
+	p := &exprParser{text: text}
+	f, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.text) {
+		return nil, &exprError{p.pos, fmt.Sprintf("caractere inesperado: %q", p.text[p.pos])}
+	}
+	return f, nil
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.text) && (p.text[p.pos] == ' ' || p.text[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// Consome op se for o próximo caractere
+func (p *exprParser) accept(op byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.text) && p.text[p.pos] == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expr() (exprFunc, error) {
+	left, err := p.term()
+	for err == nil {
+		var op byte
+		switch {
+		case p.accept('+'):
+			op = '+'
+		case p.accept('-'):
+			op = '-'
+		default:
+			return left, nil
+		}
+		var right exprFunc
+		if right, err = p.term(); err == nil {
+			a, b := left, right
+			if op == '+' {
+				left = func(v *exprVars) float64 { return a(v) + b(v) }
+			} else {
+				left = func(v *exprVars) float64 { return a(v) - b(v) }
+			}
+		}
+	}
+	return nil, err
+}
+
+func (p *exprParser) term() (exprFunc, error) {
+	left, err := p.unary()
+	for err == nil {
+		var op byte
+		switch {
+		case p.accept('*'):
+			op = '*'
+		case p.accept('/'):
+			op = '/'
+		default:
+			return left, nil
+		}
+		var right exprFunc
+		if right, err = p.unary(); err == nil {
+			a, b := left, right
+			if op == '*' {
+				left = func(v *exprVars) float64 { return a(v) * b(v) }
+			} else {
+				left = func(v *exprVars) float64 { return a(v) / b(v) }
+			}
+		}
+	}
+	return nil, err
+}
+
+func (p *exprParser) unary() (exprFunc, error) {
+	if p.accept('-') {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(v *exprVars) float64 { return -operand(v) }, nil
+	}
+	if p.accept('(') {
+		start := p.pos - 1
+		inner, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(')') {
+			if p.pos < len(p.text) {
+				return nil, &exprError{p.pos, "esperado )"}
+			}
+			return nil, &exprError{start, "parêntese sem fechamento"}
+		}
+		return inner, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.text) && strings.IndexByte(exprWordChars, p.text[p.pos]) >= 0 {
+		p.pos++
+	}
+	word := p.text[start:p.pos]
+	switch {
+	case word == "" && start == len(p.text):
+		return nil, &exprError{start, "expressão incompleta"}
+	case word == "":
+		return nil, &exprError{start, fmt.Sprintf("caractere inesperado: %q", p.text[start])}
+	case strings.IndexByte("0123456789.", word[0]) >= 0:
+		value, err := strconv.ParseFloat(word, 64)
+		if err != nil {
+			return nil, &exprError{start, fmt.Sprintf("número inválido: %s", word)}
+		}
+		return func(*exprVars) float64 { return value }, nil
+	}
+	variable, ok := exprVariables[word]
+	if !ok {
+		return nil, &exprError{start, fmt.Sprintf("variável desconhecida: %s; use age, idle, refbit, dirty, accesses ou loads", word)}
+	}
+	return variable, nil
+}
+
+type exprPolicy struct {
+	frames      []*PageFrame
+	pageToFrame map[string]int
+	score       exprFunc
+	now         int
+	loadTime    []int // acesso em que a página do frame foi carregada
+	lastUse     []int
+	uses        []int          // acessos desde a carga
+	loads       map[string]int // cargas de cada página na execução
+	vars        exprVars
+}
+
+func newExprPolicy(totalFrames int, score exprFunc) *exprPolicy {
+	if score == nil {
+		score, _ = compileExpr(defaultPolicyExpr)
+	}
+	return &exprPolicy{
+		frames:      make([]*PageFrame, totalFrames),
+		pageToFrame: make(map[string]int),
+		score:       score,
+		loadTime:    make([]int, totalFrames),
+		lastUse:     make([]int, totalFrames),
+		uses:        make([]int, totalFrames),
+		loads:       make(map[string]int),
+	}
+}
+
+func (e *exprPolicy) Frames() []*PageFrame {
+	return e.frames
+}
+
+func (e *exprPolicy) Access(pageID string) StepResult {
+	e.now++
+
+	if frameIdx, exists := e.pageToFrame[pageID]; exists {
+		e.frames[frameIdx].Referenced = true
+		e.lastUse[frameIdx] = e.now
+		e.uses[frameIdx]++
+		return StepResult{PageID: pageID, Hit: true, Frame: frameIdx}
+	}
+
+	frameIdx := -1
+	var victimPage string
+	for i, frame := range e.frames {
+		if frame == nil {
+			frameIdx = i
+			e.frames[i] = &PageFrame{PageID: pageID}
+			break
+		}
+	}
+
+	if frameIdx == -1 {
+		best := math.Inf(-1)
+		for i, frame := range e.frames {
+			e.vars = exprVars{
+				age:      float64(e.now - e.loadTime[i]),
+				idle:     float64(e.now - e.lastUse[i]),
+				accesses: float64(e.uses[i]),
+				loads:    float64(e.loads[frame.PageID]),
+			}
+			if frame.Referenced {
+				e.vars.refbit = 1
+			}
+			if frame.Dirty {
+				e.vars.dirty = 1
+			}
+			if score := e.score(&e.vars); frameIdx == -1 || score > best {
+				frameIdx, best = i, score
+			}
+		}
+		// refbit conta o uso desde a última substituição
+		for _, frame := range e.frames {
+			frame.Referenced = false
+		}
+		victimPage = e.frames[frameIdx].PageID
+		delete(e.pageToFrame, victimPage)
+		e.frames[frameIdx].PageID = pageID
+	}
+
+	e.frames[frameIdx].Referenced = true
+	e.frames[frameIdx].LoadCount++
+	e.pageToFrame[pageID] = frameIdx
+	e.loadTime[frameIdx], e.lastUse[frameIdx] = e.now, e.now
+	e.uses[frameIdx] = 1
+	e.loads[pageID]++
+	return StepResult{PageID: pageID, Frame: frameIdx, Victim: victimPage}
+}
+
+// Estatísticas de uso de um frame ao longo da execução
+type FrameStats struct {
+	Loads         int // vezes que o frame recebeu uma página
+	Evictions     int // vezes que a página do frame foi substituída
+	DistinctPages int // páginas diferentes que ocuparam o frame
+}
+
+// Executa um acesso na política e mantém o bit M dos frames. As políticas
+// reaproveitam o frame da vítima, que ainda guarda o bit M dela.
+func step(policy ReplacementPolicy, access PageAccess) StepResult {
+	result := policy.Access(access.PageID)
+	frame := policy.Frames()[result.Frame]
+	if !result.Hit {
+		result.WriteBack = result.Victim != "" && frame.Dirty
+		frame.Dirty = false
+	}
+	if access.Write {
+		frame.Dirty = true
+	}
+	return result
+}
+
+// Execução de uma política acesso a acesso, para quem embute o simulador
+// (REPL, TUI, quiz) sem repetir o laço de runPolicy. Não coleta as
+// estatísticas do relatório, só faltas e gravações.
+type Stepper struct {
+	label      string
+	policy     ReplacementPolicy
+	accesses   []PageAccess
+	next       int // próximo acesso a simular
+	faults     int
+	writeBacks int
+}
+
+// Cria o Stepper da política name (nome da linha de comando, ex.: clock)
+// sobre accesses com frames frames; as opções das políticas vêm de s
+func (s *Simulator) NewStepper(accesses []PageAccess, frames int, name string) (*Stepper, error) {
+	if frames < 1 {
+		return nil, fmt.Errorf("número de frames inválido: %d", frames)
+	}
+	// Cópia limitada: Append nunca escreve no vetor de quem chamou
+	accesses = accesses[:len(accesses):len(accesses)]
+	if name == "optimal" {
+		// O trace carregado usa o índice guardado; outro trace ganha o seu
+		var index *NextUseIndex
+		if len(accesses) > 0 && len(accesses) == len(s.accesses) && &accesses[0] == &s.accesses[0] {
+			index = s.BuildNextUseIndex()
+		} else {
+			index = newNextUseIndex(accesses)
+		}
+		return &Stepper{label: "Ótimo", policy: newOptimalPolicy(frames, index), accesses: accesses}, nil
+	}
+	info, ok := findPolicy(name)
+	if !ok {
+		return nil, fmt.Errorf("algoritmo desconhecido: %s (disponíveis: optimal, %s)", name, strings.Join(policyNames(), ", "))
+	}
+	config := *s
+	config.accesses = accesses
+	config.totalFrames = frames
+	config.memorySize = frames * PAGE_SIZE
+	config.nextUse = &nextUseCache{}
+	return &Stepper{label: info.Label, policy: info.New(&config), accesses: accesses}, nil
+}
+
+// Simula o próximo acesso; ok é false quando o trace acabou
+func (st *Stepper) Step() (result StepResult, ok bool) {
+	if st.next == len(st.accesses) {
+		return StepResult{}, false
+	}
+	result = step(st.policy, st.accesses[st.next])
+	st.next++
+	if !result.Hit {
+		st.faults++
+	}
+	if result.WriteBack {
+		st.writeBacks++
+	}
+	return result, true
+}
+
+// Acrescenta acessos ao fim do trace (modo interativo). O Ótimo precisa
+// conhecer o trace inteiro na criação e não aceita acessos novos.
+func (st *Stepper) Append(accesses ...PageAccess) error {
+	if _, ok := st.policy.(*optimalPolicy); ok {
+		return fmt.Errorf("o Ótimo não aceita acessos depois de criado")
+	}
+	st.accesses = append(st.accesses, accesses...)
+	return nil
+}
+
+// Acessos ainda não simulados
+func (st *Stepper) Remaining() int {
+	return len(st.accesses) - st.next
+}
+
+// Último acesso simulado; ok é false antes do primeiro Step
+func (st *Stepper) Current() (access PageAccess, ok bool) {
+	if st.next == 0 {
+		return PageAccess{}, false
+	}
+	return st.accesses[st.next-1], true
+}
+
+// Estado atual dos frames. Os ponteiros são os da política: valem até o
+// próximo Step e não devem ser alterados.
+func (st *Stepper) Frames() []*PageFrame {
+	return st.policy.Frames()
+}
+
+// Frame do ponteiro, ou -1 em políticas sem ponteiro
+func (st *Stepper) Hand() int {
+	if hp, ok := st.policy.(handPolicy); ok {
+		return hp.Hand()
+	}
+	return -1
+}
+
+// Totais dos acessos simulados até agora
+func (st *Stepper) Summary() Result {
+	return Result{Algorithm: st.label, Accesses: st.next, Faults: st.faults, WriteBacks: st.writeBacks}
+}
+
+// Eventos de uma execução entregues aos observadores. Access é o número
+// do acesso, a partir de 1; Warmup marca os acessos do aquecimento
+// (-warmup), que ficam fora das estatísticas.
+type HitEvent struct {
+	Access int
+	Page   PageAccess
+	Frame  int
+	Warmup bool
+}
+
+type FaultEvent struct {
+	Access     int
+	Page       PageAccess
+	Frame      int
+	Load       int      // cargas da página até aqui, esta inclusive
+	Cold       bool     // primeira carga da página (falta fria); senão, recarga
+	Victim     string   // "" quando o frame estava vazio
+	Candidates []string // sorteio das políticas aleatórias
+	Warmup     bool
+}
+
+// Enviado antes do FaultEvent da página que toma o frame
+type EvictEvent struct {
+	Access    int
+	Page      string // página substituída
+	Frame     int
+	By        string // página que tomou o frame
+	WriteBack bool
+	Warmup    bool
+}
+
+type CompleteEvent struct {
+	Accesses int // fora do aquecimento
+	Faults   int
+	Err      error // erro de um observador que interrompeu a execução
+}
+
+// Observador de uma execução. Um erro devolvido interrompe a execução
+// depois do acesso atual; o resultado fica com os acessos já simulados e
+// com o erro em Result.Err.
+type Observer interface {
+	OnHit(HitEvent) error
+	OnFault(FaultEvent) error
+	OnEvict(EvictEvent) error
+	OnComplete(CompleteEvent) error
+}
+
+// Registra um observador das execuções do relatório (Run e Simulate); as
+// execuções auxiliares (estimativa, bootstrap, variantes) não são
+// observadas. Os observadores recebem os eventos na ordem do registro.
+func (s *Simulator) AddObserver(o Observer) {
+	s.observers = append(s.observers, o)
+}
+
+// Observadores de uma execução do relatório: os registrados e, no modo
+// didático, a narração
+func (s *Simulator) reportObservers(policy ReplacementPolicy) []Observer {
+	observers := s.observers[:len(s.observers):len(s.observers)]
+	if s.didacticMode {
+		observers = append(observers, &narrator{s: s, policy: policy})
+	}
+	if s.pace != nil {
+		observers = append(observers, &playbackObserver{s: s, pace: s.pace})
+	}
+	return observers
+}
+
+// Entrega os eventos de um acesso: a substituição, depois a falta ou o hit
+func notifyAccess(observers []Observer, access PageAccess, n int, result StepResult, load int, warmup bool) error {
+	for _, o := range observers {
+		var err error
+		switch {
+		case result.Hit:
+			err = o.OnHit(HitEvent{n, access, result.Frame, warmup})
+		case result.Victim != "":
+			err = o.OnEvict(EvictEvent{n, result.Victim, result.Frame, access.PageID, result.WriteBack, warmup})
+			if err == nil {
+				err = o.OnFault(FaultEvent{n, access, result.Frame, load, load == 1, result.Victim, result.Candidates, warmup})
+			}
+		default:
+			err = o.OnFault(FaultEvent{n, access, result.Frame, load, load == 1, "", result.Candidates, warmup})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Narração do modo didático, restrita aos acessos de -didactic-range
+type narrator struct {
+	s      *Simulator
+	policy ReplacementPolicy
+}
+
+func (n *narrator) OnHit(e HitEvent) error {
+	if n.s.inDidacticRange(e.Access) {
+		fmt.Printf("Acesso %d - Página %s: Hit\n", e.Access, e.Page.PageID)
+	}
+	return nil
+}
+
+func (n *narrator) OnFault(e FaultEvent) error {
+	if !n.s.inDidacticRange(e.Access) {
+		return nil
+	}
+	fmt.Printf("Acesso %d - Página %s: Falta de página (%s)\n",
+		e.Access, e.Page.PageID, faultKind(e.Load))
+	n.s.printMemoryState(n.policy.Frames())
+	if len(e.Candidates) > 0 {
+		fmt.Printf("Candidatas: [%s] -> sorteada %s\n",
+			strings.Join(e.Candidates, ", "), e.Victim)
+	}
+	if qp, ok := n.policy.(queuePolicy); ok {
+		fmt.Print("Fila (mais antiga -> mais nova): ")
+		printFrameList(qp.Queue())
+	}
+	if n.s.showPageTableLive {
+		recent := n.s.accesses[max(0, e.Access-livePageTableRecent):e.Access]
+		printPageTable(n.policy.Frames(), recent, n.s.pageRadix)
+	}
+	fmt.Println("---")
+	return nil
+}
+
+func (n *narrator) OnEvict(EvictEvent) error       { return nil }
+func (n *narrator) OnComplete(CompleteEvent) error { return nil }
+
+// Séries de -rss-interval: frames ocupados e faltas a cada interval
+// acessos fora do aquecimento
+type seriesObserver struct {
+	interval int
+	counted  int // acessos fora do aquecimento
+	resident int
+	policy   residentPolicy // nil: os frames ocupados só aumentam, a cada carga
+	faults   int            // faltas da janela atual
+	Resident []int
+	Faults   []int
+}
+
+func (o *seriesObserver) tick() {
+	o.counted++
+	if o.counted%o.interval == 0 {
+		if o.policy != nil {
+			o.resident = o.policy.Resident()
+		}
+		o.Resident = append(o.Resident, o.resident)
+		o.Faults = append(o.Faults, o.faults)
+		o.faults = 0
+	}
+}
+
+func (o *seriesObserver) OnHit(e HitEvent) error {
+	if !e.Warmup {
+		o.tick()
+	}
+	return nil
+}
+
+func (o *seriesObserver) OnFault(e FaultEvent) error {
+	if e.Victim == "" {
+		o.resident++
+	}
+	if !e.Warmup {
+		o.faults++
+		o.tick()
+	}
+	return nil
+}
+
+func (o *seriesObserver) OnEvict(EvictEvent) error       { return nil }
+func (o *seriesObserver) OnComplete(CompleteEvent) error { return nil }
+
+// Trace das vítimas (-victims F): um arquivo por algoritmo, com o nome do
+// algoritmo antes da extensão de F (vitimas.txt -> vitimas-clock.txt).
+// Cada substituição fora do aquecimento vira a linha "acesso página",
+// gravada durante a simulação no formato aceito por LoadAccessFile.
+type victimTrace struct {
+	file *os.File
+	w    *bufio.Writer
+}
+
+func victimsPath(base, name string) string {
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "-" + name + ext
+}
+
+// Observador que grava as vítimas do algoritmo; nenhum sem -victims
+func (s *Simulator) victimObservers(name, label string) ([]Observer, error) {
+	if s.victimsFile == "" {
+		return nil, nil
+	}
+	file, err := os.Create(victimsPath(s.victimsFile, name))
+	if err != nil {
+		return nil, err
+	}
+	v := &victimTrace{file: file, w: bufio.NewWriter(file)}
+	fmt.Fprintf(v.w, "# Vítimas do algoritmo %s: acesso da substituição e página substituída\n", label)
+	return []Observer{v}, nil
+}
+
+func (s *Simulator) showVictims(name string) {
+	if s.victimsFile != "" {
+		fmt.Printf("Vítimas gravadas em %s\n", victimsPath(s.victimsFile, name))
+	}
+}
+
+func (v *victimTrace) OnEvict(e EvictEvent) error {
+	if e.Warmup {
+		return nil
+	}
+	_, err := fmt.Fprintf(v.w, "%d %s\n", e.Access, e.Page)
+	return err
+}
+
+func (v *victimTrace) OnComplete(CompleteEvent) error {
+	err := v.w.Flush()
+	if closeErr := v.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (v *victimTrace) OnHit(HitEvent) error     { return nil }
+func (v *victimTrace) OnFault(FaultEvent) error { return nil }
+
+// Log de eventos por acesso (-events-csv, -events-parquet): uma linha por
+// acesso de cada algoritmo fora do aquecimento, com as colunas
+// access_index, page_id, type, algorithm, hit, victim e frame. Os arquivos
+// ficam abertos durante o Run e cada algoritmo acrescenta as suas linhas.
+type eventRow struct {
+	Access    int
+	PageID    string
+	Type      string
+	Algorithm string
+	Hit       bool
+	Victim    string // "" no hit e na falta com frame vazio
+	Frame     int
+}
+
+type eventSink interface {
+	Write(eventRow) error
+	Close() error
+	Name() string // arquivo de saída
+}
+
+// Repassa os eventos de uma execução às saídas abertas pelo Run
+type eventObserver struct {
+	sinks     []eventSink
+	algorithm string
+}
+
+func (o *eventObserver) write(row eventRow) error {
+	for _, sink := range o.sinks {
+		if err := sink.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *eventObserver) OnHit(e HitEvent) error {
+	if e.Warmup {
+		return nil
+	}
+	return o.write(eventRow{e.Access, e.Page.PageID, e.Page.Type, o.algorithm, true, "", e.Frame})
+}
+
+func (o *eventObserver) OnFault(e FaultEvent) error {
+	if e.Warmup {
+		return nil
+	}
+	return o.write(eventRow{e.Access, e.Page.PageID, e.Page.Type, o.algorithm, false, e.Victim, e.Frame})
+}
+
+func (o *eventObserver) OnEvict(EvictEvent) error       { return nil }
+func (o *eventObserver) OnComplete(CompleteEvent) error { return nil }
+
+// Colunas do log, na ordem do CSV e do esquema Parquet; victim é a única
+// opcional (nula no hit e na falta com frame vazio)
+var eventColumns = []struct {
+	name     string
+	kind     int // tipo físico do Parquet
+	optional bool
+}{
+	{"access_index", parquetInt64, false},
+	{"page_id", parquetByteArray, false},
+	{"type", parquetByteArray, false},
+	{"algorithm", parquetByteArray, false},
+	{"hit", parquetBoolean, false},
+	{"victim", parquetByteArray, true},
+	{"frame", parquetInt32, false},
+}
+
+type csvEventSink struct {
+	file *os.File
+	w    *csv.Writer
+}
+
+func createEventsCSV(path string) (*csvEventSink, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &csvEventSink{file: file, w: csv.NewWriter(file)}
+	var header []string
+	for _, column := range eventColumns {
+		header = append(header, column.name)
+	}
+	return c, c.w.Write(header)
+}
+
+func (c *csvEventSink) Write(row eventRow) error {
+	return c.w.Write([]string{strconv.Itoa(row.Access), row.PageID, row.Type, row.Algorithm,
+		strconv.FormatBool(row.Hit), row.Victim, strconv.Itoa(row.Frame)})
+}
+
+func (c *csvEventSink) Name() string { return c.file.Name() }
+
+func (c *csvEventSink) Close() error {
+	c.w.Flush()
+	err := c.w.Error()
+	if closeErr := c.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Abre as saídas de -events-csv e -events-parquet (nil sem nenhuma delas)
+func (s *Simulator) openEventLog() ([]eventSink, error) {
+	var sinks []eventSink
+	if s.eventsCSV != "" {
+		c, err := createEventsCSV(s.eventsCSV)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, c)
+	}
+	if s.eventsParquet != "" {
+		p, err := createEventsParquet(s.eventsParquet, s.parquetRowGroup, s.parquetDictionary)
+		if err != nil {
+			for _, sink := range sinks {
+				sink.Close()
+			}
+			return nil, err
+		}
+		sinks = append(sinks, p)
+	}
+	return sinks, nil
+}
+
+func closeEventLog(sinks []eventSink) {
+	for _, sink := range sinks {
+		if err := sink.Close(); err != nil {
+			logger.Error("erro ao gravar os eventos", "file", sink.Name(), "err", err)
+		} else {
+			fmt.Printf("\nEventos por acesso gravados em %s\n", sink.Name())
+		}
+	}
+}
+
+// Observadores dos arquivos de um algoritmo: as vítimas de -victims e o
+// log de eventos
+func (s *Simulator) fileObservers(name, label string) ([]Observer, error) {
+	observers, err := s.victimObservers(name, label)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.eventSinks) > 0 {
+		observers = append(observers, &eventObserver{sinks: s.eventSinks, algorithm: label})
+	}
+	return observers, nil
+}
+
+// Parquet sem bibliotecas externas: cada grupo de linhas tem uma página
+// de dados por coluna, comprimida com gzip, e o rodapé (FileMetaData) vai
+// no protocolo compacto do Thrift, como no formato de referência. Com
+// dictionary, page_id e victim levam um dicionário por grupo e a página
+// de dados só guarda os índices.
+const (
+	parquetMagic           = "PAR1"
+	parquetDefaultRowGroup = 65536
+
+	// Tipos físicos, codificações e demais enums do parquet.thrift
+	parquetBoolean   = 0
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetRequired = 0
+	parquetOptional = 1
+	parquetUTF8     = 0 // ConvertedType
+
+	parquetPlain           = 0
+	parquetPlainDictionary = 2
+	parquetRLE             = 3
+
+	parquetDataPage       = 0
+	parquetDictionaryPage = 2
+	parquetGzip           = 2
+)
+
+// Tipos de campo do protocolo compacto do Thrift
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// Struct Thrift no protocolo compacto; os campos têm de vir em ordem
+// crescente, e o cabeçalho de cada um guarda a diferença para o anterior
+type thriftWriter struct {
+	b    []byte
+	last int
+}
+
+func (t *thriftWriter) field(id int, kind byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.b = append(t.b, byte(delta<<4)|kind)
+	} else {
+		t.b = append(t.b, kind)
+		t.b = binary.AppendVarint(t.b, int64(id))
+	}
+	t.last = id
+}
+
+// i32 e i64 usam o mesmo varint em zigue-zague
+func (t *thriftWriter) int(id int, kind byte, v int64) {
+	t.field(id, kind)
+	t.b = binary.AppendVarint(t.b, v)
+}
+
+func (t *thriftWriter) binary(id int, s string) {
+	t.field(id, thriftBinary)
+	t.b = appendThriftString(t.b, s)
+}
+
+func appendThriftString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func (t *thriftWriter) structField(id int, inner *thriftWriter) {
+	t.field(id, thriftStruct)
+	t.b = append(t.b, inner.end()...)
+}
+
+// Lista de itens já codificados (structs terminadas, varints, binários)
+func (t *thriftWriter) list(id int, kind byte, items [][]byte) {
+	t.field(id, thriftList)
+	if len(items) < 15 {
+		t.b = append(t.b, byte(len(items)<<4)|kind)
+	} else {
+		t.b = append(t.b, 0xf0|kind)
+		t.b = binary.AppendUvarint(t.b, uint64(len(items)))
+	}
+	for _, item := range items {
+		t.b = append(t.b, item...)
+	}
+}
+
+func (t *thriftWriter) end() []byte {
+	return append(t.b, 0)
+}
+
+// Valores de width bits, do bit menos significativo para o mais, com o
+// último byte completado com zeros
+func packBits(b []byte, values []uint32, width int) []byte {
+	var acc uint64
+	n := 0
+	for _, v := range values {
+		acc |= uint64(v) << n
+		for n += width; n >= 8; n -= 8 {
+			b = append(b, byte(acc))
+			acc >>= 8
+		}
+	}
+	if n > 0 {
+		b = append(b, byte(acc))
+	}
+	return b
+}
+
+// Híbrido RLE/bit-packed do Parquet com uma única sequência bit-packed,
+// em grupos de 8 valores (o último completado com zeros)
+func appendHybrid(b []byte, values []uint32, width int) []byte {
+	groups := (len(values) + 7) / 8
+	b = binary.AppendUvarint(b, uint64(groups)<<1|1)
+	padded := make([]uint32, groups*8)
+	copy(padded, values)
+	return packBits(b, padded, width)
+}
+
+func appendPlainString(b []byte, s string) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+type parquetEventWriter struct {
+	file       *os.File
+	w          *bufio.Writer
+	offset     int64
+	rowGroup   int
+	dictionary bool
+	rows       []eventRow
+	groups     [][]byte // RowGroup já codificados para o rodapé
+	total      int64
+}
+
+func createEventsParquet(path string, rowGroup int, dictionary bool) (*parquetEventWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if rowGroup <= 0 {
+		rowGroup = parquetDefaultRowGroup
+	}
+	p := &parquetEventWriter{file: file, w: bufio.NewWriter(file), rowGroup: rowGroup, dictionary: dictionary}
+	p.w.WriteString(parquetMagic)
+	p.offset = int64(len(parquetMagic))
+	return p, nil
+}
+
+func (p *parquetEventWriter) Name() string { return p.file.Name() }
+
+func (p *parquetEventWriter) Write(row eventRow) error {
+	p.rows = append(p.rows, row)
+	if len(p.rows) == p.rowGroup {
+		return p.flush()
+	}
+	return nil
+}
+
+// Grava uma página: o PageHeader, com o cabeçalho específico do tipo no
+// campo field, e o conteúdo comprimido. Devolve os bytes gravados e o
+// tamanho descomprimido, cabeçalho incluído.
+func (p *parquetEventWriter) writePage(pageType, field int, inner *thriftWriter, data []byte) (compressed, uncompressed int64, err error) {
+	var zipped bytes.Buffer
+	gz := gzip.NewWriter(&zipped)
+	gz.Write(data)
+	if err := gz.Close(); err != nil {
+		return 0, 0, err
+	}
+	header := &thriftWriter{}
+	header.int(1, thriftI32, int64(pageType))
+	header.int(2, thriftI32, int64(len(data)))
+	header.int(3, thriftI32, int64(zipped.Len()))
+	header.structField(field, inner)
+	encoded := header.end()
+	if _, err := p.w.Write(encoded); err != nil {
+		return 0, 0, err
+	}
+	if _, err := p.w.Write(zipped.Bytes()); err != nil {
+		return 0, 0, err
+	}
+	compressed = int64(len(encoded) + zipped.Len())
+	p.offset += compressed
+	return compressed, int64(len(encoded) + len(data)), nil
+}
+
+// Valores de uma coluna do grupo: as colunas de texto ficam como strings
+// (em victim, só as definidas, com os níveis de definição à parte) e as
+// outras já no encoding PLAIN
+func (p *parquetEventWriter) column(name string) (plain []byte, strs []string, defined []uint32) {
+	var hits []uint32
+	for _, row := range p.rows {
+		switch name {
+		case "access_index":
+			plain = binary.LittleEndian.AppendUint64(plain, uint64(row.Access))
+		case "page_id":
+			strs = append(strs, row.PageID)
+		case "type":
+			strs = append(strs, row.Type)
+		case "algorithm":
+			strs = append(strs, row.Algorithm)
+		case "hit":
+			hits = append(hits, uint32(protoBool(row.Hit)))
+		case "victim":
+			defined = append(defined, uint32(protoBool(row.Victim != "")))
+			if row.Victim != "" {
+				strs = append(strs, row.Victim)
+			}
+		case "frame":
+			plain = binary.LittleEndian.AppendUint32(plain, uint32(row.Frame))
+		}
+	}
+	if hits != nil {
+		plain = packBits(nil, hits, 1)
+	}
+	return plain, strs, defined
+}
+
+// Grava o grupo de linhas pendente, uma coluna depois da outra
+func (p *parquetEventWriter) flush() error {
+	if len(p.rows) == 0 {
+		return nil
+	}
+	rows := int64(len(p.rows))
+	var chunks [][]byte
+	var groupSize int64
+	for _, column := range eventColumns {
+		values, strs, defined := p.column(column.name)
+		start := p.offset
+		var compressed, uncompressed int64
+		dictionaryOffset := int64(-1)
+		encoding := parquetPlain
+		if p.dictionary && (column.name == "page_id" || column.name == "victim") && len(strs) > 0 {
+			index := make(map[string]uint32)
+			var dict []byte
+			ids := make([]uint32, len(strs))
+			for i, str := range strs {
+				id, ok := index[str]
+				if !ok {
+					id = uint32(len(index))
+					index[str] = id
+					dict = appendPlainString(dict, str)
+				}
+				ids[i] = id
+			}
+			dictHeader := &thriftWriter{}
+			dictHeader.int(1, thriftI32, int64(len(index)))
+			dictHeader.int(2, thriftI32, parquetPlainDictionary)
+			c, u, err := p.writePage(parquetDictionaryPage, 7, dictHeader, dict)
+			if err != nil {
+				return err
+			}
+			dictionaryOffset, compressed, uncompressed = start, c, u
+			width := max(bits.Len32(uint32(len(index)-1)), 1)
+			values = appendHybrid([]byte{byte(width)}, ids, width)
+			encoding = parquetPlainDictionary
+		} else {
+			for _, str := range strs {
+				values = appendPlainString(values, str)
+			}
+		}
+		if column.optional {
+			// Níveis de definição da página v1: tamanho em 4 bytes e o híbrido
+			levels := appendHybrid(nil, defined, 1)
+			prefixed := binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))
+			values = append(append(prefixed, levels...), values...)
+		}
+
+		dataOffset := p.offset
+		dataHeader := &thriftWriter{}
+		dataHeader.int(1, thriftI32, rows)
+		dataHeader.int(2, thriftI32, int64(encoding))
+		dataHeader.int(3, thriftI32, parquetRLE)
+		dataHeader.int(4, thriftI32, parquetRLE)
+		c, u, err := p.writePage(parquetDataPage, 5, dataHeader, values)
+		if err != nil {
+			return err
+		}
+		compressed += c
+		uncompressed += u
+		groupSize += uncompressed
+
+		meta := &thriftWriter{}
+		meta.int(1, thriftI32, int64(column.kind))
+		meta.list(2, thriftI32, [][]byte{binary.AppendVarint(nil, int64(encoding)), binary.AppendVarint(nil, parquetRLE)})
+		meta.list(3, thriftBinary, [][]byte{appendThriftString(nil, column.name)})
+		meta.int(4, thriftI32, parquetGzip)
+		meta.int(5, thriftI64, rows)
+		meta.int(6, thriftI64, uncompressed)
+		meta.int(7, thriftI64, compressed)
+		meta.int(9, thriftI64, dataOffset)
+		if dictionaryOffset >= 0 {
+			meta.int(11, thriftI64, dictionaryOffset)
+		}
+		chunk := &thriftWriter{}
+		chunk.int(2, thriftI64, start)
+		chunk.structField(3, meta)
+		chunks = append(chunks, chunk.end())
+	}
+	group := &thriftWriter{}
+	group.list(1, thriftStruct, chunks)
+	group.int(2, thriftI64, groupSize)
+	group.int(3, thriftI64, rows)
+	p.groups = append(p.groups, group.end())
+	p.total += rows
+	p.rows = p.rows[:0]
+	return nil
+}
+
+// Grava o último grupo e o rodapé: FileMetaData, o tamanho dele e a
+// assinatura de novo
+func (p *parquetEventWriter) Close() error {
+	err := p.flush()
+	if err == nil {
+		root := &thriftWriter{}
+		root.binary(4, "schema")
+		root.int(5, thriftI32, int64(len(eventColumns)))
+		schema := [][]byte{root.end()}
+		for _, column := range eventColumns {
+			element := &thriftWriter{}
+			element.int(1, thriftI32, int64(column.kind))
+			repetition := parquetRequired
+			if column.optional {
+				repetition = parquetOptional
+			}
+			element.int(3, thriftI32, int64(repetition))
+			element.binary(4, column.name)
+			if column.kind == parquetByteArray {
+				element.int(6, thriftI32, parquetUTF8)
+			}
+			schema = append(schema, element.end())
+		}
+		meta := &thriftWriter{}
+		meta.int(1, thriftI32, 1)
+		meta.list(2, thriftStruct, schema)
+		meta.int(3, thriftI64, p.total)
+		meta.list(4, thriftStruct, p.groups)
+		meta.binary(6, "memory-management-go")
+		footer := meta.end()
+		p.w.Write(footer)
+		p.w.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+		p.w.WriteString(parquetMagic)
+		err = p.w.Flush()
+	}
+	if closeErr := p.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Conta os eventos (testes e bench); com stopAt > 0, devolve erro ao
+// chegar nesse acesso
+type countingObserver struct {
+	hits, faults, evictions, completes int
+	stopAt                             int
+}
+
+func (o *countingObserver) stop(access int) error {
+	if o.stopAt > 0 && access >= o.stopAt {
+		return fmt.Errorf("parada pedida no acesso %d", access)
+	}
+	return nil
+}
+
+func (o *countingObserver) OnHit(e HitEvent) error {
+	o.hits++
+	return o.stop(e.Access)
+}
+
+func (o *countingObserver) OnFault(e FaultEvent) error {
+	o.faults++
+	return o.stop(e.Access)
+}
+
+func (o *countingObserver) OnEvict(EvictEvent) error {
+	o.evictions++
+	return nil
+}
+
+func (o *countingObserver) OnComplete(CompleteEvent) error {
+	o.completes++
+	return nil
+}
+
+// Cancela a execução (como um Ctrl-C) ao ver o acesso at
+type cancellingObserver struct {
+	countingObserver
+	at     int
+	cancel func()
+}
+
+func (o *cancellingObserver) OnHit(e HitEvent) error {
+	o.check(e.Access)
+	return o.countingObserver.OnHit(e)
+}
+
+func (o *cancellingObserver) OnFault(e FaultEvent) error {
+	o.check(e.Access)
+	return o.countingObserver.OnFault(e)
+}
+
+func (o *cancellingObserver) check(access int) {
+	if access == o.at {
+		o.cancel()
+	}
+}
+
+// Interrupção da simulação (Ctrl-C ou -timeout): o algoritmo em execução
+// para e devolve o que contou até ali em Result.Err, os seguintes não são
+// executados e Run mostra um resumo parcial. O código de saída diz o motivo.
+var (
+	errInterrupted = errors.New("interrompida por Ctrl-C")
+	errTimeout     = errors.New("tempo limite esgotado (-timeout)")
+)
+
+const (
+	cancelCheckEvery = 4096
+	exitInterrupted  = 130 // como um processo encerrado por SIGINT no shell
+	exitTimeout      = 124 // como o timeout(1)
+)
+
+func cancelled(err error) bool {
+	return errors.Is(err, errInterrupted) || errors.Is(err, errTimeout)
+}
+
+// Liga s.ctx ao Ctrl-C e ao -timeout; o segundo Ctrl-C encerra na hora.
+// stop desfaz o tratamento do sinal.
+func (s *Simulator) cancelOnInterrupt(timeout time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	s.ctx = ctx
+	stopTimer := func() bool { return false }
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, func() { cancel(errTimeout) })
+		stopTimer = timer.Stop
+	}
+	interrupt := make(chan os.Signal, 2)
+	signal.Notify(interrupt, os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-interrupt:
+		case <-done:
+			return
+		}
+		fmt.Println("\nInterrompido: encerrando o algoritmo atual (Ctrl-C de novo sai imediatamente)")
+		cancel(errInterrupted)
+		select {
+		case <-interrupt:
+			fmt.Println("\nEncerrado")
+			os.Exit(exitInterrupted)
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(interrupt)
+		stopTimer()
+		close(done)
+	}
+}
+
+// Encerra Run depois da interrupção durante o algoritmo current ("" no
+// Ótimo), guardando o motivo para o código de saída
+func (s *Simulator) stopRun(results []Result, partial Result, current string) {
+	var notRun []string
+	after := current == ""
+	for _, info := range streamingPolicies {
+		if after && s.algorithmSelected(info.Name) {
+			notRun = append(notRun, info.Label)
+		}
+		after = after || info.Name == current
+	}
+	s.printInterrupted(results, partial, notRun)
+	s.stopped = partial.Err
+}
+
+// Resumo de uma execução interrompida: os algoritmos completos, o parcial
+// e os que não chegaram a rodar
+func (s *Simulator) printInterrupted(results []Result, partial Result, notRun []string) {
+	fmt.Printf("\n=== EXECUÇÃO INTERROMPIDA: RESULTADOS PARCIAIS (%v) ===\n", partial.Err)
+	for _, r := range results {
+		fmt.Printf("%-20s completo: %d acessos, %d faltas\n", r.Algorithm, r.Accesses, r.Faults)
+	}
+	fmt.Printf("%-20s PARCIAL: %d de %d acessos processados, %d faltas até aqui\n", partial.Algorithm,
+		partial.WarmupAccesses+partial.Accesses, len(s.accesses), partial.Faults)
+	if len(notRun) > 0 {
+		fmt.Printf("Não executados: %s\n", strings.Join(notRun, ", "))
+	}
+}
+
+// Executa uma política sobre todos os acessos carregados, entregando os
+// eventos de cada acesso aos observadores
+func (s *Simulator) runPolicy(policy ReplacementPolicy, observers ...Observer) Result {
+	pageFaults, writeBacks, zeroFills, instrFaults := 0, 0, 0, 0
+	observers = observers[:len(observers):len(observers)]
+	var series *seriesObserver
+	if s.seriesInterval > 0 {
+		series = &seriesObserver{interval: s.seriesInterval}
+		series.policy, _ = policy.(residentPolicy)
+		observers = append(observers, series)
+	}
+	var costs *costAccount
+	if s.costReport {
+		costs = s.newCostAccount()
+		observers = append(observers, costs)
+	}
+	stalls := s.newStallSampler()
+	var classStats [2]ClassStats
+	loadCount := make(map[string]int)
+	evictions := make([]int, s.totalFrames)
+	hosted := make([]map[string]bool, s.totalFrames)
+
+	colors := s.pageColors()
+	var colorStats ColorStats
+	var hot map[string]bool
+	var residentHot []int
+	if colors > 0 {
+		colorStats = ColorStats{Accesses: make([]int, colors), HotLoads: make([]int, colors)}
+		hot = s.hotPages()
+		residentHot = make([]int, colors)
+	}
+
+	start := time.Now()
+
+	// Atalho das sequências repetidas (-rle): só quando nada precisa ser
+	// observado acesso a acesso
+	repeater, fastRuns := policy.(repeatPolicy)
+	fastRuns = fastRuns && s.runs != nil && len(observers) == 0 && colors == 0
+	run := 0
+
+	// O Ótimo fica de fora de -converge: o índice já leu o trace inteiro
+	var converge *convergence
+	if _, optimal := policy.(*optimalPolicy); s.convergeEpsilon > 0 && !optimal {
+		converge = &convergence{epsilon: s.convergeEpsilon}
+		fastRuns = false
+	}
+	consumed := len(s.accesses)
+	warmup, warmupFaults := s.warmupBoundary(), 0
+	saves := newSaveTracker(s.totalFrames)
+	var patternFaults []int
+	if s.pagePatterns != nil {
+		patternFaults = make([]int, numPatterns)
+	}
+	var abort error
+	check := 1
+
+	// -checkpoint-every e -resume: só com o estado todo nos contadores
+	// abaixo e na política
+	var ckpt *checkpointRun
+	if len(observers) == 0 && stalls == nil && converge == nil && colors == 0 {
+		ckpt = s.checkpoints(policy)
+	}
+	snapshot := func(offset int) *checkpoint {
+		return &checkpoint{Offset: offset, Elapsed: time.Since(start),
+			Faults: pageFaults, WriteBacks: writeBacks, ZeroFills: zeroFills, InstrFaults: instrFaults,
+			WarmupFaults: warmupFaults, LoadCount: loadCount, Evictions: evictions, Hosted: hosted,
+			ClassStats: classStats, PatternFaults: patternFaults,
+			Saves: saves.SaveStats, SavePending: saves.pending, SaveFrameOf: saves.frameOf}
+	}
+	first := 0
+	if saved := ckpt.resume(); saved != nil {
+		if saved.Result != nil {
+			return *saved.Result
+		}
+		first, start = saved.Offset, time.Now().Add(-saved.Elapsed)
+		pageFaults, writeBacks, zeroFills, instrFaults = saved.Faults, saved.WriteBacks, saved.ZeroFills, saved.InstrFaults
+		warmupFaults, loadCount, evictions, hosted = saved.WarmupFaults, saved.LoadCount, saved.Evictions, saved.Hosted
+		classStats, patternFaults = saved.ClassStats, saved.PatternFaults
+		saves.SaveStats, saves.pending, saves.frameOf = saved.Saves, saved.SavePending, saved.SaveFrameOf
+		if loadCount == nil {
+			loadCount = make(map[string]int) // gob não distingue um mapa vazio de nil
+		}
+		if saves.frameOf == nil {
+			saves.frameOf = make(map[string]int)
+		}
+	}
+
+	for i := first; i < len(s.accesses); i++ {
+		if converge != nil && converge.done {
+			consumed = i
+			break
+		}
+		if ckpt != nil && s.checkpointEvery > 0 && i >= ckpt.next {
+			if err := ckpt.save(snapshot(i)); err != nil {
+				logger.Warn("checkpoints desligados", "algorithm", ckpt.algorithm, "err", err)
+				ckpt = nil
+			} else {
+				ckpt.next = i + s.checkpointEvery
+			}
+		}
+		// Ctrl-C e -timeout: o contexto é consultado a cada cancelCheckEvery acessos
+		if check--; check == 0 && s.ctx != nil {
+			check = cancelCheckEvery
+			if s.ctx.Err() != nil {
+				abort = context.Cause(s.ctx)
+				consumed = i
+				// Uma interrupção retoma exatamente daqui
+				if ckpt != nil && cancelled(abort) {
+					if err := ckpt.save(snapshot(i)); err != nil {
+						logger.Warn("checkpoint da interrupção não gravado", "algorithm", ckpt.algorithm, "err", err)
+					}
+				}
+				break
+			}
+		}
+		access := s.accesses[i]
+		pageID := access.PageID
+
+		result := step(policy, access)
+		counted := i >= warmup
+		if !result.Hit || saves.pending[result.Frame] {
+			saves.add(&result, counted)
+		}
+		if fastRuns {
+			for s.runs[run].Start+s.runs[run].Count <= i {
+				run++
+			}
+			if r := s.runs[run]; r.Start == i && r.Count > 1 && repeater.Repeat(pageID, r.Count-1) {
+				// Os demais acessos da sequência são acertos na mesma página
+				if r.Write {
+					policy.Frames()[result.Frame].Dirty = true
+				}
+				i += r.Count - 1
+			}
+		}
+		if converge != nil {
+			converge.add(!result.Hit, i+1)
+		}
+		if colors > 0 {
+			color := result.Frame % colors
+			colorStats.Accesses[color]++
+			if !result.Hit {
+				if hot[result.Victim] {
+					residentHot[color]--
+				}
+				if hot[pageID] {
+					if residentHot[color] > 0 {
+						colorStats.Collisions++
+					}
+					residentHot[color]++
+					colorStats.HotLoads[color]++
+				}
+			}
+		}
+		if result.Hit {
+			if len(observers) > 0 {
+				if abort = notifyAccess(observers, access, i+1, result, 0, !counted); abort != nil {
+					consumed = i + 1
+					break
+				}
+			}
+			continue
+		}
+
+		// Falta de página; as do aquecimento só são contadas à parte
+		loadCount[pageID]++
+		if result.Victim != "" {
+			evictions[result.Frame]++
+		}
+		if !counted {
+			warmupFaults++
+		} else {
+			pageFaults++
+			if access.Type == "I" {
+				instrFaults++
+			}
+			if patternFaults != nil {
+				patternFaults[s.pagePatterns[pageID]]++
+			}
+			if result.WriteBack {
+				writeBacks++
+			}
+			if s.isZeroFill(access, loadCount[pageID]) {
+				zeroFills++
+				if stalls != nil {
+					stalls.add(time.Duration(s.zeroFillCost) * time.Microsecond)
+				}
+			} else if stalls != nil {
+				stalls.add(stalls.draw())
+			}
+			if s.fileRanges != nil {
+				classStats[s.pageClass(pageID)].Faults++
+				if result.Victim != "" {
+					victimClass := s.pageClass(result.Victim)
+					classStats[victimClass].Evictions++
+					if victimClass == classAnon || result.WriteBack {
+						classStats[victimClass].Writes++
+					}
+				}
+			}
+		}
+		if hosted[result.Frame] == nil {
+			hosted[result.Frame] = make(map[string]bool)
+		}
+		hosted[result.Frame][pageID] = true
+
+		if len(observers) > 0 {
+			if abort = notifyAccess(observers, access, i+1, result, loadCount[pageID], !counted); abort != nil {
+				consumed = i + 1
+				break
+			}
+		}
+	}
+
+	stats := &RunStats{LoadCount: loadCount, ClassStats: classStats, ColorStats: colorStats}
+	elapsed := time.Since(start)
+
+	stats.FrameStats = make([]FrameStats, s.totalFrames)
+	for i, frame := range policy.Frames() {
+		if frame == nil {
+			continue
+		}
+		stats.FrameStats[i] = FrameStats{
+			Loads:         frame.LoadCount,
+			Evictions:     evictions[i],
+			DistinctPages: len(hosted[i]),
+		}
+	}
+
+	totalEvictions := 0
+	for _, n := range evictions {
+		totalEvictions += n
+	}
+	warmup = min(warmup, consumed)
+	for _, o := range observers {
+		if err := o.OnComplete(CompleteEvent{consumed - warmup, pageFaults, abort}); err != nil && abort == nil {
+			abort = err
+		}
+	}
+	result := Result{Accesses: consumed - warmup, Faults: pageFaults,
+		WarmupAccesses: warmup, WarmupFaults: warmupFaults, WriteBacks: writeBacks,
+		ZeroFills: zeroFills, Final: finalState(policy, totalEvictions), Stats: stats,
+		Elapsed: elapsed, Err: abort, Saves: saves.finish(), InstrFaults: instrFaults,
+		PatternFaults: patternFaults}
+	if series != nil {
+		result.Resident, result.FaultSeries = series.Resident, series.Faults
+	}
+	if costs != nil {
+		result.Costs = &costs.breakdown
+	}
+	if stalls != nil {
+		result.Stalls = stalls.finish()
+	}
+	if consumed < len(s.accesses) && converge != nil && converge.done {
+		result.Partial = true
+		result.RateMargin = converge.margin()
+	}
+	if ckpt != nil && abort == nil {
+		final := &checkpoint{Offset: consumed, Result: &result}
+		if err := ckpt.save(final); err != nil {
+			logger.Warn("checkpoint final não gravado", "algorithm", ckpt.algorithm, "err", err)
+		}
+	}
+	return result
+}
+
+// Acessos de aquecimento (-warmup), os mesmos para todos os algoritmos.
+// Com -warmup auto o aquecimento vai até o acesso que traz a página
+// distinta de número totalFrames, quando a memória enche pela primeira vez.
+// Nunca passa do tamanho do trace.
+func (s *Simulator) warmupBoundary() int {
+	if !s.warmupAuto {
+		return min(s.warmup, len(s.accesses))
+	}
+	seen := make(map[string]bool)
+	for i, access := range s.accesses {
+		seen[access.PageID] = true
+		if len(seen) == s.totalFrames {
+			return i + 1
+		}
+	}
+	return len(s.accesses)
+}
+
+// Parada antecipada (-converge): a taxa de faltas de cada janela de
+// convergeWindow acessos entra numa amostra das últimas convergeWindows
+// janelas. Quando o intervalo de 95% da média fica mais estreito que
+// epsilon por convergeSustain janelas seguidas, a simulação para.
+const (
+	convergeWindow  = 1000
+	convergeWindows = 20
+	convergeSustain = 5
+)
+
+type convergence struct {
+	epsilon float64
+	rates   []float64 // taxas das últimas janelas
+	faults  int       // faltas da janela atual
+	narrow  int       // janelas seguidas com intervalo estreito
+	done    bool
+}
+
+// Registra um acesso; accesses é o total simulado até aqui
+func (c *convergence) add(fault bool, accesses int) {
+	if fault {
+		c.faults++
+	}
+	if accesses%convergeWindow != 0 {
+		return
+	}
+	c.rates = append(c.rates, float64(c.faults)/convergeWindow)
+	if len(c.rates) > convergeWindows {
+		c.rates = c.rates[1:]
+	}
+	c.faults = 0
+	if len(c.rates) < convergeWindows || 2*c.margin() >= c.epsilon {
+		c.narrow = 0
+		return
+	}
+	c.narrow++
+	c.done = c.narrow >= convergeSustain
+}
+
+// Meia largura do intervalo de 95% da taxa média das janelas
+func (c *convergence) margin() float64 {
+	n := float64(len(c.rates))
+	if n < 2 {
+		return math.Inf(1)
+	}
+	mean := 0.0
+	for _, rate := range c.rates {
+		mean += rate
+	}
+	mean /= n
+	variance := 0.0
+	for _, rate := range c.rates {
+		variance += (rate - mean) * (rate - mean)
+	}
+	variance /= n - 1
+	return 1.96 * math.Sqrt(variance/n)
+}
+
+// Acessos narrados pelo modo didático e pela lição (-didactic-range A-B)
+func (s *Simulator) inDidacticRange(access int) bool {
+	return access >= s.didacticFrom && (s.didacticTo == 0 || access <= s.didacticTo)
+}
+
+// Falta de demanda-zero: a primeira carga de uma página anônima que começa
+// por uma escrita (ou marcada com Z no trace) não lê nada do disco. Nas
+// recargas a página já foi modificada e volta do swap.
+func (s *Simulator) isZeroFill(access PageAccess, loads int) bool {
+	if loads != 1 {
+		return false
+	}
+	return access.Zero || (access.Write && s.pageClass(access.PageID) == classAnon)
+}
+
+// Classifica a falta pelo número de vezes que a página já foi carregada:
+// a primeira é compulsória (fria), as demais poderiam ter sido evitadas
+func faultKind(loads int) string {
+	if loads <= 1 {
+		return "fria"
+	}
+	return fmt.Sprintf("recarga, %dª vez", loads)
+}
+
+// Estatísticas de uma execução usadas pelos relatórios. Cada execução cria
+// as suas, então o mesmo trace carregado pode ser simulado várias vezes ou
+// por várias goroutines sem interferência.
+type RunStats struct {
+	LoadCount  map[string]int
+	FrameStats []FrameStats
+	ClassStats [2]ClassStats
+	ColorStats ColorStats
+}
+
+// Passa a reportar as estatísticas da execução r (-loadcount, -framestats,
+// classes e cores de página)
+func (s *Simulator) keepStats(r Result) {
+	s.pageLoadCount = r.Stats.LoadCount
+	s.frameStats = r.Stats.FrameStats
+	s.classStats = r.Stats.ClassStats
+	s.colorStats = r.Stats.ColorStats
+}
+
+// Descarta as estatísticas reportadas; configuração e trace são mantidos
+func (s *Simulator) Reset() {
+	s.pageLoadCount = make(map[string]int)
+	s.frameStats = nil
+	s.classStats = [2]ClassStats{}
+	s.colorStats = ColorStats{}
+}
+
+// Algoritmo do Relógio (Clock)
+func (s *Simulator) ClockAlgorithm() int {
+	clock := s.newClock()
+	result := s.runPolicy(clock, s.reportObservers(clock)...)
+	s.keepStats(result)
+	return result.Faults
+}
+
+// FIFO: a página carregada há mais tempo sai primeiro
+func (s *Simulator) FIFOAlgorithm() int {
+	fifo := newFIFOPolicy(s.totalFrames)
+	result := s.runPolicy(fifo, s.reportObservers(fifo)...)
+	s.keepStats(result)
+	return result.Faults
+}
+
+// LRU exato, a referência que o Relógio aproxima
+func (s *Simulator) LRUAlgorithm() int {
+	lru := newLRUPolicy(s.totalFrames)
+	result := s.runPolicy(lru, s.reportObservers(lru)...)
+	s.keepStats(result)
+	return result.Faults
+}
+
+// Segunda chance melhorada: o Relógio com o bit M, que prefere vítimas limpas
+func (s *Simulator) EnhancedClockAlgorithm() int {
+	enhanced := newEnhancedClockPolicy(s.totalFrames)
+	result := s.runPolicy(enhanced, s.reportObservers(enhanced)...)
+	s.keepStats(result)
+	return result.Faults
+}
+
+// MFU, o contraste do LFU: a página mais usada sai primeiro
+func (s *Simulator) MFUAlgorithm() int {
+	mfu := newMFUPolicy(s.totalFrames)
+	result := s.runPolicy(mfu, s.reportObservers(mfu)...)
+	s.keepStats(result)
+	return result.Faults
+}
+
+// Faltas do aquecimento, que não entram nas demais estatísticas
+func (s *Simulator) showWarmup(r Result) {
+	if r.WarmupAccesses > 0 {
+		fmt.Printf("Faltas no aquecimento: %d em %d acessos (total com aquecimento: %d)\n",
+			r.WarmupFaults, r.WarmupAccesses, r.WarmupFaults+r.Faults)
+	}
+}
+
+// Avisa que o resultado é uma estimativa quando -converge parou a execução
+func (s *Simulator) showConvergence(r Result) {
+	if s.convergeEpsilon == 0 {
+		return
+	}
+	if !r.Partial {
+		fmt.Println("A taxa de faltas não convergiu; o trace foi simulado inteiro")
+		return
+	}
+	fmt.Printf("ESTIMATIVA: parou após %d de %d acessos (%.1f%% do trace); taxa de faltas %.2f%% ± %.2f%%\n",
+		r.Accesses, len(s.accesses), float64(r.Accesses)/float64(len(s.accesses))*100,
+		r.FaultRate()*100, r.RateMargin*100)
+}
+
+// Segundas chances do bit R (páginas em StepResult.Spared): a página
+// poupada é útil se for acessada de novo antes de ser substituída e
+// desperdiçada se for substituída sem novo acesso, mesmo que na própria
+// volta que a poupou. Só as segundas chances fora do aquecimento contam.
+type SaveStats struct {
+	Saves   int `json:"saves"`
+	Useful  int `json:"useful"`
+	Wasted  int `json:"wasted"`
+	Pending int `json:"pending"` // sem desfecho ao final da execução
+}
+
+type saveTracker struct {
+	SaveStats
+	pending []bool         // frame com uma segunda chance sem desfecho
+	frameOf map[string]int // frame de cada página carregada
+}
+
+func newSaveTracker(frames int) *saveTracker {
+	return &saveTracker{pending: make([]bool, frames), frameOf: make(map[string]int)}
+}
+
+// Chamado nas faltas e nos acertos em frames com segunda chance pendente;
+// o mapa de páginas muda apenas nas faltas
+func (t *saveTracker) add(r *StepResult, counted bool) {
+	for _, page := range r.Spared {
+		if counted {
+			t.Saves++
+			t.pending[t.frameOf[page]] = true
+		}
+	}
+	if t.pending[r.Frame] {
+		t.pending[r.Frame] = false
+		if r.Hit {
+			t.Useful++
+		} else {
+			t.Wasted++ // a vítima ocupava este frame
+		}
+	}
+	if !r.Hit {
+		t.frameOf[r.PageID] = r.Frame
+	}
+}
+
+func (t *saveTracker) finish() SaveStats {
+	for _, pending := range t.pending {
+		if pending {
+			t.Pending++
+		}
+	}
+	return t.SaveStats
+}
+
+// Resumo didático das segundas chances, nas políticas com bit R
+func (s *Simulator) showSaves(r Result) {
+	sv := r.Saves
+	if sv.Saves == 0 {
+		return
+	}
+	fmt.Printf("Segundas chances: %d (úteis %d, %.1f%%; desperdiçadas %d, %.1f%%; sem desfecho %d)\n",
+		sv.Saves, sv.Useful, float64(sv.Useful)/float64(sv.Saves)*100,
+		sv.Wasted, float64(sv.Wasted)/float64(sv.Saves)*100, sv.Pending)
+}
+
+// Faltas por ms do trace, quando os acessos têm tempo (coluna +N ou
+// -access-time); só o trecho contado e simulado
+func (s *Simulator) showTraceTime(r Result) {
+	if s.traceTimes == nil {
+		return
+	}
+	start := s.warmupBoundary()
+	if span := s.traceTimes[start+r.Accesses] - s.traceTimes[start]; span > 0 {
+		fmt.Printf("Faltas por ms do trace: %.3f (%d faltas em %s)\n",
+			float64(r.Faults)/(float64(span)/1e6), r.Faults, time.Duration(span))
+	}
+}
+
+// Mostra o tempo de parede da execução; no Ótimo separa o índice nextUse
+// do laço principal
+func (s *Simulator) showTiming(r Result) {
+	perFault := "N/A"
+	if r.Faults+r.WarmupFaults > 0 {
+		perFault = r.TimePerFault().String()
+	}
+	fmt.Printf("Tempo: %s (%.0f acessos/s, %s por falta)\n",
+		r.Elapsed.Round(time.Microsecond), r.AccessesPerSecond(), perFault)
+	if r.IndexTime > 0 {
+		fmt.Printf("  índice nextUse: %s, laço principal: %s\n",
+			r.IndexTime.Round(time.Microsecond), (r.Elapsed - r.IndexTime).Round(time.Microsecond))
+	}
+}
+
+// Mostra as gravações em disco quando o trace tem acessos de escrita
+func (s *Simulator) showWriteBacks(r Result) {
+	if s.writeCount > 0 {
+		fmt.Printf("Páginas modificadas gravadas ao serem substituídas (%s): %d\n", r.Algorithm, r.WriteBacks)
+	}
+}
+
+// Separa as faltas de demanda-zero das lidas do disco e estima quanto tempo
+// de serviço a otimização economiza
+func (s *Simulator) showZeroFills(r Result) {
+	if r.ZeroFills == 0 {
+		return
+	}
+
+	diskFaults := r.Faults - r.ZeroFills
+	readTime := time.Duration(s.faultReadCost) * time.Microsecond
+	zeroTime := time.Duration(s.zeroFillCost) * time.Microsecond
+	total := time.Duration(diskFaults)*readTime + time.Duration(r.ZeroFills)*zeroTime
+	unoptimized := time.Duration(r.Faults) * readTime
+	fmt.Printf("Faltas de demanda-zero: %d | Faltas lidas do disco: %d\n", r.ZeroFills, diskFaults)
+	fmt.Printf("Tempo atendendo faltas: %s (lendo todas do disco: %s, economia de %s)\n",
+		total, unoptimized, unoptimized-total)
+}
+
+// Até este número de faltas os percentis são exatos; acima, são calculados
+// sobre uma amostra de reservatório deste tamanho
+const maxExactStalls = 1 << 22
+
+// Tempos de espera das faltas sorteados com -fault-dist
+type StallStats struct {
+	Count  int
+	Total  time.Duration
+	Sample []time.Duration // ordenada
+}
+
+// Sorteia o tempo de serviço das faltas lidas do disco. A média é sempre
+// -fault-read-cost; a distribuição muda a cauda.
+type stallSampler struct {
+	dist   string
+	mean   float64
+	spread float64
+	sigma  float64
+	rng    *rand.Rand
+	stats  StallStats
+}
+
+func (s *Simulator) newStallSampler() *stallSampler {
+	if s.faultDist == "" {
+		return nil
+	}
+	return &stallSampler{
+		dist:   s.faultDist,
+		mean:   float64(s.faultReadCost),
+		spread: s.faultSpread,
+		sigma:  s.faultSigma,
+		rng:    rand.New(rand.NewSource(s.seed)),
+	}
+}
+
+func (st *stallSampler) draw() time.Duration {
+	micros := st.mean
+	switch st.dist {
+	case "uniform":
+		micros = st.mean * (1 - st.spread + 2*st.spread*st.rng.Float64())
+	case "lognormal":
+		// mu escolhido para que a média da lognormal seja a do custo
+		mu := math.Log(st.mean) - st.sigma*st.sigma/2
+		micros = math.Exp(mu + st.sigma*st.rng.NormFloat64())
+	}
+	return time.Duration(micros * float64(time.Microsecond))
+}
+
+func (st *stallSampler) add(stall time.Duration) {
+	st.stats.Count++
+	st.stats.Total += stall
+	if len(st.stats.Sample) < maxExactStalls {
+		st.stats.Sample = append(st.stats.Sample, stall)
+	} else if j := st.rng.Intn(st.stats.Count); j < maxExactStalls {
+		st.stats.Sample[j] = stall
+	}
+}
+
+func (st *stallSampler) finish() StallStats {
+	sort.Slice(st.stats.Sample, func(i, j int) bool { return st.stats.Sample[i] < st.stats.Sample[j] })
+	return st.stats
+}
+
+// Percentil p (0 a 100) de uma amostra ordenada
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return sorted[max(0, min(idx, len(sorted)-1))]
+}
+
+// Distribuição dos tempos de espera das faltas (-fault-dist)
+func (s *Simulator) showStalls(r Result) {
+	stats := r.Stalls
+	if stats.Count == 0 {
+		return
+	}
+
+	fmt.Printf("Espera por faltas (%s): total %s, média %s\n", s.faultDist,
+		stats.Total.Round(time.Microsecond), (stats.Total / time.Duration(stats.Count)).Round(time.Microsecond))
+	fmt.Printf("  p50 %s | p90 %s | p99 %s | p99.9 %s | máx %s\n",
+		percentile(stats.Sample, 50).Round(time.Microsecond),
+		percentile(stats.Sample, 90).Round(time.Microsecond),
+		percentile(stats.Sample, 99).Round(time.Microsecond),
+		percentile(stats.Sample, 99.9).Round(time.Microsecond),
+		stats.Sample[len(stats.Sample)-1].Round(time.Microsecond))
+	if stats.Count > len(stats.Sample) {
+		fmt.Printf("  (percentis estimados sobre %d de %d faltas)\n", len(stats.Sample), stats.Count)
+	}
+}
+
+// Contabilidade do custo simulado (-cost). Cada acesso é cobrado uma vez,
+// num único balde, pelo caminho inteiro que percorreu:
+//
+//	acerto na TLB        tlb-hit-cost
+//	falta na TLB + walk  tlb-hit-cost + walk-cost (página residente)
+//	falta menor          tlb-hit-cost + walk-cost + zero-fill-cost (demanda-zero)
+//	falta maior          tlb-hit-cost + walk-cost + fault-read-cost
+//	gravação da vítima   a falta (menor ou maior) + gravação da vítima
+//	                     modificada (swap-write-cost ou file-write-cost)
+//
+// Sem -tlb-entries a TLB é perfeita: todo acesso a página residente acerta.
+// As faltas custam os valores fixos das opções, sem o sorteio de -fault-dist.
+type costBucket int
+
+const (
+	costTLBHit costBucket = iota
+	costTLBMiss
+	costMinor
+	costMajor
+	costWriteBack
+	costBuckets
+)
+
+var costBucketNames = [costBuckets]string{"acerto na TLB", "falta na TLB + walk", "falta menor", "falta maior", "gravação da vítima"}
+var costBucketKeys = [costBuckets]string{"tlb_hit", "tlb_miss_walk", "minor_fault", "major_fault", "write_back"}
+
+// Custos de cada etapa, em ns
+type costModel struct {
+	tlbEntries     int
+	tlbHit, walk   int64
+	zeroFill, read int64
+	writes         [2]int64 // por classe da vítima (anônima, arquivo)
+}
+
+func (s *Simulator) costModel() costModel {
+	return costModel{
+		tlbEntries: s.tlbEntries,
+		tlbHit:     int64(s.tlbHitCost),
+		walk:       int64(s.walkCost),
+		zeroFill:   int64(s.zeroFillCost) * 1000,
+		read:       int64(s.faultReadCost) * 1000,
+		writes:     [2]int64{int64(s.swapWriteCost) * 1000, int64(s.fileWriteCost) * 1000},
+	}
+}
+
+// Único ponto em que um acesso vira custo: path é o caminho da tradução
+// (acerto ou falta na TLB, falta menor ou maior); writeBack, a gravação
+// da vítima da classe victimClass
+func (m costModel) charge(path costBucket, writeBack bool, victimClass int) (costBucket, int64) {
+	cost := m.tlbHit
+	if path != costTLBHit {
+		cost += m.walk
+	}
+	switch path {
+	case costMinor:
+		cost += m.zeroFill
+	case costMajor:
+		cost += m.read
+	}
+	if writeBack {
+		return costWriteBack, cost + m.writes[victimClass]
+	}
+	return path, cost
+}
+
+// TLB totalmente associativa com substituição LRU. A entrada da página
+// que sai da memória é invalidada; sem entradas, a TLB é perfeita.
+type tlbModel struct {
+	entries int
+	stamp   map[string]int // página -> último uso
+	now     int
+}
+
+func (t *tlbModel) lookup(pageID string) bool {
+	if t.entries == 0 {
+		return true
+	}
+	t.now++
+	if _, ok := t.stamp[pageID]; !ok {
+		return false
+	}
+	t.stamp[pageID] = t.now
+	return true
+}
+
+func (t *tlbModel) insert(pageID string) {
+	if t.entries == 0 {
+		return
+	}
+	if len(t.stamp) == t.entries {
+		oldest, first := "", true
+		for page, used := range t.stamp {
+			if first || used < t.stamp[oldest] || used == t.stamp[oldest] && page < oldest {
+				oldest, first = page, false
+			}
+		}
+		delete(t.stamp, oldest)
+	}
+	t.now++
+	t.stamp[pageID] = t.now
+}
+
+func (t *tlbModel) invalidate(pageID string) {
+	delete(t.stamp, pageID)
+}
+
+// Acessos e custo de cada balde de uma execução
+type CostBreakdown struct {
+	Counts [costBuckets]int
+	Nanos  [costBuckets]int64
+}
+
+func (c *CostBreakdown) Total() int64 {
+	var total int64
+	for _, n := range c.Nanos {
+		total += n
+	}
+	return total
+}
+
+func (c *CostBreakdown) add(bucket costBucket, cost int64) {
+	c.Counts[bucket]++
+	c.Nanos[bucket] += cost
+}
+
+func (c *CostBreakdown) MarshalJSON() ([]byte, error) {
+	type bucket struct {
+		Bucket   string  `json:"bucket"`
+		Accesses int     `json:"accesses"`
+		Nanos    int64   `json:"ns"`
+		Share    float64 `json:"share"`
+	}
+	out := struct {
+		TotalNanos int64    `json:"total_ns"`
+		Buckets    []bucket `json:"buckets"`
+	}{TotalNanos: c.Total()}
+	for b := costBucket(0); b < costBuckets; b++ {
+		share := 0.0
+		if out.TotalNanos > 0 {
+			share = float64(c.Nanos[b]) / float64(out.TotalNanos)
+		}
+		out.Buckets = append(out.Buckets, bucket{costBucketKeys[b], c.Counts[b], c.Nanos[b], share})
+	}
+	return json.Marshal(out)
+}
+
+// Observador que classifica os acessos e cobra cada um pelo costModel;
+// os acessos do aquecimento movem a TLB mas não são cobrados
+type costAccount struct {
+	s           *Simulator
+	model       costModel
+	tlb         tlbModel
+	writeBack   bool // a falta atual substituiu uma vítima modificada
+	victimClass int
+	breakdown   CostBreakdown
+}
+
+func (s *Simulator) newCostAccount() *costAccount {
+	model := s.costModel()
+	return &costAccount{s: s, model: model, tlb: tlbModel{entries: model.tlbEntries, stamp: make(map[string]int)}}
+}
+
+func (a *costAccount) OnHit(e HitEvent) error {
+	path := costTLBHit
+	if !a.tlb.lookup(e.Page.PageID) {
+		path = costTLBMiss
+		a.tlb.insert(e.Page.PageID)
+	}
+	if !e.Warmup {
+		a.breakdown.add(a.model.charge(path, false, 0))
+	}
+	return nil
+}
+
+func (a *costAccount) OnEvict(e EvictEvent) error {
+	a.tlb.invalidate(e.Page)
+	a.writeBack, a.victimClass = e.WriteBack, a.s.pageClass(e.Page)
+	return nil
+}
+
+func (a *costAccount) OnFault(e FaultEvent) error {
+	path := costMajor
+	if a.s.isZeroFill(e.Page, e.Load) {
+		path = costMinor
+	}
+	a.tlb.insert(e.Page.PageID)
+	if !e.Warmup {
+		bucket, cost := a.model.charge(path, a.writeBack, a.victimClass)
+		if a.s.weighted {
+			cost = int64(math.Round(float64(cost) * a.s.weights.of(e.Page)))
+		}
+		a.breakdown.add(bucket, cost)
+	}
+	a.writeBack = false
+	return nil
+}
+
+func (a *costAccount) OnComplete(CompleteEvent) error { return nil }
+
+// Divisão do custo simulado por balde (-cost)
+func (s *Simulator) showCosts(r Result) {
+	c := r.Costs
+	if c == nil {
+		return
+	}
+	total := c.Total()
+	fmt.Printf("Custo simulado (%s): %s", r.Algorithm, time.Duration(total))
+	if r.Accesses > 0 {
+		fmt.Printf(" em %d acessos (%s por acesso)", r.Accesses, time.Duration(total/int64(r.Accesses)))
+	}
+	fmt.Println()
+	for b := costBucket(0); b < costBuckets; b++ {
+		share := 0.0
+		if total > 0 {
+			share = float64(c.Nanos[b]) / float64(total) * 100
+		}
+		line := fmt.Sprintf("  %-22s %10d acessos %14s %6.2f%% %s", costBucketNames[b], c.Counts[b],
+			time.Duration(c.Nanos[b]), share, strings.Repeat("#", int(share/5+0.5)))
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
+
+// Grava em JSON a divisão do custo de cada algoritmo (-cost-json)
+func writeCosts(filename string, results []Result) error {
+	type algorithmCosts struct {
+		Algorithm string         `json:"algorithm"`
+		Accesses  int            `json:"accesses"`
+		Costs     *CostBreakdown `json:"costs"`
+	}
+	var costs []algorithmCosts
+	for _, r := range results {
+		costs = append(costs, algorithmCosts{r.Algorithm, r.Accesses, r.Costs})
+	}
+	return writeVersionedList(filename, costs)
+}
+
+// Resumo dos frames residentes ao longo da execução (-rss-interval)
+func (s *Simulator) showResident(r Result) {
+	if len(r.Resident) == 0 {
+		return
+	}
+
+	threshold := s.rssThreshold
+	if threshold == 0 {
+		threshold = s.totalFrames
+	}
+	peak, sum, above, full := 0, 0, 0, -1
+	for i, frames := range r.Resident {
+		peak = max(peak, frames)
+		sum += frames
+		if frames >= threshold {
+			above++
+		}
+		if full < 0 && frames == s.totalFrames {
+			full = r.WarmupAccesses + (i+1)*s.seriesInterval
+		}
+	}
+	fmt.Printf("Frames residentes (a cada %d acessos): pico %d, média %.1f, %d de %d amostras com %d ou mais\n",
+		s.seriesInterval, peak, float64(sum)/float64(len(r.Resident)), above, len(r.Resident), threshold)
+	if full >= 0 {
+		fmt.Printf("Memória cheia na amostra do acesso %d\n", full)
+	}
+}
+
+// Grava em JSON o estado final de cada algoritmo executado
+func writeFinalStates(filename string, results []Result) error {
+	type algorithmState struct {
+		Algorithm string     `json:"algorithm"`
+		Final     FinalState `json:"final"`
+		Simulated int        `json:"simulated,omitempty"` // acessos simulados, se -converge parou antes
+	}
+	var states []algorithmState
+	for _, r := range results {
+		state := algorithmState{Algorithm: r.Algorithm, Final: r.Final}
+		if r.Partial {
+			state.Simulated = r.Accesses
+		}
+		states = append(states, state)
+	}
+	return writeVersionedList(filename, states)
+}
+
+// Grava séries temporais em CSV: uma linha por amostra, com o número do
+// acesso (as amostras começam depois de start) e uma coluna por série
+func writeSeriesCSV(filename string, start, interval int, names []string, series [][]int) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("erro ao criar %s: %v", filename, err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write(append([]string{"acesso"}, names...))
+	rows := 0
+	for _, values := range series {
+		rows = max(rows, len(values))
+	}
+	for row := 0; row < rows; row++ {
+		record := []string{strconv.Itoa(start + (row+1)*interval)}
+		for _, values := range series {
+			if row < len(values) {
+				record = append(record, strconv.Itoa(values[row]))
+			} else {
+				record = append(record, "")
+			}
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// Faltas, substituições e gravações por classe de página da última execução
+func (s *Simulator) ShowClassStats() {
+	if s.fileRanges == nil {
+		return
+	}
+
+	costs := [...]int{s.swapWriteCost, s.fileWriteCost}
+	fmt.Printf("%-10s %10s %14s %10s %16s\n", "Classe", "Faltas", "Substituições", "Gravações", "Tempo gravando")
+	for class, stats := range s.classStats {
+		writeTime := time.Duration(stats.Writes*costs[class]) * time.Microsecond
+		fmt.Printf("%-10s %10d %14d %10d %16s\n", pageClassNames[class],
+			stats.Faults, stats.Evictions, stats.Writes, writeTime)
+	}
+}
+
+// Distribuição dos acessos e das páginas quentes pelas cores da cache
+func (s *Simulator) ShowColorStats() {
+	stats := s.colorStats
+	if len(stats.Accesses) == 0 {
+		return
+	}
+
+	fmt.Printf("Cores de página: %d (cache de %s, %d vias, linhas de %d bytes)\n",
+		len(stats.Accesses), formatBytes(float64(s.cacheSize)), s.cacheAssoc, s.cacheLine)
+	worst := 0
+	for color := range stats.Accesses {
+		if color < maxFrameStatsRows {
+			fmt.Printf("  Cor %-3d acessos: %8d | páginas quentes carregadas: %d\n",
+				color, stats.Accesses[color], stats.HotLoads[color])
+		}
+		if stats.Accesses[color] > stats.Accesses[worst] {
+			worst = color
+		}
+	}
+	if len(stats.Accesses) > maxFrameStatsRows {
+		fmt.Printf("  ... (%d cores omitidas)\n", len(stats.Accesses)-maxFrameStatsRows)
+	}
+	fmt.Printf("Cor mais carregada: %d, com %.1f%% dos acessos (uniforme: %.1f%%)\n",
+		worst, 100*float64(stats.Accesses[worst])/float64(len(s.accesses)), 100/float64(len(stats.Accesses)))
+	fmt.Printf("Colisões de páginas quentes na mesma cor: %d\n", stats.Collisions)
+}
+
+// Compara o Ótimo clássico com a variante que, nos empates, prefere
+// substituir páginas limpas (as faltas são sempre as mesmas)
+func (s *Simulator) ShowCleanOptimal(classic Result) {
+	policy := newOptimalPolicy(s.totalFrames, s.BuildNextUseIndex())
+	policy.preferClean = true
+	clean := s.runPolicy(policy)
+
+	fmt.Printf("Ótimo com desempate por páginas limpas: %d faltas, %d gravações\n",
+		clean.Faults, clean.WriteBacks)
+	fmt.Printf("Gravações evitadas pelo desempate: %d\n", classic.WriteBacks-clean.WriteBacks)
+}
+
+// Compara a segunda chance melhorada com o Relógio comum, que substitui
+// páginas modificadas sem preferir as limpas
+func (s *Simulator) ShowEnhancedClock(enhanced Result) {
+	if s.writeCount == 0 {
+		fmt.Println("Trace sem escritas: nenhuma página modificada para evitar")
+		return
+	}
+	plain := s.runPolicy(newClockPolicy(s.totalFrames))
+	fmt.Printf("Relógio comum: %d faltas, %d gravações\n", plain.Faults, plain.WriteBacks)
+	fmt.Printf("Gravações evitadas pelo bit M: %d (faltas: %+d)\n",
+		plain.WriteBacks-enhanced.WriteBacks, enhanced.Faults-plain.Faults)
+}
+
+// Compara o Relógio adaptativo (última execução) com o Relógio comum
+func (s *Simulator) ShowAdaptiveClock(clock *clockPolicy, adaptiveFaults int) {
+	if !s.clockAdaptive {
+		return
+	}
+
+	fmt.Println("\n=== RELÓGIO ADAPTATIVO ===")
+	fmt.Printf("Varredura: %d a %d frames por falta | Janela de pressão: %d acessos\n",
+		clock.sweepMin, clock.sweepMax, len(clock.window))
+	fmt.Printf("Varredura média: %.2f frames por substituição\n", clock.AverageSweep())
+
+	plain := s.newClock()
+	plain.adaptive = false
+	plainFaults := s.runPolicy(plain).Faults
+	fmt.Printf("Faltas de página (adaptativo): %d\n", adaptiveFaults)
+	fmt.Printf("Faltas de página (Relógio comum): %d\n", plainFaults)
+	fmt.Printf("Diferença: %+d faltas\n", adaptiveFaults-plainFaults)
+}
+
+// Executa o Relógio para cada intervalo de limpeza dos bits R
+func (s *Simulator) RefClearSweep() {
+	if len(s.refClearSweep) == 0 {
+		return
+	}
+
+	mode := "ponteiro e interrupção"
+	if s.refClearOnlyTimer {
+		mode = "apenas interrupção"
+	}
+	fmt.Printf("\n=== VARREDURA DO INTERVALO DE LIMPEZA DOS BITS R (%s) ===\n", mode)
+	fmt.Printf("%12s %10s\n", "Intervalo", "Faltas")
+
+	original := s.refClearInterval
+	for _, interval := range s.refClearSweep {
+		s.refClearInterval = interval
+		faults := s.runPolicy(s.newClock()).Faults
+		label := strconv.Itoa(interval)
+		if interval == 0 {
+			label = "sem limpeza"
+		}
+		fmt.Printf("%12s %10d\n", label, faults)
+	}
+	s.refClearInterval = original
+}
+
+// Um ponto da varredura de τ (-sweep-tau) pelo modelo de Working Set de
+// Denning: a página está na memória se foi acessada nos últimos τ acessos.
+// O número de frames ocupados varia; OverFrames é a fração dos acessos em
+// que o working set passou da memória configurada.
+type workingSetPoint struct {
+	Tau        int
+	Faults     int
+	AvgSize    float64
+	OverFrames float64
+}
+
+// Acima do joelho as faltas caem menos que esta fração
+const kneeTolerance = 0.01
+
+func workingSetModel(accesses []PageAccess, tau, frames int) workingSetPoint {
+	point := workingSetPoint{Tau: tau}
+	if len(accesses) == 0 {
+		return point
+	}
+	ids := make(map[string]int)
+	pages := make([]int, len(accesses))
+	for t, access := range accesses {
+		id, ok := ids[access.PageID]
+		if !ok {
+			id = len(ids)
+			ids[access.PageID] = id
+		}
+		pages[t] = id
+	}
+	last := make([]int, len(ids))
+	for i := range last {
+		last[i] = -1
+	}
+
+	size, total, over := 0, 0, 0
+	for t, page := range pages {
+		previous := last[page]
+		if previous < 0 || previous < t-tau {
+			point.Faults++
+		}
+		// O acesso t-τ sai da janela; a página sai junto se não voltou depois
+		if e := t - tau; e >= 0 && last[pages[e]] == e {
+			size--
+		}
+		if previous < 0 || previous <= t-tau {
+			size++
+		}
+		last[page] = t
+		total += size
+		if size > frames {
+			over++
+		}
+	}
+	point.AvgSize = float64(total) / float64(len(pages))
+	point.OverFrames = float64(over) / float64(len(pages))
+	return point
+}
+
+// O mesmo modelo sobre o tempo: τ é uma duração e a página está no working
+// set se foi referenciada há no máximo τ; o tamanho médio e a fração acima
+// dos frames são ponderados pelo atraso de cada acesso. Com atrasos iguais
+// a d, τ·d dá o mesmo resultado que workingSetModel com τ acessos.
+func workingSetModelTime(accesses []PageAccess, times []int64, tau int64, frames int) workingSetPoint {
+	point := workingSetPoint{Tau: int(tau)}
+	if len(accesses) == 0 {
+		return point
+	}
+	ids := make(map[string]int)
+	last := []int(nil)
+	pages := make([]int, len(accesses))
+	for t, access := range accesses {
+		id, ok := ids[access.PageID]
+		if !ok {
+			id = len(ids)
+			ids[access.PageID] = id
+			last = append(last, -1)
+		}
+		pages[t] = id
+	}
+
+	size, head := 0, 0
+	var total, over float64
+	for t, page := range pages {
+		now := times[t]
+		previous := last[page]
+		if previous < 0 || now-times[previous] > tau {
+			point.Faults++
+		}
+		// Saem da janela as referências de há τ ou mais
+		for head < t && now-times[head] >= tau {
+			if last[pages[head]] == head {
+				size--
+			}
+			head++
+		}
+		if previous < head {
+			size++
+		}
+		last[page] = t
+		weight := float64(times[t+1] - now)
+		total += float64(size) * weight
+		if size > frames {
+			over += weight
+		}
+	}
+	if span := float64(times[len(pages)] - times[0]); span > 0 {
+		point.AvgSize = total / span
+		point.OverFrames = over / span
+	}
+	return point
+}
+
+// Roda os pontos da varredura em paralelo, um por processador
+func sweepWorkingSet(taus []int, model func(tau int) workingSetPoint) []workingSetPoint {
+	points := make([]workingSetPoint, len(taus))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, runtime.NumCPU())
+	for i, tau := range taus {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			points[i] = model(tau)
+			<-slots
+		}()
+	}
+	wg.Wait()
+	return points
+}
+
+// O trace tem a coluna de atraso em algum acesso
+func timedTrace(accesses []PageAccess) bool {
+	for _, access := range accesses {
+		if access.Delay != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Início de cada acesso, mais o fim do trace, somando os atrasos; sem a
+// coluna +N todo acesso dura -access-time (ou 1 ns). Erro se a soma não
+// cabe em int64.
+func (s *Simulator) accessTimes() ([]int64, error) {
+	timed := timedTrace(s.accesses)
+	fixed := max(s.accessTime, 1)
+	times := make([]int64, len(s.accesses)+1)
+	for i, access := range s.accesses {
+		delay := fixed
+		if timed {
+			delay = access.Delay
+		}
+		if delay > math.MaxInt64-times[i] {
+			return nil, fmt.Errorf("o tempo acumulado passa de %d ns no acesso %d", int64(math.MaxInt64), i+1)
+		}
+		times[i+1] = times[i] + delay
+	}
+	return times, nil
+}
+
+// Menor τ a partir do qual as faltas não melhoram mais que kneeTolerance
+// até o maior τ da varredura; -1 se a varredura tem menos de dois pontos
+func workingSetKnee(points []workingSetPoint) int {
+	if len(points) < 2 {
+		return -1
+	}
+	sorted := append([]workingSetPoint(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Tau < sorted[j].Tau })
+	best := sorted[len(sorted)-1].Faults
+	for _, point := range sorted {
+		if float64(point.Faults-best) <= kneeTolerance*float64(point.Faults) {
+			return point.Tau
+		}
+	}
+	return sorted[len(sorted)-1].Tau
+}
+
+func (s *Simulator) SweepTau() {
+	if len(s.sweepTau) == 0 {
+		return
+	}
+	fmt.Println("\n=== VARREDURA DE τ (WORKING SET) ===")
+	model := func(tau int) workingSetPoint { return workingSetModel(s.accesses, tau, s.totalFrames) }
+	tauText := strconv.Itoa
+	if s.sweepTauTime {
+		times, err := s.accessTimes()
+		if err != nil {
+			logger.Error("varredura de τ no tempo impossível", "err", err)
+			return
+		}
+		model = func(tau int) workingSetPoint {
+			return workingSetModelTime(s.accesses, times, int64(tau), s.totalFrames)
+		}
+		tauText = func(tau int) string { return time.Duration(tau).String() }
+		fmt.Println("τ em tempo: janelas sobre os atrasos do trace (coluna +N ou -access-time)")
+	}
+	points := sweepWorkingSet(s.sweepTau, model)
+	largest := 0
+	for _, tau := range s.sweepTau {
+		largest = max(largest, tau)
+	}
+	fmt.Printf("%10s %10s %11s %10s %14s\n", "τ", "Faltas", "Taxa falta", "WS médio", "WS > frames")
+	for _, point := range points {
+		fmt.Printf("%10s %10d %10.2f%% %10.1f %13.2f%%\n", tauText(point.Tau), point.Faults,
+			float64(point.Faults)/float64(max(len(s.accesses), 1))*100, point.AvgSize, point.OverFrames*100)
+	}
+	switch knee := workingSetKnee(points); {
+	case knee < 0:
+		fmt.Println("Informe ao menos dois valores de τ para localizar o joelho")
+	case knee == largest:
+		fmt.Printf("As faltas ainda caem no maior τ (%s); amplie a varredura para achar o joelho\n", tauText(knee))
+	default:
+		fmt.Printf("Joelho: τ = %s; acima dele as faltas caem no máximo %.0f%%\n", tauText(knee), kneeTolerance*100)
+	}
+
+	if s.sweepTauCSV != "" {
+		if err := writeSweepTauCSV(s.sweepTauCSV, len(s.accesses), points, s.sweepTauTime); err != nil {
+			logger.Error("erro ao gravar o CSV", "file", s.sweepTauCSV, "err", err)
+		} else {
+			fmt.Printf("Varredura gravada em %s\n", s.sweepTauCSV)
+		}
+	}
+}
+
+// Número com sufixo opcional k (mil) ou M (milhão), como em 10k ou 1M
+func parseCount(text string) (int, error) {
+	multiplier := 1
+	switch {
+	case strings.HasSuffix(text, "k"), strings.HasSuffix(text, "K"):
+		multiplier = 1000
+	case strings.HasSuffix(text, "M"):
+		multiplier = 1000000
+	}
+	if multiplier > 1 {
+		text = text[:len(text)-1]
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, err
+	}
+	return n * multiplier, nil
+}
+
+func writeSweepTauCSV(filename string, accesses int, points []workingSetPoint, inTime bool) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("erro ao criar %s: %v", filename, err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	tau := "tau"
+	if inTime {
+		tau = "tau_ns"
+	}
+	w.Write([]string{tau, "faltas", "taxa", "ws_medio", "fracao_acima_frames"})
+	for _, point := range points {
+		w.Write([]string{strconv.Itoa(point.Tau), strconv.Itoa(point.Faults),
+			strconv.FormatFloat(float64(point.Faults)/float64(max(accesses, 1)), 'f', 6, 64),
+			strconv.FormatFloat(point.AvgSize, 'f', 3, 64),
+			strconv.FormatFloat(point.OverFrames, 'f', 6, 64)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// Menor memória que cumpre um orçamento de faltas (-target-faults,
+// -target-rate). O Ótimo é um algoritmo de pilha: as faltas nunca aumentam
+// com mais frames e a curva de uma passada dá a resposta. Os demais são
+// procurados por bisseção, que supõe faltas monótonas; o Relógio e as
+// variantes do FIFO podem não ser (anomalia de Belady, examples/belady.txt),
+// então os targetScanWindow frames abaixo da resposta da bisseção são
+// conferidos um a um e o menor que cumpre o orçamento vence.
+const targetScanWindow = 16
+
+type targetProbe struct {
+	Frames int  `json:"frames"`
+	Faults int  `json:"faults"`
+	Meets  bool `json:"meets"`
+}
+
+type targetRecommendation struct {
+	Algorithm string        `json:"algorithm"`
+	Budget    int           `json:"budget"`
+	Method    string        `json:"method"` // "curve" ou "bisect+scan"
+	Frames    int           `json:"frames"` // 0: nenhuma memória até Limit cumpre o orçamento
+	Memory    int           `json:"memory_bytes"`
+	Faults    int           `json:"faults"`
+	Limit     int           `json:"limit"`
+	Probes    []targetProbe `json:"probes"`
+}
+
+func (s *Simulator) recommendFrames(name string, budget int) (targetRecommendation, error) {
+	rec := targetRecommendation{Algorithm: name, Budget: budget, Limit: max(len(s.distinctPages), 1)}
+	if name == "optimal" {
+		rec.Method = "curve"
+		curve := optimalMissCurve(s.accesses)
+		for c := 1; c <= rec.Limit; c++ {
+			faults := 0
+			if c <= len(curve) {
+				faults = curve[c-1]
+			} else if len(curve) > 0 {
+				faults = curve[len(curve)-1]
+			}
+			if faults <= budget {
+				rec.Frames, rec.Faults = c, faults
+				rec.Probes = append(rec.Probes, targetProbe{c, faults, true})
+				break
+			}
+		}
+		rec.Memory = rec.Frames * PAGE_SIZE
+		return rec, nil
+	}
+
+	rec.Method = "bisect+scan"
+	faultsAt := make(map[int]int)
+	probe := func(frames int) (bool, error) {
+		faults, ok := faultsAt[frames]
+		if !ok {
+			var err error
+			if faults, err = s.stepperFaults(s.accesses, frames, name); err != nil {
+				return false, err
+			}
+			faultsAt[frames] = faults
+			rec.Probes = append(rec.Probes, targetProbe{frames, faults, faults <= budget})
+		}
+		return faults <= budget, nil
+	}
+
+	meets, err := probe(rec.Limit)
+	if err != nil || !meets {
+		return rec, err
+	}
+	lo, hi := 1, rec.Limit // hi cumpre o orçamento
+	for lo < hi {
+		mid := (lo + hi) / 2
+		meets, err := probe(mid)
+		if err != nil {
+			return rec, err
+		}
+		if meets {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	for c := max(1, hi-targetScanWindow); c < hi; c++ {
+		meets, err := probe(c)
+		if err != nil {
+			return rec, err
+		}
+		if meets {
+			hi = c
+			break
+		}
+	}
+	rec.Frames, rec.Faults, rec.Memory = hi, faultsAt[hi], hi*PAGE_SIZE
+	return rec, nil
+}
+
+func (s *Simulator) RecommendMemory() {
+	if s.targetFaults < 0 && s.targetRate < 0 {
+		return
+	}
+	budget := s.targetFaults
+	if s.targetRate >= 0 {
+		budget = int(s.targetRate * float64(len(s.accesses)))
+	}
+	fmt.Printf("\n=== MEMÓRIA MÍNIMA PARA ATÉ %d FALTAS ===\n", budget)
+
+	var recs []targetRecommendation
+	for _, name := range s.algorithms {
+		rec, err := s.recommendFrames(name, budget)
+		if err != nil {
+			logger.Error("erro ao recomendar a memória", "algorithm", name, "err", err)
+			return
+		}
+		recs = append(recs, rec)
+
+		label := "Ótimo"
+		if info, ok := findPolicy(name); ok {
+			label = info.Label
+		}
+		method := "curva de uma passada"
+		if rec.Method != "curve" {
+			method = fmt.Sprintf("bisseção e conferência dos %d frames abaixo", targetScanWindow)
+		}
+		fmt.Printf("%s (%s):\n", label, method)
+		if rec.Method != "curve" {
+			for _, p := range rec.Probes {
+				mark := "acima do orçamento"
+				if p.Meets {
+					mark = "cumpre"
+				}
+				fmt.Printf("  %6d frames: %10d faltas  %s\n", p.Frames, p.Faults, mark)
+			}
+		}
+		if rec.Frames == 0 {
+			fmt.Printf("  Nenhuma memória até %d frames (uma por página distinta) cumpre o orçamento\n", rec.Limit)
+			continue
+		}
+		fmt.Printf("  Recomendação: %d frames (%s), %d faltas\n", rec.Frames, formatBytes(float64(rec.Memory)), rec.Faults)
+	}
+
+	if s.targetJSON != "" {
+		if err := writeTargetJSON(s.targetJSON, recs); err != nil {
+			logger.Error("erro ao gravar o JSON", "file", s.targetJSON, "err", err)
+		} else {
+			fmt.Printf("Recomendações gravadas em %s\n", s.targetJSON)
+		}
+	}
+}
+
+func writeTargetJSON(filename string, recs []targetRecommendation) error {
+	return writeVersionedList(filename, recs)
+}
+
+// Faltas do Ótimo para todos os números de frames numa única passada
+// (algoritmo de pilha de Mattson et al.): a memória de c frames contém
+// sempre as c páginas do topo da pilha. A cada acesso a página vai para o
+// topo e, em cada nível acima da posição que ela ocupava, fica a página
+// usada de novo mais cedo; a de uso mais distante desce um nível, como a
+// vítima que o Ótimo com aquele número de frames escolheria.
+// faults[c-1] são as faltas com c frames, para c até o número de páginas
+// distintas; com mais frames só há as faltas compulsórias.
+func optimalMissCurve(accesses []PageAccess) []int {
+	n := len(accesses)
+	next := make([]int, n) // próximo acesso à mesma página (n: nenhum)
+	last := make(map[string]int)
+	for i := n - 1; i >= 0; i-- {
+		next[i] = n
+		if j, ok := last[accesses[i].PageID]; ok {
+			next[i] = j
+		}
+		last[accesses[i].PageID] = i
+	}
+
+	type entry struct {
+		page string
+		next int
+	}
+	var stack []entry
+	var hits []int // hits[d]: acessos encontrados na profundidade d da pilha
+	for i, access := range accesses {
+		depth := len(stack)
+		for d, e := range stack {
+			if e.page == access.PageID {
+				depth = d
+				hits[d]++
+				break
+			}
+		}
+		if len(stack) == 0 {
+			stack = append(stack, entry{access.PageID, next[i]})
+			hits = append(hits, 0)
+			continue
+		}
+		carried := stack[0]
+		stack[0] = entry{access.PageID, next[i]}
+		if depth == 0 {
+			continue
+		}
+		for d := 1; d < depth; d++ {
+			if stack[d].next > carried.next {
+				stack[d], carried = carried, stack[d]
+			}
+		}
+		if depth == len(stack) {
+			stack = append(stack, carried)
+			hits = append(hits, 0)
+		} else {
+			stack[depth] = carried
+		}
+	}
+
+	faults := make([]int, len(stack))
+	found := 0
+	for d := range faults {
+		found += hits[d]
+		faults[d] = n - found
+	}
+	return faults
+}
+
+// Números de frames mostrados na tabela da curva: de um em um no começo,
+// depois em passos de ~10%, sempre incluindo o último
+func curveSizes(limit int) []int {
+	var sizes []int
+	for c := 1; c <= limit; c = max(c+1, c*11/10) {
+		sizes = append(sizes, c)
+	}
+	if len(sizes) > 0 && sizes[len(sizes)-1] != limit {
+		sizes = append(sizes, limit)
+	}
+	return sizes
+}
+
+// Curva de taxa de faltas por número de frames (-mrc); só o Ótimo tem a
+// propriedade de inclusão que permite calculá-la numa passada
+func (s *Simulator) ShowMissRatioCurve() {
+	if !s.showMRC {
+		return
+	}
+	fmt.Println("\n=== CURVA DE TAXA DE FALTAS (ÓTIMO) ===")
+	if !s.algorithmSelected("optimal") || len(s.accesses) == 0 {
+		fmt.Println("A curva em uma passada só existe para o algoritmo ótimo (inclua optimal em -algorithms)")
+		return
+	}
+	faults := optimalMissCurve(s.accesses)
+	fmt.Printf("%8s %10s %11s\n", "Frames", "Faltas", "Taxa falta")
+	for _, c := range curveSizes(len(faults)) {
+		marker := ""
+		if c == s.totalFrames {
+			marker = "  <- memória configurada"
+		}
+		fmt.Printf("%8d %10d %10.2f%%%s\n", c, faults[c-1], float64(faults[c-1])/float64(len(s.accesses))*100, marker)
+	}
+	compulsory := faults[len(faults)-1]
+	enough := sort.Search(len(faults), func(c int) bool { return faults[c] == compulsory }) + 1
+	fmt.Printf("Com %d frames ou mais só restam as %d faltas compulsórias\n", enough, compulsory)
+
+	if s.mrcCSV != "" {
+		if err := writeMissRatioCSV(s.mrcCSV, len(s.accesses), faults); err != nil {
+			logger.Error("erro ao gravar o CSV", "file", s.mrcCSV, "err", err)
+		} else {
+			fmt.Printf("Curva gravada em %s\n", s.mrcCSV)
+		}
+	}
+}
+
+// Grava a curva em CSV: uma linha por número de frames
+func writeMissRatioCSV(filename string, accesses int, faults []int) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("erro ao criar %s: %v", filename, err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write([]string{"frames", "faltas", "taxa"})
+	for c, n := range faults {
+		w.Write([]string{strconv.Itoa(c + 1), strconv.Itoa(n),
+			strconv.FormatFloat(float64(n)/float64(accesses), 'f', 6, 64)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// Linha do tempo de páginas escolhidas (-page-timeline, -top-faulted):
+// cada acesso, cada substituição e os intervalos em que a página ficou
+// residente. Os eventos vêm de step, então vale para qualquer política.
+type timelineEvent struct {
+	Page   string
+	Kind   string // hit, falta ou substituída
+	Access int    // número do acesso, a partir de 1
+	Frame  int
+	Other  string // na falta, a página substituída; na substituição, a que tomou o frame
+	Load   int    // na falta, cargas da página até aqui (1: falta fria)
+}
+
+type residency struct {
+	Page  string
+	Frame int
+	Start int // acesso que carregou a página
+	End   int // acesso que a substituiu; 0 se ficou residente até o fim
+}
+
+func pageTimeline(policy ReplacementPolicy, accesses []PageAccess, pages map[string]bool) ([]timelineEvent, []residency) {
+	var events []timelineEvent
+	var intervals []residency
+	open := make(map[string]int) // página residente -> índice em intervals
+	loads := make(map[string]int)
+	for i, access := range accesses {
+		r := step(policy, access)
+		if !r.Hit {
+			loads[access.PageID]++
+		}
+		if r.Victim != "" && pages[r.Victim] {
+			events = append(events, timelineEvent{r.Victim, "substituída", i + 1, r.Frame, access.PageID, 0})
+			intervals[open[r.Victim]].End = i + 1
+			delete(open, r.Victim)
+		}
+		if !pages[access.PageID] {
+			continue
+		}
+		if r.Hit {
+			events = append(events, timelineEvent{access.PageID, "hit", i + 1, r.Frame, "", 0})
+			continue
+		}
+		events = append(events, timelineEvent{access.PageID, "falta", i + 1, r.Frame, r.Victim, loads[access.PageID]})
+		open[access.PageID] = len(intervals)
+		intervals = append(intervals, residency{access.PageID, r.Frame, i + 1, 0})
+	}
+	return events, intervals
+}
+
+// As n páginas com mais faltas (cargas); empates pela ordem das páginas
+func topFaulted(loadCount map[string]int, n, radix int) []string {
+	var pages []string
+	for page := range loadCount {
+		pages = append(pages, page)
+	}
+	sortPageIDs(pages, radix)
+	sort.SliceStable(pages, func(i, j int) bool { return loadCount[pages[i]] > loadCount[pages[j]] })
+	return pages[:min(n, len(pages))]
+}
+
+// Mostra e grava (-out) a linha do tempo das páginas escolhidas em cada
+// algoritmo executado
+func (s *Simulator) ShowPageTimeline(results []Result) {
+	if len(s.timelinePages) == 0 && s.topFaulted == 0 {
+		return
+	}
+	fmt.Println("\n=== LINHA DO TEMPO DAS PÁGINAS ===")
+	records := [][]string{{"algoritmo", "pagina", "evento", "acesso", "frame", "outra_pagina", "fim", "carga"}}
+	for _, r := range results {
+		pages := make(map[string]bool)
+		for _, page := range s.timelinePages {
+			pages[page] = true
+		}
+		for _, page := range topFaulted(r.Stats.LoadCount, s.topFaulted, s.pageRadix) {
+			pages[page] = true
+		}
+		events, intervals := pageTimeline(s.newPolicyFor(r.Algorithm), s.accesses, pages)
+
+		var ids []string
+		for page := range pages {
+			ids = append(ids, page)
+		}
+		sortPageIDs(ids, s.pageRadix)
+		for _, page := range ids {
+			faults, hits := 0, 0
+			for _, e := range events {
+				if e.Page == page && e.Kind == "falta" {
+					faults++
+				} else if e.Page == page && e.Kind == "hit" {
+					hits++
+				}
+			}
+			var spans []string
+			for _, iv := range intervals {
+				if iv.Page != page {
+					continue
+				}
+				if iv.End == 0 {
+					spans = append(spans, fmt.Sprintf("[%d, fim] no frame %d", iv.Start, iv.Frame))
+				} else {
+					spans = append(spans, fmt.Sprintf("[%d, %d) no frame %d", iv.Start, iv.End, iv.Frame))
+				}
+			}
+			if len(spans) == 0 {
+				fmt.Printf("%s (%s): nunca acessada\n", page, r.Algorithm)
+				continue
+			}
+			fmt.Printf("%s (%s): %d faltas, %d hits, residente em %s\n",
+				page, r.Algorithm, faults, hits, strings.Join(spans, ", "))
+		}
+
+		for _, e := range events {
+			load := ""
+			if e.Load > 0 {
+				load = faultKind(e.Load) // fria ou recarga, Nª vez
+			}
+			records = append(records, []string{r.Algorithm, e.Page, e.Kind,
+				strconv.Itoa(e.Access), strconv.Itoa(e.Frame), e.Other, "", load})
+		}
+		for _, iv := range intervals {
+			end := ""
+			if iv.End > 0 {
+				end = strconv.Itoa(iv.End)
+			}
+			records = append(records, []string{r.Algorithm, iv.Page, "residente",
+				strconv.Itoa(iv.Start), strconv.Itoa(iv.Frame), "", end, ""})
+		}
+	}
+
+	if s.timelineOut == "" {
+		return
+	}
+	file, err := os.Create(s.timelineOut)
+	if err != nil {
+		logger.Error("erro ao criar arquivo", "file", s.timelineOut, "err", err)
+		return
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+	w.WriteAll(records)
+	if err := w.Error(); err != nil {
+		logger.Error("erro ao gravar arquivo", "file", s.timelineOut, "err", err)
+		return
+	}
+	fmt.Printf("Linha do tempo gravada em %s\n", s.timelineOut)
+}
+
+// Mapa de calor do conteúdo dos frames (-heatmap): uma faixa por
+// algoritmo, com o tempo no eixo x e os frames no eixo y. Cada página tem
+// sua cor, então cada substituição aparece como uma troca de cor.
+const (
+	heatmapMaxColumns = 2000 // janelas de acessos
+	heatmapMaxRows    = 512  // frames mostrados
+	heatmapWidth      = 1000 // largura da área do gráfico, em px
+	heatmapLegend     = 10   // páginas na legenda
+)
+
+type heatmapPanel struct {
+	Title string
+	Grid  [][]string // Grid[linha][coluna]: página no frame ("" se vazio)
+}
+
+// Cor fixa de cada página, sorteada pelo hash do identificador
+func pageColor(pageID string) string {
+	h := fnv.New32a()
+	h.Write([]byte(pageID))
+	v := h.Sum32()
+	return fmt.Sprintf("hsl(%d,%d%%,%d%%)", v%360, 50+v/360%35, 40+v/12600%25)
+}
+
+// Amostra o conteúdo dos frames ao fim de cada janela de window acessos,
+// mostrando um frame a cada every
+func frameSnapshots(policy ReplacementPolicy, accesses []PageAccess, window, every int) [][]string {
+	frames := len(policy.Frames())
+	grid := make([][]string, (frames+every-1)/every)
+	for i, access := range accesses {
+		step(policy, access)
+		if (i+1)%window != 0 && i+1 != len(accesses) {
+			continue
+		}
+		for row := range grid {
+			page := ""
+			if frame := policy.Frames()[row*every]; frame != nil {
+				page = frame.PageID
+			}
+			grid[row] = append(grid[row], page)
+		}
+	}
+	return grid
+}
+
+func renderHeatmap(w io.Writer, panels []heatmapPanel, accesses, window, frames, step, radix int) error {
+	const left, legendWidth, titleHeight, axisHeight = 60, 160, 24, 36
+	columns := (accesses + window - 1) / window
+	rows := (frames + step - 1) / step
+	cellWidth := float64(heatmapWidth) / float64(columns)
+	cellHeight := min(14, max(2, 600/rows))
+	panelHeight := titleHeight + rows*cellHeight + axisHeight
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="11">`+"\n",
+		left+heatmapWidth+legendWidth, len(panels)*panelHeight)
+	for p, panel := range panels {
+		top := p*panelHeight + titleHeight
+		b.WriteString(`<text x="` + strconv.Itoa(left) + `" y="` + strconv.Itoa(top-8) + `" font-size="13">`)
+		xml.EscapeText(&b, []byte(panel.Title))
+		b.WriteString("</text>\n")
+
+		// Sequências de colunas com a mesma página viram um só retângulo
+		occupancy := make(map[string]int)
+		for row, cells := range panel.Grid {
+			for start := 0; start < len(cells); {
+				end := start + 1
+				for end < len(cells) && cells[end] == cells[start] {
+					end++
+				}
+				if page := cells[start]; page != "" {
+					occupancy[page] += end - start
+					fmt.Fprintf(&b, `<rect class="cell" x="%.2f" y="%d" width="%.2f" height="%d" fill="%s"/>`+"\n",
+						left+float64(start)*cellWidth, top+row*cellHeight, float64(end-start)*cellWidth, cellHeight, pageColor(page))
+				}
+				start = end
+			}
+		}
+
+		// Eixos: acessos embaixo, frames à esquerda
+		bottom := top + rows*cellHeight
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="none" stroke="#000"/>`+"\n",
+			left, top, heatmapWidth, rows*cellHeight)
+		for _, tick := range []int{0, accesses / 2, accesses} {
+			x := left + tick*heatmapWidth/accesses
+			fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle">%d</text>`+"\n", x, bottom+14, tick)
+		}
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle">acesso (janelas de %d)</text>`+"\n",
+			left+heatmapWidth/2, bottom+30, window)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">0</text>`+"\n", left-4, top+cellHeight)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%d</text>`+"\n", left-4, bottom, (rows-1)*step)
+		label := "frame"
+		if step > 1 {
+			label = fmt.Sprintf("frame (1 a cada %d)", step)
+		}
+		fmt.Fprintf(&b, `<text x="12" y="%d" transform="rotate(-90 12 %d)" text-anchor="middle">%s</text>`+"\n",
+			(top+bottom)/2, (top+bottom)/2, label)
+
+		// Legenda: as páginas que ocuparam mais células
+		var pages []string
+		for page := range occupancy {
+			pages = append(pages, page)
+		}
+		sortPageIDs(pages, radix)
+		sort.SliceStable(pages, func(i, j int) bool { return occupancy[pages[i]] > occupancy[pages[j]] })
+		for k, page := range pages[:min(heatmapLegend, len(pages))] {
+			y := top + k*16
+			fmt.Fprintf(&b, `<rect class="legend" x="%d" y="%d" width="10" height="10" fill="%s"/>`+"\n",
+				left+heatmapWidth+12, y, pageColor(page))
+			fmt.Fprintf(&b, `<text x="%d" y="%d">`, left+heatmapWidth+28, y+9)
+			xml.EscapeText(&b, []byte(page))
+			b.WriteString("</text>\n")
+		}
+	}
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Grava o mapa de calor dos algoritmos executados em -heatmap
+func (s *Simulator) WriteHeatmap(results []Result) {
+	if s.heatmapFile == "" || len(s.accesses) == 0 {
+		return
+	}
+	window := (len(s.accesses) + heatmapMaxColumns - 1) / heatmapMaxColumns
+	step := (s.totalFrames + heatmapMaxRows - 1) / heatmapMaxRows
+	var panels []heatmapPanel
+	for _, r := range results {
+		grid := frameSnapshots(s.newPolicyFor(r.Algorithm), s.accesses, window, step)
+		panels = append(panels, heatmapPanel{Title: r.Algorithm, Grid: grid})
+	}
+
+	file, err := os.Create(s.heatmapFile)
+	if err != nil {
+		logger.Error("erro ao criar arquivo", "file", s.heatmapFile, "err", err)
+		return
+	}
+	defer file.Close()
+	if err := renderHeatmap(file, panels, len(s.accesses), window, s.totalFrames, step, s.pageRadix); err != nil {
+		logger.Error("erro ao gravar arquivo", "file", s.heatmapFile, "err", err)
+		return
+	}
+	fmt.Printf("\nMapa de calor dos frames gravado em %s\n", s.heatmapFile)
+}
+
+// Animação passo a passo (-animate): um SVG por quadro com os frames, o
+// ponteiro do relógio e o acesso atual, sempre no mesmo tamanho para que
+// os arquivos possam ser juntados num GIF, e um index.html que os folheia.
+// Cada algoritmo executado grava num subdiretório com o seu nome.
+const (
+	animateColumns   = 8  // frames por linha
+	animateMaxFrames = 64 // frames desenhados; os demais são só contados
+)
+
+// Quais acessos viram quadro: todos, só as faltas ou um a cada N
+type animateFilter struct {
+	Every      int // 1 = todos
+	FaultsOnly bool
+	From, To   int // acessos, a partir de 1 (To = 0: até o fim)
+	Max        int
+}
+
+func (f animateFilter) wants(access int, r StepResult) bool {
+	if access < f.From || (f.To > 0 && access > f.To) {
+		return false
+	}
+	if f.FaultsOnly {
+		return !r.Hit
+	}
+	return access%f.Every == 0
+}
+
+func renderAnimationFrame(w io.Writer, title string, access, total int, current PageAccess, r StepResult,
+	frames []*PageFrame, hand int) error {
+	const box, gap, left, top = 64, 8, 16, 64
+	shown := min(len(frames), animateMaxFrames)
+	rows := (shown + animateColumns - 1) / animateColumns
+	width := left*2 + animateColumns*(box+gap)
+	height := top + rows*(box+gap) + 40
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="monospace" font-size="12">`+"\n",
+		width, height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/>`+"\n", width, height)
+	b.WriteString(`<text x="16" y="22" font-size="14" font-family="sans-serif">`)
+	xml.EscapeText(&b, []byte(fmt.Sprintf("%s - acesso %d de %d: %s", title, access, total, current)))
+	b.WriteString("</text>\n")
+	b.WriteString(`<text x="16" y="44">`)
+	xml.EscapeText(&b, []byte(r.Explain()))
+	b.WriteString("</text>\n")
+
+	for i, frame := range frames[:shown] {
+		x := left + i%animateColumns*(box+gap)
+		y := top + i/animateColumns*(box+gap)
+		fill, stroke := "#f4f4f4", "#999"
+		if i == r.Frame {
+			fill, stroke = "#c8f0c8", "#2a2"
+			if !r.Hit {
+				fill, stroke = "#f8c8c8", "#c22"
+			}
+		}
+		if i == hand {
+			stroke = "#00f"
+		}
+		fmt.Fprintf(&b, `<rect class="frame" x="%d" y="%d" width="%d" height="%d" fill="%s" stroke="%s" stroke-width="2"/>`+"\n",
+			x, y, box, box, fill, stroke)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" fill="#666">%d</text>`+"\n", x+4, y+14, i)
+		if frame == nil {
+			continue
+		}
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle" font-size="14">`, x+box/2, y+38)
+		xml.EscapeText(&b, []byte(frame.PageID))
+		b.WriteString("</text>\n")
+		bitR, bitM := 0, 0
+		if frame.Referenced {
+			bitR = 1
+		}
+		if frame.Dirty {
+			bitM = 1
+		}
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle" fill="#666">R=%d M=%d</text>`+"\n",
+			x+box/2, y+box-8, bitR, bitM)
+	}
+	footer := ""
+	if hand >= 0 {
+		footer = fmt.Sprintf("ponteiro no frame %d (borda azul)", hand)
+	}
+	if len(frames) > shown {
+		footer += fmt.Sprintf("  +%d frames não desenhados", len(frames)-shown)
+	}
+	fmt.Fprintf(&b, `<text x="16" y="%d">%s</text>`+"\n", height-14, footer)
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Grava os quadros de uma política em dir; devolve quantos foram gravados
+// e se o limite cortou a animação
+func writeAnimation(dir, title string, policy ReplacementPolicy, accesses []PageAccess, filter animateFilter) (int, bool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, false, fmt.Errorf("erro ao criar diretório %s: %v", dir, err)
+	}
+	var names []string
+	truncated := false
+	for i, access := range accesses {
+		r := step(policy, access)
+		if !filter.wants(i+1, r) {
+			continue
+		}
+		if len(names) == filter.Max {
+			truncated = true
+			break
+		}
+		hand := -1
+		if hp, ok := policy.(handPolicy); ok {
+			hand = hp.Hand()
+		}
+		name := fmt.Sprintf("frame-%05d.svg", len(names)+1)
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return len(names), false, fmt.Errorf("erro ao criar %s: %v", name, err)
+		}
+		err = renderAnimationFrame(file, title, i+1, len(accesses), access, r, policy.Frames(), hand)
+		file.Close()
+		if err != nil {
+			return len(names), false, fmt.Errorf("erro ao gravar %s: %v", name, err)
+		}
+		names = append(names, name)
+	}
+
+	index, err := os.Create(filepath.Join(dir, "index.html"))
+	if err != nil {
+		return len(names), truncated, fmt.Errorf("erro ao criar index.html: %v", err)
+	}
+	defer index.Close()
+	err = flipbookTemplate.Execute(index, struct {
+		Title  string
+		Frames []string
+	}{title, names})
+	return len(names), truncated, err
+}
+
+// Grava a animação de cada algoritmo executado em -animate
+func (s *Simulator) WriteAnimations(results []Result) {
+	if s.animateDir == "" {
+		return
+	}
+	fmt.Println("\n=== ANIMAÇÃO ===")
+	for _, r := range results {
+		name := "optimal"
+		for _, info := range streamingPolicies {
+			if info.Label == r.Algorithm {
+				name = info.Name
+			}
+		}
+		dir := filepath.Join(s.animateDir, name)
+		count, truncated, err := writeAnimation(dir, r.Algorithm, s.newPolicyFor(r.Algorithm), s.accesses, s.animate)
+		if err != nil {
+			logger.Error("erro ao gravar a animação", "dir", dir, "err", err)
+			return
+		}
+		fmt.Printf("%s: %d quadros em %s (abra index.html)\n", r.Algorithm, count, dir)
+		if truncated {
+			fmt.Printf("  limite de %d quadros atingido; use -animate-max ou -animate-range\n", s.animate.Max)
+		}
+	}
+}
+
+// Imprime a tabela de páginas com as páginas residentes e as acessadas
+// recentemente, em largura fixa para que impressões seguidas se alinhem
+func printPageTable(frames []*PageFrame, recent []PageAccess, radix int) {
+	type entry struct {
+		frame      int
+		referenced bool
+		dirty      bool
+	}
+	resident := make(map[string]entry)
+	for i, frame := range frames {
+		if frame != nil {
+			resident[frame.PageID] = entry{i, frame.Referenced, frame.Dirty}
+		}
+	}
+
+	pages := make(map[string]bool)
+	for pageID := range resident {
+		pages[pageID] = true
+	}
+	for _, access := range recent {
+		pages[access.PageID] = true
+	}
+
+	var sorted []string
+	for pageID := range pages {
+		sorted = append(sorted, pageID)
+	}
+	sortPageIDs(sorted, radix)
+
+	bit := func(set bool) string {
+		if set {
+			return "1"
+		}
+		return "0"
+	}
+	fmt.Printf("  %-12s %-6s %-6s %-2s %s\n", "Página", "Válida", "Frame", "R", "M")
+	for _, pageID := range sorted {
+		e, ok := resident[pageID]
+		if !ok {
+			fmt.Printf("  %-12s %-6s %-6s %-2s %s\n", pageID, "0", "-", "-", "-")
+			continue
+		}
+		fmt.Printf("  %-12s %-6s %-6d %-2s %s\n", pageID, "1", e.frame, bit(e.referenced), bit(e.dirty))
+	}
+}
+
+func (s *Simulator) printMemoryState(frames []*PageFrame) {
+	fmt.Print("Estado da memória: ")
+	printFrameList(frames)
+}
+
+func printFrameList(frames []*PageFrame) {
+	fmt.Print("[")
+	for i, frame := range frames {
+		if frame != nil {
+			refChar := "R"
+			if !frame.Referenced {
+				refChar = "NR"
+			}
+			// #N = quantas vezes este frame já foi carregado
+			fmt.Printf("%s(%s,#%d)", frame.PageID, refChar, frame.LoadCount)
+		} else {
+			fmt.Print("vazio")
+		}
+		if i < len(frames)-1 {
+			fmt.Print(", ")
+		}
+	}
+	fmt.Println("]")
+}
+
+func (s *Simulator) ShowLoadCount() {
+	if !s.showLoadCount {
+		return
+	}
+
+	fmt.Println("\n=== NÚMERO DE CARREGAMENTOS POR PÁGINA ===")
+	fmt.Println("(carregamentos de cada página; para os carregamentos de cada frame use -framestats)")
+
+	// Ordena as páginas para exibição organizada
+	var pages []string
+	for page := range s.pageLoadCount {
+		pages = append(pages, page)
+	}
+	sortPageIDs(pages, s.pageRadix)
+
+	for _, page := range pages {
+		fmt.Printf("Página %s: %d carregamentos\n", page, s.pageLoadCount[page])
+	}
+}
+
+// Resumo por frame da última execução
+func (s *Simulator) ShowFrameStats() {
+	if !s.showFrameStats {
+		return
+	}
+
+	fmt.Println("\n=== SUBSTITUIÇÕES POR FRAME ===")
+	if s.totalFrames > maxFrameStatsRows {
+		fmt.Printf("Omitido: %d frames (o resumo é exibido para até %d frames)\n",
+			s.totalFrames, maxFrameStatsRows)
+		return
+	}
+
+	fmt.Printf("%6s %14s %14s %18s\n", "Frame", "Carregamentos", "Substituições", "Páginas distintas")
+	for i, stats := range s.frameStats {
+		fmt.Printf("%6d %14d %14d %18d\n", i, stats.Loads, stats.Evictions, stats.DistinctPages)
+	}
+}
+
+// Tabela de frames (mapeamento reverso): uma entrada por frame físico com
+// a página dona (8 bytes) e os bits R e M, o contador de cargas e o número
+// de mapeadores (8 bytes). Páginas compartilhadas teriam ainda uma lista
+// de mapeadores, de 8 bytes por mapeador além do primeiro.
+const frameTableEntrySize = 16
+
+// Conteúdo final da tabela de frames de uma execução (-frame-table)
+func (s *Simulator) showFrameTable(r Result) {
+	if !s.showFrameTableFlag {
+		return
+	}
+	fmt.Printf("Tabela de frames ao final (%s):\n", r.Algorithm)
+	mappers := make(map[int]int)
+	for _, frame := range r.Final.Frames {
+		mappers[frame.Mappers]++
+	}
+	if len(r.Final.Frames) > maxFrameStatsRows {
+		fmt.Printf("  Omitida: %d frames (a tabela é exibida para até %d; use -final-state)\n",
+			len(r.Final.Frames), maxFrameStatsRows)
+	} else {
+		fmt.Printf("  %6s %-12s %2s %2s %7s %-8s %11s\n", "Frame", "Página", "R", "M", "Cargas", "Classe", "Mapeadores")
+		for i, frame := range r.Final.Frames {
+			if frame.PageID == "" {
+				fmt.Printf("  %6d %-12s\n", i, "(vazio)")
+				continue
+			}
+			fmt.Printf("  %6d %-12s %2s %2s %7d %-8s %11d\n", i, frame.PageID, bit(frame.Referenced), bit(frame.Dirty),
+				frame.LoadCount, pageClassNames[s.pageClass(frame.PageID)], frame.Mappers)
+		}
+	}
+	var counts []int
+	for n := range mappers {
+		counts = append(counts, n)
+	}
+	sort.Ints(counts)
+	fmt.Print("  Frames por número de mapeadores:")
+	for _, n := range counts {
+		fmt.Printf(" %d: %d", n, mappers[n])
+	}
+	fmt.Println()
+}
+
+func bit(set bool) string {
+	if set {
+		return "1"
+	}
+	return "0"
+}
+
+// Entrada da tabela de páginas: número do frame e bits de controle
+const pageTableEntrySize = 8
+
+func (s *Simulator) EstimatePageTableSize() {
+	if !s.showPageTable {
+		return
+	}
+
+	fmt.Println("\n=== ESTIMATIVA DO TAMANHO DA TABELA DE PÁGINAS ===")
+
+	entrySize := pageTableEntrySize
+	numDistinctPages := len(s.distinctPages)
+
+	tableSize := numDistinctPages * entrySize
+
+	fmt.Printf("Páginas distintas acessadas: %d\n", numDistinctPages)
 	fmt.Printf("Tamanho por entrada: %d bytes\n", entrySize)
 	fmt.Printf("Tamanho estimado da tabela: %d bytes (%.2f KB)\n",
 		tableSize, float64(tableSize)/1024.0)
+
+	// Lado físico: a tabela de frames tem tamanho fixo pela memória
+	frameTable := s.totalFrames * frameTableEntrySize
+	fmt.Printf("\nTabela de frames (mapeamento reverso): %d entradas de %d bytes = %s (%.3f%% da memória)\n",
+		s.totalFrames, frameTableEntrySize, formatBytes(float64(frameTable)),
+		float64(frameTable)/float64(s.memorySize)*100)
+	fmt.Println("  (sem vários processos cada frame tem um só mapeador e não há listas de mapeadores)")
+
+	// Tabela linear (um nível): uma entrada para cada página do espaço virtual
+	pageBits := bits.TrailingZeros(PAGE_SIZE)
+	fullEntries := uint64(1) << uint(s.vaddrBits-pageBits)
+
+	// I7 e D7 são a mesma página 7 para a tabela
+	numbers := make(map[uint64]bool)
+	var highest uint64
+	unnumbered, outOfRange := 0, 0
+	for pageID := range s.distinctPages {
+		number, ok := pageNumber(pageID, s.pageRadix)
+		if !ok {
+			unnumbered++
+			continue
+		}
+		if number >= fullEntries {
+			outOfRange++
+			continue
+		}
+		numbers[number] = true
+		if number > highest {
+			highest = number
+		}
+	}
+
+	if len(numbers) == 0 {
+		fmt.Println("Nenhum ID de página pôde ser interpretado como número de página")
+		return
+	}
+	validEntries := float64(len(numbers))
+
+	fmt.Printf("\nTabela de um nível para o espaço de %d bits:\n", s.vaddrBits)
+	fmt.Printf("  Entradas: %d\n", fullEntries)
+	fmt.Printf("  Tamanho: %s\n", formatBytes(float64(fullEntries)*float64(entrySize)))
+	fmt.Printf("  Entradas válidas: %.6f%%\n", validEntries/float64(fullEntries)*100)
+
+	touchedEntries := highest + 1
+	fmt.Printf("\nTabela de um nível até a maior página acessada (%d):\n", highest)
+	fmt.Printf("  Entradas: %d\n", touchedEntries)
+	fmt.Printf("  Tamanho: %s\n", formatBytes(float64(touchedEntries)*float64(entrySize)))
+	fmt.Printf("  Entradas válidas: %.2f%%\n", validEntries/float64(touchedEntries)*100)
+
+	if unnumbered > 0 {
+		fmt.Printf("Páginas sem número reconhecível (ignoradas): %d\n", unnumbered)
+	}
+	if outOfRange > 0 {
+		fmt.Printf("Páginas fora do espaço de %d bits (ignoradas): %d\n", s.vaddrBits, outOfRange)
+	}
+}
+
+// Segmentação com paginação: cada segmento tem a própria tabela de
+// páginas, com uma entrada por página até o limite do segmento (ou até a
+// maior página acessada quando o limite não foi dado). As violações de
+// limite não chegaram aos algoritmos e não são faltas de página.
+func (s *Simulator) ShowSegments(results []Result) {
+	type segmentInfo struct {
+		accesses int
+		pages    map[uint64]bool
+		highest  uint64
+	}
+	segments := make(map[uint64]*segmentInfo)
+	for _, access := range s.accesses {
+		segment, page, ok := segmentPage(access.PageID)
+		if !ok {
+			continue
+		}
+		info := segments[segment]
+		if info == nil {
+			info = &segmentInfo{pages: make(map[uint64]bool)}
+			segments[segment] = info
+		}
+		info.accesses++
+		info.pages[page] = true
+		info.highest = max(info.highest, page)
+	}
+	if len(segments) == 0 && len(s.segmentViolations) == 0 {
+		return
+	}
+	var order []uint64
+	for segment := range segments {
+		order = append(order, segment)
+	}
+	for segment := range s.segmentViolations {
+		if segments[segment] == nil {
+			order = append(order, segment)
+		}
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+
+	fmt.Println("\n=== SEGMENTAÇÃO COM PAGINAÇÃO ===")
+	fmt.Printf("%-8s %10s %9s %9s %14s %10s\n", "Segmento", "Limite", "Acessos", "Páginas", "Tabela", "Violações")
+	totalTable, totalViolations := 0.0, 0
+	for _, segment := range order {
+		info := segments[segment]
+		if info == nil {
+			info = &segmentInfo{}
+		}
+		limit, entries := "-", uint64(0)
+		if l, ok := s.segmentLimits[segment]; ok {
+			limit = fmt.Sprintf("0x%x", l)
+			entries = (l + PAGE_SIZE - 1) / PAGE_SIZE
+		} else if len(info.pages) > 0 {
+			entries = info.highest + 1
+		}
+		table := float64(entries) * pageTableEntrySize
+		totalTable += table
+		totalViolations += s.segmentViolations[segment]
+		fmt.Printf("S%-7d %10s %9d %9d %14s %10d\n", segment, limit, info.accesses,
+			len(info.pages), formatBytes(table), s.segmentViolations[segment])
+	}
+	fmt.Printf("Tabelas de páginas: %s no total (%d bytes por entrada)\n", formatBytes(totalTable), pageTableEntrySize)
+	if totalViolations > 0 {
+		fmt.Printf("Violações de segmentação: %d acessos fora dos limites (não são faltas de página)\n", totalViolations)
+	}
+
+	fmt.Println("\nFaltas de página por segmento (inclui o aquecimento):")
+	fmt.Printf("%-20s", "Algoritmo")
+	for _, segment := range order {
+		fmt.Printf(" %8s", fmt.Sprintf("S%d", segment))
+	}
+	fmt.Println()
+	for _, r := range results {
+		if r.Stats == nil {
+			continue
+		}
+		faults := make(map[uint64]int)
+		for pageID, loads := range r.Stats.LoadCount {
+			if segment, _, ok := segmentPage(pageID); ok {
+				faults[segment] += loads
+			}
+		}
+		fmt.Printf("%-20s", r.Algorithm)
+		for _, segment := range order {
+			fmt.Printf(" %8d", faults[segment])
+		}
+		fmt.Println()
+	}
+}
+
+// Tabela de páginas ocupando frames (-pt-frames): o Relógio roda sobre um
+// conjunto único de frames em que as páginas da tabela multinível que
+// mapeiam as páginas residentes também ficam residentes. A raiz ocupa um
+// frame fixo; cada tabela abaixo dela cobre ptEntriesPerPage entradas do
+// nível de baixo. Na falta, as tabelas que faltam no caminho são alocadas
+// antes do frame da página. Uma tabela que ainda mapeia páginas residentes
+// nunca é substituída; quando esvazia, com "pinned" o frame é liberado na
+// hora e com "evictable" a tabela fica até o ponteiro substituí-la.
+const (
+	ptEntrySize       = 8
+	ptEntriesPerPage  = PAGE_SIZE / ptEntrySize
+	ptIndexBits       = 9 // log2(ptEntriesPerPage)
+	ptUnnumberedIndex = math.MaxUint64
+)
+
+// Tabela do nível level (1: a que mapeia páginas de dados) no índice index
+type ptKey struct {
+	level int
+	index uint64
+}
+
+type ptFrame struct {
+	page  string // página de dados; "" em frame de tabela
+	table ptKey
+	ref   bool
+}
+
+type pageTableSim struct {
+	frames     []*ptFrame
+	hand       int
+	levels     int  // níveis abaixo da raiz mais a raiz
+	radix      int  // base dos números de página (-page-radix)
+	tables     bool // false: tabelas de graça, como no Relógio comum
+	evictable  bool
+	dataFrame  map[string]int
+	tableFrame map[ptKey]int
+	children   map[ptKey]int // entradas residentes de cada tabela
+	busy       map[ptKey]bool
+
+	dataFaults, tableLoads int
+	tableFrameSum, peak    int
+}
+
+// Níveis da tabela para o espaço de endereçamento de vaddrBits bits
+func pageTableLevels(vaddrBits int) int {
+	pageBits := bits.TrailingZeros(PAGE_SIZE)
+	return max(1, (vaddrBits-pageBits+ptIndexBits-1)/ptIndexBits)
+}
+
+func newPageTableSim(totalFrames, levels, radix int, tables, evictable bool) *pageTableSim {
+	p := &pageTableSim{
+		frames:     make([]*ptFrame, totalFrames),
+		levels:     levels,
+		radix:      radix,
+		tables:     tables,
+		evictable:  evictable,
+		dataFrame:  make(map[string]int),
+		tableFrame: make(map[ptKey]int),
+		children:   make(map[ptKey]int),
+		busy:       make(map[ptKey]bool),
+	}
+	if tables {
+		p.frames[0] = &ptFrame{table: ptKey{levels, 0}} // raiz
+	}
+	return p
+}
+
+// Tabela que mapeia a página ou a tabela key; a raiz é o nível levels
+func (p *pageTableSim) parent(key ptKey) ptKey {
+	if key.index == ptUnnumberedIndex {
+		return ptKey{key.level + 1, ptUnnumberedIndex}
+	}
+	return ptKey{key.level + 1, key.index >> ptIndexBits}
+}
+
+// Tabela de nível 1 da página; páginas sem número dividem uma tabela
+func (p *pageTableSim) leafTable(pageID string) ptKey {
+	number, ok := pageNumber(pageID, p.radix)
+	if !ok {
+		return ptKey{1, ptUnnumberedIndex}
+	}
+	return ptKey{1, number >> ptIndexBits}
+}
+
+// Frames ocupados por tabelas, a raiz inclusive
+func (p *pageTableSim) tableFrames() int {
+	if !p.tables {
+		return 0
+	}
+	return len(p.tableFrame) + 1
+}
+
+func (p *pageTableSim) Access(pageID string) (hit bool, err error) {
+	defer func() {
+		p.tableFrameSum += p.tableFrames()
+		p.peak = max(p.peak, p.tableFrames())
+	}()
+	if f, ok := p.dataFrame[pageID]; ok {
+		p.frames[f].ref = true
+		return true, nil
+	}
+	p.dataFaults++
+
+	leaf := p.leafTable(pageID)
+	if p.tables {
+		// Tabelas do caminho, de cima para baixo, antes da página
+		var path []ptKey
+		for key := leaf; key.level < p.levels; key = p.parent(key) {
+			path = append([]ptKey{key}, path...)
+		}
+		for _, key := range path {
+			p.busy[key] = true
+		}
+		defer clear(p.busy)
+		for _, key := range path {
+			if f, ok := p.tableFrame[key]; ok {
+				p.frames[f].ref = true
+				continue
+			}
+			f, err := p.allocate()
+			if err != nil {
+				return false, err
+			}
+			p.frames[f] = &ptFrame{table: key, ref: true}
+			p.tableFrame[key] = f
+			p.tableLoads++
+			p.adopt(key)
+		}
+	}
+	f, err := p.allocate()
+	if err != nil {
+		return false, err
+	}
+	p.frames[f] = &ptFrame{page: pageID, ref: true}
+	p.dataFrame[pageID] = f
+	if p.tables {
+		p.children[leaf]++
+	}
+	return false, nil
+}
+
+// Registra a tabela key como entrada residente da tabela de cima
+func (p *pageTableSim) adopt(key ptKey) {
+	if parent := p.parent(key); parent.level < p.levels {
+		p.children[parent]++
+	}
+}
+
+// Tira uma entrada da tabela key; vazia, a tabela fixa é liberada
+func (p *pageTableSim) release(key ptKey) {
+	if key.level >= p.levels {
+		return
+	}
+	p.children[key]--
+	if p.children[key] > 0 || p.evictable || p.busy[key] {
+		return
+	}
+	p.removeTable(key)
+}
+
+func (p *pageTableSim) removeTable(key ptKey) {
+	p.frames[p.tableFrame[key]] = nil
+	delete(p.tableFrame, key)
+	delete(p.children, key)
+	p.release(p.parent(key))
+}
+
+// Frame livre de menor índice ou, com a memória cheia, a vítima do
+// Relógio entre os frames substituíveis
+func (p *pageTableSim) allocate() (int, error) {
+	for f, frame := range p.frames {
+		if frame == nil {
+			return f, nil
+		}
+	}
+	for scanned := 0; scanned <= 2*len(p.frames); scanned++ {
+		f := p.hand
+		frame := p.frames[f]
+		p.hand = (p.hand + 1) % len(p.frames)
+		if frame.page == "" && (frame.table.level >= p.levels || !p.evictable ||
+			p.children[frame.table] > 0 || p.busy[frame.table]) {
+			continue // tabela em uso ou fixa
+		}
+		if frame.ref {
+			frame.ref = false
+			continue
+		}
+		if frame.page != "" {
+			delete(p.dataFrame, frame.page)
+			p.frames[f] = nil
+			if p.tables {
+				p.release(p.leafTable(frame.page))
+			}
+		} else {
+			p.removeTable(frame.table)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("memória insuficiente: todos os %d frames estão ocupados por tabelas em uso", len(p.frames))
+}
+
+// Invariantes conferidas pelos testes; devolve a primeira violação ou ""
+func (p *pageTableSim) check() string {
+	used := 0
+	for _, frame := range p.frames {
+		if frame != nil {
+			used++
+		}
+	}
+	if used != len(p.dataFrame)+p.tableFrames() {
+		return fmt.Sprintf("%d frames ocupados para %d páginas e %d tabelas", used, len(p.dataFrame), p.tableFrames())
+	}
+	resident := make(map[ptKey]int)
+	for page := range p.dataFrame {
+		resident[p.leafTable(page)]++
+	}
+	for key := range p.tableFrame {
+		if parent := p.parent(key); parent.level < p.levels {
+			resident[parent]++
+		}
+	}
+	for key, n := range resident {
+		if _, ok := p.tableFrame[key]; !ok && key.level < p.levels {
+			return fmt.Sprintf("tabela %v ausente com %d entradas residentes", key, n)
+		}
+	}
+	for key := range p.tableFrame {
+		if p.children[key] != resident[key] {
+			return fmt.Sprintf("tabela %v conta %d entradas, residentes %d", key, p.children[key], resident[key])
+		}
+		if !p.evictable && resident[key] == 0 {
+			return fmt.Sprintf("tabela fixa %v vazia", key)
+		}
+	}
+	return ""
+}
+
+// Resultado de -pt-frames para um trace
+type pageTableReport struct {
+	Levels        int
+	DataFaults    int
+	Baseline      int // faltas do Relógio sem tabelas na memória
+	TableLoads    int
+	AverageTables float64
+	PeakTables    int
+}
+
+func (s *Simulator) simulatePageTableFrames() (pageTableReport, error) {
+	levels := pageTableLevels(s.vaddrBits)
+	report := pageTableReport{Levels: levels}
+	if s.totalFrames < levels+1 {
+		return report, fmt.Errorf("são necessários ao menos %d frames (%d tabelas e uma página)", levels+1, levels)
+	}
+	baseline := newPageTableSim(s.totalFrames, levels, s.pageRadix, false, false)
+	sim := newPageTableSim(s.totalFrames, levels, s.pageRadix, true, s.ptFrames == "evictable")
+	for _, access := range s.accesses {
+		baseline.Access(access.PageID)
+		if _, err := sim.Access(access.PageID); err != nil {
+			return report, err
+		}
+	}
+	report.DataFaults, report.Baseline = sim.dataFaults, baseline.dataFaults
+	report.TableLoads, report.PeakTables = sim.tableLoads, sim.peak
+	if len(s.accesses) > 0 {
+		report.AverageTables = float64(sim.tableFrameSum) / float64(len(s.accesses))
+	}
+	return report, nil
+}
+
+// Relatório de -pt-frames
+func (s *Simulator) ShowPageTableFrames() {
+	if s.ptFrames == "" {
+		return
+	}
+	fmt.Println("\n=== TABELA DE PÁGINAS NA MEMÓRIA (RELÓGIO) ===")
+	r, err := s.simulatePageTableFrames()
+	if err != nil {
+		logger.Error("erro na simulação das tabelas de páginas", "err", err)
+		return
+	}
+	mode := "liberadas ao esvaziar"
+	if s.ptFrames == "evictable" {
+		mode = "substituíveis quando vazias"
+	}
+	fmt.Printf("Níveis: %d para %d bits (%d entradas de %d bytes por página de tabela); tabelas %s\n",
+		r.Levels, s.vaddrBits, ptEntriesPerPage, ptEntrySize, mode)
+	fmt.Printf("Frames ocupados por tabelas: média %.1f, pico %d (de %d)\n", r.AverageTables, r.PeakTables, s.totalFrames)
+	fmt.Printf("Páginas de tabela alocadas: %d\n", r.TableLoads)
+	extra := r.DataFaults - r.Baseline
+	fmt.Printf("Faltas de página: %d (tabelas fora da memória: %d; %+d", r.DataFaults, r.Baseline, extra)
+	if r.Baseline > 0 {
+		fmt.Printf(", %+.2f%%", float64(extra)/float64(r.Baseline)*100)
+	}
+	fmt.Println(")")
+}
+
+const hugePageSize = 2 * 1024 * 1024 // 2MB
+
+// Fração das janelas de `window` acessos cujo conjunto de trabalho
+// (páginas distintas na janela) cabe em `entries` entradas da TLB.
+// key mapeia o acesso para a página considerada (normal ou huge).
+func (s *Simulator) windowsWithinReach(entries, window int, key func(PageAccess) (uint64, bool)) (fit, total int) {
+	pages := make(map[uint64]bool)
+	for i, access := range s.accesses {
+		if k, ok := key(access); ok {
+			pages[k] = true
+		}
+		if (i+1)%window == 0 || i == len(s.accesses)-1 {
+			total++
+			if len(pages) <= entries {
+				fit++
+			}
+			pages = make(map[uint64]bool)
+		}
+	}
+	return fit, total
+}
+
+// Relatório de alcance da TLB (sem simular a TLB em si)
+func (s *Simulator) ShowTLBReach() {
+	if s.tlbEntries == 0 {
+		return
+	}
+
+	fmt.Println("\n=== ALCANCE DA TLB ===")
+	fmt.Printf("Entradas da TLB: %d | Janela do conjunto de trabalho: %d acessos\n",
+		s.tlbEntries, s.tlbWindow)
+
+	pagesPerHuge := uint64(hugePageSize / PAGE_SIZE)
+	smallPages := make(map[uint64]bool)
+	hugePages := make(map[uint64]bool)
+	for pageID := range s.distinctPages {
+		if number, ok := pageNumber(pageID, s.pageRadix); ok {
+			smallPages[number] = true
+			hugePages[number/pagesPerHuge] = true
+		}
+	}
+	if len(smallPages) == 0 {
+		fmt.Println("Nenhum ID de página pôde ser interpretado como número de página")
+		return
+	}
+
+	report := func(label string, pageSize int, distinct int, key func(PageAccess) (uint64, bool)) {
+		reach := float64(s.tlbEntries) * float64(pageSize)
+		covered := math.Min(1, float64(s.tlbEntries)/float64(distinct))
+		fit, total := s.windowsWithinReach(s.tlbEntries, s.tlbWindow, key)
+
+		fmt.Printf("\n%s (%s):\n", label, formatBytes(float64(pageSize)))
+		fmt.Printf("  Alcance: %s\n", formatBytes(reach))
+		fmt.Printf("  Páginas distintas: %d (%.2f%% cabem na TLB)\n", distinct, covered*100)
+		fmt.Printf("  Janelas cujo conjunto de trabalho cabe na TLB: %d de %d (%.2f%%)\n",
+			fit, total, float64(fit)/float64(total)*100)
+	}
+
+	report("Páginas normais", PAGE_SIZE, len(smallPages), func(a PageAccess) (uint64, bool) {
+		return pageNumber(a.PageID, s.pageRadix)
+	})
+	report("Páginas grandes", hugePageSize, len(hugePages), func(a PageAccess) (uint64, bool) {
+		number, ok := pageNumber(a.PageID, s.pageRadix)
+		return number / pagesPerHuge, ok
+	})
+}
+
+// Base dos identificadores de página sem prefixo quando não há
+// -page-radix. A base vale para o trace inteiro (Simulator.pageRadix):
+// com a escolha por ID, D26 e D1a seriam a mesma página 26.
+const defaultPageRadix = 10
+
+// Interpreta o ID como número de página: o que segue a letra de tipo é
+// lido na base radix (decimal por padrão: D0042 = página 42); o prefixo 0x
+// sempre indica hexadecimal (D0x1f = página 31, e D1f também na base 16)
+func pageNumber(pageID string, radix int) (uint64, bool) {
+	if len(pageID) < 2 {
+		return 0, false
+	}
+	digits := strings.ToLower(pageID[1:])
+	if strings.HasPrefix(digits, "0x") {
+		n, err := strconv.ParseUint(digits[2:], 16, 64)
+		return n, err == nil
+	}
+	n, err := strconv.ParseUint(digits, radix, 64)
+	return n, err == nil
+}
+
+// Uma página sem número em decimal que seria numerada em hexadecimal
+// (D1f); "" quando não há nenhuma ou a base já é 16
+func hexLookingPage(pages map[string]bool, radix int) string {
+	if radix != 10 {
+		return ""
+	}
+	for pageID := range pages {
+		digits := pageID[1:]
+		if _, err := strconv.ParseUint(digits, 10, 64); err == nil {
+			continue
+		}
+		if _, err := strconv.ParseUint(digits, 16, 64); err == nil {
+			return pageID
+		}
+	}
+	return ""
+}
+
+// Ordem das páginas nos relatórios: pela letra do tipo (D antes de I) e
+// pelo número da página, de modo que D2 vem antes de D10; páginas sem
+// número (na base radix) vêm depois, em ordem alfabética
+func sortPageIDs(pages []string, radix int) {
+	sort.Slice(pages, func(i, j int) bool {
+		a, b := pages[i], pages[j]
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		// Páginas de segmentos: pelo segmento e pela página dentro dele
+		if sa, pa, okA := segmentPage(a); okA {
+			if sb, pb, okB := segmentPage(b); okB && (sa != sb || pa != pb) {
+				if sa != sb {
+					return sa < sb
+				}
+				return pa < pb
+			}
+		}
+		na, okA := pageNumber(a, radix)
+		nb, okB := pageNumber(b, radix)
+		switch {
+		case okA && okB && na != nb:
+			return na < nb
+		case okA != okB:
+			return okA
+		}
+		return a < b
+	})
+}
+
+func formatBytes(size float64) string {
+	units := []string{"KB", "MB", "GB", "TB", "PB"}
+	if size < 1024 {
+		return fmt.Sprintf("%.0f bytes", size)
+	}
+	unit := -1
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.2f %s", size, units[unit])
+}
+
+// Resultado da execução de um algoritmo sobre o trace
+type Result struct {
+	Algorithm   string
+	Accesses    int
+	Faults      int
+	WriteBacks  int   // páginas modificadas gravadas em disco ao serem substituídas
+	ZeroFills   int   // faltas atendidas zerando um frame, sem ler o disco
+	Resident    []int // frames ocupados a cada -rss-interval acessos
+	FaultSeries []int // faltas em cada janela de -rss-interval acessos
+	Stalls      StallStats
+	Final       FinalState
+	Stats       *RunStats
+	Elapsed     time.Duration // tempo de parede da execução, índice incluído
+	Partial     bool          // -converge parou antes do fim; Accesses é o trecho simulado
+	// Aquecimento (-warmup): acessos e faltas fora de Accesses e Faults
+	WarmupAccesses int
+	WarmupFaults   int
+	Bootstrap      *rateInterval  // IC da taxa de faltas entre blocos do trace (-bootstrap)
+	RateMargin     float64        // meia largura do intervalo de 95% da taxa de faltas (-converge)
+	IndexTime      time.Duration  // construção do índice nextUse (só no Ótimo)
+	Err            error          // um observador interrompeu a execução depois de Accesses acessos
+	Costs          *CostBreakdown // divisão do custo simulado (-cost)
+	Trials         *TrialStats    // faltas com cada semente (-trials)
+	Saves          SaveStats      // desfecho das segundas chances do bit R
+	InstrFaults    int            // das Faults, as em páginas de instrução (I)
+	PatternFaults  []int          // Faults por padrão de acesso da página (-patterns)
+}
+
+// Estado da memória ao final de uma execução
+type FinalState struct {
+	Resident  map[string]int `json:"resident"` // página -> frame
+	Frames    []FrameState   `json:"frames"`
+	Hand      int            `json:"hand"` // -1 em políticas sem ponteiro
+	Evictions int            `json:"evictions"`
+}
+
+type FrameState struct {
+	PageID     string `json:"page"` // "" em frame vazio
+	Referenced bool   `json:"referenced"`
+	Dirty      bool   `json:"dirty"`
+	LoadCount  int    `json:"loads"`
+	Mappers    int    `json:"mappers"` // espaços de endereçamento que mapeiam o frame
+}
+
+func finalState(policy ReplacementPolicy, evictions int) FinalState {
+	state := FinalState{Resident: make(map[string]int), Hand: -1, Evictions: evictions}
+	for i, frame := range policy.Frames() {
+		if frame == nil {
+			state.Frames = append(state.Frames, FrameState{})
+			continue
+		}
+		state.Resident[frame.PageID] = i
+		// Um único espaço de endereçamento: cada página tem um mapeador
+		state.Frames = append(state.Frames, FrameState{
+			PageID:     frame.PageID,
+			Referenced: frame.Referenced,
+			Dirty:      frame.Dirty,
+			LoadCount:  frame.LoadCount,
+			Mappers:    1,
+		})
+	}
+	if hp, ok := policy.(handPolicy); ok {
+		state.Hand = hp.Hand()
+	}
+	return state
+}
+
+func (r Result) Hits() int {
+	return r.Accesses - r.Faults
+}
+
+// Fração dos acessos que foram hits (0 quando não há acessos)
+func (r Result) HitRate() float64 {
+	if r.Accesses == 0 {
+		return 0
+	}
+	return float64(r.Hits()) / float64(r.Accesses)
+}
+
+// Fração dos acessos que causaram falta de página (0 quando não há acessos)
+func (r Result) FaultRate() float64 {
+	if r.Accesses == 0 {
+		return 0
+	}
+	return float64(r.Faults) / float64(r.Accesses)
+}
+
+func (r Result) FaultsPer1K() float64 {
+	return r.FaultRate() * 1000
+}
+
+// Acessos simulados por segundo de tempo de parede (0 sem medição)
+func (r Result) AccessesPerSecond() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Accesses+r.WarmupAccesses) / r.Elapsed.Seconds()
+}
+
+// Tempo médio gasto por falta de página (0 quando não há faltas)
+func (r Result) TimePerFault() time.Duration {
+	faults := r.Faults + r.WarmupFaults
+	if faults == 0 {
+		return 0
+	}
+	return r.Elapsed / time.Duration(faults)
+}
+
+// Eficiência em relação ao ótimo (faltas do ótimo / faltas do algoritmo).
+// Sem faltas o algoritmo não pode ser pior que o ótimo: 100%. Uma
+// execução interrompida por -converge é comparada pela taxa de faltas.
+func (r Result) Efficiency(optimal Result) float64 {
+	if r.Faults == 0 {
+		return 100
+	}
+	if r.Partial {
+		return optimal.FaultRate() / r.FaultRate() * 100
+	}
+	return float64(optimal.Faults) / float64(r.Faults) * 100
+}
+
+// Faltas a mais que o algoritmo ótimo; numa execução interrompida por
+// -converge, as faltas são projetadas para o trace inteiro
+func (r Result) ExtraFaults(optimal Result) int {
+	return r.projectedFaults(optimal.Accesses) - optimal.Faults
+}
+
+func (r Result) projectedFaults(total int) int {
+	if !r.Partial {
+		return r.Faults
+	}
+	return int(math.Round(r.FaultRate() * float64(total)))
+}
+
+// Pesos das faltas por tipo de página (-weight I=1,D=0.6): uma falta de
+// instrução para o pipeline na hora, enquanto parte das de dados se
+// sobrepõe a outro trabalho
+type FaultWeights struct {
+	I float64 `json:"I"`
+	D float64 `json:"D"`
+}
+
+func parseFaultWeights(text string) (FaultWeights, error) {
+	w := FaultWeights{1, 1}
+	for _, item := range strings.Split(text, ",") {
+		kind, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		weight, err := strconv.ParseFloat(value, 64)
+		switch {
+		case !ok || err != nil:
+			return w, fmt.Errorf("peso inválido: %q (use I=1.0,D=0.6)", item)
+		case weight < 0 || math.IsInf(weight, 0) || math.IsNaN(weight):
+			return w, fmt.Errorf("peso negativo ou não finito: %q", item)
+		}
+		switch strings.ToUpper(kind) {
+		case "I":
+			w.I = weight
+		case "D":
+			w.D = weight
+		default:
+			return w, fmt.Errorf("tipo de página desconhecido: %q (use I ou D)", kind)
+		}
+	}
+	return w, nil
+}
+
+func (w FaultWeights) of(access PageAccess) float64 {
+	if access.Type == "I" {
+		return w.I
+	}
+	return w.D
+}
+
+func (w FaultWeights) String() string {
+	return fmt.Sprintf("I=%g, D=%g", w.I, w.D)
+}
+
+// Faltas ponderadas pelo tipo da página
+func (r Result) WeightedFaults(w FaultWeights) float64 {
+	return float64(r.InstrFaults)*w.I + float64(r.Faults-r.InstrFaults)*w.D
+}
+
+// Algoritmos do menor para o maior valor de key ("A < B = C"); empates
+// na ordem dada
+func rankResults(results []Result, key func(Result) float64) string {
+	sorted := append([]Result(nil), results...)
+	sort.SliceStable(sorted, func(a, b int) bool { return key(sorted[a]) < key(sorted[b]) })
+	var b strings.Builder
+	for i, r := range sorted {
+		switch {
+		case i == 0:
+		case key(sorted[i-1]) == key(r):
+			b.WriteString(" = ")
+		default:
+			b.WriteString(" < ")
+		}
+		b.WriteString(r.Algorithm)
+	}
+	return b.String()
+}
+
+func (s *Simulator) showWeighted(r Result) {
+	if s.weighted {
+		fmt.Printf("Faltas ponderadas (%s): %.2f (%d de instrução, %d de dados)\n",
+			s.weights, r.WeightedFaults(s.weights), r.InstrFaults, r.Faults-r.InstrFaults)
+	}
+}
+
+// Descreve o resultado em relação ao ótimo (nil quando não foi executado)
+func compareWithOptimal(r Result, optimal *Result) string {
+	switch {
+	case optimal == nil:
+		return "não comparável (algoritmo ótimo não executado)"
+	case r.Partial:
+		return fmt.Sprintf("estimativa: ~%d faltas a mais que o ótimo (%.2f%%), pela taxa de %.2f%% ± %.2f%%",
+			r.ExtraFaults(*optimal), r.Efficiency(*optimal), r.FaultRate()*100, r.RateMargin*100)
+	case r.Faults == optimal.Faults:
+		return fmt.Sprintf("idêntico ao ótimo (%d faltas)", r.Faults)
+	case r.Faults < optimal.Faults:
+		// Não deveria acontecer: o ótimo é um limite inferior
+		return fmt.Sprintf("inconsistente (%d faltas a menos que o ótimo)", optimal.Faults-r.Faults)
+	default:
+		return fmt.Sprintf("%d faltas a mais que o ótimo (%.2f%%)", r.ExtraFaults(*optimal), r.Efficiency(*optimal))
+	}
+}
+
+// Padrões de acesso por página (-patterns). Acessos seguidos à mesma
+// página formam uma visita (depois do primeiro, são sempre acertos) e as
+// medidas usam os intervalos entre o início de visitas seguidas. As classes
+// são testadas nesta ordem:
+//
+//	uso único - uma só visita
+//	laço      - 3 ou mais visitas com intervalos regulares (CV <= loop)
+//	varredura - ao menos a fração scan das visitas vem logo antes ou logo
+//	            depois de uma visita a uma página vizinha do mesmo tipo
+//	            (D4 junto de D3 ou D5)
+//	rajada    - 3 ou mais visitas com intervalos irregulares (CV >= burst):
+//	            grupos de visitas próximas separados por lacunas longas
+//	estável   - as demais
+//
+// CV é o desvio padrão dos intervalos dividido pela média.
+type PagePattern int
+
+const (
+	patternSingle PagePattern = iota
+	patternLoop
+	patternScan
+	patternBursty
+	patternSteady
+	numPatterns
+)
+
+var patternNames = [numPatterns]string{"uso único", "laço", "varredura", "rajada", "estável"}
+
+// Nomes dos padrões no JSON do serve
+var patternKeys = [numPatterns]string{"single_use", "loop", "scan", "bursty", "steady"}
+
+func (p PagePattern) String() string {
+	return patternNames[p]
+}
+
+// Limiares da classificação (-pattern-thresholds scan=0.5,loop=0.25,burst=1.5)
+type PatternThresholds struct {
+	Scan  float64 `json:"scan"`
+	Loop  float64 `json:"loop"`
+	Burst float64 `json:"burst"`
+}
+
+var defaultPatternThresholds = PatternThresholds{Scan: 0.5, Loop: 0.25, Burst: 1.5}
+
+func parsePatternThresholds(text string) (PatternThresholds, error) {
+	th := defaultPatternThresholds
+	for _, item := range strings.Split(text, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		limit, err := strconv.ParseFloat(value, 64)
+		switch {
+		case !ok || err != nil:
+			return th, fmt.Errorf("limiar inválido: %q (use scan=0.5,loop=0.25,burst=1.5)", item)
+		case limit < 0 || math.IsInf(limit, 0) || math.IsNaN(limit):
+			return th, fmt.Errorf("limiar negativo ou não finito: %q", item)
+		}
+		switch strings.ToLower(name) {
+		case "scan":
+			if limit > 1 {
+				return th, fmt.Errorf("scan é uma fração das visitas, entre 0 e 1: %q", item)
+			}
+			th.Scan = limit
+		case "loop":
+			th.Loop = limit
+		case "burst":
+			th.Burst = limit
+		default:
+			return th, fmt.Errorf("limiar desconhecido: %q (use scan, loop ou burst)", name)
+		}
+	}
+	return th, nil
+}
+
+func (th PatternThresholds) String() string {
+	return fmt.Sprintf("scan=%g, loop=%g, burst=%g", th.Scan, th.Loop, th.Burst)
+}
+
+// Medidas de uma página e o padrão resultante
+type PageProfile struct {
+	Page       string
+	Pattern    PagePattern
+	Accesses   int
+	Visits     int
+	MeanGap    float64 // acessos entre o início de visitas seguidas
+	GapCV      float64
+	Sequential float64 // fração das visitas junto de uma página vizinha
+}
+
+func (th PatternThresholds) classify(p PageProfile) PagePattern {
+	switch {
+	case p.Visits == 1:
+		return patternSingle
+	case p.Visits >= 3 && p.GapCV <= th.Loop:
+		return patternLoop
+	case p.Sequential >= th.Scan:
+		return patternScan
+	case p.Visits >= 3 && p.GapCV >= th.Burst:
+		return patternBursty
+	}
+	return patternSteady
+}
+
+// Classifica as páginas do trace numa passada; a vizinhança de cada visita
+// só é conhecida quando a visita seguinte começa
+func classifyPages(accesses []PageAccess, th PatternThresholds, radix int) map[string]*PageProfile {
+	type pageState struct {
+		profile    *PageProfile
+		number     uint64
+		numbered   bool
+		last       int // início da última visita
+		sum        float64
+		squares    float64
+		sequential int
+	}
+	neighbors := func(a, b *pageState) bool {
+		return a != nil && b != nil && a.numbered && b.numbered && a.profile.Page[0] == b.profile.Page[0] &&
+			(a.number == b.number+1 || b.number == a.number+1)
+	}
+	states := make(map[string]*pageState)
+	var prev, cur *pageState
+	finish := func(next *pageState) {
+		if cur != nil && (neighbors(prev, cur) || neighbors(cur, next)) {
+			cur.sequential++
+		}
+	}
+	for i, access := range accesses {
+		st := states[access.PageID]
+		if st == nil {
+			st = &pageState{profile: &PageProfile{Page: access.PageID}, last: -1}
+			st.number, st.numbered = pageNumber(access.PageID, radix)
+			states[access.PageID] = st
+		}
+		st.profile.Accesses++
+		if st == cur {
+			continue
+		}
+		finish(st)
+		prev, cur = cur, st
+		if st.last >= 0 {
+			gap := float64(i - st.last)
+			st.sum += gap
+			st.squares += gap * gap
+		}
+		st.last = i
+		st.profile.Visits++
+	}
+	finish(nil)
+
+	profiles := make(map[string]*PageProfile, len(states))
+	for page, st := range states {
+		p := st.profile
+		p.Sequential = float64(st.sequential) / float64(p.Visits)
+		if gaps := float64(p.Visits - 1); gaps > 0 {
+			p.MeanGap = st.sum / gaps
+			p.GapCV = math.Sqrt(max(st.squares/gaps-p.MeanGap*p.MeanGap, 0)) / p.MeanGap
+		}
+		p.Pattern = th.classify(*p)
+		profiles[page] = p
+	}
+	return profiles
+}
+
+// Classifica as páginas do trace carregado para a divisão das faltas por
+// padrão (Result.PatternFaults)
+func (s *Simulator) classifyTrace() map[string]*PageProfile {
+	profiles := classifyPages(s.accesses, s.patternThresholds, s.pageRadix)
+	s.pagePatterns = make(map[string]PagePattern, len(profiles))
+	for page, p := range profiles {
+		s.pagePatterns[page] = p.Pattern
+	}
+	return profiles
+}
+
+// Páginas de cada padrão
+func (s *Simulator) patternPopulation() []int {
+	population := make([]int, numPatterns)
+	for _, pattern := range s.pagePatterns {
+		population[pattern]++
+	}
+	return population
+}
+
+// Contagens por padrão com os nomes do JSON
+func patternCounts(counts []int) map[string]int {
+	named := make(map[string]int, len(counts))
+	for p, n := range counts {
+		named[patternKeys[p]] = n
+	}
+	return named
+}
+
+func (s *Simulator) showPatterns(profiles map[string]*PageProfile) {
+	population := s.patternPopulation()
+	var parts []string
+	for p, n := range population {
+		parts = append(parts, fmt.Sprintf("%s %d (%.1f%%)", PagePattern(p), n, float64(n)/float64(max(len(profiles), 1))*100))
+	}
+	fmt.Printf("Padrões de acesso (%s): %s\n", s.patternThresholds, strings.Join(parts, ", "))
+	if s.patternsCSV == "" {
+		return
+	}
+	if err := writePatternsCSV(s.patternsCSV, profiles, s.pageRadix); err != nil {
+		logger.Error("erro ao gravar o CSV", "file", s.patternsCSV, "err", err)
+	} else {
+		fmt.Printf("Padrão de cada página gravado em %s\n", s.patternsCSV)
+	}
+}
+
+func writePatternsCSV(filename string, profiles map[string]*PageProfile, radix int) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("erro ao criar %s: %v", filename, err)
+	}
+	defer file.Close()
+
+	pages := make([]string, 0, len(profiles))
+	for page := range profiles {
+		pages = append(pages, page)
+	}
+	sortPageIDs(pages, radix)
+	w := csv.NewWriter(file)
+	w.Write([]string{"pagina", "padrao", "acessos", "visitas", "intervalo_medio", "cv_intervalo", "sequencial"})
+	for _, page := range pages {
+		p := profiles[page]
+		w.Write([]string{p.Page, p.Pattern.String(), strconv.Itoa(p.Accesses), strconv.Itoa(p.Visits),
+			strconv.FormatFloat(p.MeanGap, 'f', 2, 64), strconv.FormatFloat(p.GapCV, 'f', 3, 64),
+			strconv.FormatFloat(p.Sequential, 'f', 3, 64)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// Tabela das faltas de cada algoritmo por padrão de acesso; com o Ótimo,
+// aponta o padrão com mais faltas além das dele em cada algoritmo
+func (s *Simulator) printPatternFaults(results []Result, optimal *Result) {
+	fmt.Println("\n=== FALTAS POR PADRÃO DE ACESSO ===")
+	width := labelWidth(results, "(páginas)")
+	cell := func(text string) string { return fmt.Sprintf(" %16s", text) }
+	row := func(label string, counts []int) {
+		total := 0
+		for _, n := range counts {
+			total += n
+		}
+		fmt.Printf("%-*s", width, label)
+		for _, n := range counts {
+			fmt.Print(cell(fmt.Sprintf("%d (%.1f%%)", n, float64(n)/float64(max(total, 1))*100)))
+		}
+		fmt.Println()
+	}
+	fmt.Printf("%-*s", width, "Algoritmo")
+	for p := range numPatterns {
+		fmt.Print(cell(p.String()))
+	}
+	fmt.Println()
+	row("(páginas)", s.patternPopulation())
+	for _, r := range results {
+		row(r.Algorithm, r.PatternFaults)
+	}
+	if optimal == nil {
+		return
+	}
+	for _, r := range results {
+		if r.Algorithm == optimal.Algorithm || r.Partial {
+			continue
+		}
+		worst, extra := patternSingle, 0
+		for p, n := range r.PatternFaults {
+			if d := n - optimal.PatternFaults[p]; d > extra {
+				worst, extra = PagePattern(p), d
+			}
+		}
+		if extra > 0 {
+			fmt.Printf("%s: mais faltas além do ótimo nas páginas do padrão %s (+%d)\n", r.Algorithm, worst, extra)
+		}
+	}
+}
+
+// Largura da coluna dos nomes dos algoritmos nas tabelas: ao menos 16, ou
+// o maior nome (com o " *" das estimativas) entre os resultados e extra.
+// A largura do fmt conta runas, então os acentos não precisam de ajuste.
+func labelWidth(results []Result, extra ...string) int {
+	width := 16
+	for _, r := range results {
+		name := r.Algorithm
+		if r.Partial {
+			name += " *"
+		}
+		width = max(width, utf8.RuneCountInString(name))
+	}
+	for _, label := range extra {
+		width = max(width, utf8.RuneCountInString(label))
+	}
+	return width
+}
+
+// Tabela comparativa; optimal é nil quando o algoritmo ótimo não foi
+// executado e weights, sem -weight
+func printComparison(results []Result, optimal *Result, weights *FaultWeights) {
+	fmt.Println("\n=== COMPARAÇÃO ===")
+	intervals := len(results) > 0 && results[0].Bootstrap != nil
+	labels := labelWidth(results)
+	// Com -trials a coluna de faltas traz média ± desvio
+	width := 10
+	for _, r := range results {
+		if r.Trials != nil {
+			width = 18
+		}
+	}
+	fmt.Printf("%-*s %*s %10s %10s %10s %10s %11s %8s %10s",
+		labels, "Algoritmo", width, "Faltas", "Hits", "Taxa hit", "Taxa falta", "Faltas/1K", "Eficiência", "Extras", "Tempo")
+	if intervals {
+		fmt.Printf(" %10s", "IC 95%")
+	}
+	if weights != nil {
+		fmt.Printf(" %11s", "Ponderadas")
+	}
+	fmt.Println()
+	partial, trials := false, 0
+	for _, r := range results {
+		efficiency, extra := "N/A", "N/A"
+		if optimal != nil {
+			efficiency = fmt.Sprintf("%.2f%%", r.Efficiency(*optimal))
+			extra = strconv.Itoa(r.ExtraFaults(*optimal))
+		}
+		name := r.Algorithm
+		if r.Partial {
+			name += " *"
+			partial = true
+		}
+		faults := strconv.Itoa(r.Faults)
+		if r.Trials != nil {
+			faults = fmt.Sprintf("%.1f±%.1f", r.Trials.Mean, r.Trials.StdDev)
+			trials = len(r.Trials.Runs)
+		}
+		fmt.Printf("%-*s %*s %10d %9.2f%% %9.2f%% %10.2f %11s %8s %10s",
+			labels, name, width, faults, r.Hits(), r.HitRate()*100, r.FaultRate()*100,
+			r.FaultsPer1K(), efficiency, extra, r.Elapsed.Round(time.Microsecond))
+		if intervals {
+			// Meia largura do intervalo do bootstrap, em pontos percentuais
+			fmt.Printf(" %9s%%", fmt.Sprintf("±%.2f", (r.Bootstrap.High-r.Bootstrap.Low)/2*100))
+		}
+		if weights != nil {
+			fmt.Printf(" %11.2f", r.WeightedFaults(*weights))
+		}
+		fmt.Println()
+	}
+	if partial {
+		fmt.Println("* estimativa: simulação interrompida por -converge; faltas e hits são do trecho")
+		fmt.Println("  simulado e as faltas extras foram projetadas para o trace inteiro")
+	}
+	if trials > 0 {
+		fmt.Printf("±: média e desvio padrão das faltas em %d repetições; as demais colunas são da\n", trials)
+		fmt.Println("  execução com a semente de -seed")
+	}
+	if weights != nil && len(results) > 1 {
+		order := rankResults(results, func(r Result) float64 { return float64(r.Faults) })
+		weightedOrder := rankResults(results, func(r Result) float64 { return r.WeightedFaults(*weights) })
+		fmt.Printf("Ordem por faltas: %s\n", order)
+		fmt.Printf("Ordem ponderada (%s): %s\n", *weights, weightedOrder)
+		if order != weightedOrder {
+			fmt.Println("A ponderação por tipo de página muda a ordem dos algoritmos")
+		}
+	}
+}
+
+func (s *Simulator) Run() {
+	s.cacheTrace = "" // o trace pode ter mudado (-watch)
+	fmt.Println("=== SIMULADOR DE PAGINAÇÃO ===")
+	fmt.Printf("Tamanho da memória física: %d bytes (%.2f MB)\n",
+		s.memorySize, float64(s.memorySize)/(1024*1024))
+	fmt.Printf("Tamanho da página: %d bytes\n", PAGE_SIZE)
+	fmt.Printf("Número de frames: %d\n", s.totalFrames)
+	fmt.Printf("Número de acessos: %d\n", len(s.accesses))
+	fmt.Printf("Páginas distintas: %d\n", len(s.distinctPages))
+
+	if s.totalFrames == 0 {
+		logger.Error("memória insuficiente", "memory", s.memorySize, "minimum", PAGE_SIZE)
+		return
+	}
+	if !s.noEstimate {
+		fmt.Printf("Tempo estimado: %s\n", s.estimateExecutionTime())
+	}
+	s.traceTimes = nil
+	if s.accessTime > 0 || timedTrace(s.accesses) {
+		if times, err := s.accessTimes(); err != nil {
+			logger.Warn("sem as medidas por tempo", "err", err)
+		} else {
+			s.traceTimes = times
+			span := times[len(s.accesses)]
+			fmt.Printf("Duração do trace: %s (%s por acesso em média)\n", time.Duration(span),
+				time.Duration(span/int64(max(len(s.accesses), 1))))
+		}
+	}
+	if s.pacing(os.Stdout) {
+		// Uma só leitura das teclas para todas as execuções (-watch)
+		var keys <-chan byte
+		if isTerminal(os.Stdin) {
+			if s.keys == nil {
+				s.keys = stdinKeys()
+			}
+			keys = s.keys
+			restore, _ := cbreakTerminal()
+			defer restore()
+		}
+		s.pace = s.newPacer(keys)
+		defer func() { s.pace = nil }()
+		fmt.Printf("Reprodução: %gx (+/- muda o ritmo, espaço pausa)\n", s.playback)
+	}
+	if s.warmup > 0 || s.warmupAuto {
+		warmup := s.warmupBoundary()
+		fmt.Printf("Aquecimento: %d acessos, fora das faltas, taxas e séries\n", warmup)
+		if warmup == len(s.accesses) {
+			logger.Warn("o aquecimento cobre o trace inteiro; nenhum acesso é contado", "warmup", warmup)
+		}
+	}
+	s.pagePatterns = nil
+	if s.patterns {
+		s.showPatterns(s.classifyTrace())
+	}
+	fmt.Println()
+
+	sinks, err := s.openEventLog()
+	if err != nil {
+		logger.Error("erro ao criar o log de eventos", "err", err)
+		return
+	}
+	s.eventSinks = sinks
+	defer func() {
+		closeEventLog(sinks)
+		s.eventSinks = nil
+	}()
+
+	var results []Result
+	var optimal *Result
+
+	// Executa algoritmo Ótimo
+	if s.algorithmSelected("optimal") {
+		fmt.Println("=== ALGORITMO ÓTIMO ===")
+		if !s.skipOptimal {
+			victims, err := s.fileObservers("optimal", "Ótimo")
+			if err != nil {
+				logger.Error("erro ao criar o trace das vítimas", "err", err)
+				return
+			}
+			result, _ := s.cachedRun("optimal", nil, func() Result { return s.runOptimal(victims...) })
+			if cancelled(result.Err) {
+				s.stopRun(results, result, "")
+				return
+			}
+			if result.Err != nil {
+				logger.Error("execução interrompida", "algorithm", "Ótimo", "access", result.WarmupAccesses+result.Accesses, "err", result.Err)
+				return
+			}
+			s.keepStats(result)
+			fmt.Printf("Faltas de página (Ótimo): %d\n", result.Faults)
+			s.showWeighted(result)
+			s.showWarmup(result)
+			s.showTiming(result)
+			s.showTraceTime(result)
+			s.showWriteBacks(result)
+			s.showZeroFills(result)
+			s.showStalls(result)
+			s.showCosts(result)
+			s.showResident(result)
+			s.ShowClassStats()
+			s.ShowColorStats()
+			if s.optimalPreferClean {
+				s.ShowCleanOptimal(result)
+			}
+			s.ShowFrameStats()
+			s.showFrameTable(result)
+			s.showVictims("optimal")
+			optimal = &result
+			results = append(results, result)
+		} else {
+			fmt.Println("Algoritmo ótimo ignorado (use -skipoptimal para casos extremos)")
+		}
+	}
+
+	// Executa as políticas selecionadas, na ordem do registro
+	var clock *clockPolicy
+	var clockFaults int
+	for _, info := range streamingPolicies {
+		if !s.algorithmSelected(info.Name) {
+			continue
+		}
+		fmt.Printf("\n=== ALGORITMO %s ===\n", info.Title)
+		policy := info.New(s)
+		victims, err := s.fileObservers(info.Name, info.Label)
+		if err != nil {
+			logger.Error("erro ao criar o trace das vítimas", "err", err)
+			return
+		}
+		result, cached := s.cachedRun(info.Name, policy, func() Result {
+			return s.runPolicy(policy, append(s.reportObservers(policy), victims...)...)
+		})
+		result.Algorithm = info.Label
+		if cancelled(result.Err) {
+			s.stopRun(results, result, info.Name)
+			return
+		}
+		if result.Err != nil {
+			logger.Error("execução interrompida", "algorithm", info.Label, "access", result.WarmupAccesses+result.Accesses, "err", result.Err)
+			return
+		}
+		if s.trials > 1 && seededPolicies[info.Name] {
+			trials, err := s.runTrials(info, result)
+			if err != nil {
+				// O contexto foi cancelado: o próximo algoritmo encerra a execução
+				logger.Warn("repetições interrompidas; resultado só com a semente base", "algorithm", info.Label, "err", err)
+				s.stopped = context.Cause(s.ctx)
+			}
+			result.Trials = trials
+		}
+		s.keepStats(result)
+		fmt.Printf("Faltas de página (%s): %d\n", info.Label, result.Faults)
+		s.showWeighted(result)
+		s.showTrials(result)
+		s.showSaves(result)
+		s.showWarmup(result)
+		s.showConvergence(result)
+		s.showTiming(result)
+		s.showTraceTime(result)
+		s.showWriteBacks(result)
+		s.showZeroFills(result)
+		s.showStalls(result)
+		s.showCosts(result)
+		s.showResident(result)
+		s.ShowClassStats()
+		s.ShowColorStats()
+		if reporter, ok := policy.(policyReporter); ok {
+			reporter.Report()
+		}
+		if _, ok := policy.(*enhancedClockPolicy); ok {
+			s.ShowEnhancedClock(result)
+		}
+		s.ShowFrameStats()
+		s.showFrameTable(result)
+		s.showVictims(info.Name)
+		results = append(results, result)
+		if c, ok := policy.(*clockPolicy); ok && !cached {
+			clock, clockFaults = c, result.Faults
+		}
+	}
+
+	if s.bootstrapBlocks > 0 {
+		s.bootstrap(results)
+		s.printBootstrap(results)
+	}
+
+	var weights *FaultWeights
+	if s.weighted {
+		weights = &s.weights
+	}
+	printComparison(results, optimal, weights)
+
+	// Compara cada algoritmo com o ótimo
+	for _, r := range results {
+		if optimal != nil && r.Algorithm == optimal.Algorithm {
+			continue
+		}
+		fmt.Printf("Eficiência do algoritmo do %s: %s\n", r.Algorithm, compareWithOptimal(r, optimal))
+	}
+	if s.pagePatterns != nil {
+		s.printPatternFaults(results, optimal)
+	}
+
+	if s.protoOut != "" {
+		data := marshalResultsProto(len(s.accesses), len(s.distinctPages), s.totalFrames, results)
+		if err := os.WriteFile(s.protoOut, data, 0644); err != nil {
+			logger.Error("erro ao gravar arquivo", "file", s.protoOut, "err", err)
+		} else {
+			fmt.Printf("\nResultados gravados em %s\n", s.protoOut)
+		}
+	}
+
+	if s.finalStateFile != "" {
+		if err := writeFinalStates(s.finalStateFile, results); err != nil {
+			logger.Error("erro ao gravar o estado final", "file", s.finalStateFile, "err", err)
+		} else {
+			fmt.Printf("\nEstado final da memória gravado em %s\n", s.finalStateFile)
+		}
+	}
+
+	if s.costJSON != "" {
+		if err := writeCosts(s.costJSON, results); err != nil {
+			logger.Error("erro ao gravar os custos", "file", s.costJSON, "err", err)
+		} else {
+			fmt.Printf("\nDivisão dos custos gravada em %s\n", s.costJSON)
+		}
+	}
+
+	if s.trialsJSON != "" {
+		if err := writeTrials(s.trialsJSON, results); err != nil {
+			logger.Error("erro ao gravar as repetições", "file", s.trialsJSON, "err", err)
+		} else {
+			fmt.Printf("\nRepetições gravadas em %s\n", s.trialsJSON)
+		}
+	}
+
+	if s.resultsJSON != "" {
+		if err := writeResultsJSON(s.resultsJSON, s.response(results)); err != nil {
+			logger.Error("erro ao gravar os resultados", "file", s.resultsJSON, "err", err)
+		} else {
+			fmt.Printf("\nResultados gravados em %s\n", s.resultsJSON)
+		}
+	}
+
+	if s.rssCSV != "" {
+		var names []string
+		var series [][]int
+		for _, r := range results {
+			names = append(names, r.Algorithm)
+			series = append(series, r.Resident)
+		}
+		if err := writeSeriesCSV(s.rssCSV, s.warmupBoundary(), s.seriesInterval, names, series); err != nil {
+			logger.Error("erro ao gravar o CSV", "file", s.rssCSV, "err", err)
+		} else {
+			fmt.Printf("\nFrames residentes gravados em %s\n", s.rssCSV)
+		}
+	}
+
+	if clock != nil {
+		s.ShowAdaptiveClock(clock, clockFaults)
+	}
+	s.RefClearSweep()
+	s.SweepTau()
+	s.RecommendMemory()
+	s.ShowMissRatioCurve()
+	s.ShowPageTimeline(results)
+	s.WriteHeatmap(results)
+	s.WriteAnimations(results)
+	s.WriteLesson(results)
+	s.ShowLoadCount()
+	s.EstimatePageTableSize()
+	s.ShowSegments(results)
+	s.ShowPageTableFrames()
+	s.ShowTLBReach()
+}
+
+// Cache de resultados (-cache DIR): cada execução completa de um algoritmo
+// fica em DIR/result-<chave>.json. A chave é o SHA-256 dos acessos do trace
+// e da configuração efetiva do simulador, então qualquer opção diferente
+// (inclusive as que só mudam relatórios) é outra entrada. Entradas
+// ilegíveis ou de outra versão são ignoradas e simuladas de novo.
+const resultCacheVersion = 3
+
+type resultCacheEntry struct {
+	Version int         `json:"version"`
+	Key     string      `json:"key"`
+	Created time.Time   `json:"created"`
+	Result  Result      `json:"result"`
+	Costs   *costCounts `json:"costs,omitempty"`
+}
+
+// CostBreakdown sem o JSON de -cost-json, que não pode ser lido de volta
+type costCounts CostBreakdown
+
+// Execuções que dependem de algo além do Result (narração, observadores,
+// relatórios da própria política) não passam pelo cache
+func (s *Simulator) cacheable(policy ReplacementPolicy) bool {
+	if s.cacheDir == "" || s.noCache || s.didacticMode || len(s.observers) > 0 || s.victimsFile != "" || len(s.eventSinks) > 0 {
+		return false
+	}
+	if _, ok := policy.(policyReporter); ok {
+		return false
+	}
+	if _, ok := policy.(*clockPolicy); ok && s.clockAdaptive {
+		return false
+	}
+	return true
+}
+
+func (s *Simulator) cacheKey(algorithm string) string {
+	if s.cacheTrace == "" {
+		hash := sha256.New()
+		for _, access := range s.accesses {
+			fmt.Fprintf(hash, "%s %t %t\n", access.PageID, access.Write, access.Zero)
+		}
+		s.cacheTrace = fmt.Sprintf("%x", hash.Sum(nil))
+	}
+	// Configuração: o simulador sem o trace, as estatísticas e os campos
+	// do próprio cache e da interrupção
+	config := *s
+	config.accesses, config.distinctPages, config.pageLoadCount, config.runs = nil, nil, nil, nil
+	config.frameStats, config.classStats, config.colorStats = nil, [2]ClassStats{}, ColorStats{}
+	config.observers, config.nextUse, config.policyExpr = nil, nil, nil
+	config.traceName, config.cacheDir, config.noCache, config.cacheClear, config.cacheTrace = "", "", false, false, ""
+	config.ctx, config.stopped, config.diagnostics, config.traceTimes = nil, nil, nil, nil
+	config.pace, config.keys, config.pagePatterns = nil, nil, nil
+	config.checkpointDir, config.checkpointEvery, config.resume, config.checkpointAlgorithm = "", 0, false, ""
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d\n%s\n%s\n%#v", resultCacheVersion, s.cacheTrace, algorithm, config)))
+	return fmt.Sprintf("%x", sum)
+}
+
+func (s *Simulator) cachePath(key string) string {
+	return filepath.Join(s.cacheDir, "result-"+key+".json")
+}
+
+// Resultado guardado para o algoritmo, se houver um válido
+func (s *Simulator) cachedResult(algorithm string) (Result, time.Time, bool) {
+	key := s.cacheKey(algorithm)
+	data, err := os.ReadFile(s.cachePath(key))
+	if err != nil {
+		return Result{}, time.Time{}, false
+	}
+	var entry resultCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Version != resultCacheVersion || entry.Key != key {
+		logger.Warn("entrada do cache inválida ou de outra versão; simulando de novo", "file", s.cachePath(key))
+		return Result{}, time.Time{}, false
+	}
+	entry.Result.Costs = (*CostBreakdown)(entry.Costs)
+	return entry.Result, entry.Created, true
+}
+
+func (s *Simulator) storeResult(algorithm string, r Result) error {
+	if err := os.MkdirAll(s.cacheDir, 0755); err != nil {
+		return fmt.Errorf("erro ao criar diretório %s: %v", s.cacheDir, err)
+	}
+	key := s.cacheKey(algorithm)
+	entry := resultCacheEntry{Version: resultCacheVersion, Key: key, Created: time.Now(), Result: r,
+		Costs: (*costCounts)(r.Costs)}
+	entry.Result.Costs = nil
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.cachePath(key), data)
+}
+
+// Grava em outro nome no mesmo diretório e renomeia: quem lê (outra
+// execução, ou -resume depois de uma queda) nunca encontra meio arquivo
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("erro ao gravar em %s: %v", filepath.Dir(path), err)
+	}
+	_, err = tmp.Write(data)
+	if syncErr := tmp.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("erro ao gravar %s: %v", path, err)
+	}
+	return nil
+}
+
+// Executa run, ou devolve o resultado guardado no cache; cached indica o
+// segundo caso
+func (s *Simulator) cachedRun(algorithm string, policy ReplacementPolicy, run func() Result) (result Result, cached bool) {
+	s.checkpointAlgorithm = algorithm
+	defer func() { s.checkpointAlgorithm = "" }()
+	if !s.cacheable(policy) {
+		return run(), false
+	}
+	if r, created, ok := s.cachedResult(algorithm); ok {
+		fmt.Printf("Resultado do cache (simulado em %s)\n", created.Format("2006-01-02 15:04:05"))
+		return r, true
+	}
+	result = run()
+	if result.Err == nil {
+		if err := s.storeResult(algorithm, result); err != nil {
+			logger.Warn("resultado fora do cache", "algorithm", algorithm, "err", err)
+		}
+	}
+	return result, false
+}
+
+// Remove as entradas do cache (-cache-clear); só os arquivos do cache
+func (s *Simulator) clearCache() (int, error) {
+	paths, err := filepath.Glob(filepath.Join(s.cacheDir, "result-*"))
+	if err != nil {
+		return 0, err
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil {
+			return 0, err
+		}
+	}
+	return len(paths), nil
+}
+
+// Checkpoints de execuções longas (-checkpoint-every N -checkpoint-dir DIR):
+// a cada N acessos o estado de runPolicy (contadores e política) vai para
+// DIR/checkpoint-<algoritmo>-<acesso>.gob, gravado de forma atômica; só os
+// checkpointKeep mais recentes de cada algoritmo ficam. Com -resume a
+// execução continua do último checkpoint válido, e um algoritmo que já
+// terminou devolve o resultado guardado. A chave é a mesma do cache: outro
+// trace ou outra configuração não retomam nada.
+const (
+	checkpointVersion = 1
+	checkpointKeep    = 2
+)
+
+// Implementada pelas políticas cujo estado cabe num checkpoint
+type checkpointPolicy interface {
+	checkpointState() policyState
+	restoreState(policyState)
+}
+
+// Estado de uma política; cada uma usa só os campos de que precisa
+type policyState struct {
+	Frames       []PageFrame // frame vazio: PageID ""
+	Hand         int         // ponteiro do Relógio, início da fila do FIFO, acesso do Ótimo
+	Count        int         // acessos vistos pelo Relógio (limpeza dos bits R)
+	Newer, Older []int       // lista do LRU
+	Head, Tail   int
+	Window       []bool // Relógio adaptativo
+	WindowFaults int
+	Swept        int
+	Sweeps       int
+	ColorLoad    []int
+}
+
+func saveFrames(frames []*PageFrame) []PageFrame {
+	saved := make([]PageFrame, len(frames))
+	for i, frame := range frames {
+		if frame != nil {
+			saved[i] = *frame
+		}
+	}
+	return saved
+}
+
+// Refaz os frames e o mapa página -> frame; used conta os ocupados
+func restoreFrames(frames []*PageFrame, saved []PageFrame) (pageToFrame map[string]int, used int) {
+	pageToFrame = make(map[string]int)
+	for i := range frames {
+		frames[i] = nil
+		if i < len(saved) && saved[i].PageID != "" {
+			frame := saved[i]
+			frames[i] = &frame
+			pageToFrame[frame.PageID] = i
+			used++
+		}
+	}
+	return pageToFrame, used
+}
+
+type checkpoint struct {
+	Version   int
+	Key       string // chave do cache: trace e configuração
+	Trace     string // SHA-256 dos acessos, para explicar uma chave diferente
+	Algorithm string
+	Offset    int // acessos já simulados, aquecimento incluído
+	Elapsed   time.Duration
+
+	Faults, WriteBacks, ZeroFills, InstrFaults, WarmupFaults int
+
+	LoadCount     map[string]int
+	Evictions     []int
+	Hosted        []map[string]bool
+	ClassStats    [2]ClassStats
+	PatternFaults []int
+	Saves         SaveStats
+	SavePending   []bool
+	SaveFrameOf   map[string]int
+	Policy        policyState
+
+	Result *Result // a execução terminou
+}
+
+// Checkpoints da execução de um algoritmo em Run
+type checkpointRun struct {
+	s         *Simulator
+	policy    checkpointPolicy
+	algorithm string
+	key       string
+	next      int // acesso do próximo checkpoint
+}
+
+// Checkpoints da execução atual de Run; nil fora de Run, sem
+// -checkpoint-dir ou numa política sem estado salvável
+func (s *Simulator) checkpoints(policy ReplacementPolicy) *checkpointRun {
+	if s.checkpointDir == "" || s.checkpointAlgorithm == "" {
+		return nil
+	}
+	cp, ok := policy.(checkpointPolicy)
+	if !ok {
+		logger.Warn("algoritmo sem suporte a checkpoint; simulado do início e sem checkpoints", "algorithm", s.checkpointAlgorithm)
+		return nil
+	}
+	return &checkpointRun{s: s, policy: cp, algorithm: s.checkpointAlgorithm, key: s.cacheKey(s.checkpointAlgorithm)}
+}
+
+func (c *checkpointRun) paths() []string {
+	paths, _ := filepath.Glob(filepath.Join(c.s.checkpointDir, "checkpoint-"+c.algorithm+"-*.gob"))
+	// O acesso tem largura fixa: a ordem dos nomes é a dos checkpoints
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	return paths
+}
+
+// Último checkpoint válido com -resume; sem -resume os antigos são
+// descartados e a execução começa do início
+func (c *checkpointRun) resume() *checkpoint {
+	if c == nil {
+		return nil
+	}
+	c.next = c.s.checkpointEvery
+	if !c.s.resume {
+		for _, path := range c.paths() {
+			os.Remove(path)
+		}
+		return nil
+	}
+	for _, path := range c.paths() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var saved checkpoint
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&saved); err != nil || saved.Version != checkpointVersion {
+			logger.Warn("checkpoint ilegível ou de outra versão ignorado", "file", path)
+			continue
+		}
+		if saved.Key != c.key {
+			reason := "outra configuração"
+			if saved.Trace != c.s.cacheTrace {
+				reason = "outro trace"
+			}
+			logger.Warn("checkpoint de "+reason+" ignorado; simulando do início", "file", path)
+			return nil
+		}
+		if saved.Result != nil {
+			fmt.Println("Resultado do checkpoint final (execução já concluída)")
+			return &saved
+		}
+		fmt.Printf("Retomado do checkpoint no acesso %d\n", saved.Offset)
+		c.policy.restoreState(saved.Policy)
+		c.next = saved.Offset + c.s.checkpointEvery
+		return &saved
+	}
+	return nil
+}
+
+// Grava o checkpoint e apaga os mais antigos; um erro desliga os
+// checkpoints desta execução, que continua
+func (c *checkpointRun) save(saved *checkpoint) error {
+	saved.Version, saved.Key, saved.Trace, saved.Algorithm = checkpointVersion, c.key, c.s.cacheTrace, c.algorithm
+	saved.Policy = c.policy.checkpointState()
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(saved); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.s.checkpointDir, 0755); err != nil {
+		return fmt.Errorf("erro ao criar diretório %s: %v", c.s.checkpointDir, err)
+	}
+	path := filepath.Join(c.s.checkpointDir, fmt.Sprintf("checkpoint-%s-%012d.gob", c.algorithm, saved.Offset))
+	if err := writeFileAtomic(path, b.Bytes()); err != nil {
+		return err
+	}
+	for i, old := range c.paths() {
+		if i >= checkpointKeep {
+			os.Remove(old)
+		}
+	}
+	return nil
+}
+
+// Opção incompatível com os checkpoints: estado fora dos contadores de
+// runPolicy e da política (observadores, amostras, parada antecipada)
+func (s *Simulator) checkpointConflict() string {
+	switch {
+	case s.didacticMode:
+		return "-didactic"
+	case s.playback > 0:
+		return "-playback"
+	case s.victimsFile != "":
+		return "-victims"
+	case s.eventsCSV != "":
+		return "-events-csv"
+	case s.eventsParquet != "":
+		return "-events-parquet"
+	case s.convergeEpsilon > 0:
+		return "-converge"
+	case s.costReport:
+		return "-cost"
+	case s.seriesInterval > 0:
+		return "-rss-interval"
+	case s.faultDist != "":
+		return "-fault-dist"
+	case s.pageColors() > 0:
+		return "-cache-size"
+	}
+	return ""
+}
+
+// Intervalos do modo -watch: de quanto em quanto tempo o arquivo é
+// consultado e por quanto tempo ele deve ficar parado antes de ser relido
+const (
+	watchPoll     = 500 * time.Millisecond
+	watchDebounce = 300 * time.Millisecond
+)
+
+type fileVersion struct {
+	size    int64
+	modTime time.Time
+}
+
+func statVersion(filename string) (fileVersion, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return fileVersion{}, err
+	}
+	return fileVersion{info.Size(), info.ModTime()}, nil
+}
+
+// Reexecuta a simulação sempre que o trace muda, mostrando as faltas de
+// cada algoritmo e a diferença para a execução anterior. Termina com Ctrl-C.
+func (s *Simulator) Watch(filename string) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	previous := make(map[string]int)
+	for _, r := range s.Simulate() {
+		previous[r.Algorithm] = r.Faults
+	}
+	version, _ := statVersion(filename)
+	fmt.Printf("\nObservando %s (Ctrl-C para sair)\n", filename)
+
+	ticker := time.NewTicker(watchPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-interrupt:
+			fmt.Println("\nEncerrando")
+			return
+		case <-ticker.C:
+		}
+
+		current, err := statVersion(filename)
+		if err != nil || current == version {
+			continue
+		}
+		// Espera as gravações terminarem antes de reler o arquivo
+		for {
+			time.Sleep(watchDebounce)
+			settled, err := statVersion(filename)
+			if err != nil || settled == current {
+				break
+			}
+			current = settled
+		}
+		version = current
+
+		s.accesses, s.distinctPages, s.writeCount = nil, make(map[string]bool), 0
+		s.segmentViolations = nil
+		fmt.Printf("\n=== %s alterado às %s ===\n", filename, time.Now().Format("15:04:05"))
+		d, err := s.LoadAccessFile(filename)
+		d.log()
+		if err != nil {
+			// Arquivo truncado ou ainda sendo reescrito: espera a próxima mudança
+			logger.Error("erro ao carregar arquivo", "file", filename, "err", err)
+			continue
+		}
+		for _, r := range s.Simulate() {
+			if faults, ok := previous[r.Algorithm]; ok {
+				fmt.Printf("%-22s %8d faltas (%+d)\n", r.Algorithm, r.Faults, r.Faults-faults)
+			} else {
+				fmt.Printf("%-22s %8d faltas\n", r.Algorithm, r.Faults)
+			}
+			previous[r.Algorithm] = r.Faults
+		}
+	}
+}
+
+// Modo interativo: os acessos são digitados um a um
+func (s *Simulator) RunREPL(in io.Reader) {
+	algo := streamingPolicies[0]
+	var stepper *Stepper
+
+	// Reaplica os acessos já digitados após mudar frames ou algoritmo
+	replay := func() {
+		stepper, _ = s.NewStepper(s.accesses, s.totalFrames, algo.Name)
+		s.pageLoadCount = make(map[string]int)
+		for {
+			result, ok := stepper.Step()
+			if !ok {
+				break
+			}
+			if !result.Hit {
+				s.pageLoadCount[result.PageID]++
+			}
+		}
+	}
+
+	printStats := func() {
+		summary := stepper.Summary()
+		fmt.Printf("Algoritmo: %s | Frames: %d\n", algo.Label, s.totalFrames)
+		fmt.Printf("Acessos: %d | Faltas de página: %d | Hits: %d\n",
+			summary.Accesses, summary.Faults, summary.Hits())
+		if summary.Accesses > 0 {
+			fmt.Printf("Taxa de faltas: %.2f%%\n", summary.FaultRate()*100)
+		}
+		fmt.Printf("Páginas distintas: %d\n", len(s.distinctPages))
+	}
+
+	replay()
+	fmt.Println("=== MODO INTERATIVO ===")
+	fmt.Printf("Algoritmo: %s | Frames: %d\n", algo.Label, s.totalFrames)
+	fmt.Println("Digite páginas (ex.: I1 D2 D3) ou :help para ver os comandos")
+
+	scanner := bufio.NewScanner(in)
+loop:
+	for {
+		fmt.Print("> ")
+		if !scanner.Scan() {
+			fmt.Println()
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			parts := strings.Fields(line)
+			switch parts[0] {
+			case ":help":
+				fmt.Println("  :reset         : descarta os acessos digitados")
+				fmt.Println("  :frames N      : altera o número de frames")
+				fmt.Printf("  :algo NOME     : altera o algoritmo (%s)\n", strings.Join(policyNames(), ", "))
+				fmt.Println("  :stats         : mostra as estatísticas atuais")
+				fmt.Println("  :quit          : encerra e mostra o resumo")
+			case ":reset":
+				s.accesses = nil
+				s.distinctPages = make(map[string]bool)
+				replay()
+				fmt.Println("Sequência de acessos descartada")
+			case ":frames":
+				if len(parts) != 2 {
+					fmt.Println("Uso: :frames N")
+					continue
+				}
+				frames, err := strconv.Atoi(parts[1])
+				if err != nil || frames < 1 {
+					fmt.Printf("Número de frames inválido: %s\n", parts[1])
+					continue
+				}
+				s.totalFrames = frames
+				s.memorySize = frames * PAGE_SIZE
+				replay()
+				fmt.Printf("Frames: %d (%d acessos reaplicados)\n", frames, len(s.accesses))
+				s.printMemoryState(stepper.Frames())
+			case ":algo":
+				if len(parts) != 2 {
+					fmt.Println("Uso: :algo NOME")
+					continue
+				}
+				info, ok := findPolicy(parts[1])
+				if !ok {
+					fmt.Printf("Algoritmo desconhecido: %s (disponíveis: %s)\n",
+						parts[1], strings.Join(policyNames(), ", "))
+					continue
+				}
+				algo = info
+				replay()
+				fmt.Printf("Algoritmo: %s (%d acessos reaplicados)\n", algo.Label, len(s.accesses))
+				s.printMemoryState(stepper.Frames())
+			case ":stats":
+				printStats()
+			case ":quit", ":q":
+				break loop
+			default:
+				fmt.Printf("Comando desconhecido: %s (use :help)\n", parts[0])
+			}
+			continue
+		}
+
+		for _, pageID := range strings.Fields(line) {
+			if len(pageID) < 2 || (pageID[0] != 'I' && pageID[0] != 'D') {
+				fmt.Printf("Página inválida: %s (use I ou D seguido do identificador)\n", pageID)
+				continue
+			}
+			access := PageAccess{PageID: pageID, Type: string(pageID[0])}
+			s.accesses = append(s.accesses, access)
+			s.distinctPages[pageID] = true
+
+			stepper.Append(access)
+			result, _ := stepper.Step()
+			if result.Hit {
+				fmt.Printf("Acesso %d - Página %s: Hit\n", len(s.accesses), pageID)
+			} else {
+				s.pageLoadCount[pageID]++
+				kind := faultKind(s.pageLoadCount[pageID])
+				if result.Victim != "" {
+					fmt.Printf("Acesso %d - Página %s: Falta de página (%s; substituiu %s no frame %d)\n",
+						len(s.accesses), pageID, kind, result.Victim, result.Frame)
+				} else {
+					fmt.Printf("Acesso %d - Página %s: Falta de página (%s; frame %d estava vazio)\n",
+						len(s.accesses), pageID, kind, result.Frame)
+				}
+			}
+			s.printMemoryState(stepper.Frames())
+		}
+	}
+
+	if len(s.accesses) == 0 {
+		fmt.Println("Nenhum acesso digitado.")
+		return
+	}
+	fmt.Println()
+	s.didacticMode = false
+	s.Run()
+}
+
+const quizMaxAccesses = 30
+
+// Normaliza a resposta do usuário: aceita o ID com ou sem o prefixo de tipo
+// e sem zeros à esquerda (D0042, 0042 e 42 se referem à mesma página)
+func normalizePageAnswer(answer string) (id string, hasPrefix bool) {
+	id = strings.ToUpper(strings.TrimSpace(answer))
+	if len(id) >= 2 && (id[0] == 'I' || id[0] == 'D') {
+		id = id[1:]
+		hasPrefix = true
+	}
+	id = strings.TrimLeft(id, "0")
+	if id == "" {
+		id = "0"
+	}
+	return id, hasPrefix
+}
+
+// Uma resposta vazia (inclusive no fim da entrada) é sempre errada, mesmo
+// para a página 0
+func pageAnswerMatches(answer, pageID string) bool {
+	if strings.TrimSpace(answer) == "" {
+		return false
+	}
+	id, hasPrefix := normalizePageAnswer(answer)
+	want, _ := normalizePageAnswer(pageID)
+	if hasPrefix && !strings.EqualFold(strings.TrimSpace(answer)[:1], pageID[:1]) {
+		return false
+	}
+	return id == want
+}
+
+// Situação do acesso, usada para agrupar os erros no resumo do quiz
+func quizSituation(r StepResult) string {
+	switch {
+	case r.Hit:
+		return "hit"
+	case r.Victim == "":
+		return "falta com frame vazio"
+	case len(r.Spared) == 0:
+		return "substituição direta"
+	default:
+		return "substituição após segunda chance"
+	}
+}
+
+// Lição em Markdown (-lesson): a narração do modo didático como exemplo
+// resolvido, com uma tabela por algoritmo (uma linha por acesso do
+// intervalo de -didactic-range) e um resumo no fim. Sem intervalo, narra
+// no máximo lessonMaxAccesses acessos.
+const lessonMaxAccesses = 100
+
+// Texto seguro dentro de uma célula de tabela Markdown
+func markdownCell(text string) string {
+	return strings.ReplaceAll(text, "|", "\\|")
+}
+
+func (s *Simulator) writeLesson(w io.Writer, results []Result) error {
+	from, to := s.didacticFrom, s.didacticTo
+	if to == 0 || to > len(s.accesses) {
+		to = min(len(s.accesses), from+lessonMaxAccesses-1)
+	}
+
+	var b strings.Builder
+	fmt.Fprintln(&b, "# Exemplo resolvido: substituição de páginas")
+	fmt.Fprintln(&b)
+	if s.traceName != "" {
+		fmt.Fprintf(&b, "- Trace: `%s` (%d acessos, %d páginas distintas)\n", s.traceName, len(s.accesses), len(s.distinctPages))
+	} else {
+		fmt.Fprintf(&b, "- Trace: %d acessos, %d páginas distintas\n", len(s.accesses), len(s.distinctPages))
+	}
+	fmt.Fprintf(&b, "- Memória: %d bytes = %d frames de %d bytes\n", s.memorySize, s.totalFrames, PAGE_SIZE)
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Algorithm)
+	}
+	fmt.Fprintf(&b, "- Algoritmos: %s\n", strings.Join(names, ", "))
+	fmt.Fprintf(&b, "- Acessos narrados: %d a %d\n", from, to)
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "Em cada linha, o frame alterado pelo acesso aparece em negrito; (R) indica o bit de referência ligado e (M), página modificada.")
+
+	narrated := make([]int, len(results))
+	for k, r := range results {
+		fmt.Fprintf(&b, "\n## %s\n\n", r.Algorithm)
+		header, align := "| Acesso | Página | Resultado |", "|---:|---|---|"
+		for f := 0; f < s.totalFrames; f++ {
+			header += fmt.Sprintf(" Frame %d |", f)
+			align += "---|"
+		}
+		fmt.Fprintln(&b, header+" Explicação |")
+		fmt.Fprintln(&b, align+"---|")
+
+		policy := s.newPolicyFor(r.Algorithm)
+		for i, access := range s.accesses[:to] {
+			result := step(policy, access)
+			if i+1 < from {
+				continue
+			}
+			outcome := "hit"
+			if !result.Hit {
+				outcome = "falta"
+				narrated[k]++
+			}
+			row := fmt.Sprintf("| %d | %s | %s |", i+1, markdownCell(access.String()), outcome)
+			for f, frame := range policy.Frames() {
+				cell := ""
+				if frame != nil {
+					cell = markdownCell(frame.PageID)
+					if frame.Referenced {
+						cell += " (R)"
+					}
+					if frame.Dirty {
+						cell += " (M)"
+					}
+					if f == result.Frame {
+						cell = "**" + cell + "**"
+					}
+				}
+				row += " " + cell + " |"
+			}
+			fmt.Fprintln(&b, row+" "+markdownCell(result.Explain())+" |")
+		}
+	}
+
+	fmt.Fprintln(&b, "\n## Resumo")
+	fmt.Fprintln(&b)
+	fmt.Fprintf(&b, "| Algoritmo | Faltas nos acessos %d a %d | Faltas no trace | Taxa de acerto no trace |\n", from, to)
+	fmt.Fprintln(&b, "|---|---:|---:|---:|")
+	for k, r := range results {
+		fmt.Fprintf(&b, "| %s | %d | %d | %.2f%% |\n", r.Algorithm, narrated[k], r.Faults, r.HitRate()*100)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Grava a lição dos algoritmos executados em -lesson
+func (s *Simulator) WriteLesson(results []Result) {
+	if s.lessonFile == "" {
+		return
+	}
+	file, err := os.Create(s.lessonFile)
+	if err != nil {
+		logger.Error("erro ao criar arquivo", "file", s.lessonFile, "err", err)
+		return
+	}
+	defer file.Close()
+	if err := s.writeLesson(file, results); err != nil {
+		logger.Error("erro ao gravar arquivo", "file", s.lessonFile, "err", err)
+		return
+	}
+	fmt.Printf("\nLição gravada em %s\n", s.lessonFile)
+}
+
+// Modo -tui: a simulação avança no ritmo escolhido e a tela é redesenhada
+// com códigos ANSI a cada acesso. Em terminais sem suporte (TERM=dumb ou
+// saída redirecionada) o estado é impresso em sequência, sem limpar a tela.
+const (
+	tuiMaxFrames  = 32 // frames na tabela
+	tuiWindow     = 10 // acessos por ponto do gráfico de faltas recentes
+	tuiSparkWidth = 40
+)
+
+type tuiState struct {
+	Title    string
+	Access   int // acessos já simulados
+	Total    int
+	Faults   int
+	Current  PageAccess
+	Last     StepResult
+	Frames   []*PageFrame
+	Hand     int       // -1 sem ponteiro
+	Recent   []float64 // taxa de faltas das últimas janelas de tuiWindow acessos
+	Paused   bool
+	Rate     float64 // acessos por segundo
+	Playback float64 // fator de -playback; 0 usa Rate
+	Finished bool
+}
+
+func sparkline(values []float64, ansi bool) string {
+	levels := []rune(" .:-=+*#")
+	if ansi {
+		levels = []rune("▁▂▃▄▅▆▇█")
+	}
+	var b strings.Builder
+	for _, v := range values {
+		b.WriteRune(levels[min(len(levels)-1, int(v*float64(len(levels))))])
+	}
+	return b.String()
+}
+
+// Desenha a tela inteira; separada do laço para poder ser testada contra
+// um buffer no lugar do terminal
+func renderTUI(w io.Writer, st tuiState, ansi bool) {
+	var b strings.Builder
+	if ansi {
+		b.WriteString("\x1b[H\x1b[2J")
+	} else {
+		b.WriteString("----\n")
+	}
+	status := fmt.Sprintf("%.1f acessos/s", st.Rate)
+	if st.Playback > 0 {
+		status = fmt.Sprintf("reprodução %gx", st.Playback)
+	}
+	switch {
+	case st.Finished:
+		status = "fim do trace"
+	case st.Paused:
+		status = "pausado"
+	}
+	fmt.Fprintf(&b, "%s - acesso %d de %d [%s]\n", st.Title, st.Access, st.Total, status)
+	hitRate := 0.0
+	if st.Access > 0 {
+		hitRate = float64(st.Access-st.Faults) / float64(st.Access) * 100
+	}
+	fmt.Fprintf(&b, "Faltas: %d  Hits: %d  Taxa de acerto: %.2f%%\n", st.Faults, st.Access-st.Faults, hitRate)
+	fmt.Fprintf(&b, "Faltas recentes: [%-*s]\n\n", tuiSparkWidth, sparkline(st.Recent, ansi))
+
+	fmt.Fprintf(&b, "   %5s  %-12s %s %s\n", "Frame", "Página", "R", "M")
+	for i, frame := range st.Frames[:min(len(st.Frames), tuiMaxFrames)] {
+		marker := "  "
+		if i == st.Hand {
+			marker = "->"
+		}
+		page, bitR, bitM := "-", " ", " "
+		if frame != nil {
+			page, bitR, bitM = frame.PageID, "0", "0"
+			if frame.Referenced {
+				bitR = "1"
+			}
+			if frame.Dirty {
+				bitM = "1"
+			}
+		}
+		line := fmt.Sprintf("%s %5d  %-12s %s %s", marker, i, page, bitR, bitM)
+		if ansi && st.Access > 0 && i == st.Last.Frame {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		b.WriteString(line + "\n")
+	}
+	if len(st.Frames) > tuiMaxFrames {
+		fmt.Fprintf(&b, "   ... mais %d frames\n", len(st.Frames)-tuiMaxFrames)
+	}
+	if st.Access > 0 {
+		fmt.Fprintf(&b, "\n%s: %s\n", st.Current, st.Last.Explain())
+	}
+	b.WriteString("\nespaço: pausa  n: um passo  +/-: velocidade  q: sair\n")
+	io.WriteString(w, b.String())
+}
+
+// Tenta pôr o terminal em modo cbreak (teclas sem Enter, sem eco);
+// devolve a função que restaura o modo anterior
+func cbreakTerminal() (restore func(), ok bool) {
+	stty := func(args ...string) (string, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = os.Stdin
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return func() {}, false
+	}
+	if _, err := stty("cbreak", "-echo"); err != nil {
+		return func() {}, false
+	}
+	return func() { stty(saved) }, true
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Teclas lidas uma a uma da entrada (com o terminal em cbreak, sem Enter);
+// o canal fecha quando a entrada termina
+func stdinKeys() <-chan byte {
+	ch := make(chan byte)
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if n, err := os.Stdin.Read(buf); err != nil || n == 0 {
+				close(ch)
+				return
+			}
+			ch <- buf[0]
+		}
+	}()
+	return ch
+}
+
+// Reprodução em ritmo real (-playback Fx), no modo didático e na TUI: depois
+// de cada acesso espera o tempo dele no trace (coluna +N) ou, sem ela,
+// -access-time (padrão playbackInterval), dividido pelo fator. Em + e -
+// o fator dobra e cai pela metade, espaço pausa; a espera em curso
+// recomeça com o novo fator.
+const (
+	playbackInterval = time.Second
+	maxPlayback      = 1e9
+)
+
+type pacer struct {
+	factor   float64
+	interval time.Duration // por acesso, sem a coluna +N
+	timed    bool
+	keys     <-chan byte // nil sem teclado
+	paused   bool
+	after    func(time.Duration) <-chan time.Time // time.After; os testes usam um relógio falso
+}
+
+func (s *Simulator) newPacer(keys <-chan byte) *pacer {
+	interval := playbackInterval
+	if s.accessTime > 0 {
+		interval = time.Duration(s.accessTime)
+	}
+	return &pacer{factor: s.playback, interval: interval, timed: timedTrace(s.accesses), keys: keys, after: time.After}
+}
+
+// Espera depois do acesso; o fator é aplicado a cada chamada
+func (p *pacer) delay(access PageAccess) time.Duration {
+	d := p.interval
+	if p.timed {
+		d = time.Duration(access.Delay)
+	}
+	return time.Duration(float64(d) / p.factor)
+}
+
+// Aplica a tecla; false se ela não controla a reprodução
+func (p *pacer) key(k byte) bool {
+	switch k {
+	case '+':
+		p.factor = min(p.factor*2, maxPlayback)
+	case '-':
+		p.factor /= 2
+	case ' ':
+		p.paused = !p.paused
+	default:
+		return false
+	}
+	return true
+}
+
+func (p *pacer) wait(access PageAccess) {
+	for {
+		var timer <-chan time.Time
+		if !p.paused {
+			timer = p.after(p.delay(access))
+		}
+		select {
+		case <-timer:
+			return
+		case k, ok := <-p.keys:
+			if !ok {
+				p.keys, p.paused = nil, false // entrada encerrada: segue sem teclado
+			} else {
+				p.key(k)
+			}
+		}
+	}
+}
+
+// O modo didático só é reproduzido em ritmo real num terminal
+func (s *Simulator) pacing(out *os.File) bool {
+	return s.playback > 0 && s.didacticMode && isTerminal(out)
+}
+
+// Espera entre os acessos narrados; o último não espera
+type playbackObserver struct {
+	s    *Simulator
+	pace *pacer
+}
+
+func (o *playbackObserver) step(access int, page PageAccess) {
+	if access < len(o.s.accesses) && o.s.inDidacticRange(access) {
+		o.pace.wait(page)
+	}
+}
+
+func (o *playbackObserver) OnHit(e HitEvent) error {
+	o.step(e.Access, e.Page)
+	return nil
+}
+
+func (o *playbackObserver) OnFault(e FaultEvent) error {
+	o.step(e.Access, e.Page)
+	return nil
+}
+
+func (o *playbackObserver) OnEvict(EvictEvent) error       { return nil }
+func (o *playbackObserver) OnComplete(CompleteEvent) error { return nil }
+
+func (s *Simulator) RunTUI() {
+	info := streamingPolicies[0]
+	for _, candidate := range streamingPolicies {
+		if s.algorithmSelected(candidate.Name) {
+			info = candidate
+			break
+		}
+	}
+	stepper, _ := s.NewStepper(s.accesses, s.totalFrames, info.Name)
+	ansi := isTerminal(os.Stdout) && os.Getenv("TERM") != "dumb" && os.Getenv("TERM") != ""
+
+	restore := func() {}
+	if isTerminal(os.Stdin) {
+		restore, _ = cbreakTerminal()
+	}
+	keys := stdinKeys()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	st := tuiState{Title: fmt.Sprintf("%s, %d frames", info.Label, s.totalFrames), Total: len(s.accesses),
+		Frames: stepper.Frames(), Hand: stepper.Hand(), Rate: s.tuiRate}
+	// Com -playback o ritmo vem do trace; as teclas continuam com a TUI
+	var pace *pacer
+	if s.playback > 0 {
+		pace = s.newPacer(nil)
+		st.Playback = pace.factor
+	}
+	interval := func() time.Duration {
+		switch {
+		case pace == nil:
+			return time.Duration(float64(time.Second) / st.Rate)
+		case st.Access == 0:
+			return 0
+		}
+		return pace.delay(st.Current)
+	}
+	windowFaults := 0
+	advance := func() {
+		st.Last, _ = stepper.Step()
+		st.Current, _ = stepper.Current()
+		st.Access++
+		if !st.Last.Hit {
+			st.Faults++
+			windowFaults++
+		}
+		if st.Access%tuiWindow == 0 {
+			st.Recent = append(st.Recent, float64(windowFaults)/tuiWindow)
+			if len(st.Recent) > tuiSparkWidth {
+				st.Recent = st.Recent[1:]
+			}
+			windowFaults = 0
+		}
+		st.Hand = stepper.Hand()
+		st.Finished = stepper.Remaining() == 0
+	}
+
+	renderTUI(os.Stdout, st, ansi)
+	timer := time.NewTimer(interval())
+	quit := false
+	for !quit && !st.Finished {
+		select {
+		case <-timer.C:
+			if !st.Paused {
+				advance()
+				renderTUI(os.Stdout, st, ansi)
+			}
+			timer.Reset(interval())
+		case key, ok := <-keys:
+			switch {
+			case !ok:
+				keys = nil // entrada encerrada: segue sem teclado
+			case key == 'q':
+				quit = true
+			case key == ' ':
+				st.Paused = !st.Paused
+			case key == 'n' && st.Paused:
+				advance()
+			case (key == '+' || key == '-') && pace != nil:
+				pace.key(key)
+				st.Playback = pace.factor
+			case key == '+':
+				st.Rate = min(st.Rate*2, 1000)
+			case key == '-':
+				st.Rate = max(st.Rate/2, 0.25)
+			}
+			renderTUI(os.Stdout, st, ansi)
+		case <-interrupt:
+			quit = true
+		}
+	}
+	restore()
+
+	fmt.Println("\n=== RESUMO ===")
+	if st.Access < st.Total {
+		fmt.Printf("Interrompido no acesso %d de %d\n", st.Access, st.Total)
+	}
+	fmt.Printf("Faltas de página (%s): %d em %d acessos\n", info.Label, st.Faults, st.Access)
+	if st.Access > 0 {
+		fmt.Printf("Taxa de acerto: %.2f%%\n", float64(st.Access-st.Faults)/float64(st.Access)*100)
+	}
+	s.printMemoryState(stepper.Frames())
+}
+
+// Modo quiz: o usuário prevê o resultado de cada acesso antes de vê-lo.
+// Com auto=true as respostas são sorteadas, sem leitura da entrada.
+func (s *Simulator) RunQuiz(in io.Reader, auto bool) {
+	accesses := s.accesses
+	if len(accesses) > quizMaxAccesses {
+		fmt.Printf("Quiz limitado aos primeiros %d acessos do arquivo\n", quizMaxAccesses)
+		accesses = accesses[:quizMaxAccesses]
+	}
+
+	algo := streamingPolicies[0]
+	stepper, _ := s.NewStepper(accesses, s.totalFrames, algo.Name)
+	scanner := bufio.NewScanner(in)
+	rng := rand.New(rand.NewSource(1))
+
+	ask := func(prompt string, options []string) string {
+		fmt.Print(prompt)
+		if auto {
+			answer := options[rng.Intn(len(options))]
+			fmt.Println(answer)
+			return answer
+		}
+		if !scanner.Scan() {
+			fmt.Println()
+			return ""
+		}
+		return strings.TrimSpace(scanner.Text())
+	}
+
+	score, questions := 0, 0
+	missed := make(map[string]int)
+
+	fmt.Printf("=== QUIZ (%s, %d frames) ===\n", algo.Label, s.totalFrames)
+	for i, access := range accesses {
+		frames := stepper.Frames()
+		fmt.Printf("\nAcesso %d - Página %s\n", i+1, access.PageID)
+		s.printMemoryState(frames)
+		if hand := stepper.Hand(); hand >= 0 {
+			fmt.Printf("Ponteiro no frame %d\n", hand)
+		}
+
+		var resident []string
+		for _, frame := range frames {
+			if frame != nil {
+				resident = append(resident, frame.PageID)
+			}
+		}
+
+		answer := strings.ToLower(ask("Hit ou falta? (h/f) > ", []string{"h", "f"}))
+		predictedHit := strings.HasPrefix(answer, "h")
+		predictedFault := strings.HasPrefix(answer, "f")
+
+		result, _ := stepper.Step()
+		situation := quizSituation(result)
+		correct := (result.Hit && predictedHit) || (!result.Hit && predictedFault)
+
+		questions++
+		if correct {
+			score++
+			fmt.Println("Correto!")
+		} else {
+			missed[situation]++
+			if result.Hit {
+				fmt.Println("Errado: foi um hit.")
+			} else {
+				fmt.Println("Errado: foi uma falta de página.")
+			}
+		}
+
+		if result.Victim != "" {
+			questions++
+			victim := ask("Qual página será substituída? > ", resident)
+			if pageAnswerMatches(victim, result.Victim) {
+				score++
+				fmt.Println("Correto!")
+			} else {
+				missed[situation]++
+				fmt.Printf("Errado: a página substituída foi %s.\n", result.Victim)
+			}
+		}
+
+		fmt.Println(result.Explain())
+	}
+
+	fmt.Println("\n=== RESULTADO DO QUIZ ===")
+	fmt.Printf("Acertos: %d de %d (%.0f%%)\n", score, questions, float64(score)/float64(questions)*100)
+
+	if len(missed) == 0 {
+		fmt.Println("Nenhum erro!")
+		return
+	}
+
+	var situations []string
+	for situation := range missed {
+		situations = append(situations, situation)
+	}
+	sort.Slice(situations, func(i, j int) bool {
+		if missed[situations[i]] != missed[situations[j]] {
+			return missed[situations[i]] > missed[situations[j]]
+		}
+		return situations[i] < situations[j]
+	})
+
+	fmt.Println("Situações com mais erros:")
+	for _, situation := range situations {
+		fmt.Printf("  %-34s %d erro(s)\n", situation, missed[situation])
+	}
+}
+
+// Estimativa do tempo de execução: os algoritmos selecionados rodam sobre
+// um prefixo do trace (1%, no máximo estimateMaxSample acessos) e o tempo
+// medido é extrapolado para o trace inteiro
+const (
+	estimateFraction  = 100
+	estimateMinSample = 1000
+	estimateMaxSample = 200000
+)
+
+// Tempo medido numa execução sobre o prefixo
+type sampleTiming struct {
+	Accesses int
+	Faults   int
+	Elapsed  time.Duration
+}
+
+type timeEstimate struct {
+	Expected time.Duration
+	Low      time.Duration
+	High     time.Duration
+}
+
+func (e timeEstimate) String() string {
+	if e.High == 0 {
+		return "amostra insuficiente"
+	}
+	return fmt.Sprintf("%s (entre %s e %s)", e.Expected.Round(time.Millisecond),
+		e.Low.Round(time.Millisecond), e.High.Round(time.Millisecond))
+}
+
+// Trabalho de uma execução em unidades de acesso. Com scan (Ótimo), cada
+// substituição percorre até frames frames em busca do uso mais distante;
+// as primeiras faltas só ocupam frames livres.
+func estimateUnits(accesses, faults, frames int, scan bool) float64 {
+	units := float64(accesses)
+	if scan && faults > frames {
+		units += float64(faults-frames) * float64(frames)
+	}
+	return units
+}
+
+// Extrapola para total acessos os tempos medidos na metade e no prefixo
+// inteiro. As faltas crescem na proporção do prefixo; o custo por unidade
+// da primeira e da segunda metade dão a faixa de incerteza, alargada em 25%
+// para cobrir a variação da máquina. Sem acessos no prefixo não há o que
+// extrapolar e a estimativa fica zerada (amostra insuficiente).
+func extrapolateTime(half, full sampleTiming, total, frames int, scan bool) timeEstimate {
+	if full.Accesses == 0 {
+		return timeEstimate{}
+	}
+	fullUnits := estimateUnits(full.Accesses, full.Faults, frames, scan)
+	halfUnits := estimateUnits(half.Accesses, half.Faults, frames, scan)
+	faults := full.Faults * total / full.Accesses
+	units := estimateUnits(total, faults, frames, scan)
+
+	perUnit := float64(full.Elapsed) / fullUnits
+	first, second := perUnit, perUnit
+	if halfUnits > 0 && fullUnits > halfUnits {
+		first = float64(half.Elapsed) / halfUnits
+		second = float64(full.Elapsed-half.Elapsed) / (fullUnits - halfUnits)
+	}
+	low, high := min(first, second, perUnit), max(first, second, perUnit)
+	return timeEstimate{
+		Expected: time.Duration(perUnit * units),
+		Low:      time.Duration(low * units * 0.8),
+		High:     time.Duration(high * units * 1.25),
+	}
+}
+
+// Simulador sobre os acessos [lo, hi) do trace, com a mesma configuração
+func (s *Simulator) segment(lo, hi int) *Simulator {
+	sample := *s
+	sample.accesses = s.accesses[lo:hi]
+	sample.nextUse = &nextUseCache{}
+	if s.runs != nil {
+		sample.runs = buildRuns(sample.accesses)
+	}
+	return &sample
+}
+
+// Mede os algoritmos selecionados sobre a metade e o prefixo inteiro e
+// soma as extrapolações
+func (s *Simulator) estimateExecutionTime() timeEstimate {
+	total := len(s.accesses)
+	n := min(max(total/estimateFraction, min(total, estimateMinSample)), estimateMaxSample)
+	var estimate timeEstimate
+	if n < 2 {
+		return estimate
+	}
+	half, full := s.segment(0, n/2), s.segment(0, n)
+
+	// O aquecimento (-warmup) também custa tempo: contam os acessos e as
+	// faltas brutos do prefixo
+	timing := func(r Result) sampleTiming {
+		return sampleTiming{r.Accesses + r.WarmupAccesses, r.Faults + r.WarmupFaults, r.Elapsed}
+	}
+	add := func(run func(*Simulator) Result, scan bool) {
+		h, f := run(half), run(full)
+		e := extrapolateTime(timing(h), timing(f), total, s.totalFrames, scan)
+		estimate.Expected += e.Expected
+		estimate.Low += e.Low
+		estimate.High += e.High
+	}
+	if s.algorithmSelected("optimal") && !s.skipOptimal {
+		add(func(s *Simulator) Result { return s.runOptimal() }, true)
+	}
+	for _, info := range streamingPolicies {
+		if s.algorithmSelected(info.Name) {
+			add(func(sample *Simulator) Result { return sample.runPolicy(info.New(sample)) }, false)
+		}
+	}
+	return estimate
+}
+
+// Grava os traces de exemplo no diretório informado
+func writeExamples(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("erro ao criar diretório %s: %v", dir, err)
+	}
+
+	entries, err := exampleTraces.ReadDir("examples")
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		data, err := exampleTraces.ReadFile("examples/" + entry.Name())
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, entry.Name())
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("erro ao gravar %s: %v", path, err)
+		}
+		fmt.Printf("Exemplo gravado: %s\n", path)
+	}
+	return nil
+}
+
+// Autoteste (-selftest): invariantes entre os algoritmos em traces
+// aleatórios pequenos, conferidos também por TestPolicyProperties
+const (
+	selfTestTraces    = 50
+	selfTestLength    = 200
+	selfTestMaxFrames = 8
+)
+
+// Trace aleatório de até maxPages páginas, com sequências da mesma página
+func randomTrace(rng *rand.Rand, length, maxPages int) []PageAccess {
+	pages := 2 + rng.Intn(maxPages-1)
+	accesses := make([]PageAccess, length)
+	for i := range accesses {
+		pageID := fmt.Sprintf("D%d", rng.Intn(pages))
+		if i > 0 && rng.Intn(4) == 0 {
+			pageID = accesses[i-1].PageID
+		}
+		accesses[i] = PageAccess{PageID: pageID, Type: "D", Write: rng.Intn(4) == 0}
+	}
+	return accesses
+}
+
+// Faltas do FWF (flush when full): com a memória cheia, a falta esvazia
+// todos os frames. Cada fase de frames páginas distintas custa frames
+// faltas, o limite do argumento de competitividade do LRU.
+func fwfFaults(accesses []PageAccess, frames int) int {
+	resident := make(map[string]bool)
+	faults := 0
+	for _, access := range accesses {
+		if resident[access.PageID] {
+			continue
+		}
+		faults++
+		if len(resident) == frames {
+			clear(resident)
+		}
+		resident[access.PageID] = true
+	}
+	return faults
+}
+
+// Executa a política passo a passo; devolve as faltas, as vítimas e o
+// maior número de frames ocupados em algum passo
+func invariantRun(policy ReplacementPolicy, accesses []PageAccess) (faults int, victims []string, peak int) {
+	for _, access := range accesses {
+		result := step(policy, access)
+		if !result.Hit {
+			faults++
+			if result.Victim != "" {
+				victims = append(victims, result.Victim)
+			}
+		}
+		resident := 0
+		for _, frame := range policy.Frames() {
+			if frame != nil {
+				resident++
+			}
+		}
+		peak = max(peak, resident)
+	}
+	return faults, victims, peak
+}
+
+// Confere num trace, de 1 a maxFrames frames: Ótimo ≤ LRU ≤ FWF ≤
+// k·Ótimo + k, nenhum algoritmo abaixo do Ótimo nem das páginas
+// distintas, frames ocupados nunca acima do total, Relógio igual ao FIFO
+// com segunda chance e as faltas do Ótimo e do LRU (algoritmos de pilha)
+// sem crescer com mais frames. Devolve as violações encontradas.
+func checkInvariants(accesses []PageAccess, maxFrames int) []string {
+	var violations []string
+	fail := func(format string, args ...any) {
+		violations = append(violations, fmt.Sprintf(format, args...))
+	}
+	distinct := make(map[string]bool)
+	for _, access := range accesses {
+		distinct[access.PageID] = true
+	}
+	previous := map[string]int{}
+	for frames := 1; frames <= maxFrames; frames++ {
+		s := NewSimulator(frames * PAGE_SIZE)
+		s.accesses = accesses
+		optimal, _, peak := invariantRun(newOptimalPolicy(frames, s.BuildNextUseIndex()), accesses)
+		if peak > frames {
+			fail("%d frames: Ótimo ocupou %d frames", frames, peak)
+		}
+		faults := map[string]int{"optimal": optimal}
+		victims := map[string][]string{}
+		for _, info := range streamingPolicies {
+			n, v, peak := invariantRun(info.New(s), accesses)
+			faults[info.Name], victims[info.Name] = n, v
+			if peak > frames {
+				fail("%d frames: %s ocupou %d frames", frames, info.Name, peak)
+			}
+			if n < optimal {
+				fail("%d frames: %s fez %d faltas, menos que o Ótimo (%d)", frames, info.Name, n, optimal)
+			}
+			if n < len(distinct) {
+				fail("%d frames: %s fez %d faltas para %d páginas distintas", frames, info.Name, n, len(distinct))
+			}
+		}
+		lru, fwf := faults["lru"], fwfFaults(accesses, frames)
+		if lru > fwf || fwf > frames*optimal+frames {
+			fail("%d frames: Ótimo %d, LRU %d e FWF %d fora de Ótimo ≤ LRU ≤ FWF ≤ k·Ótimo + k", frames, optimal, lru, fwf)
+		}
+		if strings.Join(victims["clock"], ",") != strings.Join(victims["secondchance"], ",") {
+			fail("%d frames: Relógio e FIFO 2ª chance substituíram páginas diferentes", frames)
+		}
+		for _, name := range []string{"optimal", "lru"} {
+			if last, ok := previous[name]; ok && faults[name] > last {
+				fail("%s fez %d faltas com %d frames e %d com %d", name, last, frames-1, faults[name], frames)
+			}
+			previous[name] = faults[name]
+		}
+	}
+	return violations
+}
+
+// Roda checkInvariants em selfTestTraces traces aleatórios e mostra o
+// resultado; false se alguma invariante falhou
+func runSelfTest(seed int64) bool {
+	rng := rand.New(rand.NewSource(seed))
+	failed := 0
+	for trial := 0; trial < selfTestTraces; trial++ {
+		for _, violation := range checkInvariants(randomTrace(rng, selfTestLength, 16), selfTestMaxFrames) {
+			fmt.Printf("FALHOU trace %d: %s\n", trial, violation)
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("Autoteste: %d violações em %d traces\n", failed, selfTestTraces)
+		return false
+	}
+	fmt.Printf("Autoteste: OK (%d traces de %d acessos, 1 a %d frames, %d algoritmos)\n",
+		selfTestTraces, selfTestLength, selfTestMaxFrames, len(streamingPolicies)+1)
+	return true
+}
+
+// Versão do formato dos arquivos .golden; mude ao alterar o formato
+const fixturesVersion = 1
+
+var fixtureFrames = []int{3, 4, 5}
+
+// Uma linha do arquivo .golden: o resultado de um algoritmo com um número
+// de frames em um exemplo
+type fixtureLine struct {
+	Frames    int
+	Algorithm string
+	Faults    int
+	Victims   []string // páginas substituídas, na ordem
+}
+
+func (f fixtureLine) key() string {
+	return fmt.Sprintf("%d %s", f.Frames, f.Algorithm)
+}
+
+func (f fixtureLine) String() string {
+	victims := strings.Join(f.Victims, ",")
+	if victims == "" {
+		victims = "-"
+	}
+	return fmt.Sprintf("%s %d %s", f.key(), f.Faults, victims)
+}
+
+// Executa o exemplo embutido em todos os algoritmos registrados (e no
+// Ótimo) com cada número de frames de fixtureFrames
+func exampleFixtures(name string) ([]fixtureLine, error) {
+	data, err := exampleTraces.ReadFile("examples/" + name)
+	if err != nil {
+		return nil, err
+	}
+	s := NewSimulator(PAGE_SIZE)
+	if _, err := s.LoadAccesses(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	var lines []fixtureLine
+	for _, frames := range fixtureFrames {
+		s.totalFrames = frames
+		s.memorySize = frames * PAGE_SIZE
+		policies := []ReplacementPolicy{newOptimalPolicy(frames, s.BuildNextUseIndex())}
+		names := []string{"optimal"}
+		for _, info := range streamingPolicies {
+			policies = append(policies, info.New(s))
+			names = append(names, info.Name)
+		}
+		for i, policy := range policies {
+			line := fixtureLine{Frames: frames, Algorithm: names[i]}
+			for _, access := range s.accesses {
+				result := step(policy, access)
+				if result.Hit {
+					continue
+				}
+				line.Faults++
+				if result.Victim != "" {
+					line.Victims = append(line.Victims, result.Victim)
+				}
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// Lição de referência: o exemplo do livro-texto com 3 frames, narrado pelo
+// Ótimo e pelo Relógio
+const lessonGolden = "textbook.lesson.md"
+
+func textbookLesson() (string, error) {
+	data, err := exampleTraces.ReadFile("examples/textbook.txt")
+	if err != nil {
+		return "", err
+	}
+	s := NewSimulator(3 * PAGE_SIZE)
+	if _, err := s.LoadAccesses(bytes.NewReader(data)); err != nil {
+		return "", fmt.Errorf("textbook.txt: %v", err)
+	}
+	s.traceName = "textbook.txt"
+	s.algorithms = []string{"optimal", "clock"}
+	var b strings.Builder
+	if err := s.writeLesson(&b, s.Simulate()); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func goldenName(example string) string {
+	return strings.TrimSuffix(example, ".txt") + ".golden"
+}
+
+// Gera os arquivos .golden dos exemplos embutidos em dir
+func writeFixtures(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("erro ao criar diretório %s: %v", dir, err)
+	}
+
+	entries, err := exampleTraces.ReadDir("examples")
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		lines, err := exampleFixtures(entry.Name())
+		if err != nil {
+			return err
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "# versão %d\n", fixturesVersion)
+		fmt.Fprintln(&b, "# frames algoritmo faltas vítimas")
+		for _, line := range lines {
+			fmt.Fprintln(&b, line)
+		}
+		path := filepath.Join(dir, goldenName(entry.Name()))
+		if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+			return fmt.Errorf("erro ao gravar %s: %v", path, err)
+		}
+		fmt.Printf("Resultados gravados: %s\n", path)
+	}
+
+	lesson, err := textbookLesson()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, lessonGolden)
+	if err := os.WriteFile(path, []byte(lesson), 0644); err != nil {
+		return fmt.Errorf("erro ao gravar %s: %v", path, err)
+	}
+	fmt.Printf("Lição gravada: %s\n", path)
+	return nil
+}
+
+// Compara uma execução atual com os arquivos .golden de dir e mostra os
+// números que mudaram; devolve o número de diferenças
+func checkFixtures(dir string) (int, error) {
+	entries, err := exampleTraces.ReadDir("examples")
+	if err != nil {
+		return 0, err
+	}
+
+	diffs := 0
+	for _, entry := range entries {
+		path := filepath.Join(dir, goldenName(entry.Name()))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return diffs, fmt.Errorf("erro ao ler %s: %v", path, err)
+		}
+		expected := make(map[string]string)
+		for _, text := range strings.Split(string(data), "\n") {
+			if version, ok := strings.CutPrefix(text, "# versão "); ok && version != strconv.Itoa(fixturesVersion) {
+				return diffs, fmt.Errorf("%s tem a versão %s; gere os arquivos novamente", path, version)
+			}
+			if fields := strings.Fields(text); len(fields) == 4 && !strings.HasPrefix(text, "#") {
+				expected[fields[0]+" "+fields[1]] = text
+			}
+		}
+
+		lines, err := exampleFixtures(entry.Name())
+		if err != nil {
+			return diffs, err
+		}
+		for _, line := range lines {
+			want, ok := expected[line.key()]
+			delete(expected, line.key())
+			switch {
+			case !ok:
+				fmt.Printf("%s: novo resultado: %s\n", path, line)
+			case want != line.String():
+				fmt.Printf("%s: %d frames, %s\n", path, line.Frames, line.Algorithm)
+				fmt.Printf("  esperado: %s\n", want)
+				fmt.Printf("  obtido:   %s\n", line)
+			default:
+				continue
+			}
+			diffs++
+		}
+		for _, want := range expected {
+			fmt.Printf("%s: resultado sem algoritmo correspondente: %s\n", path, want)
+			diffs++
+		}
+	}
+
+	// A lição é comparada linha a linha; só a primeira diferença é mostrada
+	path := filepath.Join(dir, lessonGolden)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return diffs, fmt.Errorf("erro ao ler %s: %v", path, err)
+	}
+	lesson, err := textbookLesson()
+	if err != nil {
+		return diffs, err
+	}
+	want, got := strings.Split(string(data), "\n"), strings.Split(lesson, "\n")
+	for i := 0; i < max(len(want), len(got)); i++ {
+		var w, g string
+		if i < len(want) {
+			w = want[i]
+		}
+		if i < len(got) {
+			g = got[i]
+		}
+		if w != g {
+			fmt.Printf("%s: linha %d\n", path, i+1)
+			fmt.Printf("  esperado: %s\n", w)
+			fmt.Printf("  obtido:   %s\n", g)
+			diffs++
+			break
+		}
+	}
+	return diffs, nil
+}
+
+// Executa os algoritmos selecionados sem imprimir nada, na mesma ordem do
+// relatório (Ótimo primeiro, depois a ordem do registro)
+func (s *Simulator) Simulate() []Result {
+	var results []Result
+	s.pagePatterns = nil
+	if s.patterns {
+		s.classifyTrace()
+	}
+	if s.algorithmSelected("optimal") {
+		results = append(results, s.runOptimal())
+		if results[0].Err != nil {
+			return results
+		}
+	}
+	for _, info := range streamingPolicies {
+		if s.algorithmSelected(info.Name) {
+			result := s.runPolicy(info.New(s), s.observers...)
+			result.Algorithm = info.Label
+			if result.Err == nil && s.trials > 1 && seededPolicies[info.Name] {
+				result.Trials, result.Err = s.runTrials(info, result)
+			}
+			results = append(results, result)
+			if result.Err != nil {
+				return results
+			}
+		}
+	}
+	if s.bootstrapBlocks > 0 {
+		s.bootstrap(results)
+	}
+	return results
+}
+
+// Intervalos de confiança por bootstrap (-bootstrap B): o trace é dividido
+// em B blocos contíguos, cada algoritmo simula cada bloco separadamente e
+// os blocos são reamostrados com reposição. No início frio cada bloco
+// começa com a memória vazia; no quente (-bootstrap-start warm) o bloco
+// anterior é simulado antes como aquecimento, sem contar as faltas, e o
+// primeiro bloco fica frio. A reamostragem usa a semente de -seed.
+const bootstrapResamples = 1000
+
+// Intervalo de 95% da taxa de faltas
+type rateInterval struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// Diferença de taxa de faltas entre dois algoritmos nas mesmas reamostras
+type pairTest struct {
+	A        string       `json:"a"`
+	B        string       `json:"b"`
+	Diff     float64      `json:"diff"` // taxa de A - taxa de B no trace inteiro
+	Interval rateInterval `json:"interval"`
+	P        float64      `json:"p"` // bilateral: a diferença tem sinal oposto ou é nula
+}
+
+// Nova instância do algoritmo com o nome mostrado nos resultados
+func (s *Simulator) newPolicyFor(label string) ReplacementPolicy {
+	if label == "Ótimo" {
+		return newOptimalPolicy(s.totalFrames, s.BuildNextUseIndex())
+	}
+	for _, info := range streamingPolicies {
+		if info.Label == label {
+			return info.New(s)
+		}
+	}
+	return nil
+}
+
+// Faltas da política nos acessos a partir de skip (os anteriores só aquecem)
+func blockFaults(policy ReplacementPolicy, accesses []PageAccess, skip int) int {
+	faults := 0
+	for i, access := range accesses {
+		if r := step(policy, access); !r.Hit && i >= skip {
+			faults++
+		}
+	}
+	return faults
+}
+
+// Calcula o intervalo de cada resultado e os testes entre pares de
+// algoritmos, guardados para o relatório
+func (s *Simulator) bootstrap(results []Result) {
+	blocks := min(s.bootstrapBlocks, len(s.accesses))
+	if blocks < 2 {
+		return
+	}
+	bounds := make([]int, blocks+1)
+	for b := range bounds {
+		bounds[b] = b * len(s.accesses) / blocks
+	}
+
+	// faults[k][b]: faltas do algoritmo k no bloco b
+	faults := make([][]int, len(results))
+	for k, r := range results {
+		faults[k] = make([]int, blocks)
+		for b := 0; b < blocks; b++ {
+			warm := bounds[b]
+			if s.bootstrapWarm && b > 0 {
+				warm = bounds[b-1]
+			}
+			sub := s.segment(warm, bounds[b+1])
+			faults[k][b] = blockFaults(sub.newPolicyFor(r.Algorithm), sub.accesses, bounds[b]-warm)
+		}
+	}
+
+	// rates[k][i]: taxa do algoritmo k na reamostra i
+	rng := rand.New(rand.NewSource(s.seed))
+	rates := make([][]float64, len(results))
+	for k := range rates {
+		rates[k] = make([]float64, bootstrapResamples)
+	}
+	for i := 0; i < bootstrapResamples; i++ {
+		picks := make([]int, blocks)
+		accesses := 0
+		for j := range picks {
+			picks[j] = rng.Intn(blocks)
+			accesses += bounds[picks[j]+1] - bounds[picks[j]]
+		}
+		for k := range results {
+			total := 0
+			for _, b := range picks {
+				total += faults[k][b]
+			}
+			rates[k][i] = float64(total) / float64(accesses)
+		}
+	}
+
+	rate := func(k int) float64 {
+		total := 0
+		for _, n := range faults[k] {
+			total += n
+		}
+		return float64(total) / float64(len(s.accesses))
+	}
+	for k := range results {
+		interval := percentileInterval(rates[k])
+		results[k].Bootstrap = &interval
+	}
+	s.bootstrapTests = nil
+	for a := range results {
+		for b := a + 1; b < len(results); b++ {
+			diffs := make([]float64, bootstrapResamples)
+			below, above := 0, 0
+			for i := range diffs {
+				diffs[i] = rates[a][i] - rates[b][i]
+				if diffs[i] <= 0 {
+					below++
+				}
+				if diffs[i] >= 0 {
+					above++
+				}
+			}
+			p := min(1, 2*float64(min(below, above))/bootstrapResamples)
+			s.bootstrapTests = append(s.bootstrapTests, pairTest{A: results[a].Algorithm, B: results[b].Algorithm,
+				Diff: rate(a) - rate(b), Interval: percentileInterval(diffs), P: p})
+		}
+	}
+}
+
+// Percentis 2,5 e 97,5 das reamostras
+func percentileInterval(samples []float64) rateInterval {
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	last := len(sorted) - 1
+	return rateInterval{Low: sorted[last*25/1000], High: sorted[last*975/1000]}
+}
+
+func (s *Simulator) printBootstrap(results []Result) {
+	if s.bootstrapBlocks == 0 {
+		return
+	}
+	start := "frio"
+	if s.bootstrapWarm {
+		start = "quente"
+	}
+	fmt.Printf("\n=== BOOTSTRAP (%d blocos, início %s, %d reamostras) ===\n",
+		min(s.bootstrapBlocks, len(s.accesses)), start, bootstrapResamples)
+	for _, r := range results {
+		if r.Bootstrap == nil {
+			fmt.Println("Trace curto demais para dividir em blocos")
+			return
+		}
+		fmt.Printf("%-16s taxa de faltas entre %.2f%% e %.2f%% (IC 95%%)\n",
+			r.Algorithm, r.Bootstrap.Low*100, r.Bootstrap.High*100)
+	}
+	for _, t := range s.bootstrapTests {
+		significance := "diferença não significativa"
+		if t.P < 0.05 {
+			significance = fmt.Sprintf("%s faz menos faltas", t.B)
+			if t.Diff < 0 {
+				significance = fmt.Sprintf("%s faz menos faltas", t.A)
+			}
+		}
+		p := fmt.Sprintf("p = %.3f", t.P)
+		if t.P == 0 {
+			p = fmt.Sprintf("p < %.3f", 1.0/bootstrapResamples)
+		}
+		fmt.Printf("%s - %s: %+.2f%% [%+.2f%%, %+.2f%%], %s: %s\n",
+			t.A, t.B, t.Diff*100, t.Interval.Low*100, t.Interval.High*100, p, significance)
+	}
+}
+
+// Repetições dos algoritmos aleatórios (-trials N): cada repetição usa uma
+// semente derivada de -seed (a primeira é a própria -seed) e as N
+// execuções são distribuídas entre os processadores. Os algoritmos
+// determinísticos executam uma vez só.
+var seededPolicies = map[string]bool{"random": true, "randunref": true, "nru": true, "hyperbolic": true}
+
+type TrialRun struct {
+	Seed   int64 `json:"seed"`
+	Faults int   `json:"faults"`
+}
+
+type TrialStats struct {
+	Runs   []TrialRun `json:"runs"`
+	Mean   float64    `json:"mean"`
+	StdDev float64    `json:"stddev"` // desvio padrão amostral
+	Min    int        `json:"min"`
+	Max    int        `json:"max"`
+}
+
+// Semente da repetição k; sementes base próximas não repetem as mesmas
+// sementes em repetições diferentes
+func trialSeed(base int64, k int) int64 {
+	return int64(uint64(base) + uint64(k)*0x9e3779b97f4a7c15)
+}
+
+// Executa as repetições do algoritmo; first é a execução com a semente
+// base, reaproveitada quando cobre o trace inteiro. As repetições não
+// usam observadores nem -converge.
+func (s *Simulator) runTrials(info policyInfo, first Result) (*TrialStats, error) {
+	stats := &TrialStats{Runs: make([]TrialRun, s.trials)}
+	errs := make([]error, s.trials)
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, runtime.NumCPU())
+	for k := range stats.Runs {
+		seed := trialSeed(s.seed, k)
+		if k == 0 && !first.Partial {
+			stats.Runs[k] = TrialRun{seed, first.Faults}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			trial := *s
+			trial.seed = seed
+			trial.observers, trial.convergeEpsilon, trial.seriesInterval, trial.costReport = nil, 0, 0, false
+			r := trial.runPolicy(info.New(&trial))
+			stats.Runs[k], errs[k] = TrialRun{seed, r.Faults}, r.Err
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	stats.Min, stats.Max = stats.Runs[0].Faults, stats.Runs[0].Faults
+	for _, run := range stats.Runs {
+		stats.Mean += float64(run.Faults)
+		stats.Min, stats.Max = min(stats.Min, run.Faults), max(stats.Max, run.Faults)
+	}
+	stats.Mean /= float64(len(stats.Runs))
+	if len(stats.Runs) > 1 {
+		for _, run := range stats.Runs {
+			stats.StdDev += (float64(run.Faults) - stats.Mean) * (float64(run.Faults) - stats.Mean)
+		}
+		stats.StdDev = math.Sqrt(stats.StdDev / float64(len(stats.Runs)-1))
+	}
+	return stats, nil
+}
+
+func (s *Simulator) showTrials(r Result) {
+	if r.Trials == nil {
+		return
+	}
+	t := r.Trials
+	fmt.Printf("Repetições: %d, faltas %.2f ± %.2f (mín. %d, máx. %d)\n", len(t.Runs), t.Mean, t.StdDev, t.Min, t.Max)
+}
+
+// Grava em JSON as repetições de cada algoritmo aleatório
+func writeTrials(filename string, results []Result) error {
+	type algorithmTrials struct {
+		Algorithm string `json:"algorithm"`
+		*TrialStats
+	}
+	var trials []algorithmTrials
+	for _, r := range results {
+		if r.Trials != nil {
+			trials = append(trials, algorithmTrials{r.Algorithm, r.Trials})
+		}
+	}
+	return writeVersionedList(filename, trials)
+}
+
+// Esquema dos resultados em JSON (-json, -cost-json, -final-state,
+// -trials-json, -target-json e a resposta do serve). Cada saída traz
+// schema_version; um arquivo sem o campo é da versão 1. Ao mudar o formato
+// de uma saída, acrescente a versão em schemaChanges com a função que
+// converte um arquivo da versão anterior; sim results migrate aplica as
+// conversões em sequência até resultsSchemaVersion.
+const resultsSchemaVersion = 2
+
+// Uma versão do esquema e a conversão a partir da anterior. doc é um JSON
+// lido com UseNumber: map[string]any, []any ou um valor simples.
+type schemaChange struct {
+	Version int
+	Summary string
+	Migrate func(doc any) (any, error)
+}
+
+var schemaChanges = []schemaChange{
+	{2, "schema_version em todas as saídas; as listas de -cost-json, -final-state, " +
+		"-trials-json e -target-json passam para o campo algorithms", migrateSchemaV2},
+}
+
+// Resultados de uma execução (-json)
+func writeResultsJSON(filename string, resp *simulateResponse) error {
+	data, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("erro ao gravar %s: %v", filename, err)
+	}
+	return nil
+}
+
+// Saídas em lista (-cost-json, -final-state, -trials-json, -target-json)
+type versionedList struct {
+	SchemaVersion int `json:"schema_version"`
+	Algorithms    any `json:"algorithms"`
+}
+
+func writeVersionedList(filename string, list any) error {
+	data, err := json.MarshalIndent(versionedList{resultsSchemaVersion, list}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("erro ao gravar %s: %v", filename, err)
+	}
+	return nil
+}
+
+func migrateSchemaV2(doc any) (any, error) {
+	switch v := doc.(type) {
+	case []any:
+		return versionedList{2, v}, nil
+	case map[string]any:
+		v["schema_version"] = 2
+		return v, nil
+	}
+	return nil, errors.New("o arquivo não é uma lista nem um objeto de resultados")
+}
+
+// Versão do esquema de um documento JSON
+func schemaVersionOf(doc any) (int, error) {
+	object, ok := doc.(map[string]any)
+	if !ok {
+		return 1, nil
+	}
+	value, ok := object["schema_version"]
+	if !ok {
+		return 1, nil
+	}
+	number, _ := value.(json.Number)
+	version, err := strconv.Atoi(number.String())
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("schema_version inválido: %v", value)
+	}
+	return version, nil
+}
+
+func decodeResultsJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Converte um arquivo de resultados para resultsSchemaVersion; devolve o
+// JSON convertido e a versão de origem
+func migrateResults(data []byte) ([]byte, int, error) {
+	doc, err := decodeResultsJSON(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	from, err := schemaVersionOf(doc)
+	if err != nil {
+		return nil, 0, err
+	}
+	if from > resultsSchemaVersion {
+		return nil, from, fmt.Errorf("esquema %d é mais novo que o desta versão do simulador (%d)", from, resultsSchemaVersion)
+	}
+	if from == resultsSchemaVersion {
+		return data, from, nil
+	}
+	for _, change := range schemaChanges {
+		if change.Version <= from {
+			continue
+		}
+		if doc, err = change.Migrate(doc); err != nil {
+			return nil, from, fmt.Errorf("versão %d: %v", change.Version, err)
+		}
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	return append(out, '\n'), from, err
+}
+
+// Lê resultados gravados por -json (ou pelo serve) para comparação; outro
+// esquema é recusado, pois os campos podem ter mudado de sentido
+func parseResults(data []byte, name string) (*simulateResponse, error) {
+	doc, err := decodeResultsJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	version, err := schemaVersionOf(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if version != resultsSchemaVersion {
+		return nil, fmt.Errorf("%s usa o esquema %d e esta versão do simulador lê o %d; converta com: sim results migrate -o <saída> %s",
+			name, version, resultsSchemaVersion, name)
+	}
+	var resp simulateResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if resp.Results == nil {
+		return nil, fmt.Errorf("%s não tem resultados (gere com -json)", name)
+	}
+	return &resp, nil
+}
+
+// Diferenças de faltas entre os resultados de base e os atuais, por
+// algoritmo; trace diferente também conta
+func diffResults(base, current *simulateResponse) []string {
+	var diffs []string
+	if base.Accesses != current.Accesses || base.Frames != current.Frames {
+		diffs = append(diffs, fmt.Sprintf("execuções diferentes: %d acessos e %d frames na base, %d e %d agora",
+			base.Accesses, base.Frames, current.Accesses, current.Frames))
+	}
+	faults := make(map[string]int)
+	for _, r := range current.Results {
+		faults[r.Algorithm] = r.Faults
+	}
+	for _, r := range base.Results {
+		now, ok := faults[r.Algorithm]
+		delete(faults, r.Algorithm)
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("%s: só na base (%d faltas)", r.Algorithm, r.Faults))
+		case now != r.Faults:
+			diffs = append(diffs, fmt.Sprintf("%s: %d faltas na base, %d agora (%+d)", r.Algorithm, r.Faults, now, now-r.Faults))
+		}
+	}
+	for _, r := range current.Results {
+		if _, ok := faults[r.Algorithm]; ok {
+			diffs = append(diffs, fmt.Sprintf("%s: só nos resultados atuais (%d faltas)", r.Algorithm, r.Faults))
+		}
+	}
+	return diffs
+}
+
+// sim results migrate [-o ARQ] arquivo | sim results compare base atual
+func runResults(args []string) {
+	if len(args) > 0 && args[0] == "compare" {
+		if len(args) != 3 {
+			fmt.Println("Uso: go run main.go results compare <base.json> <atual.json>")
+			return
+		}
+		var runs [2]*simulateResponse
+		for i, file := range args[1:] {
+			data, err := os.ReadFile(file)
+			if err == nil {
+				runs[i], err = parseResults(data, file)
+			}
+			if err != nil {
+				logger.Error("erro ao ler os resultados", "err", err)
+				os.Exit(1)
+			}
+		}
+		diffs := diffResults(runs[0], runs[1])
+		for _, diff := range diffs {
+			fmt.Println(diff)
+		}
+		if len(diffs) > 0 {
+			fmt.Printf("%d diferenças em relação a %s\n", len(diffs), args[1])
+			os.Exit(1)
+		}
+		fmt.Printf("Resultados iguais aos de %s (%d algoritmos)\n", args[1], len(runs[0].Results))
+		return
+	}
+
+	output := ""
+	var files []string
+	for i := 1; i < len(args); i++ {
+		if args[i] == "-o" && i+1 < len(args) {
+			output = args[i+1]
+			i++
+			continue
+		}
+		files = append(files, args[i])
+	}
+	if len(args) == 0 || args[0] != "migrate" || len(files) != 1 {
+		fmt.Println("Uso: go run main.go results migrate [-o saída.json] <resultados.json>")
+		fmt.Println("     go run main.go results compare <base.json> <atual.json>")
+		return
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		logger.Error("erro ao ler os resultados", "file", files[0], "err", err)
+		os.Exit(1)
+	}
+	migrated, from, err := migrateResults(data)
+	if err != nil {
+		logger.Error("erro ao converter os resultados", "file", files[0], "err", err)
+		os.Exit(1)
+	}
+	// Sem -o o JSON convertido vai para a saída padrão
+	if output == "" {
+		os.Stdout.Write(migrated)
+		return
+	}
+	if err := os.WriteFile(output, migrated, 0644); err != nil {
+		logger.Error("erro ao gravar os resultados", "file", output, "err", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Resultados convertidos do esquema %d para o %d: %s\n", from, resultsSchemaVersion, output)
+}
+
+// Modo servidor (sim serve): API JSON para o front-end da disciplina
+const (
+	maxRequestBody = 2 << 20 // trace enviado no corpo da requisição
+	maxJobsKept    = 1000
+	maxTrials      = 1000
+	maxFrames      = 1 << 20 // 4 GiB de memória simulada
+)
+
+type simulateRequest struct {
+	Trace      string        `json:"trace"` // acessos, um por linha
+	Path       string        `json:"path"`  // arquivo dentro de -trace-dir
+	Memory     int           `json:"memory"`
+	Algorithms []string      `json:"algorithms"`
+	Seed       int64         `json:"seed"`
+	Interval   int           `json:"interval"` // janela da série de faltas
+	Async      bool          `json:"async"`
+	Bootstrap  int           `json:"bootstrap"`          // blocos; 0 desliga
+	WarmBlocks bool          `json:"warm_blocks"`        // aquece cada bloco com o anterior
+	Warmup     int           `json:"warmup"`             // acessos de aquecimento
+	WarmupAuto bool          `json:"warmup_auto"`        // aquecimento até a memória encher
+	Trials     int           `json:"trials"`             // execuções dos algoritmos aleatórios
+	Weights    *FaultWeights `json:"weights"`            // pesos das faltas por tipo; null desliga
+	Phase      int           `json:"truncate_at_phase"`  // corta o trace no fim da fase; 0 desliga
+	Patterns   bool          `json:"patterns"`           // faltas por padrão de acesso das páginas
+	Thresholds string        `json:"pattern_thresholds"` // como -pattern-thresholds; vazio usa o padrão
+}
+
+type resultJSON struct {
+	Algorithm   string         `json:"algorithm"`
+	Faults      int            `json:"faults"`
+	Hits        int            `json:"hits"`
+	HitRate     float64        `json:"hit_rate"`
+	WriteBacks  int            `json:"write_backs"`
+	FaultSeries []int          `json:"fault_series,omitempty"`
+	Final       FinalState     `json:"final"`
+	ElapsedNS   int64          `json:"elapsed_ns"`
+	IndexNS     int64          `json:"index_ns,omitempty"` // só no Ótimo
+	AccessesSec float64        `json:"accesses_per_sec"`
+	FaultNS     int64          `json:"ns_per_fault"`
+	Estimate    bool           `json:"estimate,omitempty"` // interrompido por -converge
+	Simulated   int            `json:"simulated,omitempty"`
+	RateMargin  float64        `json:"fault_rate_margin,omitempty"`
+	FaultRateCI *rateInterval  `json:"fault_rate_ci,omitempty"` // -bootstrap
+	Trials      *TrialStats    `json:"trials,omitempty"`
+	Saves       *SaveStats     `json:"second_chance,omitempty"`
+	InstrFaults int            `json:"instruction_faults"`
+	Weighted    *float64       `json:"weighted_faults,omitempty"` // com weights
+	Patterns    map[string]int `json:"pattern_faults,omitempty"`  // com patterns
+	// Números sem descontar o aquecimento (iguais a faults e hits sem ele)
+	RawFaults      int `json:"raw_faults"`
+	RawHits        int `json:"raw_hits"`
+	WarmupAccesses int `json:"warmup_accesses,omitempty"`
+	WarmupFaults   int `json:"warmup_faults,omitempty"`
+}
+
+type simulateResponse struct {
+	SchemaVersion int                `json:"schema_version"` // resultsSchemaVersion
+	Accesses      int                `json:"accesses"`
+	Distinct      int                `json:"distinct"`
+	Frames        int                `json:"frames"`
+	Results       []resultJSON       `json:"results"`
+	Tests         []pairTest         `json:"bootstrap,omitempty"`
+	Diagnostics   *Diagnostics       `json:"diagnostics,omitempty"` // leitura do trace
+	Weights       *FaultWeights      `json:"weights,omitempty"`     // configuração usada
+	Patterns      map[string]int     `json:"patterns,omitempty"`    // páginas de cada padrão
+	Thresholds    *PatternThresholds `json:"pattern_thresholds,omitempty"`
+}
+
+type job struct {
+	ID     string            `json:"id"`
+	Status string            `json:"status"` // running, done, failed ou timeout
+	Error  string            `json:"error,omitempty"`
+	Result *simulateResponse `json:"result,omitempty"`
+	done   chan struct{}     // fechado quando o job termina
+}
+
+type server struct {
+	ui         bool
+	traceDir   string
+	jobTimeout time.Duration
+	slots      chan struct{} // limita as simulações simultâneas
+
+	mu     sync.Mutex
+	jobs   map[string]*job
+	order  []string
+	nextID int
+}
+
+func newServer(traceDir string, maxJobs int, jobTimeout time.Duration) *server {
+	return &server{
+		traceDir:   traceDir,
+		jobTimeout: jobTimeout,
+		slots:      make(chan struct{}, maxJobs),
+		jobs:       make(map[string]*job),
+	}
+}
+
+func (srv *server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("GET /algorithms", srv.handleAlgorithms)
+	mux.HandleFunc("POST /simulate", srv.handleSimulate)
+	mux.HandleFunc("GET /jobs/{id}", srv.handleJob)
+	mux.HandleFunc("GET /stream", srv.handleStream)
+	if srv.ui {
+		mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write(dashboardHTML)
+		})
+	}
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+func (srv *server) handleAlgorithms(w http.ResponseWriter, r *http.Request) {
+	type algorithm struct {
+		Name  string `json:"name"`
+		Label string `json:"label"`
+	}
+	algorithms := []algorithm{{"optimal", "Ótimo"}}
+	for _, info := range streamingPolicies {
+		algorithms = append(algorithms, algorithm{info.Name, info.Label})
+	}
+	writeJSON(w, http.StatusOK, algorithms)
+}
+
+// Valida a requisição e carrega o trace em um simulador novo
+func (srv *server) prepare(req simulateRequest) (*Simulator, error) {
+	if req.Memory < PAGE_SIZE {
+		return nil, fmt.Errorf("memory deve ser ao menos %d bytes", PAGE_SIZE)
+	}
+	if req.Memory/PAGE_SIZE > maxFrames {
+		return nil, fmt.Errorf("memory deve ser no máximo %d bytes", maxFrames*PAGE_SIZE)
+	}
+	s := NewSimulator(req.Memory)
+	if len(req.Algorithms) > 0 {
+		for _, name := range req.Algorithms {
+			if _, ok := findPolicy(name); !ok && name != "optimal" {
+				return nil, fmt.Errorf("algoritmo desconhecido: %s", name)
+			}
+		}
+		s.algorithms = req.Algorithms
+	}
+	if req.Seed != 0 {
+		s.seed = req.Seed
+	}
+	if req.Interval < 0 {
+		return nil, errors.New("interval não pode ser negativo")
+	}
+	s.seriesInterval = req.Interval
+	if req.Bootstrap < 0 || req.Bootstrap == 1 {
+		return nil, errors.New("bootstrap deve ter ao menos 2 blocos")
+	}
+	s.bootstrapBlocks, s.bootstrapWarm = req.Bootstrap, req.WarmBlocks
+	if req.Warmup < 0 {
+		return nil, errors.New("warmup não pode ser negativo")
+	}
+	s.warmup, s.warmupAuto = req.Warmup, req.WarmupAuto
+	if req.Trials < 0 || req.Trials > maxTrials {
+		return nil, fmt.Errorf("trials deve estar entre 0 e %d", maxTrials)
+	}
+	s.trials = req.Trials
+	if w := req.Weights; w != nil {
+		if w.I < 0 || w.D < 0 {
+			return nil, errors.New("weights não podem ser negativos")
+		}
+		s.weights, s.weighted = *w, true
+	}
+	s.patterns = req.Patterns
+	if req.Thresholds != "" {
+		th, err := parsePatternThresholds(req.Thresholds)
+		if err != nil {
+			return nil, err
+		}
+		s.patternThresholds, s.patterns = th, true
+	}
+
+	var trace io.Reader
+	switch {
+	case req.Trace != "" && req.Path != "":
+		return nil, errors.New("use trace ou path, não os dois")
+	case req.Trace != "":
+		trace = strings.NewReader(req.Trace)
+	case req.Path != "":
+		if srv.traceDir == "" {
+			return nil, errors.New("path exige que o servidor seja iniciado com -trace-dir")
+		}
+		if !filepath.IsLocal(req.Path) {
+			return nil, fmt.Errorf("path inválido: %s", req.Path)
+		}
+		file, err := os.Open(filepath.Join(srv.traceDir, req.Path))
+		if err != nil {
+			return nil, fmt.Errorf("erro ao abrir %s", req.Path)
+		}
+		defer file.Close()
+		trace = file
+	default:
+		return nil, errors.New("informe trace ou path")
+	}
+	if req.Phase < 0 {
+		return nil, errors.New("truncate_at_phase não pode ser negativo")
+	}
+	s.truncatePhase = req.Phase
+	d, err := s.LoadAccesses(trace)
+	if err == nil {
+		err = s.applyPhaseCut(&d)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.diagnostics = &d
+	return s, nil
+}
+
+func (s *Simulator) response(results []Result) *simulateResponse {
+	resp := &simulateResponse{SchemaVersion: resultsSchemaVersion, Accesses: len(s.accesses), Distinct: len(s.distinctPages), Frames: s.totalFrames,
+		Tests: s.bootstrapTests, Diagnostics: s.diagnostics}
+	if s.weighted {
+		resp.Weights = &s.weights
+	}
+	if s.pagePatterns != nil {
+		resp.Patterns, resp.Thresholds = patternCounts(s.patternPopulation()), &s.patternThresholds
+	}
+	for _, r := range results {
+		resp.Results = append(resp.Results, resultJSON{
+			Algorithm:      r.Algorithm,
+			Faults:         r.Faults,
+			Hits:           r.Hits(),
+			HitRate:        r.HitRate(),
+			WriteBacks:     r.WriteBacks,
+			FaultSeries:    r.FaultSeries,
+			Final:          r.Final,
+			ElapsedNS:      r.Elapsed.Nanoseconds(),
+			IndexNS:        r.IndexTime.Nanoseconds(),
+			AccessesSec:    r.AccessesPerSecond(),
+			FaultNS:        r.TimePerFault().Nanoseconds(),
+			FaultRateCI:    r.Bootstrap,
+			Trials:         r.Trials,
+			RawFaults:      r.Faults + r.WarmupFaults,
+			RawHits:        r.Hits() + r.WarmupAccesses - r.WarmupFaults,
+			WarmupAccesses: r.WarmupAccesses,
+			WarmupFaults:   r.WarmupFaults,
+		})
+		item := &resp.Results[len(resp.Results)-1]
+		if r.Partial {
+			item.Estimate, item.Simulated, item.RateMargin = true, r.Accesses, r.RateMargin
+		}
+		if r.Saves.Saves > 0 {
+			item.Saves = &r.Saves
+		}
+		item.InstrFaults = r.InstrFaults
+		if s.weighted {
+			weighted := r.WeightedFaults(s.weights)
+			item.Weighted = &weighted
+		}
+		if r.PatternFaults != nil {
+			item.Patterns = patternCounts(r.PatternFaults)
+		}
+	}
+	return resp
+}
+
+func (srv *server) handleSimulate(w http.ResponseWriter, r *http.Request) {
+	var req simulateRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "corpo inválido: %v", err)
+		return
+	}
+	s, err := srv.prepare(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	j := srv.start(s)
+	if req.Async {
+		writeJSON(w, http.StatusAccepted, srv.snapshot(j))
+		return
+	}
+	srv.wait(j)
+	snap := srv.snapshot(j)
+	switch snap.Status {
+	case "done":
+		writeJSON(w, http.StatusOK, snap.Result)
+	case "timeout":
+		writeError(w, http.StatusGatewayTimeout, "%s", snap.Error)
+	default:
+		writeError(w, http.StatusInternalServerError, "%s", snap.Error)
+	}
+}
+
+func (srv *server) handleJob(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	j, ok := srv.jobs[r.PathValue("id")]
+	srv.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "job desconhecido: %s", r.PathValue("id"))
+		return
+	}
+	writeJSON(w, http.StatusOK, srv.snapshot(j))
+}
+
+// Registra o job e o executa assim que houver uma vaga. O tempo limite
+// conta desde o registro e cancela a simulação pelo contexto, liberando a
+// vaga; um pânico na simulação marca o job como falho sem derrubar o
+// servidor.
+func (srv *server) start(s *Simulator) *job {
+	srv.mu.Lock()
+	srv.nextID++
+	j := &job{ID: strconv.Itoa(srv.nextID), Status: "running", done: make(chan struct{})}
+	srv.jobs[j.ID] = j
+	srv.order = append(srv.order, j.ID)
+	if len(srv.order) > maxJobsKept {
+		delete(srv.jobs, srv.order[0])
+		srv.order = srv.order[1:]
+	}
+	srv.mu.Unlock()
+
+	ctx, cancel := srv.jobContext()
+	s.ctx = ctx
+	go func() {
+		defer cancel()
+		defer func() {
+			if p := recover(); p != nil {
+				logger.Error("simulação falhou", "job", j.ID, "panic", p)
+				srv.finish(j, "failed", fmt.Sprintf("erro interno: %v", p), nil)
+			}
+		}()
+		if !srv.acquire(ctx) {
+			srv.finish(j, "timeout", srv.expired(), nil)
+			return
+		}
+		results := func() []Result {
+			defer srv.release()
+			return s.Simulate()
+		}()
+		if n := len(results); n > 0 && results[n-1].Err != nil {
+			if cancelled(results[n-1].Err) {
+				srv.finish(j, "timeout", srv.expired(), nil)
+			} else {
+				srv.finish(j, "failed", results[n-1].Err.Error(), nil)
+			}
+			return
+		}
+		srv.finish(j, "done", "", s.response(results))
+	}()
+	return j
+}
+
+// Contexto de uma simulação (job ou /stream), cancelado pelo -job-timeout
+func (srv *server) jobContext() (context.Context, context.CancelFunc) {
+	if srv.jobTimeout > 0 {
+		return context.WithTimeoutCause(context.Background(), srv.jobTimeout, errTimeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// Espera uma vaga de -max-jobs; false se o contexto terminar antes
+func (srv *server) acquire(ctx context.Context) bool {
+	select {
+	case srv.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (srv *server) release() {
+	<-srv.slots
+}
+
+func (srv *server) expired() string {
+	return fmt.Sprintf("simulação excedeu %s", srv.jobTimeout)
+}
+
+// Chamado uma única vez por job, pela goroutine que o executa
+func (srv *server) finish(j *job, status, errText string, resp *simulateResponse) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	j.Status, j.Error, j.Result = status, errText, resp
+	close(j.done)
+}
+
+func (srv *server) snapshot(j *job) job {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return *j
+}
+
+func (srv *server) wait(j *job) {
+	<-j.done
+}
+
+// Evento de um acesso enviado pelo WebSocket de /stream
+type streamEvent struct {
+	Index     int          `json:"index"` // a partir de 1
+	PageID    string       `json:"page"`
+	Hit       bool         `json:"hit"`
+	Victim    string       `json:"victim,omitempty"`
+	Frame     int          `json:"frame"`
+	Load      int          `json:"load,omitempty"` // na falta, cargas da página até aqui
+	Cold      bool         `json:"cold,omitempty"` // falta fria (primeira carga); senão, recarga
+	Frames    []FrameState `json:"frames"`
+	Coalesced int          `json:"coalesced,omitempty"` // eventos omitidos antes deste
+}
+
+// Chave fixa do protocolo WebSocket (RFC 6455) para o aceite do handshake
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Conexão WebSocket mínima: o servidor envia os eventos e só lê do cliente
+// os quadros de controle (fechamento e ping); o resto é descartado
+type websocketConn struct {
+	conn net.Conn
+	buf  *bufio.ReadWriter
+	mu   sync.Mutex // escritas do laço de eventos e do laço de leitura
+}
+
+// Maior quadro aceito do cliente; quadros de controle têm no máximo 125
+const maxClientFrame = 1 << 16
+
+var errClientClosed = errors.New("cliente fechou a conexão")
+
+// Aceita o handshake só sem Origin (clientes fora do navegador) ou com
+// Origin no mesmo host da requisição, para outra página não abrir o stream
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*websocketConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		return nil, errors.New("requisição não é um handshake WebSocket")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("conexão não suporta WebSocket")
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	fmt.Fprintf(buf, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(sum[:]))
+	if err := buf.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &websocketConn{conn: conn, buf: buf}, nil
+}
+
+// Envia um quadro final com o opcode dado (1 = texto, 8 = fechamento,
+// 10 = pong)
+func (ws *websocketConn) writeFrame(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126, byte(n>>8), byte(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.buf.Write(header)
+	ws.buf.Write(payload)
+	return ws.buf.Flush()
+}
+
+// Lê um quadro do cliente, tirando a máscara obrigatória (RFC 6455, 5.3)
+func (ws *websocketConn) readFrame() (opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(ws.buf, header[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode = header[0] & 0x0F
+	n := uint64(header[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.buf, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.buf, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if header[1]&0x80 == 0 {
+		return 0, nil, errors.New("quadro do cliente sem máscara")
+	}
+	if n > maxClientFrame || (opcode >= 8 && n > 125) {
+		return 0, nil, fmt.Errorf("quadro de %d bytes", n)
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.buf, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(ws.buf, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
+
+// Responde aos pings e cancela o stream quando o cliente fecha a conexão,
+// desconecta ou envia um quadro inválido
+func (ws *websocketConn) readLoop(cancel context.CancelCauseFunc) {
+	for {
+		opcode, payload, err := ws.readFrame()
+		if err != nil {
+			cancel(errClientClosed)
+			return
+		}
+		switch opcode {
+		case 8:
+			cancel(errClientClosed) // Close responde com o quadro de fechamento
+			return
+		case 9:
+			ws.writeFrame(10, payload)
+		}
+	}
+}
+
+// Fecha com o código de status dado (1000 = normal, 1011 = erro do servidor)
+func (ws *websocketConn) closeWith(code uint16, reason string) error {
+	ws.writeFrame(8, append(binary.BigEndian.AppendUint16(nil, code), reason...))
+	return ws.conn.Close()
+}
+
+func (ws *websocketConn) Close() error {
+	return ws.closeWith(1000, "")
+}
+
+// Lê rate ou pace da query: positivo e finito; ausente usa o padrão
+func streamRate(query url.Values, name string, fallback float64) (float64, error) {
+	v := query.Get(name)
+	if v == "" {
+		return fallback, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || !(f > 0) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("%s inválido: %s", name, v)
+	}
+	return f, nil
+}
+
+// GET /stream?path=F&memory=N&algorithm=clock&rate=50&pace=0 envia um
+// evento por acesso. No máximo rate eventos por segundo são enviados; os
+// acessos entre dois envios são contados em "coalesced". Com pace > 0 a
+// simulação processa pace acessos por segundo, para a animação ser
+// acompanhada. O stream ocupa uma vaga de -max-jobs e é encerrado pelo
+// -job-timeout como as simulações de /simulate.
+func (srv *server) handleStream(w http.ResponseWriter, r *http.Request) {
+	if !checkOrigin(r) {
+		writeError(w, http.StatusForbidden, "origem não permitida: %s", r.Header.Get("Origin"))
+		return
+	}
+	query := r.URL.Query()
+	memory, _ := strconv.Atoi(query.Get("memory"))
+	algorithm := query.Get("algorithm")
+	if algorithm == "" {
+		algorithm = streamingPolicies[0].Name
+	}
+	info, ok := findPolicy(algorithm)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "algoritmo desconhecido: %s", algorithm)
+		return
+	}
+	rate, err := streamRate(query, "rate", 50)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	pace, err := streamRate(query, "pace", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	s, err := srv.prepare(simulateRequest{Trace: query.Get("trace"), Path: query.Get("path"), Memory: memory})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	jobCtx, stop := srv.jobContext()
+	defer stop()
+	if !srv.acquire(jobCtx) {
+		writeError(w, http.StatusGatewayTimeout, "%s", srv.expired())
+		return
+	}
+	defer srv.release()
+	ws, err := upgradeWebSocket(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	ctx, cancel := context.WithCancelCause(jobCtx)
+	defer cancel(nil)
+	go ws.readLoop(cancel)
+
+	policy := info.New(s)
+	interval := time.Duration(float64(time.Second) / rate)
+	var tick *time.Ticker
+	if pace > 0 {
+		tick = time.NewTicker(time.Duration(float64(time.Second) / pace))
+		defer tick.Stop()
+	}
+	var lastSent time.Time
+	var pending *streamEvent
+	coalesced := 0
+	loads := make(map[string]int)
+	for i, access := range s.accesses {
+		if tick != nil && i > 0 {
+			select {
+			case <-tick.C:
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			if context.Cause(ctx) == errClientClosed {
+				ws.Close()
+			} else {
+				ws.closeWith(1011, srv.expired())
+			}
+			return
+		}
+		result := step(policy, access)
+		load := 0
+		if !result.Hit {
+			loads[access.PageID]++
+			load = loads[access.PageID]
+		}
+		pending = &streamEvent{
+			Index:     i + 1,
+			PageID:    access.PageID,
+			Hit:       result.Hit,
+			Victim:    result.Victim,
+			Frame:     result.Frame,
+			Load:      load,
+			Cold:      load == 1,
+			Frames:    finalState(policy, 0).Frames,
+			Coalesced: coalesced,
+		}
+		if time.Since(lastSent) < interval {
+			coalesced++
+			continue
+		}
+		data, _ := json.Marshal(pending)
+		if ws.writeFrame(1, data) != nil {
+			ws.conn.Close()
+			return // cliente desconectou
+		}
+		lastSent, pending, coalesced = time.Now(), nil, 0
+	}
+	// O último acesso é sempre enviado, para o cliente ver o estado final
+	if pending != nil {
+		data, _ := json.Marshal(pending)
+		ws.writeFrame(1, data)
+	}
+	ws.Close()
+}
+
+// Perfil de um trace para comparação (analyze diff), montado lendo o
+// arquivo uma vez; a memória usada cresce com as páginas distintas
+type traceProfile struct {
+	Accesses     int
+	Instructions int
+	Counts       map[string]int
+	// Distância de reuso: acessos desde o uso anterior da mesma página,
+	// agrupada em potências de 2 (posição k: distâncias de 2^k a 2^(k+1)-1)
+	Reuse   []int
+	lastUse map[string]int
+}
+
+func profileTrace(filename string) (*traceProfile, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir arquivo %s: %v", filename, err)
+	}
+	defer file.Close()
+
+	p := newTraceProfile()
+	scanner := newTraceScanner(file)
+	for scanner.Scan() {
+		access, err := scanner.parse()
+		if err != nil {
+			continue
+		}
+		p.add(access)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao ler %s: %v", filename, err)
+	}
+	if p.Accesses == 0 {
+		return nil, fmt.Errorf("nenhum acesso válido encontrado em %s", filename)
+	}
+	return p, nil
+}
+
+func newTraceProfile() *traceProfile {
+	return &traceProfile{Counts: make(map[string]int), lastUse: make(map[string]int)}
+}
+
+func (p *traceProfile) add(access PageAccess) {
+	if last, ok := p.lastUse[access.PageID]; ok {
+		bucket := bits.Len(uint(p.Accesses-last)) - 1
+		for len(p.Reuse) <= bucket {
+			p.Reuse = append(p.Reuse, 0)
+		}
+		p.Reuse[bucket]++
+	}
+	p.lastUse[access.PageID] = p.Accesses
+	p.Accesses++
+	p.Counts[access.PageID]++
+	if access.Type == "I" {
+		p.Instructions++
+	}
+}
+
+// Perfil de acessos já carregados (analyze validate)
+func profileAccesses(accesses []PageAccess) *traceProfile {
+	p := newTraceProfile()
+	for _, access := range accesses {
+		p.add(access)
+	}
+	return p
+}
+
+func (p *traceProfile) instructionShare() float64 {
+	return float64(p.Instructions) / float64(p.Accesses)
+}
+
+type pageDelta struct {
+	Page  string `json:"page"`
+	A     int    `json:"a"`
+	B     int    `json:"b"`
+	Delta int    `json:"delta"`
+}
+
+type traceDiff struct {
+	AccessesA         int         `json:"accesses_a"`
+	AccessesB         int         `json:"accesses_b"`
+	DistinctA         int         `json:"distinct_a"`
+	DistinctB         int         `json:"distinct_b"`
+	Intersection      int         `json:"intersection"`
+	Union             int         `json:"union"`
+	Jaccard           float64     `json:"jaccard"`
+	InstructionShareA float64     `json:"instruction_share_a"`
+	InstructionShareB float64     `json:"instruction_share_b"`
+	ReuseKS           float64     `json:"reuse_ks"`
+	TopMovers         []pageDelta `json:"top_movers"`
+}
+
+const diffTopMovers = 10
+
+func diffProfiles(a, b *traceProfile) traceDiff {
+	d := traceDiff{
+		AccessesA:         a.Accesses,
+		AccessesB:         b.Accesses,
+		DistinctA:         len(a.Counts),
+		DistinctB:         len(b.Counts),
+		InstructionShareA: a.instructionShare(),
+		InstructionShareB: b.instructionShare(),
+		ReuseKS:           ksStatistic(a.Reuse, b.Reuse),
+	}
+
+	var deltas []pageDelta
+	for page, countA := range a.Counts {
+		countB := b.Counts[page]
+		if countB > 0 {
+			d.Intersection++
+		}
+		if countA != countB {
+			deltas = append(deltas, pageDelta{page, countA, countB, countB - countA})
+		}
+	}
+	for page, countB := range b.Counts {
+		if a.Counts[page] == 0 {
+			deltas = append(deltas, pageDelta{page, 0, countB, countB})
+		}
+	}
+	d.Union = len(a.Counts) + len(b.Counts) - d.Intersection
+	d.Jaccard = float64(d.Intersection) / float64(d.Union)
+
+	// Maiores variações primeiro; empates na ordem das páginas
+	sort.Slice(deltas, func(i, j int) bool {
+		di, dj := abs(deltas[i].Delta), abs(deltas[j].Delta)
+		if di != dj {
+			return di > dj
+		}
+		pages := []string{deltas[i].Page, deltas[j].Page}
+		sortPageIDs(pages, defaultPageRadix)
+		return pages[0] == deltas[i].Page
+	})
+	d.TopMovers = deltas[:min(len(deltas), diffTopMovers)]
+	return d
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Estatística de Kolmogorov-Smirnov entre dois histogramas com as mesmas
+// faixas: a maior distância entre as distribuições acumuladas
+func ksStatistic(a, b []int) float64 {
+	totalA, totalB := 0, 0
+	for _, n := range a {
+		totalA += n
+	}
+	for _, n := range b {
+		totalB += n
+	}
+	if totalA == 0 || totalB == 0 {
+		if totalA == totalB {
+			return 0
+		}
+		return 1
+	}
+
+	ks, cumA, cumB := 0.0, 0, 0
+	for k := 0; k < max(len(a), len(b)); k++ {
+		if k < len(a) {
+			cumA += a[k]
+		}
+		if k < len(b) {
+			cumB += b[k]
+		}
+		ks = max(ks, math.Abs(float64(cumA)/float64(totalA)-float64(cumB)/float64(totalB)))
+	}
+	return ks
+}
+
+func (d traceDiff) print(nameA, nameB string) {
+	fmt.Printf("=== COMPARAÇÃO DE TRACES ===\n")
+	fmt.Printf("A: %s\nB: %s\n", nameA, nameB)
+	fmt.Printf("%-28s %12s %12s\n", "", "A", "B")
+	fmt.Printf("%-28s %12d %12d\n", "Acessos", d.AccessesA, d.AccessesB)
+	fmt.Printf("%-28s %12d %12d\n", "Páginas distintas", d.DistinctA, d.DistinctB)
+	fmt.Printf("%-28s %11.2f%% %11.2f%%\n", "Acessos a instruções", 100*d.InstructionShareA, 100*d.InstructionShareB)
+	fmt.Printf("Páginas em comum: %d | Em qualquer um: %d | Jaccard: %.4f\n", d.Intersection, d.Union, d.Jaccard)
+	fmt.Printf("Distância de reuso (KS entre as distribuições): %.4f\n", d.ReuseKS)
+	if len(d.TopMovers) == 0 {
+		fmt.Println("Todas as páginas têm o mesmo número de acessos")
+		return
+	}
+	fmt.Println("Páginas com maior variação de acessos:")
+	for _, m := range d.TopMovers {
+		fmt.Printf("  %-12s %8d -> %-8d (%+d)\n", m.Page, m.A, m.B, m.Delta)
+	}
+}
+
+// Validação de um trace sintético contra um real (analyze validate): as
+// medidas do diff mais o expoente de Zipf e as faltas do Relógio em três
+// tamanhos de memória, cada uma com o erro em relação ao real e um limite
+// de aceitação. O sintético só serve de substituto se passar em todas.
+const (
+	validateRelTolerance   = 0.10 // erro relativo aceito
+	validateShareTolerance = 0.05 // diferença aceita na fração de instruções
+)
+
+// Memórias da validação, como frações das páginas distintas do real
+var validateMemoryShares = []float64{0.10, 0.25, 0.50}
+
+type validationMetric struct {
+	Name      string  `json:"name"`
+	Real      float64 `json:"real"`
+	Synthetic float64 `json:"synthetic"`
+	Error     float64 `json:"error"` // relativo, exceto na fração de instruções e no KS
+	Tolerance float64 `json:"tolerance"`
+	Pass      bool    `json:"pass"`
+}
+
+type traceValidation struct {
+	Metrics []validationMetric `json:"metrics"`
+	Pass    bool               `json:"pass"`
+}
+
+// Expoente s da lei de Zipf (frequência ~ 1/posição^s), pela reta de
+// mínimos quadrados de log(frequência) contra log(posição)
+func zipfExponent(counts map[string]int) float64 {
+	freqs := make([]int, 0, len(counts))
+	for _, n := range counts {
+		freqs = append(freqs, n)
+	}
+	if len(freqs) < 2 {
+		return 0
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(freqs)))
+	var sx, sy, sxx, sxy float64
+	for rank, n := range freqs {
+		x, y := math.Log(float64(rank+1)), math.Log(float64(n))
+		sx, sy, sxx, sxy = sx+x, sy+y, sxx+x*x, sxy+x*y
+	}
+	k := float64(len(freqs))
+	return -(k*sxy - sx*sy) / (k*sxx - sx*sx)
+}
+
+func relativeError(reference, synthetic float64) float64 {
+	if reference == 0 {
+		return math.Abs(synthetic)
+	}
+	return math.Abs(synthetic-reference) / math.Abs(reference)
+}
+
+// Faltas de um algoritmo com frames frames sobre accesses
+func (s *Simulator) stepperFaults(accesses []PageAccess, frames int, name string) (int, error) {
+	stepper, err := s.NewStepper(accesses, frames, name)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		if _, ok := stepper.Step(); !ok {
+			break
+		}
+	}
+	return stepper.Summary().Faults, nil
+}
+
+func validateTrace(reference, synthetic []PageAccess) traceValidation {
+	a, b := profileAccesses(reference), profileAccesses(synthetic)
+	var v traceValidation
+	add := func(name string, x, y, err, tolerance float64) {
+		v.Metrics = append(v.Metrics, validationMetric{name, x, y, err, tolerance, err <= tolerance})
+	}
+	relative := func(name string, x, y float64) {
+		add(name, x, y, relativeError(x, y), validateRelTolerance)
+	}
+	relative("páginas distintas", float64(len(a.Counts)), float64(len(b.Counts)))
+	relative("expoente de Zipf", zipfExponent(a.Counts), zipfExponent(b.Counts))
+	add("distância de reuso (KS)", 0, 0, ksStatistic(a.Reuse, b.Reuse), validateRelTolerance)
+	add("fração de instruções", a.instructionShare(), b.instructionShare(),
+		math.Abs(a.instructionShare()-b.instructionShare()), validateShareTolerance)
+
+	s := NewSimulator(PAGE_SIZE)
+	previous := 0
+	for _, share := range validateMemoryShares {
+		frames := max(1, int(share*float64(len(a.Counts))))
+		if frames == previous {
+			continue // trace pequeno: frações diferentes, mesma memória
+		}
+		previous = frames
+		x, _ := s.stepperFaults(reference, frames, "clock")
+		y, _ := s.stepperFaults(synthetic, frames, "clock")
+		relative(fmt.Sprintf("faltas do Relógio, %d frames", frames), float64(x), float64(y))
+	}
+
+	v.Pass = true
+	for _, m := range v.Metrics {
+		v.Pass = v.Pass && m.Pass
+	}
+	return v
+}
+
+func (v traceValidation) print(realName, syntheticName string) {
+	fmt.Printf("=== VALIDAÇÃO DE TRACE SINTÉTICO ===\n")
+	fmt.Printf("Real: %s\nSintético: %s\n", realName, syntheticName)
+	fmt.Printf("%-34s %12s %12s %9s %9s\n", "", "Real", "Sintético", "Erro", "Limite")
+	for _, m := range v.Metrics {
+		verdict := "ok"
+		if !m.Pass {
+			verdict = "FORA"
+		}
+		if m.Name == "distância de reuso (KS)" {
+			fmt.Printf("%-34s %12s %12s %9.4f %9.4f  %s\n", m.Name, "", "", m.Error, m.Tolerance, verdict)
+			continue
+		}
+		fmt.Printf("%-34s %12s %12s %8.2f%% %8.2f%%  %s\n", m.Name, metricValue(m.Real), metricValue(m.Synthetic),
+			m.Error*100, m.Tolerance*100, verdict)
+	}
+	if v.Pass {
+		fmt.Println("O trace sintético é um substituto aceitável do real")
+	} else {
+		fmt.Println("O trace sintético NÃO é um substituto aceitável do real")
+	}
+}
+
+// Contagens sem casas decimais; expoente e frações com quatro
+func metricValue(x float64) string {
+	if x == math.Trunc(x) {
+		return strconv.FormatFloat(x+0, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(x, 'f', 4, 64)
+}
+
+func runValidate(args []string) {
+	jsonOut := false
+	var files []string
+	for _, arg := range args {
+		if arg == "-json" {
+			jsonOut = true
+		} else {
+			files = append(files, arg)
+		}
+	}
+	if len(files) != 2 {
+		fmt.Println("Uso: go run main.go analyze validate [-json] <trace_real> <trace_sintetico>")
+		return
+	}
+	var traces [2][]PageAccess
+	for i, file := range files {
+		// Sem a linha de resumo do carregamento, que atrapalharia o -json
+		f, err := os.Open(file)
+		if err != nil {
+			logger.Error("erro ao abrir arquivo", "file", file, "err", err)
+			return
+		}
+		s := NewSimulator(PAGE_SIZE)
+		_, err = s.LoadAccesses(f)
+		f.Close()
+		if err != nil {
+			logger.Error("erro ao carregar arquivo", "file", file, "err", err)
+			return
+		}
+		if len(s.accesses) == 0 {
+			logger.Error("nenhum acesso válido encontrado", "file", file)
+			return
+		}
+		traces[i] = s.accesses
+	}
+
+	v := validateTrace(traces[0], traces[1])
+	if jsonOut {
+		data, _ := json.MarshalIndent(v, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		v.print(files[0], files[1])
+	}
+	if !v.Pass {
+		os.Exit(1)
+	}
+}
+
+// Tempo de vida de cada página no trace (analyze lifetimes)
+type pageLifetime struct {
+	Page     string
+	First    int // índice do primeiro acesso, a partir de 0
+	Last     int
+	Accesses int
+	Loads    int // carregamentos num Relógio de -memory bytes (0 sem -memory)
+}
+
+func (l pageLifetime) Span() int {
+	return l.Last - l.First + 1
+}
+
+// Lê o trace uma vez; com frames > 0 simula ao mesmo tempo um Relógio
+// desse tamanho para contar os carregamentos de cada página
+func traceLifetimes(filename string, frames int) ([]pageLifetime, int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, fmt.Errorf("erro ao abrir arquivo %s: %v", filename, err)
+	}
+	defer file.Close()
+
+	var clock *clockPolicy
+	if frames > 0 {
+		clock = newClockPolicy(frames)
+	}
+	index := make(map[string]int)
+	var pages []pageLifetime
+	accesses := 0
+	scanner := newTraceScanner(file)
+	for scanner.Scan() {
+		access, err := scanner.parse()
+		if err != nil {
+			continue
+		}
+		k, ok := index[access.PageID]
+		if !ok {
+			k = len(pages)
+			index[access.PageID] = k
+			pages = append(pages, pageLifetime{Page: access.PageID, First: accesses})
+		}
+		pages[k].Last = accesses
+		pages[k].Accesses++
+		if clock != nil && !clock.Access(access.PageID).Hit {
+			pages[k].Loads++
+		}
+		accesses++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("erro ao ler %s: %v", filename, err)
+	}
+	if accesses == 0 {
+		return nil, 0, fmt.Errorf("nenhum acesso válido encontrado em %s", filename)
+	}
+	return pages, accesses, nil
+}
+
+// Ordena do maior para o menor pela chave; empates pela ordem das páginas
+func sortLifetimes(pages []pageLifetime, key string) {
+	value := map[string]func(pageLifetime) int{
+		"span":     pageLifetime.Span,
+		"accesses": func(l pageLifetime) int { return l.Accesses },
+		"loads":    func(l pageLifetime) int { return l.Loads },
+		"first":    func(l pageLifetime) int { return -l.First },
+	}[key]
+	ids := make([]string, len(pages))
+	for i, p := range pages {
+		ids[i] = p.Page
+	}
+	sortPageIDs(ids, defaultPageRadix)
+	rank := make(map[string]int, len(ids))
+	for i, id := range ids {
+		rank[id] = i
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		if a, b := value(pages[i]), value(pages[j]); a != b {
+			return a > b
+		}
+		return rank[pages[i].Page] < rank[pages[j].Page]
+	})
+}
+
+// Resumo da distribuição: páginas com vida curta (tocadas só numa janela
+// estreita, como as de uma varredura) poluem a memória sem serem reusadas
+func printLifetimeSummary(pages []pageLifetime, accesses int) {
+	limits := []struct {
+		share float64
+		label string
+	}{
+		{0.01, "menos de 1% do trace"},
+		{0.10, "de 1% a 10%"},
+		{0.50, "de 10% a 50%"},
+		{1.01, "50% ou mais (ao longo do trace)"},
+	}
+	counts := make([]int, len(limits))
+	single := 0
+	spans := make([]int, len(pages))
+	for i, p := range pages {
+		spans[i] = p.Span()
+		share := float64(p.Span()) / float64(accesses)
+		for k, limit := range limits {
+			if share < limit.share {
+				counts[k]++
+				break
+			}
+		}
+		if p.Accesses == 1 {
+			single++
+		}
+	}
+	sort.Ints(spans)
+
+	fmt.Printf("%d acessos, %d páginas distintas\n", accesses, len(pages))
+	fmt.Printf("Vida (do primeiro ao último acesso): mediana %d acessos, máximo %d\n",
+		spans[len(spans)/2], spans[len(spans)-1])
+	for k, limit := range limits {
+		fmt.Printf("  %-34s %8d páginas (%.1f%%)\n", limit.label, counts[k],
+			float64(counts[k])/float64(len(pages))*100)
+	}
+	fmt.Printf("Páginas acessadas uma única vez: %d (%.1f%%)\n", single, float64(single)/float64(len(pages))*100)
+}
+
+func writeLifetimesCSV(filename string, pages []pageLifetime) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("erro ao criar %s: %v", filename, err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write([]string{"pagina", "primeiro", "ultimo", "vida", "acessos", "cargas"})
+	for _, p := range pages {
+		w.Write([]string{p.Page, strconv.Itoa(p.First), strconv.Itoa(p.Last), strconv.Itoa(p.Span()),
+			strconv.Itoa(p.Accesses), strconv.Itoa(p.Loads)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// sim analyze lifetimes [-memory B] [-sort chave] [-limit N] [-csv ARQ] trace
+func runLifetimes(args []string) {
+	memory, limit := 0, 20
+	sortKey, csvFile := "span", ""
+	var files []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-memory", "-limit":
+			if i+1 >= len(args) {
+				logger.Error("opção requer um valor", "option", args[i])
+				return
+			}
+			value, err := strconv.Atoi(args[i+1])
+			if err != nil || value < 0 || (args[i] == "-memory" && value < PAGE_SIZE) {
+				logger.Error("valor inválido", "option", args[i], "value", args[i+1])
+				return
+			}
+			if args[i] == "-memory" {
+				memory = value
+			} else {
+				limit = value
+			}
+			i++
+		case "-sort":
+			if i+1 >= len(args) {
+				logger.Error("opção requer uma chave", "option", "-sort")
+				return
+			}
+			i++
+			switch args[i] {
+			case "span", "accesses", "loads", "first":
+				sortKey = args[i]
+			default:
+				logger.Error("chave desconhecida (use span, accesses, loads ou first)", "value", args[i])
+				return
+			}
+		case "-csv":
+			if i+1 >= len(args) {
+				logger.Error("opção requer um arquivo", "option", "-csv")
+				return
+			}
+			i++
+			csvFile = args[i]
+		default:
+			files = append(files, args[i])
+		}
+	}
+	if len(files) != 1 {
+		fmt.Println("Uso: go run main.go analyze lifetimes [-memory B] [-sort span|accesses|loads|first] [-limit N] [-csv ARQ] <trace>")
+		return
+	}
+	if sortKey == "loads" && memory == 0 {
+		logger.Error("-sort loads requer -memory")
+		return
+	}
+
+	pages, accesses, err := traceLifetimes(files[0], memory/PAGE_SIZE)
+	if err != nil {
+		logger.Error("erro ao analisar o trace", "file", files[0], "err", err)
+		return
+	}
+	printLifetimeSummary(pages, accesses)
+
+	sortLifetimes(pages, sortKey)
+	if limit > 0 && limit < len(pages) {
+		pages = pages[:limit]
+	}
+	fmt.Printf("\n%-12s %10s %10s %10s %9s", "Página", "Primeiro", "Último", "Vida", "Acessos")
+	if memory > 0 {
+		fmt.Printf(" %7s", "Cargas")
+	}
+	fmt.Println()
+	for _, p := range pages {
+		fmt.Printf("%-12s %10d %10d %10d %9d", p.Page, p.First, p.Last, p.Span(), p.Accesses)
+		if memory > 0 {
+			fmt.Printf(" %7d", p.Loads)
+		}
+		fmt.Println()
+	}
+
+	if csvFile != "" {
+		if err := writeLifetimesCSV(csvFile, pages); err != nil {
+			logger.Error("erro ao gravar o CSV", "file", csvFile, "err", err)
+		} else {
+			fmt.Printf("\n%d páginas gravadas em %s\n", len(pages), csvFile)
+		}
+	}
+}
+
+// sim analyze diff [-json] A B
+// sim analyze lifetimes ...
+// sim analyze validate ...
+// sim bench trace memória: custo por acesso do Stepper comparado ao laço
+// direto sobre a política, em cada algoritmo (melhor de benchRepeats)
+const benchRepeats = 3
+
+func runBench(args []string) {
+	if len(args) != 2 {
+		fmt.Println("Uso: go run main.go bench <trace> <tamanho_memoria_bytes>")
+		return
+	}
+	memorySize, err := strconv.Atoi(args[1])
+	if err != nil || memorySize < PAGE_SIZE {
+		logger.Error("tamanho de memória inválido", "value", args[1])
+		return
+	}
+	s := NewSimulator(memorySize)
+	d, err := s.LoadAccessFile(args[0])
+	d.log()
+	if err != nil {
+		logger.Error("erro ao carregar arquivo", "file", args[0], "err", err)
+		return
+	}
+	if len(s.accesses) == 0 {
+		logger.Error("trace vazio")
+		return
+	}
+
+	best := func(run func()) time.Duration {
+		fastest := time.Duration(math.MaxInt64)
+		for i := 0; i < benchRepeats; i++ {
+			start := time.Now()
+			run()
+			fastest = min(fastest, time.Since(start))
+		}
+		return fastest
+	}
+	perAccess := func(d time.Duration) float64 {
+		return float64(d.Nanoseconds()) / float64(len(s.accesses))
+	}
+
+	fmt.Printf("%d acessos, %d frames, melhor de %d execuções\n", len(s.accesses), s.totalFrames, benchRepeats)
+	if data, err := os.ReadFile(args[0]); err == nil && s.traceFormat != "proto" {
+		// Interpretação do trace já na memória pelos dois leitores de -reader
+		// e, no leitor em memória, com um trecho e com um por processador
+		type readerCase struct {
+			reader  string
+			workers int
+		}
+		cases := []readerCase{{"buffered", 1}, {"slice", 1}}
+		if runtime.NumCPU() > 1 {
+			cases = append(cases, readerCase{"slice", runtime.NumCPU()})
+		}
+		for _, c := range cases {
+			elapsed := best(func() {
+				parsed := NewSimulator(memorySize)
+				parsed.parseWorkers = c.workers
+				if c.reader == "slice" {
+					parsed.LoadAccessBytes(data)
+				} else {
+					parsed.LoadAccesses(bytes.NewReader(data))
+				}
+			})
+			label := c.reader
+			if c.reader == "slice" && c.workers == 1 {
+				label = "slice, sequencial"
+			} else if c.reader == "slice" {
+				label = fmt.Sprintf("slice, %d trechos em paralelo", c.workers)
+			}
+			fmt.Printf("Leitura (%s): %.1f MB/s, %.1f ns por linha\n", label,
+				float64(len(data))/elapsed.Seconds()/(1<<20), perAccess(elapsed))
+		}
+	}
+	fmt.Printf("%-22s %14s %14s %10s %14s %14s\n", "Algoritmo", "laço (ns/ac.)", "Stepper", "diferença",
+		"runPolicy", "+observador")
+	names := append([]string{"optimal"}, policyNames()...)
+	index := s.BuildNextUseIndex()
+	for _, name := range names {
+		newPolicy := func() ReplacementPolicy {
+			if name == "optimal" {
+				return newOptimalPolicy(s.totalFrames, index)
+			}
+			info, _ := findPolicy(name)
+			return info.New(s)
+		}
+		loop := best(func() {
+			policy := newPolicy()
+			for _, access := range s.accesses {
+				step(policy, access)
+			}
+		})
+		var label string
+		stepped := best(func() {
+			stepper, _ := s.NewStepper(s.accesses, s.totalFrames, name)
+			for {
+				if _, ok := stepper.Step(); !ok {
+					break
+				}
+			}
+			label = stepper.Summary().Algorithm
+		})
+		// Sem observadores runPolicy não deve pagar pelos eventos
+		plain := best(func() { s.runPolicy(newPolicy()) })
+		observed := best(func() { s.runPolicy(newPolicy(), &countingObserver{}) })
+		fmt.Printf("%-22s %14.1f %14.1f %+9.1f%% %14.1f %14.1f\n", label, perAccess(loop), perAccess(stepped),
+			(float64(stepped)/float64(loop)-1)*100, perAccess(plain), perAccess(observed))
+	}
+}
+
+func runAnalyze(args []string) {
+	if len(args) > 0 && args[0] == "lifetimes" {
+		runLifetimes(args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "validate" {
+		runValidate(args[1:])
+		return
+	}
+	jsonOut := false
+	var files []string
+	for _, arg := range args {
+		if arg == "-json" {
+			jsonOut = true
+		} else {
+			files = append(files, arg)
+		}
+	}
+	if len(files) != 3 || files[0] != "diff" {
+		fmt.Println("Uso: go run main.go analyze diff [-json] <trace_a> <trace_b>")
+		return
+	}
+
+	a, err := profileTrace(files[1])
+	if err != nil {
+		logger.Error("erro ao analisar o trace", "file", files[1], "err", err)
+		return
+	}
+	b, err := profileTrace(files[2])
+	if err != nil {
+		logger.Error("erro ao analisar o trace", "file", files[2], "err", err)
+		return
+	}
+
+	d := diffProfiles(a, b)
+	if jsonOut {
+		data, _ := json.MarshalIndent(d, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	d.print(files[1], files[2])
+}
+
+// Renumera as páginas para não expor os endereços originais. Sem chave, os
+// números são densos na ordem da primeira aparição; com chave, vêm de um
+// HMAC da página, e a mesma chave dá sempre os mesmos números. Como a
+// troca é uma bijeção entre páginas, as faltas de todo algoritmo não mudam.
+type anonymizer struct {
+	key     []byte
+	mapping map[string]string
+	used    map[string]string // página anonimizada -> original
+	order   []string
+}
+
+func newAnonymizer(key string) *anonymizer {
+	a := &anonymizer{mapping: make(map[string]string), used: make(map[string]string)}
+	if key != "" {
+		a.key = []byte(key)
+	}
+	return a
+}
+
+func (a *anonymizer) transform(access PageAccess) (PageAccess, error) {
+	anonymized, ok := a.mapping[access.PageID]
+	if !ok {
+		if a.key == nil {
+			anonymized = fmt.Sprintf("%s%d", access.Type, len(a.mapping))
+		} else {
+			mac := hmac.New(sha256.New, a.key)
+			mac.Write([]byte(access.PageID))
+			sum := mac.Sum(nil)
+			anonymized = fmt.Sprintf("%s0x%012x", access.Type, binary.BigEndian.Uint64(sum)>>16)
+		}
+		if original, taken := a.used[anonymized]; taken {
+			return access, fmt.Errorf("colisão de hash: %s e %s viram %s", original, access.PageID, anonymized)
+		}
+		a.mapping[access.PageID] = anonymized
+		a.used[anonymized] = access.PageID
+		a.order = append(a.order, access.PageID)
+	}
+	access.PageID = anonymized
+	return access, nil
+}
+
+// Grava a correspondência "original anonimizada", na ordem da primeira
+// aparição
+func (a *anonymizer) writeMapping(filename string) error {
+	var b strings.Builder
+	for _, original := range a.order {
+		fmt.Fprintf(&b, "%s %s\n", original, a.mapping[original])
+	}
+	if err := os.WriteFile(filename, []byte(b.String()), 0600); err != nil {
+		return fmt.Errorf("erro ao gravar %s: %v", filename, err)
+	}
+	return nil
+}
+
+// Lê os acessos de um trace em texto um a um, sem carregar o arquivo
+type traceReader struct {
+	file    *os.File
+	scanner *traceScanner
+	name    string
+}
+
+func openTrace(filename string) (*traceReader, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir arquivo %s: %v", filename, err)
+	}
+	return &traceReader{file: file, scanner: newTraceScanner(file), name: filename}, nil
+}
+
+// Próximo acesso válido; false ao final do arquivo
+func (t *traceReader) next() (PageAccess, bool, error) {
+	for t.scanner.Scan() {
+		if access, err := t.scanner.parse(); err == nil {
+			return access, true, nil
+		}
+	}
+	if err := t.scanner.Err(); err != nil {
+		return PageAccess{}, false, fmt.Errorf("erro ao ler %s: %v", t.name, err)
+	}
+	return PageAccess{}, false, nil
+}
+
+func (t *traceReader) Close() error {
+	return t.file.Close()
+}
+
+// Separa o trace em PREFIXO-I.txt (instruções) e PREFIXO-D.txt (dados),
+// mantendo a ordem dos acessos em cada um
+func splitTrace(input, prefix string) error {
+	in, err := openTrace(input)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	writers := make(map[string]*bufio.Writer)
+	counts := make(map[string]int)
+	for _, kind := range []string{"I", "D"} {
+		name := prefix + "-" + kind + ".txt"
+		out, err := os.Create(name)
+		if err != nil {
+			return fmt.Errorf("erro ao criar %s: %v", name, err)
+		}
+		defer out.Close()
+		writers[kind] = bufio.NewWriter(out)
+	}
+
+	for {
+		access, ok, err := in.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		fmt.Fprintln(writers[access.Type], access)
+		counts[access.Type]++
+	}
+	for kind, w := range writers {
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("erro ao gravar %s-%s.txt: %v", prefix, kind, err)
+		}
+	}
+	fmt.Printf("%s-I.txt: %d acessos a instruções\n", prefix, counts["I"])
+	fmt.Printf("%s-D.txt: %d acessos a dados\n", prefix, counts["D"])
+	return nil
+}
+
+// Intercala os traces: ratio[k] acessos seguidos da fonte k, em rodízio,
+// até todas terminarem (as que acabam antes saem do rodízio). Com offset
+// > 0 os números de página da fonte k recebem k*offset, para que
+// programas diferentes não compartilhem páginas.
+func mergeTraces(inputs []string, ratio []int, offset uint64, output string) error {
+	var sources []*traceReader
+	for _, name := range inputs {
+		t, err := openTrace(name)
+		if err != nil {
+			return err
+		}
+		defer t.Close()
+		sources = append(sources, t)
+	}
+	out, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("erro ao criar %s: %v", output, err)
+	}
+	defer out.Close()
+	w := bufio.NewWriter(out)
+
+	counts := make([]int, len(sources))
+	done := make([]bool, len(sources))
+	remaining := len(sources)
+	for remaining > 0 {
+		for k, t := range sources {
+			for n := 0; n < ratio[k] && !done[k]; n++ {
+				access, ok, err := t.next()
+				if err != nil {
+					return err
+				}
+				if !ok {
+					done[k] = true
+					remaining--
+					break
+				}
+				if offset > 0 && k > 0 {
+					number, ok := pageNumber(access.PageID, defaultPageRadix)
+					if !ok {
+						return fmt.Errorf("%s: página sem número não pode ser deslocada: %s", t.name, access.PageID)
+					}
+					access.PageID = access.Type + strconv.FormatUint(number+uint64(k)*offset, defaultPageRadix)
+				}
+				fmt.Fprintln(w, access)
+				counts[k]++
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("erro ao gravar %s: %v", output, err)
+	}
+
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	fmt.Printf("%s: %d acessos\n", output, total)
+	for k, name := range inputs {
+		fmt.Printf("  %s: %d acessos (%.1f%%)\n", name, counts[k], 100*float64(counts[k])/float64(max(total, 1)))
+	}
+	return nil
+}
+
+// sim convert merge [-ratio 3:1] [-offset N] SAÍDA ENTRADA...
+func runMerge(args []string) {
+	var ratioText string
+	var offset uint64
+	var files []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-ratio", "-offset":
+			if i+1 >= len(args) {
+				logger.Error("opção requer um valor", "option", args[i])
+				return
+			}
+			if args[i] == "-ratio" {
+				ratioText = args[i+1]
+			} else {
+				n, err := strconv.ParseUint(args[i+1], 10, 64)
+				if err != nil {
+					logger.Error("deslocamento inválido", "value", args[i+1])
+					return
+				}
+				offset = n
+			}
+			i++
+		default:
+			files = append(files, args[i])
+		}
+	}
+	if len(files) < 3 {
+		fmt.Println("Uso: go run main.go convert merge [-ratio 3:1] [-offset N] <saída> <entrada> <entrada>...")
+		return
+	}
+
+	inputs := files[1:]
+	ratio := make([]int, len(inputs))
+	for k := range ratio {
+		ratio[k] = 1
+	}
+	if ratioText != "" {
+		parts := strings.Split(ratioText, ":")
+		if len(parts) != len(inputs) {
+			logger.Error("a proporção não tem um valor para cada entrada", "value", ratioText, "inputs", len(inputs))
+			return
+		}
+		for k, part := range parts {
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 1 {
+				logger.Error("proporção inválida", "value", ratioText)
+				return
+			}
+			ratio[k] = n
+		}
+	}
+	if err := mergeTraces(inputs, ratio, offset, files[0]); err != nil {
+		logger.Error("erro ao juntar os traces", "file", files[0], "err", err)
+	}
+}
+
+// sim convert [-from text|proto] [-to text|proto] [-anonymize [-key K]
+// [-map F]] ENTRADA SAÍDA
+func runConvert(args []string) {
+	if len(args) > 0 && args[0] == "split" {
+		if len(args) != 3 {
+			fmt.Println("Uso: go run main.go convert split <entrada> <prefixo>")
+			return
+		}
+		if err := splitTrace(args[1], args[2]); err != nil {
+			logger.Error("erro ao separar o trace", "file", args[1], "err", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "merge" {
+		runMerge(args[1:])
+		return
+	}
+
+	from, to := "text", "proto"
+	anonymize := false
+	key, mapFile := "", ""
+	var files []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-anonymize":
+			anonymize = true
+		case "-key", "-map":
+			if i+1 >= len(args) {
+				logger.Error("opção requer um valor", "option", args[i])
+				return
+			}
+			if args[i] == "-key" {
+				key = args[i+1]
+			} else {
+				mapFile = args[i+1]
+			}
+			i++
+		case "-from", "-to":
+			if i+1 >= len(args) || (args[i+1] != "text" && args[i+1] != "proto") {
+				logger.Error("opção requer text ou proto", "option", args[i])
+				return
+			}
+			if args[i] == "-from" {
+				from = args[i+1]
+			} else {
+				to = args[i+1]
+			}
+			i++
+		default:
+			files = append(files, args[i])
+		}
+	}
+	if len(files) != 2 {
+		fmt.Println("Uso: go run main.go convert [-from text|proto] [-to text|proto] [-anonymize [-key K] [-map F]] <entrada> <saída>")
+		return
+	}
+	if (key != "" || mapFile != "") && !anonymize {
+		logger.Error("-key e -map só valem com -anonymize")
+		return
+	}
+
+	var transform func(PageAccess) (PageAccess, error)
+	var anon *anonymizer
+	if anonymize {
+		anon = newAnonymizer(key)
+		transform = anon.transform
+	}
+	if err := convertTrace(from, to, files[0], files[1], transform); err != nil {
+		logger.Error("erro ao converter o trace", "file", files[0], "err", err)
+		return
+	}
+	if anon != nil {
+		fmt.Printf("Páginas renumeradas: %d\n", len(anon.mapping))
+		if mapFile != "" {
+			if err := anon.writeMapping(mapFile); err != nil {
+				logger.Error("erro ao gravar o mapeamento", "file", mapFile, "err", err)
+				return
+			}
+			fmt.Printf("Correspondência gravada em %s\n", mapFile)
+		}
+	}
 }
 
-func (s *Simulator) Run() {
-	fmt.Println("=== SIMULADOR DE PAGINAÇÃO ===")
-	fmt.Printf("Tamanho da memória física: %d bytes (%.2f MB)\n",
-		s.memorySize, float64(s.memorySize)/(1024*1024))
-	fmt.Printf("Tamanho da página: %d bytes\n", PAGE_SIZE)
-	fmt.Printf("Número de frames: %d\n", s.totalFrames)
-	fmt.Printf("Número de acessos: %d\n", len(s.accesses))
-	fmt.Printf("Páginas distintas: %d\n", len(s.distinctPages))
+// sim serve [-listen ADDR] [-trace-dir DIR] [-max-jobs N] [-job-timeout D]
+func runServe(args []string) {
+	listen, traceDir := ":8080", ""
+	maxJobs, jobTimeout := 2, time.Minute
+	ui := false
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-ui" {
+			ui = true
+			continue
+		}
+		if i+1 >= len(args) {
+			logger.Error("opção requer um valor", "option", args[i])
+			return
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "-listen":
+			listen = value
+		case "-trace-dir":
+			traceDir = value
+		case "-max-jobs":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				logger.Error("número de simulações inválido", "value", value)
+				return
+			}
+			maxJobs = n
+		case "-job-timeout":
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				logger.Error("tempo limite inválido", "value", value)
+				return
+			}
+			jobTimeout = d
+		default:
+			logger.Warn("opção desconhecida ignorada", "option", args[i])
+			return
+		}
+		i++
+	}
 
-	estimatedTime := s.estimateExecutionTime()
-	fmt.Printf("Tempo estimado: %s\n", estimatedTime)
-	fmt.Println()
+	srv := newServer(traceDir, maxJobs, jobTimeout)
+	srv.ui = ui
+	fmt.Printf("Servidor ouvindo em %s\n", listen)
+	if ui {
+		fmt.Println("Painel disponível em /")
+	}
+	if err := http.ListenAndServe(listen, srv.routes()); err != nil {
+		logger.Error("erro no servidor", "listen", listen, "err", err)
+	}
+}
 
-	if s.totalFrames == 0 {
-		fmt.Printf("ERRO: Memória insuficiente! Tamanho mínimo necessário: %d bytes (1 página)\n", PAGE_SIZE)
+// Diagnósticos (avisos, erros e progresso) vão para o stderr pelo logger,
+// em texto ou JSON (-log-format); os resultados continuam no stdout
+var (
+	logger  = newLogger(os.Stderr, "text", slog.LevelInfo)
+	logJSON bool
+)
+
+func newLogger(w io.Writer, format string, level slog.Level) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+	// No texto o horário só atrapalha a leitura no terminal
+	options.ReplaceAttr = withoutTime
+	return slog.New(slog.NewTextHandler(w, options))
+}
+
+func withoutTime(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey && len(groups) == 0 {
+		return slog.Attr{}
+	}
+	return a
+}
+
+// Tira de args as opções do logger, que valem para todos os comandos, e
+// configura o logger com elas
+func configureLogging(args []string) ([]string, error) {
+	format, level := "text", slog.LevelInfo
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-log-format", "-log-level":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("%s requer um valor", args[i])
+			}
+			i++
+			if args[i-1] == "-log-level" {
+				if err := level.UnmarshalText([]byte(args[i])); err != nil {
+					return nil, fmt.Errorf("nível inválido: %s (use debug, info, warn ou error)", args[i])
+				}
+			} else if args[i] == "text" || args[i] == "json" {
+				format = args[i]
+			} else {
+				return nil, fmt.Errorf("formato de log desconhecido: %s (use text ou json)", args[i])
+			}
+		case "-v":
+			level = slog.LevelDebug
+		case "-quiet":
+			level = slog.LevelWarn
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	logger, logJSON = newLogger(os.Stderr, format, level), format == "json"
+	return rest, nil
+}
+
+func main() {
+	args, err := configureLogging(os.Args)
+	if err != nil {
+		logger.Error("opção de log inválida", "err", err)
 		return
 	}
+	os.Args = args
 
-	var optimalFaults int
+	if len(os.Args) >= 2 && os.Args[1] == "serve" {
+		runServe(os.Args[2:])
+		return
+	}
 
-	// Executa algoritmo Ótimo
-	if !s.skipOptimal {
-		fmt.Println("=== ALGORITMO ÓTIMO ===")
-		optimalFaults = s.OptimalAlgorithm()
-		fmt.Printf("Faltas de página (Ótimo): %d\n", optimalFaults)
-	} else {
-		fmt.Println("=== ALGORITMO ÓTIMO ===")
-		fmt.Println("Algoritmo ótimo ignorado (use -skipoptimal para casos extremos)")
-		optimalFaults = -1 // Indica que não foi executado, apenas para testes
+	if len(os.Args) >= 2 && os.Args[1] == "analyze" {
+		runAnalyze(os.Args[2:])
+		return
 	}
 
-	// execucao do algoritmo do relogio
-	fmt.Println("\n=== ALGORITMO DO RELÓGIO ===")
-	clockFaults := s.ClockAlgorithm()
-	fmt.Printf("Faltas de página (Relógio): %d\n", clockFaults)
+	if len(os.Args) >= 2 && os.Args[1] == "results" {
+		runResults(os.Args[2:])
+		return
+	}
 
-	// Calcula eficiência
-	if optimalFaults > 0 && clockFaults > 0 {
-		efficiency := float64(optimalFaults) / float64(clockFaults) * 100
-		fmt.Printf("Eficiência do algoritmo do Relógio: %.2f%%\n", efficiency)
-	} else if optimalFaults == -1 {
-		fmt.Println("Eficiência do algoritmo do Relógio: N/A (algoritmo ótimo não executado)")
-	} else {
-		fmt.Println("Eficiência do algoritmo do Relógio: N/A (sem faltas de página)")
+	if len(os.Args) >= 2 && os.Args[1] == "bench" {
+		runBench(os.Args[2:])
+		return
 	}
 
-	s.ShowLoadCount()
-	s.EstimatePageTableSize()
-}
+	if len(os.Args) >= 2 && os.Args[1] == "index" {
+		runIndex(os.Args[2:])
+		return
+	}
 
-func (s *Simulator) estimateExecutionTime() string {
-	// funcao utilitaria
-	accesses := len(s.accesses)
-	if s.skipOptimal {
-		return "< 5 segundos"
+	if len(os.Args) >= 2 && os.Args[1] == "convert" {
+		runConvert(os.Args[2:])
+		return
 	}
-	if accesses < 1000000 {
-		return "1-5 segundos"
-	} else if accesses < 10000000 {
-		return "5-20 segundos"
-	} else {
-		return "Pode demorar mais de 30 segundos"
+
+	if len(os.Args) == 2 && os.Args[1] == "-selftest" {
+		if !runSelfTest(1) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) == 3 && os.Args[1] == "fixtures" {
+		if err := writeFixtures(os.Args[2]); err != nil {
+			logger.Error("erro ao gravar as fixtures", "dir", os.Args[2], "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) == 4 && os.Args[1] == "fixtures" && os.Args[2] == "-check" {
+		diffs, err := checkFixtures(os.Args[3])
+		if err != nil {
+			logger.Error("erro ao conferir as fixtures", "dir", os.Args[3], "err", err)
+			os.Exit(1)
+		}
+		if diffs > 0 {
+			fmt.Printf("%d resultados diferentes dos arquivos .golden\n", diffs)
+			os.Exit(1)
+		}
+		fmt.Println("Todos os resultados conferem com os arquivos .golden")
+		return
+	}
+
+	if len(os.Args) == 3 && os.Args[1] == "examples" {
+		if err := writeExamples(os.Args[2]); err != nil {
+			logger.Error("erro ao gravar os exemplos", "dir", os.Args[2], "err", err)
+		}
+		return
+	}
+
+	if len(os.Args) == 3 && os.Args[1] == "-repl" {
+		memorySize, err := strconv.Atoi(os.Args[2])
+		if err != nil || memorySize < PAGE_SIZE {
+			logger.Error("tamanho de memória inválido", "value", os.Args[2])
+			return
+		}
+		NewSimulator(memorySize).RunREPL(os.Stdin)
+		return
 	}
-}
 
-func main() {
 	if len(os.Args) < 3 {
 		fmt.Println("Uso: go run main.go <arquivo_entrada> <tamanho_memoria_bytes> [opções]")
+		fmt.Println("     go run main.go -repl <tamanho_memoria_bytes>")
+		fmt.Println("     go run main.go examples <diretório>")
+		fmt.Println("     go run main.go fixtures [-check] <diretório>")
+		fmt.Println("     go run main.go -selftest")
+		fmt.Println("     go run main.go bench <trace> <tamanho_memoria_bytes>")
+		fmt.Println("     go run main.go analyze diff [-json] <trace_a> <trace_b>")
+		fmt.Println("     go run main.go analyze lifetimes [-memory B] [-sort span|accesses|loads|first] [-limit N] [-csv ARQ] <trace>")
+		fmt.Println("     go run main.go analyze validate [-json] <trace_real> <trace_sintetico>")
+		fmt.Println("     go run main.go convert [-from text|proto] [-to text|proto] [-anonymize [-key K] [-map F]] <entrada> <saída>")
+		fmt.Println("     go run main.go convert split <entrada> <prefixo>")
+		fmt.Println("     go run main.go index [-every K] <trace>")
+		fmt.Println("     go run main.go convert merge [-ratio 3:1] [-offset N] <saída> <entrada> <entrada>...")
+		fmt.Println("     go run main.go serve [-ui] [-listen :8080] [-trace-dir DIR] [-max-jobs N] [-job-timeout 1m]")
+		fmt.Println("     go run main.go results migrate [-o saída.json] <resultados.json>")
+		fmt.Println("     go run main.go results compare <base.json> <atual.json>")
 		fmt.Println("Opções:")
 		fmt.Println("  -didactic     : Modo didático (mostra estado da memória)")
+		fmt.Println("  -didactic-range A-B : Narra só os acessos de A a B (modo didático e -lesson)")
+		fmt.Println("  -lesson ARQ   : Grava a narração como exemplo resolvido em Markdown")
+		fmt.Println("  -show-pagetable-live : Modo didático com a tabela de páginas (válida, frame, bits R e M) após cada falta")
 		fmt.Println("  -loadcount    : Mostra número de carregamentos por página")
 		fmt.Println("  -pagetable    : Mostra estimativa do tamanho da tabela de páginas")
+		fmt.Println("  -vaddr-bits N : Bits do endereço virtual usados na estimativa (padrão 32)")
+		fmt.Println("  -segment-limits L : Limites dos segmentos (S0=0x4000,S1=8192 ou arquivo com um por")
+		fmt.Println("                  linha); acessos S<n>:<deslocamento> além do limite são violações")
+		fmt.Println("  -page-radix B : Base dos números de página do trace e de -page-classes, 10 ou 16")
+		fmt.Println("                  (padrão 10; D0x1f é sempre hexadecimal)")
+		fmt.Println("  -pt-frames M  : Simula as páginas da tabela multinível ocupando frames (Relógio) e")
+		fmt.Println("                  compara as faltas: pinned (liberadas ao esvaziar) ou evictable")
+		fmt.Println("                  (vazias ficam até serem substituídas)")
+		fmt.Println("  -page-classes F       : Classifica páginas como mapeadas de arquivo ou anônimas")
+		fmt.Println("                          (linhas \"D100-D199 file\" ou \"D7 anon\"; o padrão é anônima)")
+		fmt.Println("  -swap-write-cost N    : Custo em µs de gravar uma página anônima no swap (padrão 8000)")
+		fmt.Println("  -file-write-cost N    : Custo em µs de gravar uma página modificada no arquivo (padrão 8000)")
+		fmt.Println("  -fault-read-cost N    : Custo em µs de ler do disco a página de uma falta (padrão 8000)")
+		fmt.Println("  -zero-fill-cost N     : Custo em µs de alocar e zerar o frame de uma falta de demanda-zero (padrão 50)")
+		fmt.Println("  -cost                 : Divide o custo simulado de cada acesso em um único balde: acerto na")
+		fmt.Println("                          TLB, falta na TLB + walk, falta menor, falta maior ou gravação da vítima")
+		fmt.Println("                          (TLB LRU com -tlb-entries; sem ela, a TLB é perfeita)")
+		fmt.Println("  -cost-json F          : Grava a divisão dos custos em JSON (implica -cost)")
+		fmt.Println("  -tlb-hit-cost N       : Custo em ns de uma tradução que acerta na TLB (padrão 1)")
+		fmt.Println("  -walk-cost N          : Custo em ns do percurso na tabela de páginas (padrão 100)")
+		fmt.Println("  -fault-dist D         : Sorteia o tempo de cada falta: constant, uniform ou lognormal")
+		fmt.Println("                          (média -fault-read-cost; relata percentis da espera; usa -seed)")
+		fmt.Println("  -fault-spread F       : Variação relativa da uniforme (padrão 0.5: média ± 50%)")
+		fmt.Println("  -fault-sigma F        : Desvio do logaritmo na lognormal (padrão 1)")
+		fmt.Println("  -format text|proto    : Formato do trace (proto: mensagens Access de proto/trace.proto)")
+		fmt.Println("  -reader auto|buffered|slice : Leitura do trace texto: linha a linha (buffered) ou o arquivo")
+		fmt.Println("                          inteiro na memória (slice); auto usa slice a partir de 4 MB")
+		fmt.Println("  -log-format text|json : Formato dos avisos, erros e do progresso, que vão para o stderr")
+		fmt.Println("  -log-level N          : Nível mínimo do log: debug, info (padrão), warn ou error")
+		fmt.Println("  -v                    : Nível debug: mostra todas as linhas inválidas, não só as 10 primeiras")
+		fmt.Println("  -quiet                : Nível warn: sem as mensagens de progresso")
+		fmt.Println("  -timeout D            : Interrompe a simulação depois de D (ex.: 30s, 5m), como o Ctrl-C:")
+		fmt.Println("                          mostra os resultados parciais e sai com código 124 (Ctrl-C: 130)")
+		fmt.Println("  -cache DIR            : Guarda o resultado de cada algoritmo em DIR e o reaproveita quando")
+		fmt.Println("                          o trace e as opções são os mesmos")
+		fmt.Println("  -no-cache             : Ignora o cache de -cache nesta execução")
+		fmt.Println("  -cache-clear          : Remove as entradas do cache de -cache antes de simular")
+		fmt.Println("  -checkpoint-every N   : Grava o estado da simulação a cada N acessos (aceita 10M) em")
+		fmt.Println("                          -checkpoint-dir DIR; Ótimo, Relógio, FIFO e LRU")
+		fmt.Println("  -resume               : Continua do último checkpoint válido de -checkpoint-dir (com as")
+		fmt.Println("                          mesmas opções da execução interrompida)")
+		fmt.Println("  -from-access M        : Simula a partir do acesso M, com a memória vazia; com o índice")
+		fmt.Println("                          de 'index' (trace.idx) o começo do arquivo não é interpretado")
+		fmt.Println("  -parse-workers N      : Trechos do trace interpretados em paralelo com o leitor slice")
+		fmt.Println("                          (padrão: um por processador a partir de 1 MB)")
+		fmt.Println("  -proto-out F          : Grava os resultados como uma mensagem Results em protobuf")
+		fmt.Println("  -json F               : Grava os resultados em JSON, no formato da resposta do serve; base")
+		fmt.Println("                          para 'results compare'")
+		fmt.Println("  -rle                  : Agrupa acessos repetidos à mesma página e os aplica de uma vez")
+		fmt.Println("  -warmup N|auto        : Exclui os N primeiros acessos (auto: até a memória encher) das")
+		fmt.Println("                          faltas, taxas e séries; as faltas do aquecimento aparecem à parte")
+		fmt.Println("  -bootstrap B          : IC de 95% da taxa de faltas reamostrando B blocos do trace")
+		fmt.Println("  -bootstrap-start M    : cold (cada bloco começa com a memória vazia, padrão) ou warm")
+		fmt.Println("                          (o bloco anterior aquece a memória sem contar faltas)")
+		fmt.Println("  -converge E           : Para cada algoritmo (exceto o Ótimo) quando o intervalo de 95% da")
+		fmt.Println("                          taxa de faltas fica mais estreito que E (ex.: 0.01); o resultado é estimado")
+		fmt.Println("  -watch                : Reexecuta ao alterar o trace e mostra a variação das faltas")
+		fmt.Println("  -final-state F        : Grava em JSON os frames, bits e ponteiro ao final de cada algoritmo")
+		fmt.Println("  -victims F            : Grava as páginas substituídas, em ordem, em F-<algoritmo>, uma por")
+		fmt.Println("                          linha (\"acesso página\", lido como trace)")
+		fmt.Println("  -events-csv F         : Grava em CSV uma linha por acesso de cada algoritmo (access_index,")
+		fmt.Println("                          page_id, type, algorithm, hit, victim, frame)")
+		fmt.Println("  -events-parquet F     : Grava os mesmos eventos em Parquet, comprimidos com gzip")
+		fmt.Println("  -parquet-row-group N  : Linhas por grupo do Parquet (padrão 65536)")
+		fmt.Println("  -parquet-dict         : Codifica page_id e victim com dicionário no Parquet")
+		fmt.Println("  -rss-interval K       : Amostra os frames residentes a cada K acessos")
+		fmt.Println("  -rss-threshold N      : Conta as amostras com N ou mais frames (padrão: memória cheia)")
+		fmt.Println("  -rss-csv F            : Grava as amostras de frames residentes em CSV")
+		fmt.Println("  -sets N               : Conjuntos do algoritmo setassoc (página no conjunto número mod N; padrão 2)")
+		fmt.Println("  -assoc N              : Frames por conjunto do setassoc (substitui -sets)")
+		fmt.Println("  -cache-size N         : Tamanho em bytes da cache; relata a distribuição pelas cores de página")
+		fmt.Println("  -cache-assoc N        : Vias da cache (padrão 8)")
+		fmt.Println("  -cache-line N         : Tamanho da linha da cache em bytes (padrão 64)")
+		fmt.Println("  -color-aware          : Relógio ocupa frames vazios da cor menos acessada")
+		fmt.Println("  -numa-nodes N         : Nós NUMA do algoritmo numa (padrão 2)")
+		fmt.Println("  -numa-latency L       : Latência em ns de cada nó (padrão 100,160)")
+		fmt.Println("  -numa-placement P     : Nó das páginas que faltam: type (I no nó 0, D no nó 1),")
+		fmt.Println("                          rr (rodízio) ou first (nó da primeira carga)")
+		fmt.Println("  -numa-local           : Substitui apenas dentro do nó escolhido (padrão: global)")
+		fmt.Println("  -optimal-clean: Compara o Ótimo com a variante que prefere vítimas limpas nos empates")
+		fmt.Println("  -seed N       : Semente dos algoritmos aleatórios (padrão 1)")
+		fmt.Println("  -truncate-at-phase N  : Corta o trace no fim da N-ésima fase detectada (mudança do conjunto")
+		fmt.Println("                          de páginas usadas) em vez de um número fixo de acessos")
+		fmt.Println("  -phase-window W       : Janela da detecção de fases, em acessos (padrão 1000)")
+		fmt.Println("  -weight I=P,D=P       : Pesos das faltas por tipo de página (padrão 1); a comparação mostra")
+		fmt.Println("                          as faltas ponderadas e a ordem dos algoritmos por elas, e -cost")
+		fmt.Println("                          multiplica o custo de cada falta pelo peso")
+		fmt.Println("  -patterns             : Classifica as páginas pelo padrão de acesso (uso único, laço, varredura,")
+		fmt.Println("                          rajada, estável) e divide as faltas de cada algoritmo por padrão")
+		fmt.Println("  -pattern-thresholds T : Limiares da classificação (padrão scan=0.5,loop=0.25,burst=1.5):")
+		fmt.Println("                          fração de visitas junto de páginas vizinhas para varredura e")
+		fmt.Println("                          coeficiente de variação dos intervalos para laço (até) e rajada (a partir)")
+		fmt.Println("  -patterns-csv F       : Grava em CSV as medidas e o padrão de cada página (implica -patterns)")
+		fmt.Println("  -trials N             : Executa random, randunref, nru e hyperbolic N vezes com sementes")
+		fmt.Println("                          derivadas de -seed e mostra média, desvio padrão, mínimo e máximo das faltas")
+		fmt.Println("  -trials-json F        : Grava em JSON as faltas de cada repetição (com -trials)")
+		fmt.Println("  -hyperbolic-samples N : Frames amostrados por substituição no Hiperbólico (padrão 8)")
+		fmt.Println("  -nru-interval N       : Acessos entre as limpezas do bit R no NRU (padrão 100; 0 nunca limpa)")
+		fmt.Println("  -wsclock-tau N        : Idade em acessos a partir da qual o WSClock tira a página (padrão 1000)")
+		fmt.Println("  -ws-window N          : Janela Δ do Working Set em acessos (padrão 1000, até 2^30); a página sai da memória")
+		fmt.Println("                          Δ acessos depois do último uso e os frames ocupados variam (use")
+		fmt.Println("                          -rss-interval e -rss-csv para compará-los com os do Relógio)")
+		fmt.Println("  -policy-expr E        : Algoritmo expr: substitui o frame de maior pontuação E (padrão \"age\"),")
+		fmt.Println("                          com + - * / e parênteses sobre as variáveis de cada frame:")
+		fmt.Println("                            age (acessos desde a carga), idle (desde o último uso),")
+		fmt.Println("                            refbit (usada desde a última substituição), dirty (bit M),")
+		fmt.Println("                            accesses (usos desde a carga), loads (cargas da página)")
+		fmt.Println("                          Ex.: \"age\" (FIFO), \"idle\" (LRU), \"idle - accesses*1000000\" (LFU)")
+		fmt.Println("  -clock-variant V      : Variante do Relógio:")
+		fmt.Println("                            classic - o ponteiro só avança ao passar por um frame, como no")
+		fmt.Println("                                      relógio de Tanenbaum (padrão)")
+		fmt.Println("                            advance - também avança ao ocupar um frame vazio, como na fila")
+		fmt.Println("                                      circular da segunda chance de Silberschatz; as faltas")
+		fmt.Println("                                      são as do classic, muda só o ponteiro exibido")
+		fmt.Println("                            restart - cada busca por vítima recomeça no frame 0, como em")
+		fmt.Println("                                      listas de exercícios (muda as faltas)")
+		fmt.Println("  -clock-adaptive       : Relógio com varredura proporcional à taxa de faltas recente")
+		fmt.Println("  -clock-sweep-min N    : Frames inspecionados por falta sem pressão (padrão 1)")
+		fmt.Println("  -clock-sweep-max N    : Frames inspecionados por falta sob pressão máxima (padrão: todos)")
+		fmt.Println("  -clock-pressure-window N : Acessos usados para medir a taxa de faltas (padrão 100)")
+		fmt.Println("  -ref-clear-interval N : Limpa os bits R de todos os frames a cada N acessos (Relógio)")
+		fmt.Println("  -ref-clear-mode M     : both (ponteiro e interrupção, padrão) ou timer (só a interrupção)")
+		fmt.Println("  -ref-clear-sweep L    : Compara as faltas do Relógio para os intervalos da lista (ex.: 0,10,100)")
+		fmt.Println("  -sweep-tau L          : Faltas, working set médio e fração acima da memória do Working Set")
+		fmt.Println("                          para cada τ da lista (ex.: 1k,10k,100k,1M), apontando o joelho")
+		fmt.Println("                          ou, com durações (ex.: 500ns,5us,1ms), τ sobre o tempo dos acessos")
+		fmt.Println("  -access-time D        : Tempo entre acessos quando o trace não tem a última coluna +N")
+		fmt.Println("                          (ns até o próximo acesso, ex.: \"D7 W +120\"); padrão 1ns. Com o")
+		fmt.Println("                          tempo o relatório mostra a duração do trace e as faltas por ms")
+		fmt.Println("  -sweep-tau-csv F      : Grava a varredura de τ em CSV")
+		fmt.Println("  -target-faults N      : Menor memória com até N faltas para cada algoritmo selecionado")
+		fmt.Println("  -target-rate P        : Idem, com a taxa de faltas (ex.: 0.5% ou 0.005)")
+		fmt.Println("  -target-json F        : Grava as recomendações e as sondagens em JSON")
+		fmt.Println("  -page-timeline L      : Acessos, substituições e residência das páginas da lista (ex.: D1,I7)")
+		fmt.Println("  -top-faulted N        : Inclui na linha do tempo as N páginas com mais faltas de cada algoritmo")
+		fmt.Println("  -out ARQ              : Grava a linha do tempo em CSV")
+		fmt.Println("  -heatmap ARQ          : Grava em SVG o conteúdo dos frames ao longo do tempo (uma cor por página)")
+		fmt.Println("  -animate DIR          : Grava um SVG por passo em DIR/<algoritmo>/ e um index.html para folheá-los")
+		fmt.Println("  -animate-every E      : access (padrão), fault ou N (um quadro a cada N acessos)")
+		fmt.Println("  -animate-range A-B    : Só os acessos de A a B")
+		fmt.Println("  -animate-max N        : Máximo de quadros por algoritmo (padrão 500)")
+		fmt.Println("  -mrc                  : Faltas do Ótimo para todos os números de frames, numa única passada")
+		fmt.Println("  -mrc-csv ARQ          : Como -mrc, gravando a curva completa em CSV")
+		fmt.Println("  -tlb-entries N: Mostra o alcance de uma TLB com N entradas")
+		fmt.Println("  -tlb-window N : Janela (em acessos) do conjunto de trabalho na análise da TLB (padrão 1000)")
+		fmt.Println("  -framestats   : Mostra substituições e páginas distintas por frame")
+		fmt.Println("  -frame-table  : Mostra a tabela de frames ao final (página, bits R e M, cargas, classe,")
+		fmt.Println("                  mapeadores); o tamanho da tabela de frames aparece em -pagetable")
 		fmt.Println("  -skipoptimal  : Pula algoritmo ótimo (para arquivos muito grandes)")
+		fmt.Println("  -no-estimate  : Não estima o tempo de execução (útil em scripts)")
+		fmt.Printf("  -algorithms L : Algoritmos executados (padrão optimal,clock,lru,fifo; disponíveis: optimal, %s)\n",
+			strings.Join(policyNames(), ", "))
+		fmt.Println("  -quiz         : Modo quiz (prever hit/falta e vítima de cada acesso)")
+		fmt.Println("  -quiz-auto    : Quiz com respostas sorteadas (para testes, sem interação)")
+		fmt.Println("  -tui          : Simulação ao vivo no terminal (espaço pausa, n avança, +/- velocidade, q sai)")
+		fmt.Println("  -tui-rate N   : Acessos por segundo no modo -tui (padrão 5)")
+		fmt.Println("  -playback Fx  : No modo didático (em terminal) e em -tui, espera entre os acessos o tempo")
+		fmt.Println("                  do trace (+N, ou -access-time; padrão 1s) dividido por F (ex.: 100x)")
+		fmt.Println()
+		fmt.Println("Cada linha do arquivo pode terminar com R (leitura), W (escrita) ou Z (escrita em página")
+		fmt.Println("de demanda-zero, preenchida com zeros sem ler o disco).")
 		fmt.Println()
 		fmt.Println("Exemplos de tamanho de memória:")
 		fmt.Println("  8192          : 8 KB")
@@ -401,17 +13009,19 @@ func main() {
 	filename := os.Args[1]
 	memorySize, err := strconv.Atoi(os.Args[2])
 	if err != nil {
-		fmt.Printf("Erro: tamanho de memória inválido: %s\n", os.Args[2])
+		logger.Error("tamanho de memória inválido", "value", os.Args[2])
 		return
 	}
 
 	if memorySize < PAGE_SIZE {
-		fmt.Printf("Erro: tamanho de memória muito pequeno (%d bytes).\n", memorySize)
-		fmt.Printf("Tamanho mínimo necessário: %d bytes (1 página de 4KB)\n", PAGE_SIZE)
+		logger.Error("tamanho de memória muito pequeno", "memory", memorySize, "minimum", PAGE_SIZE)
 		return
 	}
 
 	simulator := NewSimulator(memorySize)
+	quiz, quizAuto, watch, tui := false, false, false, false
+	var timeout time.Duration
+	var pageClasses string // lido depois das opções, já com a base de -page-radix
 
 	for i := 3; i < len(os.Args); i++ {
 		switch os.Args[i] {
@@ -419,28 +13029,1044 @@ func main() {
 			simulator.didacticMode = true
 			simulator.showLoadCount = true
 			simulator.showPageTable = true
+			simulator.showFrameStats = true
 			break
 		case "-didactic":
 			simulator.didacticMode = true
+		case "-didactic-range":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um intervalo A-B", "option", "-didactic-range")
+				return
+			}
+			i++
+			from, to, ok := strings.Cut(os.Args[i], "-")
+			first, err1 := strconv.Atoi(from)
+			last, err2 := strconv.Atoi(to)
+			if !ok || err1 != nil || err2 != nil || first < 1 || last < first {
+				logger.Error("intervalo de acessos inválido (use A-B, a partir de 1)", "value", os.Args[i])
+				return
+			}
+			simulator.didacticFrom, simulator.didacticTo = first, last
+		case "-lesson":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um arquivo", "option", "-lesson")
+				return
+			}
+			i++
+			simulator.lessonFile = os.Args[i]
+		case "-show-pagetable-live":
+			simulator.didacticMode = true
+			simulator.showPageTableLive = true
 		case "-loadcount":
 			simulator.showLoadCount = true
+		case "-frame-table":
+			simulator.showFrameTableFlag = true
+		case "-pt-frames":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer pinned ou evictable", "option", "-pt-frames")
+				return
+			}
+			i++
+			if os.Args[i] != "pinned" && os.Args[i] != "evictable" {
+				logger.Error("modo inválido para -pt-frames (use pinned ou evictable)", "value", os.Args[i])
+				return
+			}
+			simulator.ptFrames = os.Args[i]
 		case "-pagetable":
 			simulator.showPageTable = true
+		case "-framestats":
+			simulator.showFrameStats = true
 		case "-skipoptimal":
 			simulator.skipOptimal = true
+		case "-no-estimate":
+			simulator.noEstimate = true
+		case "-page-radix":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-page-radix")
+				return
+			}
+			i++
+			radix, err := strconv.Atoi(os.Args[i])
+			if err != nil || (radix != 10 && radix != 16) {
+				logger.Error("base de página inválida (use 10 ou 16)", "value", os.Args[i])
+				return
+			}
+			simulator.pageRadix = radix
+		case "-segment-limits":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-segment-limits")
+				return
+			}
+			i++
+			text := os.Args[i]
+			if data, err := os.ReadFile(text); err == nil {
+				text = string(data)
+			}
+			limits, err := parseSegmentLimits(text)
+			if err != nil {
+				logger.Error("tabela de limites de segmento inválida", "value", os.Args[i], "err", err)
+				return
+			}
+			simulator.segmentLimits = limits
+		case "-vaddr-bits":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-vaddr-bits")
+				return
+			}
+			i++
+			vaddrBits, err := strconv.Atoi(os.Args[i])
+			if err != nil || vaddrBits <= bits.TrailingZeros(PAGE_SIZE) || vaddrBits > 64 {
+				logger.Error("número de bits de endereço virtual inválido", "value", os.Args[i])
+				return
+			}
+			simulator.vaddrBits = vaddrBits
+		case "-algorithms":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer uma lista de algoritmos", "option", "-algorithms")
+				return
+			}
+			i++
+			simulator.algorithms = nil
+			for _, name := range strings.Split(os.Args[i], ",") {
+				name = strings.TrimSpace(name)
+				if _, ok := findPolicy(name); !ok && name != "optimal" {
+					logger.Error("algoritmo desconhecido", "value", name,
+						"available", "optimal, "+strings.Join(policyNames(), ", "))
+					return
+				}
+				simulator.algorithms = append(simulator.algorithms, name)
+			}
+		case "-page-classes":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um arquivo", "option", "-page-classes")
+				return
+			}
+			i++
+			pageClasses = os.Args[i]
+		case "-swap-write-cost", "-file-write-cost":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", os.Args[i])
+				return
+			}
+			cost, err := strconv.Atoi(os.Args[i+1])
+			if err != nil || cost < 0 {
+				logger.Error("custo inválido", "option", os.Args[i], "value", os.Args[i+1])
+				return
+			}
+			if os.Args[i] == "-swap-write-cost" {
+				simulator.swapWriteCost = cost
+			} else {
+				simulator.fileWriteCost = cost
+			}
+			i++
+		case "-cost":
+			simulator.costReport = true
+		case "-cost-json":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um arquivo", "option", "-cost-json")
+				return
+			}
+			i++
+			simulator.costReport = true
+			simulator.costJSON = os.Args[i]
+		case "-tlb-hit-cost", "-walk-cost":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", os.Args[i])
+				return
+			}
+			cost, err := strconv.Atoi(os.Args[i+1])
+			if err != nil || cost < 0 {
+				logger.Error("custo inválido", "option", os.Args[i], "value", os.Args[i+1])
+				return
+			}
+			if os.Args[i] == "-tlb-hit-cost" {
+				simulator.tlbHitCost = cost
+			} else {
+				simulator.walkCost = cost
+			}
+			i++
+		case "-fault-read-cost", "-zero-fill-cost":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", os.Args[i])
+				return
+			}
+			cost, err := strconv.Atoi(os.Args[i+1])
+			if err != nil || cost < 0 {
+				logger.Error("custo inválido", "option", os.Args[i], "value", os.Args[i+1])
+				return
+			}
+			if os.Args[i] == "-fault-read-cost" {
+				simulator.faultReadCost = cost
+			} else {
+				simulator.zeroFillCost = cost
+			}
+			i++
+		case "-sets":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-sets")
+				return
+			}
+			i++
+			sets, err := strconv.Atoi(os.Args[i])
+			if err != nil || sets < 1 {
+				logger.Error("número de conjuntos inválido", "value", os.Args[i])
+				return
+			}
+			simulator.sets = sets
+		case "-assoc":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-assoc")
+				return
+			}
+			i++
+			assoc, err := strconv.Atoi(os.Args[i])
+			if err != nil || assoc < 1 {
+				logger.Error("associatividade inválida", "value", os.Args[i])
+				return
+			}
+			simulator.associativity = assoc
+		case "-cache-size":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-cache-size")
+				return
+			}
+			i++
+			cacheSize, err := strconv.Atoi(os.Args[i])
+			if err != nil || cacheSize < 1 {
+				logger.Error("tamanho da cache inválido", "value", os.Args[i])
+				return
+			}
+			simulator.cacheSize = cacheSize
+		case "-cache-assoc":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-cache-assoc")
+				return
+			}
+			i++
+			cacheAssoc, err := strconv.Atoi(os.Args[i])
+			if err != nil || cacheAssoc < 1 {
+				logger.Error("associatividade da cache inválida", "value", os.Args[i])
+				return
+			}
+			simulator.cacheAssoc = cacheAssoc
+		case "-cache-line":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-cache-line")
+				return
+			}
+			i++
+			cacheLine, err := strconv.Atoi(os.Args[i])
+			if err != nil || cacheLine < 1 {
+				logger.Error("tamanho de linha inválido", "value", os.Args[i])
+				return
+			}
+			simulator.cacheLine = cacheLine
+		case "-color-aware":
+			simulator.colorAware = true
+		case "-fault-dist":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-fault-dist")
+				return
+			}
+			i++
+			switch os.Args[i] {
+			case "constant", "uniform", "lognormal":
+				simulator.faultDist = os.Args[i]
+			default:
+				logger.Error("distribuição desconhecida (use constant, uniform ou lognormal)", "value", os.Args[i])
+				return
+			}
+		case "-fault-spread", "-fault-sigma":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", os.Args[i])
+				return
+			}
+			value, err := strconv.ParseFloat(os.Args[i+1], 64)
+			if err != nil || value < 0 || (os.Args[i] == "-fault-spread" && value > 1) {
+				logger.Error("valor inválido", "option", os.Args[i], "value", os.Args[i+1])
+				return
+			}
+			if os.Args[i] == "-fault-spread" {
+				simulator.faultSpread = value
+			} else {
+				simulator.faultSigma = value
+			}
+			i++
+		case "-warmup":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um número de acessos ou auto", "option", "-warmup")
+				return
+			}
+			i++
+			if os.Args[i] == "auto" {
+				simulator.warmupAuto = true
+				break
+			}
+			warmup, err := strconv.Atoi(os.Args[i])
+			if err != nil || warmup < 0 {
+				logger.Error("aquecimento inválido", "value", os.Args[i])
+				return
+			}
+			simulator.warmup = warmup
+		case "-bootstrap":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer o número de blocos", "option", "-bootstrap")
+				return
+			}
+			i++
+			blocks, err := strconv.Atoi(os.Args[i])
+			if err != nil || blocks < 2 {
+				logger.Error("número de blocos inválido (mínimo 2)", "value", os.Args[i])
+				return
+			}
+			simulator.bootstrapBlocks = blocks
+		case "-bootstrap-start":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer cold ou warm", "option", "-bootstrap-start")
+				return
+			}
+			i++
+			switch os.Args[i] {
+			case "cold", "warm":
+				simulator.bootstrapWarm = os.Args[i] == "warm"
+			default:
+				logger.Error("início desconhecido (use cold ou warm)", "value", os.Args[i])
+				return
+			}
+		case "-converge":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-converge")
+				return
+			}
+			i++
+			epsilon, err := strconv.ParseFloat(os.Args[i], 64)
+			if err != nil || epsilon <= 0 || epsilon >= 1 {
+				logger.Error("largura de intervalo inválida (use um valor entre 0 e 1)", "value", os.Args[i])
+				return
+			}
+			simulator.convergeEpsilon = epsilon
+		case "-rss-interval", "-rss-threshold":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", os.Args[i])
+				return
+			}
+			value, err := strconv.Atoi(os.Args[i+1])
+			if err != nil || value < 1 {
+				logger.Error("valor inválido", "option", os.Args[i], "value", os.Args[i+1])
+				return
+			}
+			if os.Args[i] == "-rss-interval" {
+				simulator.seriesInterval = value
+			} else {
+				simulator.rssThreshold = value
+			}
+			i++
+		case "-format":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-format")
+				return
+			}
+			i++
+			if os.Args[i] != "text" && os.Args[i] != "proto" {
+				logger.Error("formato desconhecido (use text ou proto)", "value", os.Args[i])
+				return
+			}
+			simulator.traceFormat = os.Args[i]
+		case "-from-access":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um número de acesso", "option", "-from-access")
+				return
+			}
+			i++
+			value, err := parseCount(os.Args[i])
+			if err != nil || value < 1 {
+				logger.Error("acesso inválido", "value", os.Args[i])
+				return
+			}
+			simulator.fromAccess = value
+		case "-parse-workers":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-parse-workers")
+				return
+			}
+			i++
+			value, err := strconv.Atoi(os.Args[i])
+			if err != nil || value < 0 {
+				logger.Error("número de leitores inválido", "value", os.Args[i])
+				return
+			}
+			simulator.parseWorkers = value
+		case "-reader":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-reader")
+				return
+			}
+			i++
+			if os.Args[i] != "auto" && os.Args[i] != "buffered" && os.Args[i] != "slice" {
+				logger.Error("leitor desconhecido (use auto, buffered ou slice)", "value", os.Args[i])
+				return
+			}
+			simulator.traceReader = os.Args[i]
+		case "-proto-out":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um arquivo", "option", "-proto-out")
+				return
+			}
+			i++
+			simulator.protoOut = os.Args[i]
+		case "-final-state":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um arquivo", "option", "-final-state")
+				return
+			}
+			i++
+			simulator.finalStateFile = os.Args[i]
+		case "-rss-csv":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um arquivo", "option", "-rss-csv")
+				return
+			}
+			i++
+			simulator.rssCSV = os.Args[i]
+		case "-numa-nodes":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-numa-nodes")
+				return
+			}
+			i++
+			nodes, err := strconv.Atoi(os.Args[i])
+			if err != nil || nodes < 1 {
+				logger.Error("número de nós inválido", "value", os.Args[i])
+				return
+			}
+			simulator.numaNodes = nodes
+		case "-numa-latency":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer uma lista de latências", "option", "-numa-latency")
+				return
+			}
+			i++
+			simulator.numaLatency = nil
+			for _, field := range strings.Split(os.Args[i], ",") {
+				latency, err := strconv.Atoi(strings.TrimSpace(field))
+				if err != nil || latency < 0 {
+					logger.Error("latência inválida", "value", field)
+					return
+				}
+				simulator.numaLatency = append(simulator.numaLatency, latency)
+			}
+		case "-numa-placement":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-numa-placement")
+				return
+			}
+			i++
+			switch os.Args[i] {
+			case "type", "rr", "first":
+				simulator.numaPlacement = os.Args[i]
+			default:
+				logger.Error("colocação desconhecida (use type, rr ou first)", "value", os.Args[i])
+				return
+			}
+		case "-numa-local":
+			simulator.numaLocal = true
+		case "-optimal-clean":
+			simulator.optimalPreferClean = true
+		case "-seed":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-seed")
+				return
+			}
+			i++
+			seed, err := strconv.ParseInt(os.Args[i], 10, 64)
+			if err != nil {
+				logger.Error("semente inválida", "value", os.Args[i])
+				return
+			}
+			simulator.seed = seed
+		case "-truncate-at-phase", "-phase-window":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", os.Args[i])
+				return
+			}
+			value, err := strconv.Atoi(os.Args[i+1])
+			if err != nil || value < 1 || (os.Args[i] == "-phase-window" && value < 2) {
+				logger.Error("valor inválido", "option", os.Args[i], "value", os.Args[i+1])
+				return
+			}
+			if os.Args[i] == "-truncate-at-phase" {
+				simulator.truncatePhase = value
+			} else {
+				simulator.phaseWindow = value
+			}
+			i++
+		case "-weight":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer os pesos", "option", "-weight")
+				return
+			}
+			i++
+			weights, err := parseFaultWeights(os.Args[i])
+			if err != nil {
+				logger.Error("pesos inválidos", "value", os.Args[i], "err", err)
+				return
+			}
+			simulator.weights, simulator.weighted = weights, true
+		case "-patterns":
+			simulator.patterns = true
+		case "-pattern-thresholds":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer os limiares", "option", "-pattern-thresholds")
+				return
+			}
+			i++
+			th, err := parsePatternThresholds(os.Args[i])
+			if err != nil {
+				logger.Error("limiares inválidos", "value", os.Args[i], "err", err)
+				return
+			}
+			simulator.patternThresholds, simulator.patterns = th, true
+		case "-patterns-csv":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um arquivo", "option", "-patterns-csv")
+				return
+			}
+			i++
+			simulator.patternsCSV, simulator.patterns = os.Args[i], true
+		case "-trials":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer o número de repetições", "option", "-trials")
+				return
+			}
+			i++
+			trials, err := strconv.Atoi(os.Args[i])
+			if err != nil || trials < 1 {
+				logger.Error("número de repetições inválido", "value", os.Args[i])
+				return
+			}
+			simulator.trials = trials
+		case "-victims":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um arquivo", "option", "-victims")
+				return
+			}
+			i++
+			simulator.victimsFile = os.Args[i]
+		case "-events-csv", "-events-parquet":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um arquivo", "option", os.Args[i])
+				return
+			}
+			if os.Args[i] == "-events-csv" {
+				simulator.eventsCSV = os.Args[i+1]
+			} else {
+				simulator.eventsParquet = os.Args[i+1]
+			}
+			i++
+		case "-parquet-row-group":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-parquet-row-group")
+				return
+			}
+			i++
+			rows, err := strconv.Atoi(os.Args[i])
+			if err != nil || rows < 1 {
+				logger.Error("tamanho de grupo inválido", "value", os.Args[i])
+				return
+			}
+			simulator.parquetRowGroup = rows
+		case "-parquet-dict":
+			simulator.parquetDictionary = true
+		case "-trials-json":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um arquivo", "option", "-trials-json")
+				return
+			}
+			i++
+			simulator.trialsJSON = os.Args[i]
+		case "-json":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um arquivo", "option", "-json")
+				return
+			}
+			i++
+			simulator.resultsJSON = os.Args[i]
+		case "-cache":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um diretório", "option", "-cache")
+				return
+			}
+			i++
+			simulator.cacheDir = os.Args[i]
+		case "-no-cache":
+			simulator.noCache = true
+		case "-cache-clear":
+			simulator.cacheClear = true
+		case "-checkpoint-every":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-checkpoint-every")
+				return
+			}
+			i++
+			every, err := parseCount(os.Args[i])
+			if err != nil || every < 1 {
+				logger.Error("intervalo de checkpoint inválido", "value", os.Args[i])
+				return
+			}
+			simulator.checkpointEvery = every
+		case "-checkpoint-dir":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um diretório", "option", "-checkpoint-dir")
+				return
+			}
+			i++
+			simulator.checkpointDir = os.Args[i]
+		case "-resume":
+			simulator.resume = true
+		case "-timeout":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer uma duração (ex.: 30s, 5m)", "option", "-timeout")
+				return
+			}
+			i++
+			value, err := time.ParseDuration(os.Args[i])
+			if err != nil || value <= 0 {
+				logger.Error("duração inválida", "value", os.Args[i])
+				return
+			}
+			timeout = value
+		case "-policy-expr":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer uma expressão", "option", "-policy-expr")
+				return
+			}
+			i++
+			score, err := compileExpr(os.Args[i])
+			if err != nil {
+				if exprErr, ok := err.(*exprError); ok {
+					logger.Error("expressão inválida", "option", "-policy-expr", "position", exprErr.Pos+1, "reason", exprErr.Msg)
+					if !logJSON {
+						fmt.Fprintln(os.Stderr, exprErr.Caret(os.Args[i]))
+					}
+				} else {
+					logger.Error("expressão inválida", "option", "-policy-expr", "reason", err)
+				}
+				return
+			}
+			simulator.policyExpr, simulator.policyExprText = score, os.Args[i]
+		case "-hyperbolic-samples":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-hyperbolic-samples")
+				return
+			}
+			i++
+			samples, err := strconv.Atoi(os.Args[i])
+			if err != nil || samples < 1 {
+				logger.Error("número de amostras inválido", "value", os.Args[i])
+				return
+			}
+			simulator.hyperbolicSamples = samples
+		case "-nru-interval":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-nru-interval")
+				return
+			}
+			i++
+			interval, err := strconv.Atoi(os.Args[i])
+			if err != nil || interval < 0 {
+				logger.Error("intervalo inválido", "value", os.Args[i])
+				return
+			}
+			simulator.nruInterval = interval
+		case "-wsclock-tau":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-wsclock-tau")
+				return
+			}
+			i++
+			tau, err := parseCount(os.Args[i])
+			if err != nil || tau < 0 {
+				logger.Error("τ inválido", "value", os.Args[i])
+				return
+			}
+			simulator.wsClockTau = tau
+		case "-ws-window":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-ws-window")
+				return
+			}
+			i++
+			window, err := parseCount(os.Args[i])
+			if err != nil || window < 1 || window > maxWSWindow {
+				logger.Error("janela inválida", "value", os.Args[i], "max", maxWSWindow)
+				return
+			}
+			simulator.wsWindow = window
+		case "-clock-variant":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-clock-variant")
+				return
+			}
+			i++
+			switch os.Args[i] {
+			case "classic", "advance", "restart":
+				simulator.clockVariant = os.Args[i]
+			default:
+				logger.Error("variante do relógio desconhecida (use classic, advance ou restart)", "value", os.Args[i])
+				return
+			}
+		case "-clock-adaptive":
+			simulator.clockAdaptive = true
+		case "-clock-sweep-min", "-clock-sweep-max", "-clock-pressure-window":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", os.Args[i])
+				return
+			}
+			value, err := strconv.Atoi(os.Args[i+1])
+			if err != nil || value < 1 {
+				logger.Error("valor inválido", "option", os.Args[i], "value", os.Args[i+1])
+				return
+			}
+			switch os.Args[i] {
+			case "-clock-sweep-min":
+				simulator.clockSweepMin = value
+			case "-clock-sweep-max":
+				simulator.clockSweepMax = value
+			default:
+				simulator.clockPressureWindow = value
+			}
+			i++
+		case "-ref-clear-interval":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-ref-clear-interval")
+				return
+			}
+			i++
+			interval, err := strconv.Atoi(os.Args[i])
+			if err != nil || interval < 0 {
+				logger.Error("intervalo inválido", "value", os.Args[i])
+				return
+			}
+			simulator.refClearInterval = interval
+		case "-ref-clear-mode":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-ref-clear-mode")
+				return
+			}
+			i++
+			switch os.Args[i] {
+			case "both":
+				simulator.refClearOnlyTimer = false
+			case "timer":
+				simulator.refClearOnlyTimer = true
+			default:
+				logger.Error("modo de limpeza desconhecido (use both ou timer)", "value", os.Args[i])
+				return
+			}
+		case "-page-timeline":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer uma lista de páginas", "option", "-page-timeline")
+				return
+			}
+			i++
+			for _, page := range strings.Split(os.Args[i], ",") {
+				if page = strings.TrimSpace(page); page != "" {
+					simulator.timelinePages = append(simulator.timelinePages, page)
+				}
+			}
+		case "-top-faulted":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-top-faulted")
+				return
+			}
+			i++
+			n, err := strconv.Atoi(os.Args[i])
+			if err != nil || n < 1 {
+				logger.Error("número de páginas inválido", "value", os.Args[i])
+				return
+			}
+			simulator.topFaulted = n
+		case "-heatmap":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um arquivo", "option", "-heatmap")
+				return
+			}
+			i++
+			simulator.heatmapFile = os.Args[i]
+		case "-animate":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um diretório", "option", "-animate")
+				return
+			}
+			i++
+			simulator.animateDir = os.Args[i]
+		case "-animate-every":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer access, fault ou um número", "option", "-animate-every")
+				return
+			}
+			i++
+			switch os.Args[i] {
+			case "access":
+				simulator.animate.Every, simulator.animate.FaultsOnly = 1, false
+			case "fault":
+				simulator.animate.FaultsOnly = true
+			default:
+				every, err := strconv.Atoi(os.Args[i])
+				if err != nil || every < 1 {
+					logger.Error("intervalo de quadros inválido", "value", os.Args[i])
+					return
+				}
+				simulator.animate.Every, simulator.animate.FaultsOnly = every, false
+			}
+		case "-animate-max":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-animate-max")
+				return
+			}
+			i++
+			limit, err := strconv.Atoi(os.Args[i])
+			if err != nil || limit < 1 {
+				logger.Error("limite de quadros inválido", "value", os.Args[i])
+				return
+			}
+			simulator.animate.Max = limit
+		case "-animate-range":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um intervalo A-B", "option", "-animate-range")
+				return
+			}
+			i++
+			from, to, ok := strings.Cut(os.Args[i], "-")
+			first, err1 := strconv.Atoi(from)
+			last, err2 := strconv.Atoi(to)
+			if !ok || err1 != nil || err2 != nil || first < 1 || last < first {
+				logger.Error("intervalo de acessos inválido (use A-B, a partir de 1)", "value", os.Args[i])
+				return
+			}
+			simulator.animate.From, simulator.animate.To = first, last
+		case "-out":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um arquivo", "option", "-out")
+				return
+			}
+			i++
+			simulator.timelineOut = os.Args[i]
+		case "-mrc":
+			simulator.showMRC = true
+		case "-mrc-csv":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um arquivo", "option", "-mrc-csv")
+				return
+			}
+			i++
+			simulator.showMRC = true
+			simulator.mrcCSV = os.Args[i]
+		case "-ref-clear-sweep":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer uma lista de intervalos", "option", "-ref-clear-sweep")
+				return
+			}
+			i++
+			for _, field := range strings.Split(os.Args[i], ",") {
+				interval, err := strconv.Atoi(strings.TrimSpace(field))
+				if err != nil || interval < 0 {
+					logger.Error("intervalo inválido", "value", field)
+					return
+				}
+				simulator.refClearSweep = append(simulator.refClearSweep, interval)
+			}
+		case "-sweep-tau":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer uma lista de valores de τ", "option", "-sweep-tau")
+				return
+			}
+			i++
+			// Números de acessos (10, 5k) ou durações (500ns, 2us, 1ms), sem misturar
+			for k, field := range strings.Split(os.Args[i], ",") {
+				field = strings.TrimSpace(field)
+				tau, err := parseCount(field)
+				inTime := false
+				if err != nil {
+					var d time.Duration
+					d, err = time.ParseDuration(field)
+					tau, inTime = int(d), true
+				}
+				if err != nil || tau < 1 || (k > 0 && inTime != simulator.sweepTauTime) {
+					logger.Error("τ inválido (use só números de acessos ou só durações)", "value", field)
+					return
+				}
+				simulator.sweepTau = append(simulator.sweepTau, tau)
+				simulator.sweepTauTime = inTime
+			}
+		case "-access-time":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-access-time")
+				return
+			}
+			i++
+			d, err := time.ParseDuration(os.Args[i])
+			if n, errCount := strconv.ParseInt(os.Args[i], 10, 64); errCount == nil {
+				d, err = time.Duration(n), nil // sem unidade: ns
+			}
+			if err != nil || d < 1 {
+				logger.Error("tempo entre acessos inválido", "value", os.Args[i])
+				return
+			}
+			simulator.accessTime = int64(d)
+		case "-target-faults", "-target-rate", "-target-json":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", os.Args[i])
+				return
+			}
+			switch os.Args[i] {
+			case "-target-faults":
+				value, err := parseCount(os.Args[i+1])
+				if err != nil || value < 0 {
+					logger.Error("orçamento de faltas inválido", "value", os.Args[i+1])
+					return
+				}
+				simulator.targetFaults = value
+			case "-target-rate":
+				text, scale := os.Args[i+1], 1.0
+				if trimmed, ok := strings.CutSuffix(text, "%"); ok {
+					text, scale = trimmed, 0.01
+				}
+				value, err := strconv.ParseFloat(text, 64)
+				if err != nil || value < 0 || value*scale > 1 {
+					logger.Error("taxa de faltas inválida", "value", os.Args[i+1])
+					return
+				}
+				simulator.targetRate = value * scale
+			case "-target-json":
+				simulator.targetJSON = os.Args[i+1]
+			}
+			i++
+		case "-sweep-tau-csv":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um nome de arquivo", "option", "-sweep-tau-csv")
+				return
+			}
+			i++
+			simulator.sweepTauCSV = os.Args[i]
+		case "-tlb-entries", "-tlb-window":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", os.Args[i])
+				return
+			}
+			value, err := strconv.Atoi(os.Args[i+1])
+			if err != nil || value < 1 {
+				logger.Error("valor inválido", "option", os.Args[i], "value", os.Args[i+1])
+				return
+			}
+			if os.Args[i] == "-tlb-entries" {
+				simulator.tlbEntries = value
+			} else {
+				simulator.tlbWindow = value
+			}
+			i++
+		case "-rle":
+			simulator.useRuns = true
+		case "-watch":
+			watch = true
+		case "-quiz":
+			quiz = true
+		case "-quiz-auto":
+			quiz, quizAuto = true, true
+		case "-tui":
+			tui = true
+		case "-playback":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um fator", "option", "-playback")
+				return
+			}
+			i++
+			factor, err := strconv.ParseFloat(strings.TrimSuffix(os.Args[i], "x"), 64)
+			if err != nil || factor <= 0 || factor > maxPlayback {
+				logger.Error("fator de reprodução inválido (ex.: 100x)", "value", os.Args[i])
+				return
+			}
+			simulator.playback = factor
+		case "-tui-rate":
+			if i+1 >= len(os.Args) {
+				logger.Error("opção requer um valor", "option", "-tui-rate")
+				return
+			}
+			i++
+			rate, err := strconv.ParseFloat(os.Args[i], 64)
+			if err != nil || rate <= 0 || rate > 1000 {
+				logger.Error("ritmo inválido (acessos por segundo, até 1000)", "value", os.Args[i])
+				return
+			}
+			simulator.tuiRate = rate
 		default:
-			fmt.Printf("Opção desconhecida: %s\n", os.Args[i])
+			logger.Warn("opção desconhecida ignorada", "option", os.Args[i])
 		}
 	}
+	if pageClasses != "" {
+		if err := simulator.LoadPageClasses(pageClasses); err != nil {
+			logger.Error("erro ao carregar classes de página", "file", pageClasses, "err", err)
+			return
+		}
+	}
+	if simulator.policyExpr != nil && !simulator.algorithmSelected("expr") {
+		simulator.algorithms = append(simulator.algorithms, "expr")
+	}
+	if simulator.checkpointEvery > 0 || simulator.resume {
+		option := "-resume"
+		if simulator.checkpointEvery > 0 {
+			option = "-checkpoint-every"
+		}
+		if simulator.checkpointDir == "" {
+			logger.Error("opção requer -checkpoint-dir DIR", "option", option)
+			return
+		}
+		if conflict := simulator.checkpointConflict(); conflict != "" {
+			logger.Error("opção não combina com os checkpoints", "option", option, "conflict", conflict)
+			return
+		}
+	}
+	if simulator.cacheClear {
+		if simulator.cacheDir == "" {
+			logger.Error("opção requer -cache DIR", "option", "-cache-clear")
+			return
+		}
+		removed, err := simulator.clearCache()
+		if err != nil {
+			logger.Error("erro ao limpar o cache", "dir", simulator.cacheDir, "err", err)
+			return
+		}
+		fmt.Printf("Cache %s limpo: %d entradas removidas\n", simulator.cacheDir, removed)
+	}
 
-	fmt.Printf("Carregando arquivo: %s\n", filename)
-	err = simulator.LoadAccessFile(filename)
+	logger.Info("carregando arquivo", "file", filename)
+	diagnostics, err := simulator.LoadAccessFile(filename)
+	diagnostics.log()
 	if err != nil {
-		fmt.Printf("Erro ao carregar arquivo: %v\n", err)
+		logger.Error("erro ao carregar arquivo", "file", filename, "err", err)
 		return
 	}
 
-	fmt.Printf("Arquivo carregado com sucesso!\n\n")
+	if quiz {
+		simulator.RunQuiz(os.Stdin, quizAuto)
+		return
+	}
+	if tui {
+		simulator.RunTUI()
+		return
+	}
 
+	stop := simulator.cancelOnInterrupt(timeout)
 	simulator.Run()
+	stop()
+	switch simulator.stopped {
+	case errInterrupted:
+		os.Exit(exitInterrupted)
+	case errTimeout:
+		os.Exit(exitTimeout)
+	}
+	simulator.ctx = nil
+	if watch {
+		simulator.Watch(filename)
+	}
 }
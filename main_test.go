@@ -0,0 +1,3370 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	paging "github.com/GabrielVGS/memory-management-go/proto"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+// Os avisos dos traces inválidos de propósito não interessam aos testes
+func TestMain(m *testing.M) {
+	logger = slog.New(slog.DiscardHandler)
+	os.Exit(m.Run())
+}
+
+// Traces aleatórios de TestPolicyProperties e o tamanho de cada um
+const (
+	propertyTrials = 200
+	propertyLength = 300
+)
+
+// Saída padrão produzida por run, para comparar mensagens
+func captureStdout(run func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		run()
+		return ""
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	run()
+	w.Close()
+	os.Stdout = stdout
+	return <-done
+}
+
+// Diagnósticos registrados durante run, em JSON sem o horário e com todos
+// os níveis
+func captureLog(run func()) string {
+	var b strings.Builder
+	saved := logger
+	logger = slog.New(slog.NewJSONHandler(&b, &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: withoutTime}))
+	defer func() { logger = saved }()
+	run()
+	return b.String()
+}
+
+// Referências para as expressões do -policy-expr, sobre a
+// lista de páginas residentes na ordem de carga: FIFO, LRU e LFU com
+// desempate pelo LRU
+func referenceRun(kind string, accesses []PageAccess, frames int) fixtureLine {
+	line := fixtureLine{Frames: frames, Algorithm: kind}
+	var resident []string
+	lastUse, uses := make(map[string]int), make(map[string]int)
+	for i, access := range accesses {
+		page := access.PageID
+		hit := false
+		for _, r := range resident {
+			hit = hit || r == page
+		}
+		if hit {
+			lastUse[page] = i
+			uses[page]++
+			continue
+		}
+		line.Faults++
+		if len(resident) == frames {
+			victim := 0 // FIFO: a carregada há mais tempo
+			for k, r := range resident {
+				older := resident[victim]
+				switch {
+				case kind == "lru" && lastUse[r] < lastUse[older]:
+					victim = k
+				case kind == "lfu" && (uses[r] < uses[older] || uses[r] == uses[older] && lastUse[r] < lastUse[older]):
+					victim = k
+				}
+			}
+			line.Victims = append(line.Victims, resident[victim])
+			resident = append(resident[:victim], resident[victim+1:]...)
+		}
+		resident = append(resident, page)
+		lastUse[page], uses[page] = i, 1
+	}
+	return line
+}
+
+// Referência para -cost: soma o custo de cada acesso fora do
+// aquecimento, com a TLB LRU como lista (a mais recente no fim)
+func referenceCost(s *Simulator, policy ReplacementPolicy) int64 {
+	var tlb []string
+	remove := func(page string) bool {
+		for k, p := range tlb {
+			if p == page {
+				tlb = append(tlb[:k], tlb[k+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+	insert := func(page string) {
+		if len(tlb) == s.tlbEntries {
+			tlb = tlb[1:]
+		}
+		tlb = append(tlb, page)
+	}
+	loads := make(map[string]int)
+	var total int64
+	for i, access := range s.accesses {
+		page := access.PageID
+		r := step(policy, access)
+		cost := int64(s.tlbHitCost)
+		switch {
+		case !r.Hit:
+			cost += int64(s.walkCost)
+			loads[page]++
+			if s.isZeroFill(access, loads[page]) {
+				cost += int64(s.zeroFillCost) * 1000
+			} else {
+				cost += int64(s.faultReadCost) * 1000
+			}
+			if r.WriteBack {
+				cost += int64(s.swapWriteCost) * 1000
+			}
+			if s.tlbEntries > 0 {
+				remove(r.Victim)
+				insert(page)
+			}
+		case s.tlbEntries > 0 && !remove(page):
+			cost += int64(s.walkCost)
+			insert(page)
+		case s.tlbEntries > 0:
+			tlb = append(tlb, page) // acerto: volta ao fim da lista
+		}
+		if i >= s.warmup {
+			total += cost
+		}
+	}
+	return total
+}
+
+// Executa a política conferindo a cada passo que o resultado é coerente
+// com os frames: nunca mais páginas residentes que frames e a página
+// acessada sempre na memória depois do acesso
+func checkedRun(t testing.TB, policy ReplacementPolicy, accesses []PageAccess, frames int) fixtureLine {
+	t.Helper()
+	var line fixtureLine
+	for i, access := range accesses {
+		result := step(policy, access)
+		if !result.Hit {
+			line.Faults++
+			if result.Victim != "" {
+				line.Victims = append(line.Victims, result.Victim)
+			}
+		}
+
+		resident := make(map[string]bool)
+		for _, frame := range policy.Frames() {
+			if frame == nil {
+				continue
+			}
+			if resident[frame.PageID] {
+				t.Errorf("acesso %d: página %s em dois frames", i+1, frame.PageID)
+			}
+			resident[frame.PageID] = true
+		}
+		if len(policy.Frames()) != frames || len(resident) > frames {
+			t.Errorf("acesso %d: %d páginas residentes em %d frames", i+1, len(resident), frames)
+		}
+		if !resident[access.PageID] {
+			t.Errorf("acesso %d: página %s não está na memória depois do acesso", i+1, access.PageID)
+		}
+		if result.Frame < 0 || result.Frame >= frames || policy.Frames()[result.Frame].PageID != access.PageID {
+			t.Errorf("acesso %d: frame %d informado não contém %s", i+1, result.Frame, access.PageID)
+		}
+	}
+	return line
+}
+
+// Trace sem fases: 100000 acessos a 16 páginas sorteadas
+func stationaryTrace() []PageAccess {
+	rng := rand.New(rand.NewSource(1))
+	trace := make([]PageAccess, 100000)
+	for i := range trace {
+		trace[i] = PageAccess{PageID: fmt.Sprintf("D%d", rng.Intn(16)), Type: "D"}
+	}
+	return trace
+}
+
+// Respostas do quiz para a página substituída: com ou sem o tipo e os
+// zeros à esquerda; vazia ou com o tipo errado não vale
+func TestPageAnswerMatches(t *testing.T) {
+	for _, c := range []struct {
+		answer, page string
+		want         bool
+	}{
+		{"D42", "D42", true}, {"d0042", "D42", true}, {"42", "D42", true}, {" 0042 ", "D42", true},
+		{"I42", "D42", false}, {"43", "D42", false}, {"0", "D0", true}, {"D0", "D0", true},
+		{"", "D0", false}, {"   ", "I0", false}, {"\n", "D0", false}, {"D", "D0", false},
+	} {
+		if got := pageAnswerMatches(c.answer, c.page); got != c.want {
+			t.Errorf("pageAnswerMatches(%q, %q) = %v, esperado %v", c.answer, c.page, got, c.want)
+		}
+	}
+}
+
+// Quiz do Relógio com 2 frames sobre D1 D2 D3 D1 D3: 5 perguntas de hit
+// ou falta e 2 de vítima. As respostas roteirizadas erram a falta com
+// frame vazio e a vítima da substituição direta; a vítima "1" vale por D1.
+// Com -quiz-auto as respostas sorteadas são ecoadas e a pontuação bate
+// com os acertos anunciados; uma entrada que acaba cedo conta como erro.
+func TestRunQuiz(t *testing.T) {
+	s := NewSimulator(2 * PAGE_SIZE)
+	s.LoadAccesses(strings.NewReader("D1\nD2\nD3\nD1\nD3\n"))
+
+	out := captureStdout(func() { s.RunQuiz(strings.NewReader("f\nh\nfalta\n1\nF\nD1\nh\n"), false) })
+	for _, line := range []string{
+		"Errado: foi uma falta de página.",
+		"Errado: a página substituída foi D2.",
+		"Acertos: 5 de 7 (71%)",
+		"  falta com frame vazio              1 erro(s)",
+		"  substituição direta                1 erro(s)",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("quiz roteirizado sem a linha %q:\n%s", line, out)
+		}
+	}
+
+	auto := captureStdout(func() { s.RunQuiz(nil, true) })
+	if again := captureStdout(func() { s.RunQuiz(nil, true) }); again != auto {
+		t.Errorf("quiz automático muda entre execuções")
+	}
+	correct := strings.Count(auto, "Correto!")
+	if strings.Count(auto, "Hit ou falta? (h/f) > ") != 5 || strings.Count(auto, "Qual página será substituída? > D") != 2 ||
+		!strings.Contains(auto, fmt.Sprintf("Acertos: %d de 7 (", correct)) {
+		t.Errorf("quiz automático com %d acertos:\n%s", correct, auto)
+	}
+
+	short := captureStdout(func() { s.RunQuiz(strings.NewReader("f\n"), false) })
+	if !strings.Contains(short, "Acertos: 1 de 7 (14%)") {
+		t.Errorf("quiz com entrada curta:\n%s", short)
+	}
+}
+
+// Cada exemplo embutido documenta no cabeçalho as faltas esperadas; o
+// simulador tem de reproduzir exatamente essas contagens
+func TestExampleFaultCounts(t *testing.T) {
+	names := map[string]string{"Ótimo": "optimal"}
+	for _, info := range streamingPolicies {
+		names[info.Label] = info.Name
+	}
+	documented := make(map[string]bool)
+	entries, err := exampleTraces.ReadDir("examples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		data, err := exampleTraces.ReadFile("examples/" + entry.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := NewSimulator(PAGE_SIZE)
+		if _, err := s.LoadAccesses(bytes.NewReader(data)); err != nil {
+			t.Fatalf("%s: %v", entry.Name(), err)
+		}
+		checked := 0
+		for _, line := range strings.Split(string(data), "\n") {
+			var frames, size int
+			header, counts, ok := strings.Cut(strings.TrimPrefix(line, "#"), ":")
+			if !ok {
+				continue
+			}
+			if n, _ := fmt.Sscanf(strings.TrimSpace(header), "%d frames (%d bytes)", &frames, &size); n != 2 {
+				continue
+			}
+			if size != frames*PAGE_SIZE {
+				t.Errorf("%s: %d frames documentados com %d bytes", entry.Name(), frames, size)
+			}
+			for _, count := range strings.Split(counts, ",") {
+				count = strings.TrimSpace(count)
+				split := strings.LastIndex(count, " ")
+				label, want := count[:split], 0
+				if _, err := fmt.Sscanf(count[split+1:], "%d", &want); err != nil {
+					t.Fatalf("%s: contagem ilegível %q", entry.Name(), count)
+				}
+				name, ok := names[label]
+				if !ok {
+					t.Fatalf("%s: algoritmo desconhecido %q", entry.Name(), label)
+				}
+				got, err := s.stepperFaults(s.accesses, frames, name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("%s, %d frames, %s: %d faltas, cabeçalho documenta %d", entry.Name(), frames, label, got, want)
+				}
+				documented[fmt.Sprintf("%s, %d frames, %s", entry.Name(), frames, label)] = true
+				checked++
+			}
+		}
+		if checked == 0 {
+			t.Errorf("%s: nenhuma contagem esperada no cabeçalho", entry.Name())
+		}
+	}
+
+	// A sequência dos livros-texto compara FIFO e LRU com o Ótimo: as
+	// contagens clássicas (FIFO 15/10, LRU 12/8) não podem sumir do cabeçalho
+	for _, frames := range []int{3, 4} {
+		for _, label := range []string{"Ótimo", "FIFO", "LRU"} {
+			if key := fmt.Sprintf("textbook.txt, %d frames, %s", frames, label); !documented[key] {
+				t.Errorf("%s: contagem não documentada no cabeçalho", key)
+			}
+		}
+	}
+}
+
+// Classificação das faltas: a primeira carga é fria, as seguintes são
+// recargas numeradas
+func TestFaultKind(t *testing.T) {
+	for _, c := range []struct {
+		loads int
+		want  string
+	}{
+		{0, "fria"}, {1, "fria"}, {2, "recarga, 2ª vez"}, {3, "recarga, 3ª vez"}, {12, "recarga, 12ª vez"},
+	} {
+		if got := faultKind(c.loads); got != c.want {
+			t.Errorf("faultKind(%d) = %q, esperado %q", c.loads, got, c.want)
+		}
+	}
+
+	// Com 2 frames o FIFO expulsa cada página antes de ela voltar: as três
+	// primeiras faltas são frias e as demais recargas, na ordem do trace
+	s := NewSimulator(2 * PAGE_SIZE)
+	s.LoadAccesses(strings.NewReader("D1\nD2\nD3\nD1\nD2\nD3\nD1\nD1\n"))
+	fifo := newFIFOPolicy(2)
+	var got []string
+	out := captureStdout(func() { s.runPolicy(fifo, &narrator{s: s, policy: fifo}) })
+	for _, line := range strings.Split(out, "\n") {
+		if _, kind, ok := strings.Cut(line, "Falta de página ("); ok {
+			got = append(got, strings.TrimSuffix(kind, ")"))
+		}
+	}
+	want := []string{"fria", "fria", "fria", "recarga, 2ª vez", "recarga, 2ª vez", "recarga, 2ª vez", "recarga, 3ª vez"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("narração: %q, esperado %q", got, want)
+	}
+
+	// O mesmo marcador nos eventos dos observadores
+	recorder := &coldRecorder{}
+	s.runPolicy(newFIFOPolicy(2), recorder)
+	if fmt.Sprint(recorder.cold) != "[true true true false false false false]" {
+		t.Errorf("FaultEvent.Cold: %v", recorder.cold)
+	}
+
+	// e na coluna carga do CSV da linha do tempo (-page-timeline D1 -out)
+	s.algorithms = []string{"fifo"}
+	s.timelinePages = []string{"D1"}
+	s.timelineOut = filepath.Join(t.TempDir(), "timeline.csv")
+	results := s.Simulate()
+	captureStdout(func() { s.ShowPageTimeline(results) })
+	file, err := os.Open(s.timelineOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, _ := csv.NewReader(file).ReadAll()
+	var loads []string
+	for _, record := range records {
+		if record[2] == "falta" {
+			loads = append(loads, record[3]+" "+record[7])
+		}
+	}
+	if got := strings.Join(loads, "|"); records[0][7] != "carga" || got != "1 fria|4 recarga, 2ª vez|7 recarga, 3ª vez" {
+		t.Errorf("linha do tempo de D1: %q", got)
+	}
+}
+
+// Registra, falta a falta, se a carga foi fria
+type coldRecorder struct {
+	cold []bool
+}
+
+func (c *coldRecorder) OnHit(HitEvent) error { return nil }
+func (c *coldRecorder) OnFault(e FaultEvent) error {
+	c.cold = append(c.cold, e.Cold)
+	return nil
+}
+func (c *coldRecorder) OnEvict(EvictEvent) error       { return nil }
+func (c *coldRecorder) OnComplete(CompleteEvent) error { return nil }
+
+// Colunas das tabelas alinhadas com nomes acentuados e maiores que 16
+// letras: as células têm largura fixa, então as linhas da tabela têm todas
+// o mesmo número de runas
+func TestTableAlignment(t *testing.T) {
+	s := NewSimulator(2 * PAGE_SIZE)
+	s.LoadAccesses(strings.NewReader("D1\nD2\nD3\nD1\nD2\nD3\nI1\nI1\n"))
+	s.algorithms = []string{"optimal", "setassoc", "fifo", "clock"}
+	s.patterns = true
+	results := s.Simulate()
+	for i := range results {
+		results[i].Elapsed = 0
+	}
+	optimal := &results[0]
+	for name, print := range map[string]func(){
+		"comparação": func() { printComparison(results, optimal, nil) },
+		"padrões":    func() { s.printPatternFaults(results, optimal) },
+	} {
+		lines := strings.Split(captureStdout(print), "\n")[2:] // linha vazia e título
+		rows := lines[:len(results)+1]
+		if name == "padrões" {
+			rows = lines[:len(results)+2] // com a linha (páginas)
+		}
+		for _, line := range rows {
+			if got, want := utf8.RuneCountInString(line), utf8.RuneCountInString(rows[0]); got != want {
+				t.Errorf("%s: %d runas em %q, %d no cabeçalho %q", name, got, line, want, rows[0])
+			}
+		}
+	}
+}
+
+// Métricas derivadas de Result, inclusive sem acessos, sem faltas e numa
+// execução interrompida por -converge
+func TestResultMetrics(t *testing.T) {
+	optimal := Result{Accesses: 1000, Faults: 40}
+	for _, c := range []struct {
+		name        string
+		r           Result
+		hits        int
+		hitRate     float64
+		faultsPer1K float64
+		efficiency  float64
+		extra       int
+	}{
+		{"sem acessos", Result{}, 0, 0, 0, 100, -40},
+		{"sem faltas", Result{Accesses: 1000}, 1000, 1, 0, 100, -40},
+		{"igual ao ótimo", Result{Accesses: 1000, Faults: 40}, 960, 0.96, 40, 100, 0},
+		{"pior que o ótimo", Result{Accesses: 1000, Faults: 80}, 920, 0.92, 80, 50, 40},
+		{"só faltas", Result{Accesses: 1000, Faults: 1000}, 0, 0, 1000, 4, 960},
+		{"parcial", Result{Accesses: 250, Faults: 20, Partial: true}, 230, 0.92, 80, 50, 40},
+	} {
+		r := c.r
+		if r.Hits() != c.hits || math.Abs(r.HitRate()-c.hitRate) > 1e-9 ||
+			math.Abs(r.FaultsPer1K()-c.faultsPer1K) > 1e-9 ||
+			math.Abs(r.Efficiency(optimal)-c.efficiency) > 1e-9 || r.ExtraFaults(optimal) != c.extra {
+			t.Errorf("%s: hits %d, taxa de hit %g, faltas/1K %g, eficiência %g, extras %d; esperado %d, %g, %g, %g, %d",
+				c.name, r.Hits(), r.HitRate(), r.FaultsPer1K(), r.Efficiency(optimal), r.ExtraFaults(optimal),
+				c.hits, c.hitRate, c.faultsPer1K, c.efficiency, c.extra)
+		}
+	}
+
+	// Na tabela as colunas de eficiência e extras ficam N/A sem o ótimo
+	clock := Result{Algorithm: "Relógio", Accesses: 1000, Faults: 80}
+	for _, c := range []struct {
+		optimal *Result
+		want    string
+	}{
+		{&optimal, "50.00%       40"},
+		{nil, "N/A      N/A"},
+	} {
+		out := captureStdout(func() { printComparison([]Result{clock}, c.optimal, nil) })
+		if !strings.Contains(out, "920     92.00%      8.00%      80.00") || !strings.Contains(out, c.want) {
+			t.Errorf("tabela comparativa (ótimo %v):\n%s", c.optimal != nil, out)
+		}
+	}
+}
+
+// Cada ramo de compareWithOptimal
+func TestCompareWithOptimal(t *testing.T) {
+	optimal := Result{Accesses: 1000, Faults: 40}
+	for _, c := range []struct {
+		name    string
+		r       Result
+		optimal *Result
+		want    string
+	}{
+		{"ótimo não executado", Result{Accesses: 1000, Faults: 40}, nil, "não comparável (algoritmo ótimo não executado)"},
+		{"idêntico", Result{Accesses: 1000, Faults: 40}, &optimal, "idêntico ao ótimo (40 faltas)"},
+		{"sem faltas dos dois lados", Result{Accesses: 1000}, &Result{Accesses: 1000}, "idêntico ao ótimo (0 faltas)"},
+		{"pior", Result{Accesses: 1000, Faults: 50}, &optimal, "10 faltas a mais que o ótimo (80.00%)"},
+		{"ótimo sem faltas", Result{Accesses: 1000, Faults: 5}, &Result{Accesses: 1000}, "5 faltas a mais que o ótimo (0.00%)"},
+		{"inconsistente", Result{Accesses: 1000, Faults: 30}, &optimal, "inconsistente (10 faltas a menos que o ótimo)"},
+		{"parcial", Result{Accesses: 500, Faults: 40, Partial: true, RateMargin: 0.01}, &optimal,
+			"estimativa: ~40 faltas a mais que o ótimo (50.00%), pela taxa de 8.00% ± 1.00%"},
+	} {
+		if got := compareWithOptimal(c.r, c.optimal); got != c.want {
+			t.Errorf("%s: %q, esperado %q", c.name, got, c.want)
+		}
+	}
+}
+
+// Números de página: a base vem de -page-radix, nunca do ID; 0x sempre é
+// hexadecimal. A base é de cada simulador, e as classes de página são
+// lidas nela
+func TestPageNumber(t *testing.T) {
+	for _, c := range []struct {
+		radix  int
+		pageID string
+		want   uint64
+		ok     bool
+	}{
+		{10, "D0042", 42, true}, {10, "D26", 26, true}, {10, "D1a", 0, false}, {10, "D0x1a", 26, true},
+		{10, "I0X1F", 31, true}, {10, "D", 0, false}, {10, "Dfoo", 0, false},
+		{16, "D1a", 26, true}, {16, "D26", 38, true}, {16, "D0x1a", 26, true}, {16, "Dxyz", 0, false},
+	} {
+		if got, ok := pageNumber(c.pageID, c.radix); got != c.want || ok != c.ok {
+			t.Errorf("base %d, pageNumber(%q) = %d, %v; esperado %d, %v", c.radix, c.pageID, got, ok, c.want, c.ok)
+		}
+	}
+
+	// D26 e D1a nunca são a mesma página
+	for _, radix := range []int{10, 16} {
+		a, okA := pageNumber("D26", radix)
+		b, okB := pageNumber("D1a", radix)
+		if okA && okB && a == b {
+			t.Errorf("base %d: D26 e D1a viraram a página %d", radix, a)
+		}
+	}
+
+	if page := hexLookingPage(map[string]bool{"D1": true, "D0x1f": true}, 10); page != "" {
+		t.Errorf("aviso de base sem página hexadecimal: %s", page)
+	}
+	if page := hexLookingPage(map[string]bool{"D1": true, "D1f": true}, 10); page != "D1f" {
+		t.Errorf("aviso de base: %q, esperado D1f", page)
+	}
+	if page := hexLookingPage(map[string]bool{"D1": true, "D1f": true}, 16); page != "" {
+		t.Errorf("aviso de base já em 16: %q", page)
+	}
+	pages := []string{"D1a", "D26", "D3"}
+	sortPageIDs(pages, 10)
+	if fmt.Sprint(pages) != "[D3 D26 D1a]" {
+		t.Errorf("ordem na base 10: %v", pages)
+	}
+	sortPageIDs(pages, 16)
+	if fmt.Sprint(pages) != "[D3 D1a D26]" {
+		t.Errorf("ordem na base 16: %v", pages)
+	}
+
+	classes := filepath.Join(t.TempDir(), "classes.txt")
+	if err := os.WriteFile(classes, []byte("D10-D1f file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hex, dec := NewSimulator(PAGE_SIZE), NewSimulator(PAGE_SIZE)
+	hex.pageRadix = 16
+	if err := hex.LoadPageClasses(classes); err != nil || hex.pageClass("D1a") != classFile || hex.pageClass("D26") != classAnon {
+		t.Errorf("classes na base 16: %v, D1a %d, D26 %d", err, hex.pageClass("D1a"), hex.pageClass("D26"))
+	}
+	if err := dec.LoadPageClasses(classes); err == nil {
+		t.Errorf("D1f aceito como número de página na base 10 enquanto outro simulador usa 16")
+	}
+	out := captureLog(func() {
+		s := NewSimulator(PAGE_SIZE)
+		s.LoadAccesses(strings.NewReader("D1\nD1f\n"))
+	})
+	if !strings.Contains(out, "-page-radix 16") {
+		t.Errorf("trace com D1f em decimal sem aviso:\n%s", out)
+	}
+}
+
+// Extrapolação da estimativa de tempo com tempos fixos no lugar do relógio
+func TestExtrapolateTime(t *testing.T) {
+	half := sampleTiming{Accesses: 500, Faults: 5, Elapsed: time.Millisecond}
+	full := sampleTiming{Accesses: 1000, Faults: 10, Elapsed: 3 * time.Millisecond}
+	if e := extrapolateTime(half, full, 100000, 4, false); e.Expected != 300*time.Millisecond ||
+		e.Low != 160*time.Millisecond || e.High != 500*time.Millisecond {
+		t.Errorf("estimativa linear: %+v", e)
+	}
+
+	// Ótimo: 1000 faltas e 1000-4 substituições de 4 frames cada
+	if e := extrapolateTime(full, full, 100000, 4, true); e.Expected != 3*time.Millisecond*(100000+996*4)/(1000+6*4) {
+		t.Errorf("estimativa do Ótimo: %+v", e)
+	}
+
+	// Prefixo todo no aquecimento (-warmup): nenhum acesso contado
+	if e := extrapolateTime(sampleTiming{}, sampleTiming{Elapsed: time.Millisecond}, 100000, 4, false); e.String() != "amostra insuficiente" {
+		t.Errorf("prefixo sem acessos: %+v", e)
+	}
+}
+
+// -converge para num trace estacionário, mas não num que alterna entre
+// uma fase que cabe na memória e outra que não cabe
+func TestConverge(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	stationary, phases := make([]PageAccess, 100000), make([]PageAccess, 100000)
+	for i := range stationary {
+		stationary[i] = PageAccess{PageID: fmt.Sprintf("D%d", rng.Intn(16)), Type: "D"}
+		page := i % 4
+		if i/(2*convergeWindow)%2 == 1 {
+			page = i % 12
+		}
+		phases[i] = PageAccess{PageID: fmt.Sprintf("D%d", page), Type: "D"}
+	}
+	for _, trace := range [][]PageAccess{stationary, phases} {
+		s := NewSimulator(8 * PAGE_SIZE)
+		s.accesses = trace
+		s.convergeEpsilon = 0.02
+		r := s.runPolicy(s.newClock())
+		if stopped := &trace[0] == &stationary[0]; r.Partial != stopped {
+			t.Errorf("-converge: interrompido=%v depois de %d acessos", r.Partial, r.Accesses)
+		}
+	}
+}
+
+// Com blocos do mesmo tamanho, mais blocos estreitam o intervalo do
+// bootstrap num trace estacionário
+func TestBootstrapNarrowsWithBlocks(t *testing.T) {
+	stationary := stationaryTrace()
+	previousWidth := math.Inf(1)
+	for _, blocks := range []int{5, 20, 80} {
+		s := NewSimulator(8 * PAGE_SIZE)
+		s.accesses = stationary[:blocks*1000]
+		s.bootstrapBlocks = blocks
+		results := []Result{s.runPolicy(s.newClock())}
+		results[0].Algorithm = "Relógio"
+		s.bootstrap(results)
+		width := results[0].Bootstrap.High - results[0].Bootstrap.Low
+		if width >= previousWidth {
+			t.Errorf("bootstrap: intervalo de %.4f com %d blocos, não mais estreito que %.4f", width, blocks, previousWidth)
+		}
+		previousWidth = width
+	}
+}
+
+// -warmup maior que o trace: nada é contado, mas as faltas aparecem à parte
+func TestWarmup(t *testing.T) {
+	stationary := stationaryTrace()
+	short := NewSimulator(4 * PAGE_SIZE)
+	short.accesses = stationary[:50]
+	short.warmup = 100
+	if r := short.runPolicy(short.newClock()); r.Accesses != 0 || r.Faults != 0 ||
+		r.WarmupAccesses != 50 || r.WarmupFaults == 0 || r.HitRate() != 0 {
+		t.Errorf("-warmup além do trace: %d acessos, %d faltas, aquecimento %d/%d",
+			r.Accesses, r.Faults, r.WarmupAccesses, r.WarmupFaults)
+	}
+	short.warmup, short.warmupAuto = 0, true
+	if r := short.runPolicy(newOptimalPolicy(4, short.BuildNextUseIndex())); r.WarmupFaults != 4 {
+		t.Errorf("-warmup auto: %d faltas no aquecimento com 4 frames", r.WarmupFaults)
+	}
+}
+
+// -warmup pelo Run com a estimativa de tempo ligada: o aquecimento cobre
+// o prefixo medido pela estimativa (e com -warmup auto e mais frames que
+// páginas, o trace inteiro), que antes dividia por zero
+func TestWarmupRun(t *testing.T) {
+	stationary := stationaryTrace()
+	for _, c := range []struct {
+		name     string
+		accesses []PageAccess
+		frames   int
+		warmup   int
+		auto     bool
+	}{
+		{"-warmup 5000", stationary[:20000], 10, 5000, false},
+		{"-warmup 10", []PageAccess{{PageID: "D1", Type: "D"}, {PageID: "D1", Type: "D"}}, 1, 10, false},
+		{"-warmup auto", stationary[:20000], 32, 0, true},
+	} {
+		s := NewSimulator(c.frames * PAGE_SIZE)
+		for _, access := range c.accesses {
+			s.accesses = append(s.accesses, access)
+			s.distinctPages[access.PageID] = true
+		}
+		s.warmup, s.warmupAuto = c.warmup, c.auto
+		out := captureStdout(s.Run)
+		if !strings.Contains(out, "Tempo estimado: ") || !strings.Contains(out, "=== COMPARAÇÃO ===") {
+			t.Errorf("%s: saída sem a estimativa ou a comparação:\n%s", c.name, out)
+		}
+	}
+}
+
+// Linha do tempo de um exemplo feito à mão (Ótimo, 2 frames):
+// D1 D2 D3 D1 D2 -> D3 substitui D2 no acesso 3; D2 volta no 5 no
+// lugar de D1, que não é mais usada
+func TestPageTimeline(t *testing.T) {
+	hand := []PageAccess{{PageID: "D1"}, {PageID: "D2"}, {PageID: "D3"}, {PageID: "D1"}, {PageID: "D2"}}
+	events, intervals := pageTimeline(newOptimalPolicy(2, newNextUseIndex(hand)), hand, map[string]bool{"D2": true})
+	want := []residency{{"D2", 1, 2, 3}, {"D2", 0, 5, 0}}
+	if fmt.Sprint(intervals) != fmt.Sprint(want) || len(events) != 3 || events[1].Other != "D3" {
+		t.Errorf("linha do tempo de D2: %v %v", intervals, events)
+	}
+}
+
+// Mapa de calor: XML válido e uma célula por sequência de colunas com a
+// mesma página (D1 no frame 0; o frame 1 vazio na 1ª coluna e D2 depois)
+func TestHeatmap(t *testing.T) {
+	small := []PageAccess{{PageID: "D1"}, {PageID: "D2"}, {PageID: "D1"}, {PageID: "D2"}}
+	var svg strings.Builder
+	grid := frameSnapshots(newOptimalPolicy(2, newNextUseIndex(small)), small, 1, 1)
+	renderHeatmap(&svg, []heatmapPanel{{"Ótimo <teste>", grid}}, len(small), 1, 2, 1, defaultPageRadix)
+	decoder := xml.NewDecoder(strings.NewReader(svg.String()))
+	cells := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Errorf("mapa de calor inválido: %v", err)
+			break
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "rect" {
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "class" && attr.Value == "cell" {
+					cells++
+				}
+			}
+		}
+	}
+	if cells != 2 {
+		t.Errorf("mapa de calor com %d células, esperado 2", cells)
+	}
+}
+
+// As expressões de exemplo do -policy-expr reproduzem FIFO, LRU e LFU
+// (desempate pelo LRU) nos exemplos embutidos
+func TestPolicyExprExamples(t *testing.T) {
+	entries, _ := exampleTraces.ReadDir("examples")
+	for _, entry := range entries {
+		data, _ := exampleTraces.ReadFile("examples/" + entry.Name())
+		example := NewSimulator(PAGE_SIZE)
+		example.LoadAccesses(bytes.NewReader(data))
+		for _, frames := range fixtureFrames {
+			for _, c := range []struct{ kind, expr string }{
+				{"fifo", "age"}, {"lru", "idle"}, {"lfu", "idle - accesses*1000000"},
+			} {
+				score, err := compileExpr(c.expr)
+				if err != nil {
+					t.Errorf("expressão %q: %v", c.expr, err)
+					continue
+				}
+				got := checkedRun(t, newExprPolicy(frames, score), example.accesses, frames)
+				got.Frames, got.Algorithm = frames, c.kind
+				if want := referenceRun(c.kind, example.accesses, frames); got.String() != want.String() {
+					t.Errorf("%s, %d frames: %q deu %s, %s deu %s", entry.Name(), frames, c.expr, got, c.kind, want)
+				}
+			}
+		}
+	}
+}
+
+// -cost: cada acesso cai em um único balde e o total dos baldes é a soma
+// dos custos de cada acesso, recalculados com uma TLB de referência
+func TestCostBuckets(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 20; trial++ {
+		trace := make([]PageAccess, 2000)
+		for i := range trace {
+			trace[i] = PageAccess{PageID: fmt.Sprintf("D%d", rng.Intn(12)), Type: "D", Write: rng.Intn(3) == 0}
+		}
+		s := NewSimulator((2 + rng.Intn(6)) * PAGE_SIZE)
+		s.accesses = trace
+		s.costReport, s.tlbEntries, s.warmup = true, rng.Intn(6), rng.Intn(100)
+		s.tlbHitCost, s.walkCost = 1+rng.Intn(5), 50+rng.Intn(100)
+		s.zeroFillCost, s.faultReadCost, s.swapWriteCost = 1+rng.Intn(100), 100+rng.Intn(9000), 100+rng.Intn(9000)
+		r := s.runPolicy(s.newClock())
+		counted := 0
+		for _, n := range r.Costs.Counts {
+			counted += n
+		}
+		if want := referenceCost(s, s.newClock()); counted != r.Accesses || r.Costs.Total() != want {
+			t.Errorf("-cost: %d acessos nos baldes de %d, total %d, esperado %d", counted, r.Accesses, r.Costs.Total(), want)
+		}
+	}
+}
+
+// -pt-frames: sem tabelas a simulação é o Relógio comum. Com elas, toda
+// página residente tem as tabelas do caminho residentes, as tabelas
+// fixas nunca ficam vazias e as faltas não diminuem.
+func TestPageTableFrames(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 20; trial++ {
+		frames := 5 + rng.Intn(12)
+		trace := make([]PageAccess, 3000)
+		for i := range trace {
+			// Páginas espalhadas por várias tabelas de nível 1 e 2
+			trace[i] = PageAccess{PageID: fmt.Sprintf("D%d", rng.Intn(6)*ptEntriesPerPage*(1+rng.Intn(600)/500)+rng.Intn(8))}
+		}
+		s := NewSimulator(frames * PAGE_SIZE)
+		s.accesses = trace
+		plain := checkedRun(t, newClockPolicy(frames), trace, frames)
+		free := newPageTableSim(frames, pageTableLevels(32), defaultPageRadix, false, false)
+		for _, access := range trace {
+			free.Access(access.PageID)
+		}
+		for _, evictable := range []bool{false, true} {
+			sim := newPageTableSim(frames, pageTableLevels(32), defaultPageRadix, true, evictable)
+			for i, access := range trace {
+				if _, err := sim.Access(access.PageID); err != nil {
+					t.Errorf("-pt-frames: %v", err)
+					break
+				}
+				if msg := sim.check(); msg != "" {
+					t.Errorf("-pt-frames (evictable=%v), acesso %d: %s", evictable, i+1, msg)
+					break
+				}
+			}
+			if sim.dataFaults < plain.Faults {
+				t.Errorf("-pt-frames (evictable=%v): %d faltas, menos que as %d do Relógio", evictable, sim.dataFaults, plain.Faults)
+			}
+		}
+		if free.dataFaults != plain.Faults {
+			t.Errorf("-pt-frames sem tabelas: %d faltas, Relógio %d", free.dataFaults, plain.Faults)
+		}
+	}
+
+	// Duas regiões de 4 páginas em tabelas de nível 1 diferentes, com 8
+	// frames: raiz, uma tabela de nível 2 e as duas de nível 1
+	var regions []PageAccess
+	for round := 0; round < 3; round++ {
+		for _, base := range []int{0, ptEntriesPerPage} {
+			for k := 0; k < 4; k++ {
+				regions = append(regions, PageAccess{PageID: fmt.Sprintf("D%d", base+k)})
+			}
+		}
+	}
+	regionSim := NewSimulator(8 * PAGE_SIZE)
+	regionSim.accesses, regionSim.vaddrBits, regionSim.ptFrames = regions, 32, "pinned"
+	if r, err := regionSim.simulatePageTableFrames(); err != nil || r.PeakTables != 4 || r.DataFaults <= r.Baseline {
+		t.Errorf("-pt-frames com duas regiões: %+v, %v", r, err)
+	}
+}
+
+// -sweep-tau: o modelo incremental confere com a contagem direta das
+// páginas distintas na janela, e o joelho do laço de 5 páginas fica em 5
+func TestWorkingSetModel(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 20; trial++ {
+		trace := make([]PageAccess, 500)
+		for i := range trace {
+			trace[i] = PageAccess{PageID: fmt.Sprintf("D%d", rng.Intn(10))}
+		}
+		tau, frames := 1+rng.Intn(30), 1+rng.Intn(8)
+		want := workingSetPoint{Tau: tau}
+		total, over := 0, 0
+		for at := range trace {
+			window := make(map[string]bool)
+			for k := max(0, at-tau); k < at; k++ {
+				window[trace[k].PageID] = true
+			}
+			if !window[trace[at].PageID] {
+				want.Faults++
+			}
+			window = make(map[string]bool)
+			for k := max(0, at-tau+1); k <= at; k++ {
+				window[trace[k].PageID] = true
+			}
+			total += len(window)
+			if len(window) > frames {
+				over++
+			}
+		}
+		want.AvgSize = float64(total) / float64(len(trace))
+		want.OverFrames = float64(over) / float64(len(trace))
+		if got := workingSetModel(trace, tau, frames); got != want {
+			t.Errorf("-sweep-tau, τ=%d: %+v, esperado %+v", tau, got, want)
+		}
+	}
+	loop := NewSimulator(PAGE_SIZE)
+	data, _ := exampleTraces.ReadFile("examples/loop.txt")
+	loop.LoadAccesses(bytes.NewReader(data))
+	loopModel := func(tau int) workingSetPoint { return workingSetModel(loop.accesses, tau, 3) }
+	if knee := workingSetKnee(sweepWorkingSet([]int{1, 2, 4, 5, 8, 100}, loopModel)); knee != 5 {
+		t.Errorf("-sweep-tau no laço de 5 páginas: joelho em %d, esperado 5", knee)
+	}
+}
+
+// Coluna +N: com atrasos iguais a d (no trace ou por -access-time), o
+// Working Set sobre o tempo com τ·d é o de τ acessos; com atrasos
+// quaisquer, confere com a busca direta da última referência
+func TestDelayColumn(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 20; trial++ {
+		timed := NewSimulator(PAGE_SIZE)
+		timed.accessTime = int64(1 + rng.Intn(5))
+		for i := 0; i < 300; i++ {
+			timed.accesses = append(timed.accesses, PageAccess{PageID: fmt.Sprintf("D%d", rng.Intn(10))})
+		}
+		tau, frames := 1+rng.Intn(30), 1+rng.Intn(8)
+		times, _ := timed.accessTimes()
+		got := workingSetModelTime(timed.accesses, times, int64(tau)*timed.accessTime, frames)
+		if want := workingSetModel(timed.accesses, tau, frames); got.Faults != want.Faults ||
+			got.AvgSize != want.AvgSize || got.OverFrames != want.OverFrames {
+			t.Errorf("-sweep-tau no tempo, τ=%d×%dns: %+v, por acessos %+v", tau, timed.accessTime, got, want)
+		}
+		for i := range timed.accesses {
+			timed.accesses[i].Delay = int64(rng.Intn(4))
+		}
+		timed.accesses[0].Delay = 1
+		window := int64(1 + rng.Intn(20))
+		times, _ = timed.accessTimes()
+		faults := 0
+		for at, access := range timed.accesses {
+			k := at - 1
+			for k >= 0 && timed.accesses[k].PageID != access.PageID {
+				k--
+			}
+			if k < 0 || times[at]-times[k] > window {
+				faults++
+			}
+		}
+		if got := workingSetModelTime(timed.accesses, times, window, frames); got.Faults != faults {
+			t.Errorf("-sweep-tau no tempo, τ=%dns com atrasos variados: %d faltas, esperado %d", window, got.Faults, faults)
+		}
+	}
+	overflow := NewSimulator(PAGE_SIZE)
+	overflow.accesses = []PageAccess{{PageID: "D1", Delay: math.MaxInt64}, {PageID: "D2", Delay: 1}}
+	if _, err := overflow.accessTimes(); err == nil {
+		t.Errorf("atrasos somando mais que int64 aceitos")
+	}
+	withDelay := PageAccess{PageID: "I3", Type: "I", Write: true, Delay: 120}
+	if back, err := unmarshalAccessProto(withDelay.MarshalProto()); err != nil || back != withDelay {
+		t.Errorf("atraso no proto: %+v %v", back, err)
+	}
+	if back, err := parseLine(withDelay.String()); err != nil || back != withDelay {
+		t.Errorf("atraso no texto: %q deu %+v %v", withDelay.String(), back, err)
+	}
+}
+
+// analyze validate: um trace de Zipf se valida contra si mesmo sem erro
+// e contra outro sorteado das frequências ajustadas dele; um uniforme
+// sobre as mesmas páginas é rejeitado
+func TestAnalyzeValidate(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(rng, 1.3, 1, 199)
+	measured := make([]PageAccess, 20000)
+	for i := range measured {
+		measured[i] = PageAccess{PageID: fmt.Sprintf("D%d", zipf.Uint64()), Type: "D"}
+	}
+	if v := validateTrace(measured, measured); !v.Pass {
+		t.Errorf("analyze validate: o trace não se valida contra si mesmo: %+v", v.Metrics)
+	}
+	profile := profileAccesses(measured)
+	var pages []string
+	for page := range profile.Counts {
+		pages = append(pages, page)
+	}
+	sortPageIDs(pages, defaultPageRadix)
+	cumulative := make([]int, len(pages))
+	for i, page := range pages {
+		cumulative[i] = profile.Counts[page]
+		if i > 0 {
+			cumulative[i] += cumulative[i-1]
+		}
+	}
+	fitted, uniform := make([]PageAccess, len(measured)), make([]PageAccess, len(measured))
+	for i := range fitted {
+		k := sort.SearchInts(cumulative, 1+rng.Intn(len(measured)))
+		fitted[i] = PageAccess{PageID: pages[k], Type: "D"}
+		uniform[i] = PageAccess{PageID: pages[rng.Intn(len(pages))], Type: "D"}
+	}
+	if v := validateTrace(measured, fitted); !v.Pass {
+		t.Errorf("analyze validate: o trace ajustado foi rejeitado: %+v", v.Metrics)
+	}
+	if v := validateTrace(measured, uniform); v.Pass {
+		t.Errorf("analyze validate: o trace uniforme foi aceito: %+v", v.Metrics)
+	}
+}
+
+// parseLine nunca entra em pânico; uma linha aceita volta igual pela forma
+// normalizada, e o leitor em memória concorda com ela
+func FuzzParseLine(f *testing.F) {
+	for _, line := range []string{
+		"D1", "I2", "D0042", "D0x1f", "X D3", "D D4", "D5 R", "D6 W", "D7 Z", "d8 w", "I9 +120",
+		"D10 W +0", "X D11 r +5", "\ufeffD12", "D13\r", "  \tD14 \t", "# comentário", "", "D", "P1", "9",
+		"D1 W extra", "D D2", "D2\vW", "Dé", "D4 +", "D5 +-3", "D7 +9223372036854775808", "\x00", "\xff\xfe",
+		"S3:0x1f40 W", "S03:0X1F40", "S1:4096 +7", "S2:0x",
+	} {
+		f.Add(line)
+	}
+	f.Fuzz(func(t *testing.T, line string) {
+		access, err := parseLine(line)
+		if err == nil {
+			back, errBack := parseLine(access.String())
+			if errBack != nil || back != access {
+				t.Errorf("%q: %+v normalizado como %q volta %+v %v", line, access, access.String(), back, errBack)
+			}
+		}
+		if strings.ContainsAny(line, "\n\x00") || strings.HasPrefix(line, "\ufeff") || strings.HasSuffix(line, "\r") {
+			return // o leitor trata quebras, NUL, BOM e CR antes de parseLine
+		}
+		sliced := newSliceScanner([]byte(line))
+		if !sliced.Scan() {
+			return
+		}
+		if b, errB := sliced.parse(); b != access || fmt.Sprint(errB) != fmt.Sprint(err) {
+			t.Errorf("%q: %+v %v, e em memória %+v %v", line, access, err, b, errB)
+		}
+	})
+}
+
+// -reader: o leitor em memória vê as mesmas linhas, com os mesmos
+// acessos, erros e normalizações do bufio, inclusive nas linhas inválidas
+func TestReaders(t *testing.T) {
+	fixtures := []string{
+		"\ufeffD1\r\nI2 W\r\nD6 w\r\n# comentário\r\n\r\nX D3 r\nD4 z\n  \tD5 \t\nDX\nP1\n9\nD\n",
+		"D1 W extra\nA B C D E F G H I J\nD\u00a0D2\nD2\vW\nI3\u2003\nDé\n1 D9 R W\nD7\r\nD8\rD9\r",
+		"D1\nD2\x00lixo\nD3\n",
+		"D1\n\x00\nD2\n",
+		"D1 +5\nD2 W +0\nI3 Z +12\n+7\nD4 +\nD5 +-3\nD6 ++4\nD7 +9223372036854775808\nX D8 r +9223372036854775807\n",
+		"D1\n" + strings.Repeat("D", maxLineLength-1) + "\nD2\n",
+		"D1\n" + strings.Repeat("D", maxLineLength) + "\nD2\n",
+		"",
+	}
+	for k, text := range fixtures {
+		buffered, sliced := newTraceScanner(strings.NewReader(text)), newSliceScanner([]byte(text))
+		for line := 1; ; line++ {
+			more := buffered.Scan()
+			if sliced.Scan() != more {
+				t.Errorf("-reader, texto %d, linha %d: os leitores discordam sobre o fim", k, line)
+				break
+			}
+			if !more {
+				break
+			}
+			a, errA := buffered.parse()
+			b, errB := sliced.parse()
+			if buffered.Text() != sliced.Text() || a != b || fmt.Sprint(errA) != fmt.Sprint(errB) {
+				t.Errorf("-reader, texto %d, linha %d: %q %+v %v, e em memória %q %+v %v",
+					k, line, buffered.Text(), a, errA, sliced.Text(), b, errB)
+			}
+		}
+		if fmt.Sprint(buffered.Err()) != fmt.Sprint(sliced.Err()) ||
+			strings.Join(buffered.normalizations(), ";") != strings.Join(sliced.normalizations(), ";") {
+			t.Errorf("-reader, texto %d: %v %v, e em memória %v %v", k, buffered.Err(), buffered.normalizations(),
+				sliced.Err(), sliced.normalizations())
+		}
+	}
+
+	// Uma linha longa demais é rejeitada sozinha; a leitura continua
+	long := "D1\n" + strings.Repeat("D", 3*maxLineLength) + " W\nD2\n" + strings.Repeat("I", maxLineLength)
+	for _, scanner := range []traceLines{newTraceScanner(strings.NewReader(long)), newSliceScanner([]byte(long))} {
+		l := readLines(scanner)
+		if len(l.accesses) != 2 || l.invalid != 2 || l.reasons[errLineTooLong.Error()] != 2 || l.err != nil ||
+			len(l.warnings) != 2 || l.warnings[0].line != 2 || l.warnings[1].line != 4 {
+			t.Errorf("linhas longas (%T): %d acessos, %d inválidas %v, erro %v", scanner, len(l.accesses), l.invalid, l.reasons, l.err)
+		}
+	}
+	s := NewSimulator(PAGE_SIZE)
+	if d, err := s.LoadAccesses(strings.NewReader(long)); err != nil || d.Accesses != 2 || d.Invalid != 2 {
+		t.Errorf("linhas longas: %d acessos e %d inválidas (%v), esperado 2 e 2", d.Accesses, d.Invalid, err)
+	}
+}
+
+// Logger: cada linha inválida é um aviso com arquivo, linha e motivo em
+// campos próprios, fora da mensagem; além de maxLoadWarnings os avisos
+// descem para debug (-v). As opções do logger saem dos argumentos.
+func TestLoggerWarnings(t *testing.T) {
+	var invalidText strings.Builder
+	for i := 1; i <= maxLoadWarnings+2; i++ {
+		fmt.Fprintf(&invalidText, "D%d\nlinha %d inválida\n", i, i)
+	}
+	logged := NewSimulator(PAGE_SIZE)
+	logged.traceName = "avisos.txt"
+	records := strings.Split(strings.TrimSpace(captureLog(func() {
+		d, _ := logged.LoadAccesses(strings.NewReader(invalidText.String()))
+		d.log()
+	})), "\n")
+	if len(records) != maxLoadWarnings+3 {
+		t.Errorf("logger: %d registros para %d linhas inválidas e o resumo", len(records), maxLoadWarnings+2)
+		records = append(records, make([]string, maxLoadWarnings+3)...)
+	}
+	for k, record := range records[:maxLoadWarnings+3] {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(record), &entry); err != nil {
+			t.Errorf("logger: registro não é JSON: %s", record)
+			continue
+		}
+		if k == maxLoadWarnings+2 {
+			reasons, _ := entry["reasons"].(map[string]any)
+			if entry["msg"] != "arquivo processado" || entry["invalid"] != float64(maxLoadWarnings+2) ||
+				reasons[errPageKind.Error()] != float64(maxLoadWarnings+2) {
+				t.Errorf("logger: resumo sem os campos esperados: %s", record)
+			}
+			continue
+		}
+		level := "WARN"
+		if k >= maxLoadWarnings {
+			level = "DEBUG"
+		}
+		if entry["level"] != level || entry["msg"] != "linha ignorada" || entry["file"] != "avisos.txt" ||
+			entry["line"] != float64(2*k+2) || entry["reason"] != errPageKind.Error() ||
+			entry["text"] != fmt.Sprintf("linha %d inválida", k+1) {
+			t.Errorf("logger: registro %d sem os campos esperados: %s", k, record)
+		}
+	}
+}
+
+// Diagnostics: cada motivo de rejeição, no texto e no proto, contado à
+// parte; sem -v só as primeiras linhas ficam guardadas
+func TestDiagnostics(t *testing.T) {
+	diagnosed := NewSimulator(PAGE_SIZE)
+	d, err := diagnosed.LoadAccesses(strings.NewReader(
+		"# comentário\nD1\nP9\n\nD\nI2 W\nX\n" + strings.Repeat("Q1\n", maxLoadWarnings)))
+	if err != nil || d.Lines != 7+maxLoadWarnings || d.Accesses != 2 || d.Invalid != 3+maxLoadWarnings ||
+		d.Reasons[errPageKind.Error()] != 2+maxLoadWarnings || d.Reasons[errPageNumber.Error()] != 1 ||
+		len(d.Rejected) != maxLoadWarnings || d.Rejected[1] != (RejectedLine{5, errPageNumber.Error(), "D"}) {
+		t.Errorf("Diagnostics do texto: %+v %v", d, err)
+	}
+	if text := d.String(); !strings.Contains(text, "linha 3 (página sem o tipo I ou D): P9") ||
+		!strings.Contains(text, "... e mais 3 linhas inválidas") {
+		t.Errorf("Diagnostics.String:\n%s", text)
+	}
+	var stream []byte
+	for _, a := range []PageAccess{{PageID: "D1", Type: "D"}, {PageID: "P1", Type: "D"}, {PageID: "D", Type: "D"},
+		{PageID: "D 1", Type: "D"}, {PageID: "D2", Type: "I"}, {PageID: "I3", Type: "I", Write: true}} {
+		msg := a.MarshalProto()
+		stream = append(binary.AppendUvarint(stream, uint64(len(msg))), msg...)
+	}
+	d, err = diagnosed.LoadProtoAccesses(bytes.NewReader(stream))
+	reasons := map[string]int{errPageKind.Error(): 1, errPageNumber.Error(): 1, errPageFormat.Error(): 1, errKindMismatch.Error(): 1}
+	if err != nil || d.Format != "proto" || d.Lines != 6 || d.Accesses != 2 || d.Invalid != 4 ||
+		fmt.Sprint(d.Reasons) != fmt.Sprint(reasons) || d.Rejected[3].Line != 5 {
+		t.Errorf("Diagnostics do proto: %+v %v", d, err)
+	}
+	srv := newServer("", 1, time.Minute)
+	if s, err := srv.prepare(simulateRequest{Memory: PAGE_SIZE, Trace: "D1\nP2\n"}); err != nil {
+		t.Errorf("serve: %v", err)
+	} else if data, _ := json.Marshal(s.response(nil)); !strings.Contains(string(data),
+		`"diagnostics":{"format":"text","lines":2,"accesses":1,"invalid":1,"reasons":{"página sem o tipo I ou D":1}`) {
+		t.Errorf("serve: diagnóstico ausente da resposta: %s", data)
+	}
+	if rest, err := configureLogging([]string{"sim", "t.txt", "-log-format", "json", "4096", "-v"}); err != nil ||
+		strings.Join(rest, " ") != "sim t.txt 4096" || !logJSON || !logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Errorf("-log-format/-v: argumentos %v, JSON %v, erro %v", rest, logJSON, err)
+	}
+	if _, err := configureLogging([]string{"sim", "-log-level", "alto"}); err == nil {
+		t.Errorf("-log-level alto aceito")
+	}
+	logger, logJSON = slog.New(slog.DiscardHandler), false
+}
+
+// -parse-workers: em qualquer número de trechos o resultado, os avisos
+// e os números de linha são os da leitura sequencial pelo bufio. Os
+// trechos terminam em quebras de linha e, juntos, são o arquivo.
+func TestParseWorkers(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	lineKinds := []string{"D1", "\ufeffD2", "I2 W", "D3\r", "# nota", "", "DX", "P9", "x D4 r", "D5\u00a0W", "  D6\t"}
+	for trial := 0; trial < 60; trial++ {
+		var b strings.Builder
+		for i := 0; i < rng.Intn(200); i++ {
+			b.WriteString(lineKinds[rng.Intn(len(lineKinds))])
+			b.WriteByte('\n')
+		}
+		switch trial % 6 {
+		case 1:
+			b.WriteString("D7\x00D8\nD9\n")
+		case 2:
+			b.WriteString(strings.Repeat("D", maxLineLength) + "\nD9\n")
+		case 3:
+			b.WriteString("D9") // sem quebra de linha no fim
+		}
+		text := b.String()
+		for i := 0; i < rng.Intn(50); i++ {
+			text = lineKinds[rng.Intn(len(lineKinds))] + "\n" + text
+		}
+		load := func(sequential bool, workers int) string {
+			sim := NewSimulator(PAGE_SIZE)
+			sim.parseWorkers = workers
+			var d Diagnostics
+			var err error
+			if sequential {
+				d, err = sim.LoadAccesses(strings.NewReader(text))
+			} else {
+				d, err = sim.LoadAccessBytes([]byte(text))
+			}
+			return fmt.Sprintf("%v %d %d %+v %v", sim.accesses, len(sim.distinctPages), sim.writeCount, d, err)
+		}
+		want := load(true, 0)
+		for workers := 1; workers <= 9; workers++ {
+			if got := load(false, workers); got != want {
+				t.Errorf("-parse-workers %d, texto %d: resultado diferente da leitura sequencial", workers, trial)
+				break
+			}
+			parts := splitLines([]byte(text), workers)
+			joined := 0
+			for k, part := range parts {
+				joined += len(part)
+				if k < len(parts)-1 && part[len(part)-1] != '\n' {
+					t.Errorf("-parse-workers %d: trecho %d não termina numa quebra de linha", workers, k)
+				}
+			}
+			if joined != len(text) || len(parts) > workers {
+				t.Errorf("-parse-workers %d: %d trechos com %d de %d bytes", workers, len(parts), joined, len(text))
+			}
+		}
+	}
+}
+
+// index e -from-access: a partir de qualquer acesso, com ou sem índice,
+// a leitura dá o fim da leitura completa, com os números de linha do
+// arquivo nos avisos; o índice deixa de valer quando o trace muda
+func TestIndexFromAccess(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	if dir, err := os.MkdirTemp("", "sim-index"); err != nil {
+		t.Errorf("index: %v", err)
+	} else {
+		// Só a última linha é inválida, para o aviso dela aparecer sempre
+		valid := []string{"D1", "I2 W", "D3\r", "# nota", "", "x D4 r", "  D6\t"}
+		var b strings.Builder
+		for i := 0; i < 300; i++ {
+			b.WriteString(valid[rng.Intn(len(valid))] + "\n")
+		}
+		b.WriteString("LINHA-FINAL\n")
+		text := b.String()
+		trace := filepath.Join(dir, "trace.txt")
+		os.WriteFile(trace, []byte(text), 0644)
+		full := NewSimulator(PAGE_SIZE)
+		full.LoadAccessFile(trace)
+		lastLine := strings.Count(text, "\n")
+		for _, indexed := range []bool{false, true} {
+			if indexed {
+				index, err := buildTraceIndex(trace, 7)
+				if err == nil {
+					err = writeTraceIndex(traceIndexName(trace), index)
+				}
+				if err != nil {
+					t.Errorf("index: %v", err)
+				}
+			}
+			for _, from := range []int{2, 7, 8, 9, 50, len(full.accesses)} {
+				part := NewSimulator(PAGE_SIZE)
+				part.fromAccess = from
+				var d Diagnostics
+				output := captureLog(func() { d, _ = part.LoadAccessFile(trace) })
+				if fmt.Sprint(part.accesses) != fmt.Sprint(full.accesses[from-1:]) ||
+					len(d.Rejected) != 1 || d.Rejected[0].Line != lastLine ||
+					strings.Contains(output, "leitura pelo índice") != indexed {
+					t.Errorf("-from-access %d (índice: %v): leitura diferente do fim da completa\n%s", from, indexed, output)
+				}
+			}
+		}
+		if index, err := loadTraceIndex(trace); index == nil || err != nil {
+			t.Errorf("index: índice recém-gravado não foi aceito (%v)", err)
+		}
+		os.WriteFile(trace, []byte(strings.Replace(text, "D", "I", 1)), 0644)
+		if index, _ := loadTraceIndex(trace); index != nil {
+			t.Errorf("index: índice aceito para um trace com o mesmo tamanho e outro conteúdo")
+		}
+		os.WriteFile(trace, []byte(text+"D1\n"), 0644)
+		if index, _ := loadTraceIndex(trace); index != nil {
+			t.Errorf("index: índice aceito para um trace de outro tamanho")
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+// -trials: com a mesma semente base as repetições se reproduzem; cada
+// uma é a execução simples com a sua semente, a primeira é a de -seed
+// e os algoritmos determinísticos executam uma vez
+func TestTrials(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	s := NewSimulator(6 * PAGE_SIZE)
+	for i := 0; i < 2000; i++ {
+		s.accesses = append(s.accesses, PageAccess{PageID: fmt.Sprintf("D%d", rng.Intn(14)), Type: "D"})
+	}
+	s.algorithms = []string{"clock", "random", "randunref", "hyperbolic"}
+	s.seed, s.trials, s.hyperbolicSamples = 42, 6, 2
+	trials := func(results []Result) string {
+		var out []any
+		for _, r := range results {
+			out = append(out, r.Faults, r.Trials)
+		}
+		data, _ := json.Marshal(out)
+		return string(data)
+	}
+	first := s.Simulate()
+	if len(first) != 4 || first[0].Trials != nil || trials(first) != trials(s.Simulate()) {
+		t.Errorf("-trials: repetições diferentes com a mesma semente base")
+	}
+	for _, r := range first[1:] {
+		stats := r.Trials
+		if stats == nil || len(stats.Runs) != 6 || stats.Runs[0] != (TrialRun{42, r.Faults}) {
+			t.Errorf("-trials, %s: a primeira repetição não é a execução com -seed", r.Algorithm)
+			continue
+		}
+		single := *s
+		single.trials = 0
+		distinct := map[int]bool{}
+		total, low, high := 0, stats.Runs[0].Faults, stats.Runs[0].Faults
+		for _, run := range stats.Runs {
+			single.seed = run.Seed
+			if got := single.runPolicy(single.newPolicyFor(r.Algorithm)).Faults; got != run.Faults {
+				t.Errorf("-trials, %s, semente %d: %d faltas, execução simples %d", r.Algorithm, run.Seed, run.Faults, got)
+			}
+			distinct[run.Faults] = true
+			total += run.Faults
+			low, high = min(low, run.Faults), max(high, run.Faults)
+		}
+		if len(distinct) < 2 || stats.Min != low || stats.Max != high || math.Abs(stats.Mean-float64(total)/6) > 1e-9 || stats.StdDev <= 0 {
+			t.Errorf("-trials, %s: estatísticas inconsistentes com as repetições: %+v", r.Algorithm, *stats)
+		}
+	}
+}
+
+// -victims: cada arquivo é um trace válido, com uma linha por falta que
+// substituiu uma página (as que ocuparam frames vazios ficam de fora),
+// na ordem dos acessos
+func TestVictimsFiles(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	if dir, err := os.MkdirTemp("", "sim-victims"); err != nil {
+		t.Errorf("-victims: %v", err)
+	} else {
+		s := NewSimulator(5 * PAGE_SIZE)
+		distinct := map[string]bool{}
+		for i := 0; i < 1500; i++ {
+			page := fmt.Sprintf("%c%d", "ID"[i%2], rng.Intn(12))
+			s.accesses = append(s.accesses, PageAccess{PageID: page, Type: page[:1], Write: i%5 == 0})
+			distinct[page] = true
+		}
+		s.algorithms = []string{"optimal", "clock", "randunref", "expr"}
+		s.victimsFile, s.noEstimate = filepath.Join(dir, "vitimas.txt"), true
+		captureStdout(s.Run)
+		for _, r := range s.Simulate() {
+			name := "optimal"
+			for _, info := range streamingPolicies {
+				if info.Label == r.Algorithm {
+					name = info.Name
+				}
+			}
+			victims := NewSimulator(PAGE_SIZE)
+			d, err := victims.LoadAccessFile(victimsPath(s.victimsFile, name))
+			if err != nil || d.Invalid != 0 {
+				t.Errorf("-victims, %s: o arquivo não é um trace válido (%v, %d linhas inválidas)", name, err, d.Invalid)
+				continue
+			}
+			if want := r.Faults - min(s.totalFrames, len(distinct)); len(victims.accesses) != want {
+				t.Errorf("-victims, %s: %d vítimas, esperado %d", name, len(victims.accesses), want)
+			}
+			var got, want []string
+			for _, v := range victims.accesses {
+				got = append(got, v.PageID)
+			}
+			policy := s.newPolicyFor(r.Algorithm)
+			for _, access := range s.accesses {
+				if result := step(policy, access); result.Victim != "" {
+					want = append(want, result.Victim)
+				}
+			}
+			if strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("-victims, %s: sequência de vítimas diferente da simulação", name)
+			}
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+// -events-csv e -events-parquet com o Ótimo, o Relógio e o LRU sobre
+// testdata/bursty.txt (4500 eventos) em grupos de 1000 linhas: o rodapé
+// tem o esquema das sete colunas e cinco grupos, e as colunas lidas de
+// volta (com e sem dicionário) são as linhas do CSV. Comprimido, o
+// Parquet fica bem menor que o CSV.
+func TestEventsParquet(t *testing.T) {
+	dir := t.TempDir()
+	for _, dictionary := range []bool{false, true} {
+		s := NewSimulator(6 * PAGE_SIZE)
+		if _, err := s.LoadAccessFile("testdata/bursty.txt"); err != nil {
+			t.Fatal(err)
+		}
+		s.algorithms = []string{"optimal", "clock", "lru"}
+		s.noEstimate = true
+		s.eventsCSV = filepath.Join(dir, "eventos.csv")
+		s.eventsParquet = filepath.Join(dir, "eventos.parquet")
+		s.parquetRowGroup, s.parquetDictionary = 1000, dictionary
+		out := captureStdout(s.Run)
+		if !strings.Contains(out, "Eventos por acesso gravados em "+s.eventsParquet) {
+			t.Errorf("dicionário %t: saída sem o arquivo Parquet:\n%s", dictionary, out)
+		}
+
+		file, err := os.Open(s.eventsCSV)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(file).ReadAll()
+		file.Close()
+		if err != nil || len(records) != 4501 {
+			t.Fatalf("CSV com %d linhas (%v)", len(records), err)
+		}
+
+		data, err := os.ReadFile(s.eventsParquet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+			t.Fatalf("assinatura PAR1 ausente")
+		}
+		footerSize := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+		meta := (&thriftReader{b: data[len(data)-8-footerSize : len(data)-8]}).readStruct()
+		var schema []string
+		for _, element := range meta[2].([]any) {
+			e := element.(map[int]any)
+			schema = append(schema, fmt.Sprintf("%v %v %v", e[4], e[1], e[3]))
+		}
+		wantSchema := "[schema <nil> <nil> access_index 2 0 page_id 6 0 type 6 0 algorithm 6 0 hit 0 0 victim 6 1 frame 1 0]"
+		if fmt.Sprint(schema) != wantSchema || meta[3] != int64(4500) || len(meta[4].([]any)) != 5 {
+			t.Errorf("rodapé: esquema %v, %v linhas, %d grupos", schema, meta[3], len(meta[4].([]any)))
+		}
+
+		var rows [][]string
+		for _, group := range meta[4].([]any) {
+			chunks := group.(map[int]any)[1].([]any)
+			count := int(group.(map[int]any)[3].(int64))
+			columns := make([][]string, len(chunks))
+			for c, chunk := range chunks {
+				columns[c] = readParquetChunk(t, data, chunk.(map[int]any)[3].(map[int]any), count)
+				_, hasDictionary := chunk.(map[int]any)[3].(map[int]any)[11]
+				if name := eventColumns[c].name; hasDictionary != (dictionary && (name == "page_id" || name == "victim")) {
+					t.Errorf("dicionário %t: coluna %s com dicionário %t", dictionary, name, hasDictionary)
+				}
+			}
+			for i := 0; i < count; i++ {
+				row := make([]string, len(columns))
+				for c := range columns {
+					row[c] = columns[c][i]
+				}
+				rows = append(rows, row)
+			}
+		}
+		if fmt.Sprint(rows) != fmt.Sprint(records[1:]) {
+			t.Errorf("dicionário %t: colunas do Parquet diferentes do CSV", dictionary)
+		}
+
+		csvInfo, _ := os.Stat(s.eventsCSV)
+		if len(data)*4 > int(csvInfo.Size()) {
+			t.Errorf("dicionário %t: Parquet com %d bytes, CSV com %d", dictionary, len(data), csvInfo.Size())
+		}
+	}
+}
+
+// Leitor mínimo do protocolo compacto do Thrift para o rodapé e os
+// cabeçalhos de página: cada struct vira um mapa campo -> valor
+type thriftReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) value(kind byte) any {
+	switch kind {
+	case 1, 2:
+		return kind == 1
+	case 4, 5, 6:
+		v, n := binary.Varint(r.b[r.pos:])
+		r.pos += n
+		return v
+	case 8:
+		n := int(r.uvarint())
+		r.pos += n
+		return string(r.b[r.pos-n : r.pos])
+	case 9:
+		header := r.b[r.pos]
+		r.pos++
+		items := make([]any, header>>4)
+		if header>>4 == 15 {
+			items = make([]any, r.uvarint())
+		}
+		for i := range items {
+			items[i] = r.value(header & 0x0f)
+		}
+		return items
+	case 12:
+		return r.readStruct()
+	}
+	panic(fmt.Sprintf("tipo Thrift %d", kind))
+}
+
+func (r *thriftReader) readStruct() map[int]any {
+	fields := map[int]any{}
+	last := 0
+	for {
+		header := r.b[r.pos]
+		r.pos++
+		if header == 0 {
+			return fields
+		}
+		id := last + int(header>>4)
+		if header>>4 == 0 {
+			v, n := binary.Varint(r.b[r.pos:])
+			r.pos += n
+			id = int(v)
+		}
+		fields[id] = r.value(header & 0x0f)
+		last = id
+	}
+}
+
+// Híbrido RLE/bit-packed: count valores de width bits e os bytes lidos
+func readHybrid(b []byte, width, count int) ([]uint32, int) {
+	var values []uint32
+	pos := 0
+	for len(values) < count {
+		header, n := binary.Uvarint(b[pos:])
+		pos += n
+		if header&1 == 0 {
+			var v uint32
+			for i := 0; i < (width+7)/8; i++ {
+				v |= uint32(b[pos+i]) << (8 * i)
+			}
+			pos += (width + 7) / 8
+			for i := uint64(0); i < header>>1; i++ {
+				values = append(values, v)
+			}
+			continue
+		}
+		bitPos := 0
+		for i := 0; i < int(header>>1)*8; i++ {
+			var v uint32
+			for bit := 0; bit < width; bit++ {
+				v |= uint32(b[pos+(bitPos>>3)]>>(bitPos&7)&1) << bit
+				bitPos++
+			}
+			values = append(values, v)
+		}
+		pos += int(header>>1) * width
+	}
+	return values[:count], pos
+}
+
+// Lê as páginas de uma coluna do grupo e devolve os valores como no CSV
+func readParquetChunk(t *testing.T, data []byte, meta map[int]any, count int) []string {
+	t.Helper()
+	if meta[4] != int64(parquetGzip) {
+		t.Fatalf("codec %v", meta[4])
+	}
+	offset, ok := meta[11]
+	if !ok {
+		offset = meta[9]
+	}
+	r := &thriftReader{b: data, pos: int(offset.(int64))}
+	var dictionary []string
+	for {
+		header := r.readStruct()
+		compressed := int(header[3].(int64))
+		zr, err := gzip.NewReader(bytes.NewReader(data[r.pos : r.pos+compressed]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, _ := io.ReadAll(zr)
+		r.pos += compressed
+		if len(page) != int(header[2].(int64)) {
+			t.Fatalf("página com %d bytes, cabeçalho diz %v", len(page), header[2])
+		}
+		plainStrings := func(b []byte, n int) []string {
+			var strs []string
+			for i := 0; i < n; i++ {
+				size := int(binary.LittleEndian.Uint32(b))
+				strs = append(strs, string(b[4:4+size]))
+				b = b[4+size:]
+			}
+			return strs
+		}
+		if header[1] == int64(parquetDictionaryPage) {
+			dictionary = plainStrings(page, int(header[7].(map[int]any)[1].(int64)))
+			continue
+		}
+
+		dataHeader := header[5].(map[int]any)
+		defined := make([]uint32, count)
+		present := count
+		if meta[3].([]any)[0] == "victim" {
+			size := int(binary.LittleEndian.Uint32(page))
+			defined, _ = readHybrid(page[4:4+size], 1, count)
+			page = page[4+size:]
+			present = 0
+			for _, d := range defined {
+				present += int(d)
+			}
+		} else {
+			for i := range defined {
+				defined[i] = 1
+			}
+		}
+		var values []string
+		switch {
+		case dataHeader[2] == int64(parquetPlainDictionary):
+			ids, _ := readHybrid(page[1:], int(page[0]), present)
+			for _, id := range ids {
+				values = append(values, dictionary[id])
+			}
+		case meta[1] == int64(parquetByteArray):
+			values = plainStrings(page, present)
+		case meta[1] == int64(parquetInt64):
+			for i := 0; i < present; i++ {
+				values = append(values, strconv.FormatInt(int64(binary.LittleEndian.Uint64(page[8*i:])), 10))
+			}
+		case meta[1] == int64(parquetInt32):
+			for i := 0; i < present; i++ {
+				values = append(values, strconv.Itoa(int(int32(binary.LittleEndian.Uint32(page[4*i:])))))
+			}
+		case meta[1] == int64(parquetBoolean):
+			for i := 0; i < present; i++ {
+				values = append(values, strconv.FormatBool(page[i/8]>>(i%8)&1 == 1))
+			}
+		}
+		column := make([]string, count)
+		for i, next := 0, 0; i < count; i++ {
+			if defined[i] == 1 {
+				column[i] = values[next]
+				next++
+			}
+		}
+		return column
+	}
+}
+
+// Segundas chances num exemplo feito à mão, 3 frames, D1 D2 D3 D4 D2 D5 D6:
+// D4 poupa D1, D2 e D3 e substitui D1 (desperdiçada); D2 é acessada
+// (útil); D5 poupa D2 e substitui D3 (desperdiçada); D6 poupa D4 e
+// substitui D2 (desperdiçada). D4 fica sem desfecho. Com -warmup 4 as
+// três primeiras ficam de fora, e o acerto em D2 também.
+func TestSecondChanceOutcomes(t *testing.T) {
+	for _, name := range []string{"clock", "secondchance"} {
+		s := NewSimulator(3 * PAGE_SIZE)
+		for _, page := range strings.Fields("D1 D2 D3 D4 D2 D5 D6") {
+			s.accesses = append(s.accesses, PageAccess{PageID: page, Type: "D"})
+		}
+		info, _ := findPolicy(name)
+		for warmup, want := range map[int]SaveStats{0: {5, 1, 3, 1}, 4: {2, 0, 1, 1}} {
+			s.warmup = warmup
+			if got := s.runPolicy(info.New(s)).Saves; got != want {
+				t.Errorf("segundas chances, %s com -warmup %d: %+v, esperado %+v", name, warmup, got, want)
+			}
+		}
+	}
+}
+
+// -playback: com um relógio falso, as esperas seguem os atrasos do trace
+// divididos pelo fator; + dobra o fator e a pausa suspende a espera até
+// o espaço seguinte. Sem a coluna +N vale -access-time. Fora de um
+// terminal o modo didático não espera.
+func TestPlayback(t *testing.T) {
+	s := NewSimulator(2 * PAGE_SIZE)
+	for _, line := range []string{"D1 +500000000", "D2 +2000000000", "D1", "D3 +1000000000"} {
+		access, _ := parseLine(line)
+		s.accesses = append(s.accesses, access)
+	}
+	s.didacticMode, s.playback = true, 100
+	keys := make(chan byte, 2)
+	never := make(chan time.Time)
+	var waits []time.Duration
+	pace := s.newPacer(keys)
+	pace.after = func(d time.Duration) <-chan time.Time {
+		waits = append(waits, d)
+		switch len(waits) {
+		case 2:
+			keys <- '+'
+			return never
+		case 3:
+			keys <- ' '
+			keys <- ' '
+			return never
+		}
+		ready := make(chan time.Time, 1)
+		ready <- time.Time{}
+		return ready
+	}
+	s.pace = pace
+	clock := s.newClock()
+	captureStdout(func() { s.runPolicy(clock, s.reportObservers(clock)...) })
+	s.pace = nil
+	want := []time.Duration{5 * time.Millisecond, 20 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond, 0}
+	if fmt.Sprint(waits) != fmt.Sprint(want) || pace.factor != 200 || pace.paused {
+		t.Errorf("-playback: esperas %v (fator %g), esperado %v (fator 200)", waits, pace.factor, want)
+	}
+	untimed := NewSimulator(2 * PAGE_SIZE)
+	untimed.accesses, untimed.playback = []PageAccess{{PageID: "D1", Type: "D"}}, 100
+	if d := untimed.newPacer(nil).delay(untimed.accesses[0]); d != 10*time.Millisecond {
+		t.Errorf("-playback sem +N: espera %v, esperado 10ms", d)
+	}
+	untimed.accessTime = int64(3 * time.Second)
+	if d := untimed.newPacer(nil).delay(untimed.accesses[0]); d != 30*time.Millisecond {
+		t.Errorf("-playback com -access-time 3s: espera %v, esperado 30ms", d)
+	}
+	if r, w, err := os.Pipe(); err == nil {
+		if s.pacing(w) {
+			t.Errorf("-playback: modo didático fora de um terminal esperaria entre os acessos")
+		}
+		r.Close()
+		w.Close()
+	}
+}
+
+// -weight: no trace abaixo, com 3 frames, o Ótimo faz 6 faltas (2 em
+// páginas I) e o Relógio 7 (1 em I); com I=3,D=1 o Relógio passa à
+// frente. No custo simulado o peso multiplica só as faltas.
+func TestWeightedFaults(t *testing.T) {
+	s := NewSimulator(3 * PAGE_SIZE)
+	for _, page := range strings.Fields("D3 D3 D4 D1 I1 D2 D1 D3 D2 D3 D2 I1 D1 D2") {
+		s.accesses = append(s.accesses, PageAccess{PageID: page, Type: page[:1]})
+	}
+	results := []Result{s.runOptimal(), s.runPolicy(s.newClock())}
+	results[1].Algorithm = "Relógio"
+	weights, err := parseFaultWeights("I=3,D=1")
+	raw := rankResults(results, func(r Result) float64 { return float64(r.Faults) })
+	weighted := rankResults(results, func(r Result) float64 { return r.WeightedFaults(weights) })
+	if err != nil || results[0].Faults != 6 || results[0].InstrFaults != 2 || results[1].Faults != 7 || results[1].InstrFaults != 1 ||
+		raw != "Ótimo < Relógio" || weighted != "Relógio < Ótimo" {
+		t.Errorf("-weight: faltas %d/%d (I %d/%d), ordem %q, ponderada %q", results[0].Faults, results[1].Faults,
+			results[0].InstrFaults, results[1].InstrFaults, raw, weighted)
+	}
+	for text, ok := range map[string]bool{"I=1,D=0.6": true, "D=0": true, " i=2 , d=1 ": true,
+		"I=-1": false, "X=1": false, "I": false, "I=1,": false, "D=NaN": false, "I=+Inf": false} {
+		if _, err := parseFaultWeights(text); (err == nil) != ok {
+			t.Errorf("-weight %q: erro %v", text, err)
+		}
+	}
+	if w, _ := parseFaultWeights("I=2"); w != (FaultWeights{2, 1}) {
+		t.Errorf("-weight I=2: %+v, esperado D=1", w)
+	}
+	s.costReport = true
+	cost := func(weighted bool, w FaultWeights) int64 {
+		s.weighted, s.weights = weighted, w
+		return s.runPolicy(s.newClock()).Costs.Total()
+	}
+	plain, none, double := cost(false, FaultWeights{}), cost(true, FaultWeights{0, 0}), cost(true, FaultWeights{2, 2})
+	if none >= plain || double != 2*plain-none {
+		t.Errorf("-weight no custo: sem pesos %d, pesos 0 %d, pesos 2 %d", plain, none, double)
+	}
+}
+
+// -truncate-at-phase: três fases sobre conjuntos de páginas disjuntos são
+// cortadas exatamente nas fronteiras; um trace estacionário tem uma fase
+func TestTruncateAtPhase(t *testing.T) {
+	s := NewSimulator(4 * PAGE_SIZE)
+	phaseRng := rand.New(rand.NewSource(2))
+	for phase, length := range []int{3700, 5100, 2900} {
+		for range length {
+			page := fmt.Sprintf("D%d", 20*phase+phaseRng.Intn(20))
+			s.accesses = append(s.accesses, PageAccess{PageID: page, Type: "D", Write: phase == 2})
+		}
+	}
+	trace := s.accesses
+	if got := detectPhases(trace, defaultPhaseWindow); fmt.Sprint(got) != "[3700 8800]" {
+		t.Errorf("-truncate-at-phase: fronteiras %v, esperado [3700 8800]", got)
+	}
+	for n, want := range []int{3700, 8800, len(trace)} {
+		s.accesses = trace
+		cut, err := s.truncateAtPhase(n + 1)
+		if err != nil || cut != want || len(s.accesses) != want {
+			t.Errorf("-truncate-at-phase %d: %d acessos (%v), esperado %d", n+1, cut, err, want)
+		}
+	}
+	if len(s.distinctPages) != 60 || s.writeCount != 2900 {
+		t.Errorf("-truncate-at-phase: %d páginas e %d escritas após o corte, esperado 60 e 2900",
+			len(s.distinctPages), s.writeCount)
+	}
+	s.accesses, s.truncatePhase = trace, 1
+	var d Diagnostics
+	if err := s.applyPhaseCut(&d); err != nil || d.TruncatedAt != 3700 || d.Phase != 1 ||
+		len(s.distinctPages) != 20 || s.writeCount != 0 {
+		t.Errorf("-truncate-at-phase: diagnóstico %+v (%v), %d páginas", d, err, len(s.distinctPages))
+	}
+	s.accesses = trace
+	if _, err := s.truncateAtPhase(4); err == nil {
+		t.Errorf("-truncate-at-phase 4 num trace de 3 fases não deu erro")
+	}
+	stationary := make([]PageAccess, 10000)
+	for i := range stationary {
+		stationary[i] = PageAccess{PageID: fmt.Sprintf("D%d", phaseRng.Intn(40)), Type: "D"}
+	}
+	if got := detectPhases(stationary, defaultPhaseWindow); len(got) != 0 {
+		t.Errorf("-truncate-at-phase: trace estacionário com fronteiras %v", got)
+	}
+}
+
+// -patterns: rodadas de 6 acessos; D10, D20 e D30 abrem todas (laço),
+// D100-D114 são varridos duas vezes nas 10 primeiras, e nas demais D50
+// alterna intervalos de 4 e 8 (estável), D40 e D60 vêm em dois grupos
+// (rajada) e o resto são páginas I de uso único
+func TestPatterns(t *testing.T) {
+	s := NewSimulator(8 * PAGE_SIZE)
+	single := 0
+	for r := range 40 {
+		round := []string{"D10", "D20", "D30", "", "", ""}
+		if r < 10 {
+			for k := range 3 {
+				round[3+k] = fmt.Sprintf("D%d", 100+3*(r%5)+k)
+			}
+		} else {
+			round[3+2*(r%2)] = "D50"
+			if r <= 12 || r >= 37 {
+				round[4] = "D40"
+			} else if r >= 13 && r <= 16 || r >= 33 && r <= 36 {
+				round[4] = "D60"
+			}
+		}
+		for _, page := range round {
+			if page == "" {
+				page = fmt.Sprintf("I%d", 2*single+1)
+				single++
+			}
+			s.accesses = append(s.accesses, PageAccess{PageID: page, Type: page[:1]})
+		}
+	}
+	s.recount()
+	want := []int{46, 3, 15, 2, 1}
+	profiles := classifyPages(s.accesses, defaultPatternThresholds, defaultPageRadix)
+	s.classifyTrace()
+	if got := s.patternPopulation(); fmt.Sprint(got) != fmt.Sprint(want) || single != 46 {
+		t.Errorf("-patterns: população %v, esperado %v", got, want)
+	}
+	for page, pattern := range map[string]PagePattern{"D10": patternLoop, "D107": patternScan,
+		"D40": patternBursty, "D60": patternBursty, "D50": patternSteady, "I1": patternSingle} {
+		if p := profiles[page]; p == nil || p.Pattern != pattern {
+			t.Errorf("-patterns: %s classificada como %+v, esperado %s", page, p, pattern)
+		}
+	}
+	// O aquecimento (5 rodadas) não tem páginas de uso único
+	s.warmup = 30
+	for _, r := range []Result{s.runOptimal(), s.runPolicy(s.newClock())} {
+		total := 0
+		for _, n := range r.PatternFaults {
+			total += n
+		}
+		if len(r.PatternFaults) != int(numPatterns) || total != r.Faults || r.PatternFaults[patternSingle] != single {
+			t.Errorf("-patterns: faltas por padrão %v, %d faltas no total", r.PatternFaults, r.Faults)
+		}
+	}
+	th, err := parsePatternThresholds("burst=2")
+	if err != nil || th.Scan != defaultPatternThresholds.Scan {
+		t.Errorf("-pattern-thresholds burst=2: %+v (%v)", th, err)
+	}
+	s.patternThresholds = th
+	s.classifyTrace()
+	if got := s.patternPopulation(); got[patternBursty] != 0 || got[patternSteady] != 3 {
+		t.Errorf("-pattern-thresholds burst=2: população %v, esperado as rajadas como estáveis", got)
+	}
+	for _, text := range []string{"scan=1.5", "loop=-1", "burst", "cv=1", "loop=NaN"} {
+		if _, err := parsePatternThresholds(text); err == nil {
+			t.Errorf("-pattern-thresholds %q não deu erro", text)
+		}
+	}
+	if dir, err := os.MkdirTemp("", "sim-patterns"); err == nil {
+		file := filepath.Join(dir, "pages.csv")
+		err := writePatternsCSV(file, profiles, defaultPageRadix)
+		data, _ := os.ReadFile(file)
+		if lines := strings.Count(string(data), "\n"); err != nil || lines != len(profiles)+1 {
+			t.Errorf("-patterns-csv: %d linhas (%v), esperado %d", lines, err, len(profiles)+1)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+// go test -run TestReportGolden -update regrava testdata/golden depois de
+// uma mudança intencional nos relatórios
+var updateGolden = flag.Bool("update", false, "regrava os arquivos de testdata/golden")
+
+// Tempos de parede mudam a cada execução; o resto dos relatórios não
+var (
+	goldenTiming   = regexp.MustCompile(`(?m)^Tempo: .*\n(  índice nextUse: .*\n)?`)
+	goldenDuration = regexp.MustCompile(`(?m) +[0-9.]+(ns|µs|ms|s)$`)
+	goldenJSONTime = regexp.MustCompile(`"(elapsed_ns|index_ns|accesses_per_sec|ns_per_fault)": [0-9.e+-]+`)
+)
+
+// Ordem dos relatórios: o resumo em texto, o JSON de -json e o CSV de
+// -patterns-csv sobre testdata/golden/trace.txt são os arquivos .golden,
+// byte a byte. As páginas (D2 antes de D10, D antes de I) e os algoritmos
+// (na ordem do registro) não podem depender da ordem dos mapas, então
+// cada execução repetida tem de dar o mesmo resultado.
+func TestReportGolden(t *testing.T) {
+	dir := t.TempDir()
+	outputs := map[string]string{}
+	for run := 0; run < 3; run++ {
+		s := NewSimulator(3 * PAGE_SIZE)
+		if _, err := s.LoadAccessFile("testdata/golden/trace.txt"); err != nil {
+			t.Fatal(err)
+		}
+		s.algorithms = []string{"lru", "optimal", "fifo", "clock"}
+		s.noEstimate, s.showLoadCount, s.patterns = true, true, true
+		s.resultsJSON = filepath.Join(dir, "report.json")
+		s.patternsCSV = filepath.Join(dir, "patterns.csv")
+		text := captureStdout(s.Run)
+		text = goldenTiming.ReplaceAllString(text, "Tempo: -\n")
+		text = goldenDuration.ReplaceAllString(text, " -")
+		text = strings.ReplaceAll(text, dir+string(filepath.Separator), "")
+		data, _ := os.ReadFile(s.resultsJSON)
+		csv, _ := os.ReadFile(s.patternsCSV)
+		got := map[string]string{
+			"report.txt":   text,
+			"report.json":  goldenJSONTime.ReplaceAllString(string(data), `"$1": 0`),
+			"patterns.csv": string(csv),
+		}
+		for name, output := range got {
+			if run > 0 && output != outputs[name] {
+				t.Errorf("%s muda entre execuções iguais:\n%s\n---\n%s", name, outputs[name], output)
+			}
+			outputs[name] = output
+		}
+	}
+
+	for name, output := range outputs {
+		path := filepath.Join("testdata/golden", name)
+		if *updateGolden {
+			if err := os.WriteFile(path, []byte(output), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("%v (gere com go test -run TestReportGolden -update)", err)
+			continue
+		}
+		wantLines, gotLines := strings.Split(string(want), "\n"), strings.Split(output, "\n")
+		for i := 0; i < max(len(wantLines), len(gotLines)); i++ {
+			var w, g string
+			if i < len(wantLines) {
+				w = wantLines[i]
+			}
+			if i < len(gotLines) {
+				g = gotLines[i]
+			}
+			if w != g {
+				t.Errorf("%s, linha %d:\n  esperado: %s\n  obtido:   %s", path, i+1, w, g)
+				break
+			}
+		}
+	}
+}
+
+// Narração de -show-pagetable-live com o Relógio e 3 frames sobre um
+// trace pequeno com escritas: testdata/golden/pagetable.txt, byte a byte.
+// Das páginas fora da memória só aparecem as dos últimos 8 acessos.
+func TestPageTableGolden(t *testing.T) {
+	s := NewSimulator(3 * PAGE_SIZE)
+	s.LoadAccesses(strings.NewReader("D1 W\nD2\nD3\nD1\nD4 W\nD2\nD5\nD1 W\nD3\nD6\nD6\nD7\nD4\nD8\n"))
+	s.didacticMode, s.showPageTableLive = true, true
+	policy := s.newClock()
+	output := captureStdout(func() { s.runPolicy(policy, &narrator{s: s, policy: policy}) })
+
+	path := filepath.Join("testdata/golden", "pagetable.txt")
+	if *updateGolden {
+		if err := os.WriteFile(path, []byte(output), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (gere com go test -run TestPageTableGolden -update)", err)
+	}
+	if output != string(want) {
+		t.Errorf("%s difere da narração:\n%s", path, output)
+	}
+}
+
+// Segmentação com paginação: S<n>:<deslocamento> cai na página do
+// segmento, volta igual pela forma normalizada, e com -segment-limits os
+// acessos além do limite viram violações, fora das faltas de página
+func TestSegments(t *testing.T) {
+	access, err := parseLine("S3:0x1f40 W")
+	if want := (PageAccess{PageID: "S3:0x1000", Type: "D", Write: true, Offset: 0x1f40}); err != nil || access != want {
+		t.Errorf("S3:0x1f40 W: %+v %v, esperado %+v", access, err, want)
+	}
+	if back, err := parseLine(access.String()); err != nil || back != access {
+		t.Errorf("%q volta %+v %v", access.String(), back, err)
+	}
+	if access, err := parseLine("S1:8191"); err != nil || access.PageID != "S1:0x1000" || access.Offset != 8191 {
+		t.Errorf("S1:8191: %+v %v", access, err)
+	}
+	for _, bad := range []string{"S3", "S:0x10", "S3:", "S3:0x", "Sx:1", "S3:0x1g", "S3:-1", "S1:0x100000000"} {
+		if _, err := parseLine(bad); err != errSegment {
+			t.Errorf("%s: %v, esperado %v", bad, err, errSegment)
+		}
+	}
+	pages := []string{"S1:0x0", "D2", "S0:0x2000", "S0:0x10000", "S0:0x0"}
+	sortPageIDs(pages, defaultPageRadix)
+	if fmt.Sprint(pages) != "[D2 S0:0x0 S0:0x2000 S0:0x10000 S1:0x0]" {
+		t.Errorf("ordem das páginas de segmentos: %v", pages)
+	}
+
+	limits, err := parseSegmentLimits("S0=0x3000, S1=8192\n# sem S2\n3 0x1000\n")
+	if err != nil || fmt.Sprint(limits) != "map[0:12288 1:8192 3:4096]" {
+		t.Errorf("limites: %v %v", limits, err)
+	}
+	for _, bad := range []string{"", "S0", "S0=x", "Sx=10", "S0=1 2"} {
+		if _, err := parseSegmentLimits(bad); err == nil {
+			t.Errorf("limites %q aceitos", bad)
+		}
+	}
+
+	trace := "S0:0x10\nS0:0x1010 W\nS1:100\nS0:0x2000\nS1:0x5000\nS0:0x10\nS2:0\nS1:0x1f40\nD7\n"
+	s := NewSimulator(2 * PAGE_SIZE)
+	s.segmentLimits = limits
+	if d, err := s.LoadAccesses(strings.NewReader(trace)); err != nil || d.Accesses != 7 || s.writeCount != 1 || len(s.distinctPages) != 6 {
+		t.Errorf("com limites: %+v %v, %d escritas, %d páginas", d, err, s.writeCount, len(s.distinctPages))
+	}
+	if fmt.Sprint(s.segmentViolations) != "map[1:1 2:1]" {
+		t.Errorf("violações: %v", s.segmentViolations)
+	}
+	results := []Result{s.runPolicy(newFIFOPolicy(2)), {Algorithm: "sem estatísticas"}}
+	results[0].Algorithm = "FIFO"
+	output := captureStdout(func() { s.ShowSegments(results) })
+	for _, want := range []string{
+		"S0           0x3000         4         3       24 bytes          0",
+		"S1           0x2000         2         2       16 bytes          1",
+		"S2                -         0         0        0 bytes          1",
+		"Tabelas de páginas: 40 bytes no total (8 bytes por entrada)",
+		"Violações de segmentação: 2 acessos fora dos limites",
+		"FIFO                        4        2        0",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("relatório sem %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "sem estatísticas") {
+		t.Errorf("resultado sem estatísticas listado:\n%s", output)
+	}
+
+	plain := NewSimulator(2 * PAGE_SIZE)
+	plain.LoadAccesses(strings.NewReader("D1\nD2\n"))
+	if output := captureStdout(func() { plain.ShowSegments(nil) }); output != "" {
+		t.Errorf("relatório de segmentos num trace sem segmentos:\n%s", output)
+	}
+}
+
+// fixtures: os arquivos gerados agora são os de testdata/fixtures, byte a
+// byte, e fixtures -check não acha diferenças. Depois de uma mudança de
+// comportamento intencional: go run . fixtures testdata/fixtures
+func TestFixtures(t *testing.T) {
+	dir := t.TempDir()
+	var err error
+	captureStdout(func() { err = writeFixtures(dir) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir("testdata/fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated, _ := os.ReadDir(dir)
+	if len(generated) != len(entries) {
+		t.Errorf("fixtures: %d arquivos gerados, %d em testdata/fixtures", len(generated), len(entries))
+	}
+	for _, entry := range entries {
+		want, _ := os.ReadFile(filepath.Join("testdata/fixtures", entry.Name()))
+		got, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Errorf("fixtures: %s não foi gerado", entry.Name())
+			continue
+		}
+		wantLines, gotLines := strings.Split(string(want), "\n"), strings.Split(string(got), "\n")
+		for i := 0; i < max(len(wantLines), len(gotLines)); i++ {
+			var w, g string
+			if i < len(wantLines) {
+				w = wantLines[i]
+			}
+			if i < len(gotLines) {
+				g = gotLines[i]
+			}
+			if w != g {
+				t.Errorf("%s, linha %d:\n  esperado: %s\n  obtido:   %s", entry.Name(), i+1, w, g)
+				break
+			}
+		}
+	}
+	var diffs int
+	out := captureStdout(func() { diffs, err = checkFixtures("testdata/fixtures") })
+	if diffs != 0 || err != nil {
+		t.Errorf("fixtures -check: %d diferenças (%v):\n%s", diffs, err, out)
+	}
+}
+
+// results migrate/compare: os arquivos da versão 1 passam para a atual,
+// sem perder as listas, e uma segunda conversão não muda nada; compare
+// recusa o esquema antigo e aceita o convertido
+func TestResultsMigrateCompare(t *testing.T) {
+	entries, _ := os.ReadDir("testdata/results-v1")
+	if len(entries) != 5 {
+		t.Errorf("results migrate: %d arquivos da versão 1, esperado 5", len(entries))
+	}
+	for _, entry := range entries {
+		data, _ := os.ReadFile("testdata/results-v1/" + entry.Name())
+		migrated, from, err := migrateResults(data)
+		again, fromAgain, errAgain := migrateResults(migrated)
+		old, _ := decodeResultsJSON(data)
+		doc, _ := decodeResultsJSON(migrated)
+		version, _ := schemaVersionOf(doc)
+		if err != nil || errAgain != nil || from != 1 || fromAgain != resultsSchemaVersion ||
+			version != resultsSchemaVersion || !bytes.Equal(migrated, again) {
+			t.Errorf("results migrate %s: versão %d -> %d (%v, %v)", entry.Name(), from, version, err, errAgain)
+		}
+		if list, ok := old.([]any); ok {
+			if algorithms, _ := doc.(map[string]any)["algorithms"].([]any); len(algorithms) != len(list) {
+				t.Errorf("results migrate %s: %d algoritmos, esperado %d", entry.Name(), len(algorithms), len(list))
+			}
+		}
+	}
+	data, _ := os.ReadFile("testdata/results-v1/response.json")
+	if _, err := parseResults(data, "response.json"); err == nil || !strings.Contains(err.Error(), "results migrate") {
+		t.Errorf("results compare aceitou o esquema 1: %v", err)
+	}
+	migrated, _, _ := migrateResults(data)
+	base, err := parseResults(migrated, "response.json")
+	if err != nil || len(base.Results) != 2 || base.Results[1].Faults != 14 {
+		t.Errorf("results compare: resultados convertidos ilegíveis: %v", err)
+	} else {
+		s := NewSimulator(3 * PAGE_SIZE)
+		s.algorithms = []string{"optimal", "clock"} // os da referência
+		text, _ := exampleTraces.ReadFile("examples/textbook.txt")
+		s.LoadAccesses(bytes.NewReader(text))
+		current, _ := json.Marshal(s.response(s.Simulate()))
+		now, err := parseResults(current, "atual")
+		if diffs := diffResults(base, now); err != nil || len(diffs) != 0 {
+			t.Errorf("results compare: a execução atual difere da de referência: %v %v", diffs, err)
+		}
+		now.Results[1].Faults++
+		if diffs := diffResults(base, now); len(diffs) != 1 {
+			t.Errorf("results compare: %d diferenças com uma falta a mais, esperado 1", len(diffs))
+		}
+	}
+	if _, _, err := migrateResults([]byte(`{"schema_version": 99}`)); err == nil {
+		t.Errorf("results migrate aceitou um esquema mais novo")
+	}
+}
+
+// MFU contra LFU com 3 frames: D1, a página quente, sai no acesso de D4
+// e de novo no de D3; o LFU a mantém e faz 6 faltas contra 8
+func TestMFU(t *testing.T) {
+	var trace []PageAccess
+	for _, page := range strings.Fields("D1 D1 D1 D2 D3 D4 D1 D2 D1 D3 D1") {
+		trace = append(trace, PageAccess{PageID: page, Type: "D"})
+	}
+	lfuScore, _ := compileExpr("idle - accesses*1000000")
+	mfu := checkedRun(t, newMFUPolicy(3), trace, 3)
+	lfu := checkedRun(t, newExprPolicy(3, lfuScore), trace, 3)
+	if mfu.Faults != 8 || lfu.Faults != 6 || strings.Join(mfu.Victims, " ") != "D1 D2 D3 D1 D4" {
+		t.Errorf("MFU contra LFU: %d faltas (vítimas %v) e %d, esperado 8 e 6", mfu.Faults, mfu.Victims, lfu.Faults)
+	}
+}
+
+// NRU com 3 frames e o bit R limpo a cada 4 acessos: depois da limpeza
+// só D2 é usada, então D3 (classe 0) sai primeiro e D1, escrita e não
+// usada (classe 1), sai em seguida com gravação
+func TestNRU(t *testing.T) {
+	var trace []PageAccess
+	for _, page := range strings.Fields("D1 D2 D3 D1 D2 D4 D3") {
+		trace = append(trace, PageAccess{PageID: page, Type: "D", Write: page == "D1"})
+	}
+	nru := newNRUPolicy(3, 4, 1)
+	faults, writeBacks := 0, 0
+	var victims []string
+	for _, access := range trace {
+		if r := step(nru, access); !r.Hit {
+			faults++
+			if r.Victim != "" {
+				victims = append(victims, r.Victim)
+			}
+			if r.WriteBack {
+				writeBacks++
+			}
+		}
+	}
+	if faults != 5 || writeBacks != 1 || strings.Join(victims, " ") != "D3 D1" || nru.victims != [4]int{1, 1, 0, 0} {
+		t.Errorf("NRU: %d faltas, %d gravações, vítimas %v por classe %v; esperado 5, 1, [D3 D1] e [1 1 0 0]",
+			faults, writeBacks, victims, nru.victims)
+	}
+}
+
+// Faltas de cada -clock-variant em examples/textbook.txt. O advance só
+// muda o ponteiro durante o preenchimento (3 páginas em 4 frames), e o
+// restart, que recomeça cada busca no frame 0, tira uma falta a menos
+func TestClockVariants(t *testing.T) {
+	data, err := exampleTraces.ReadFile("examples/textbook.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		variant string
+		faults  [2]int // 3 e 4 frames
+		hand    int    // ponteiro depois de ocupar 3 dos 4 frames
+	}{
+		{"classic", [2]int{14, 9}, 0},
+		{"advance", [2]int{14, 9}, 3},
+		{"restart", [2]int{13, 8}, 0},
+	} {
+		s := NewSimulator(4 * PAGE_SIZE)
+		s.LoadAccesses(bytes.NewReader(data))
+		s.clockVariant = c.variant
+		var got [2]int
+		for i, frames := range []int{3, 4} {
+			if got[i], err = s.stepperFaults(s.accesses, frames, "clock"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		clock := s.newClock()
+		for _, access := range s.accesses[:3] {
+			clock.Access(access.PageID)
+		}
+		if got != c.faults || clock.Hand() != c.hand {
+			t.Errorf("%s: faltas %v e ponteiro %d, esperado %v e %d", c.variant, got, clock.Hand(), c.faults, c.hand)
+		}
+	}
+}
+
+// Limpeza periódica dos bits R em examples/textbook.txt com 3 frames: a
+// cada acesso o Relógio vira FIFO (15 faltas), a cada 3 ou 4 acessos fica
+// abaixo do Relógio sem interrupção (14) e, sem a limpeza pelo ponteiro,
+// um intervalo longo volta ao FIFO
+func TestRefClearInterval(t *testing.T) {
+	data, err := exampleTraces.ReadFile("examples/textbook.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		interval  int
+		timerOnly bool
+		faults    int
+	}{
+		{0, false, 14},
+		{1, false, 15},
+		{2, false, 12},
+		{3, false, 11},
+		{5, false, 13},
+		{8, true, 14},
+		{1000, true, 15},
+	} {
+		s := NewSimulator(3 * PAGE_SIZE)
+		s.LoadAccesses(bytes.NewReader(data))
+		s.refClearInterval = c.interval
+		s.refClearOnlyTimer = c.timerOnly
+		faults := s.runPolicy(s.newClock()).Faults
+		stepped, err := s.stepperFaults(s.accesses, 3, "clock")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if faults != c.faults || stepped != c.faults {
+			t.Errorf("intervalo %d (só interrupção: %t): %d faltas (stepper %d), esperado %d",
+				c.interval, c.timerOnly, faults, stepped, c.faults)
+		}
+	}
+
+	s := NewSimulator(3 * PAGE_SIZE)
+	s.LoadAccesses(bytes.NewReader(data))
+	s.refClearSweep = []int{0, 1, 3}
+	out := captureStdout(s.RefClearSweep)
+	for _, row := range []string{"sem limpeza         14", "           1         15", "           3         11"} {
+		if !strings.Contains(out, row) {
+			t.Errorf("varredura sem a linha %q:\n%s", row, out)
+		}
+	}
+}
+
+// Relógio adaptativo contra o comum: com acessos uniformes a 16 páginas a
+// taxa de faltas é constante e o adaptativo não pode ser pior; no trace em
+// rajadas de testdata/bursty.txt ele envelhece os bits R das páginas da
+// rajada e faz menos faltas que o Relógio comum
+func TestClockAdaptive(t *testing.T) {
+	steady := stationaryTrace()
+	for _, frames := range []int{4, 8, 12} {
+		s := NewSimulator(frames * PAGE_SIZE)
+		s.accesses = steady
+		plain := s.runPolicy(s.newClock()).Faults
+		s.clockAdaptive = true
+		if adaptive := s.runPolicy(s.newClock()).Faults; adaptive > plain {
+			t.Errorf("%d frames, carga estável: adaptativo %d faltas, Relógio %d", frames, adaptive, plain)
+		}
+	}
+
+	s := NewSimulator(6 * PAGE_SIZE)
+	if _, err := s.LoadAccessFile("testdata/bursty.txt"); err != nil {
+		t.Fatal(err)
+	}
+	s.clockAdaptive = true
+	clock := s.newClock()
+	faults := s.runPolicy(clock).Faults
+	out := captureStdout(func() { s.ShowAdaptiveClock(clock, faults) })
+	for _, line := range []string{
+		"Varredura média: 2.96 frames por substituição",
+		"Faltas de página (adaptativo): 297",
+		"Faltas de página (Relógio comum): 310",
+		"Diferença: -13 faltas",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("rajadas: sem a linha %q:\n%s", line, out)
+		}
+	}
+}
+
+// Segunda chance melhorada com 3 frames: com todos os bits R marcados,
+// o Relógio comum tira D1, escrita, e a variante com o bit M tira D2,
+// limpa; no acesso seguinte sai D3, de novo sem gravação
+func TestEnhancedClock(t *testing.T) {
+	s := NewSimulator(3 * PAGE_SIZE)
+	s.LoadAccesses(strings.NewReader("D1 W\nD2\nD3\nD4\nD5\n"))
+	victims := checkedRun(t, newEnhancedClockPolicy(3), s.accesses, 3).Victims
+	enhanced := s.runPolicy(newEnhancedClockPolicy(3))
+	out := captureStdout(func() { s.ShowEnhancedClock(enhanced) })
+	if strings.Join(victims, " ") != "D2 D3" || enhanced.WriteBacks != 0 ||
+		!strings.Contains(out, "Gravações evitadas pelo bit M: 1 (faltas: +0)") {
+		t.Errorf("segunda chance melhorada: vítimas %v, %d gravações, saída:\n%s", victims, enhanced.WriteBacks, out)
+	}
+}
+
+// WSClock com 3 frames e τ=2: na primeira substituição todas estão com
+// R=1 e sai a primeira limpa, D2; na segunda, D1, escrita e fora do
+// working set, tem a gravação agendada e sai na volta seguinte, limpa
+func TestWSClock(t *testing.T) {
+	var trace []PageAccess
+	for _, page := range strings.Fields("D1 D2 D3 D4 D5") {
+		trace = append(trace, PageAccess{PageID: page, Type: "D", Write: page == "D1"})
+	}
+	wsclock := newWSClockPolicy(3, 2)
+	victims := checkedRun(t, wsclock, trace, 3).Victims
+	if strings.Join(victims, " ") != "D2 D1" || wsclock.scheduledWrites != 1 {
+		t.Errorf("WSClock: vítimas %v, %d gravações agendadas; esperado [D2 D1] e 1", victims, wsclock.scheduledWrites)
+	}
+}
+
+// Working Set com Δ=2 e 4 frames: D2 e D1 saem da memória dois acessos
+// depois do último uso e a série de -rss-interval acompanha a saída
+func TestWorkingSetPolicy(t *testing.T) {
+	s := NewSimulator(4 * PAGE_SIZE)
+	s.LoadAccesses(strings.NewReader("D1\nD2\nD1\nD3\nD3\nD3\nD3\n"))
+	s.seriesInterval = 1
+	ws := newWorkingSetPolicy(4, 2)
+	r := s.runPolicy(ws)
+	if r.Faults != 3 || fmt.Sprint(r.Resident) != "[1 2 2 3 2 1 1]" || ws.peak != 3 || ws.released != 2 {
+		t.Errorf("Working Set: %d faltas, residentes %v, pico %d, %d liberadas; esperado 3, [1 2 2 3 2 1 1], 3 e 2",
+			r.Faults, r.Resident, ws.peak, ws.released)
+	}
+
+	// Janela maior que o trace: o anel não passa do tamanho do trace e
+	// nenhuma página sai, como com Δ igual ao trace
+	huge, whole := newWorkingSetPolicy(4, maxWSWindow), newWorkingSetPolicy(4, len(s.accesses))
+	a, b := checkedRun(t, huge, s.accesses, 4), checkedRun(t, whole, s.accesses, 4)
+	if len(huge.recent) > len(s.accesses)+1 || huge.released != 0 || a.Faults != b.Faults {
+		t.Errorf("Δ=%d: anel de %d posições, %d liberadas, %d faltas contra %d com Δ=%d",
+			maxWSWindow, len(huge.recent), huge.released, a.Faults, b.Faults, len(s.accesses))
+	}
+}
+
+// FIFO: 15 faltas no exemplo do livro-texto com 3 frames e a anomalia de
+// Belady no seu exemplo, com mais faltas em 4 frames que em 3
+func TestFIFO(t *testing.T) {
+	for _, c := range []struct {
+		example string
+		frames  int
+		faults  int
+		queue   string
+	}{
+		{"textbook.txt", 3, 15, "D7 D0 D1"}, {"belady.txt", 3, 9, "D5 D3 D4"}, {"belady.txt", 4, 10, "D2 D3 D4 D5"},
+	} {
+		s := NewSimulator(c.frames * PAGE_SIZE)
+		data, _ := exampleTraces.ReadFile("examples/" + c.example)
+		s.LoadAccesses(bytes.NewReader(data))
+		fifo := newFIFOPolicy(c.frames)
+		for _, access := range s.accesses {
+			step(fifo, access)
+		}
+		var queue []string
+		for _, frame := range fifo.Queue() {
+			queue = append(queue, frame.PageID)
+		}
+		if faults := s.FIFOAlgorithm(); faults != c.faults || strings.Join(queue, " ") != c.queue {
+			t.Errorf("FIFO em %s com %d frames: %d faltas, fila %v; esperado %d e [%s]", c.example, c.frames, faults, queue, c.faults, c.queue)
+		}
+	}
+}
+
+// LRU: no exemplo do livro-texto com 3 frames, 12 faltas, entre as 9 do
+// Ótimo e as 14 do Relógio; a fila vai da usada há mais tempo à mais recente
+func TestLRU(t *testing.T) {
+	s := NewSimulator(3 * PAGE_SIZE)
+	data, _ := exampleTraces.ReadFile("examples/textbook.txt")
+	s.LoadAccesses(bytes.NewReader(data))
+	lru := newLRUPolicy(3)
+	for _, access := range s.accesses {
+		step(lru, access)
+	}
+	var queue []string
+	for _, frame := range lru.Queue() {
+		queue = append(queue, frame.PageID)
+	}
+	if faults := s.LRUAlgorithm(); faults != 12 || strings.Join(queue, " ") != "D7 D0 D1" {
+		t.Errorf("LRU no livro-texto: %d faltas, fila %v; esperado 12 e [D7 D0 D1]", faults, queue)
+	}
+}
+
+// -cache: a segunda execução igual vem do cache com o mesmo resultado;
+// outra configuração, outro trace, -no-cache, entradas corrompidas ou de
+// outra versão simulam de novo, e -cache-clear esvazia o diretório
+func TestCache(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	if dir, err := os.MkdirTemp("", "sim-cache"); err != nil {
+		t.Errorf("-cache: %v", err)
+	} else {
+		s := NewSimulator(4 * PAGE_SIZE)
+		for i := 0; i < 500; i++ {
+			s.accesses = append(s.accesses, PageAccess{PageID: fmt.Sprintf("D%d", rng.Intn(9)), Type: "D", Write: i%3 == 0})
+		}
+		s.cacheDir, s.costReport = dir, true
+		runs := 0
+		run := func() (Result, bool) {
+			captured := Result{}
+			cached := false
+			captureStdout(func() {
+				captured, cached = s.cachedRun("clock", s.newClock(), func() Result {
+					runs++
+					return s.runPolicy(s.newClock())
+				})
+			})
+			return captured, cached
+		}
+		expect := func(what string, wantCached bool) Result {
+			before := runs
+			r, cached := run()
+			if cached != wantCached || (runs == before) != wantCached {
+				t.Errorf("-cache, %s: resultado do cache %v, esperado %v", what, cached, wantCached)
+			}
+			return r
+		}
+		first := expect("primeira execução", false)
+		second := expect("execução repetida", true)
+		a, _ := json.Marshal(first)
+		b, _ := json.Marshal(second)
+		if string(a) != string(b) || second.Costs == nil || second.Stats == nil {
+			t.Errorf("-cache: resultado guardado difere do simulado")
+		}
+		s.seed++
+		expect("outra semente", false)
+		s.seed--
+		s.accesses[0].PageID = "D99"
+		s.cacheTrace = ""
+		expect("outro trace", false)
+		expect("outro trace, repetido", true)
+		s.noCache = true
+		expect("-no-cache", false)
+		s.noCache = false
+		key := s.cacheKey("clock")
+		os.WriteFile(s.cachePath(key), []byte("{corrompido"), 0644)
+		expect("entrada corrompida", false)
+		expect("entrada regravada", true)
+		data, _ := os.ReadFile(s.cachePath(key))
+		os.WriteFile(s.cachePath(key), bytes.Replace(data, fmt.Appendf(nil, `"version":%d`, resultCacheVersion), []byte(`"version":0`), 1), 0644)
+		expect("entrada de outra versão", false)
+		if removed, err := s.clearCache(); err != nil || removed != 3 {
+			t.Errorf("-cache-clear: %d entradas removidas (%v), esperado 3", removed, err)
+		}
+		expect("depois de -cache-clear", false)
+		os.RemoveAll(dir)
+	}
+}
+
+// Contexto que responde interrompido a partir da consulta n: a interrupção
+// cai num acesso conhecido
+type interruptAfter struct {
+	context.Context
+	n int
+}
+
+func (c *interruptAfter) Err() error {
+	if c.n--; c.n < 0 {
+		return errInterrupted
+	}
+	return nil
+}
+
+// -checkpoint-every e -resume: retomada depois de uma queda (o disco fica
+// sem o checkpoint final) ou de um Ctrl-C dá os mesmos totais da execução
+// sem interrupção; um algoritmo completo devolve o resultado guardado e
+// um checkpoint de outro trace é ignorado
+func TestCheckpoint(t *testing.T) {
+	stationary := stationaryTrace()
+	const length, every = 3 * cancelCheckEvery, 1000
+	simulator := func(dir string, resume bool) *Simulator {
+		s := NewSimulator(8 * PAGE_SIZE)
+		for i, access := range stationary[:length] {
+			access.Write = i%7 == 0
+			s.accesses = append(s.accesses, access)
+		}
+		s.checkpointDir, s.checkpointEvery, s.resume = dir, every, resume
+		return s
+	}
+	run := func(s *Simulator, name string) (r Result, out string) {
+		out = captureStdout(func() {
+			r, _ = s.cachedRun(name, nil, func() Result {
+				if name == "optimal" {
+					return s.runOptimal()
+				}
+				info, _ := findPolicy(name)
+				return s.runPolicy(info.New(s))
+			})
+		})
+		r.Elapsed, r.IndexTime = 0, 0
+		return r, out
+	}
+	same := func(a, b Result) bool {
+		x, _ := json.Marshal(a)
+		y, _ := json.Marshal(b)
+		return string(x) == string(y)
+	}
+
+	for _, name := range []string{"optimal", "clock", "fifo", "lru"} {
+		want, _ := run(simulator("", false), name)
+		dir := t.TempDir()
+		run(simulator(dir, false), name)
+		paths, _ := filepath.Glob(filepath.Join(dir, "checkpoint-"+name+"-*.gob"))
+		if len(paths) != checkpointKeep {
+			t.Errorf("%s: %d checkpoints guardados, esperado %d", name, len(paths), checkpointKeep)
+			continue
+		}
+
+		// Queda: sem o checkpoint final sobra o do acesso 12000
+		os.Remove(paths[len(paths)-1])
+		got, out := run(simulator(dir, true), name)
+		if !same(got, want) || !strings.Contains(out, "Retomado do checkpoint no acesso 12000") {
+			t.Errorf("%s retomado: %d faltas e %d gravações, esperado %d e %d; saída:\n%s",
+				name, got.Faults, got.WriteBacks, want.Faults, want.WriteBacks, out)
+		}
+		if got, out := run(simulator(dir, true), name); !same(got, want) || strings.Contains(out, "Retomado") {
+			t.Errorf("%s completo: resultado guardado difere do simulado", name)
+		}
+
+		// Ctrl-C na terceira consulta ao contexto, no acesso 2*cancelCheckEvery
+		interrupted := simulator(dir, false)
+		interrupted.ctx = &interruptAfter{context.Background(), 2}
+		if r, _ := run(interrupted, name); r.Err != errInterrupted {
+			t.Errorf("%s: interrupção não aconteceu (%v)", name, r.Err)
+		}
+		got, out = run(simulator(dir, true), name)
+		if !same(got, want) || !strings.Contains(out, fmt.Sprintf("Retomado do checkpoint no acesso %d", 2*cancelCheckEvery)) {
+			t.Errorf("%s retomado do Ctrl-C: %d faltas, esperado %d; saída:\n%s", name, got.Faults, want.Faults, out)
+		}
+	}
+
+	// Outro trace: o checkpoint não vale e a simulação começa do início
+	dir := t.TempDir()
+	run(simulator(dir, false), "clock")
+	other := simulator(dir, true)
+	other.accesses = other.accesses[1:]
+	want, _ := run(simulator("", false), "clock")
+	var got Result
+	log := captureLog(func() { got, _ = run(other, "clock") })
+	if !strings.Contains(log, "outro trace") || got.Accesses != length-1 || same(got, want) {
+		t.Errorf("checkpoint de outro trace: %d acessos; log:\n%s", got.Accesses, log)
+	}
+}
+
+// -target-faults: com até targetScanWindow páginas distintas a busca
+// confere todos os tamanhos abaixo e acha o menor, como a varredura
+// direta; nos exemplos a resposta é conhecida
+func TestTargetFaults(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 20; trial++ {
+		s := NewSimulator(PAGE_SIZE)
+		for i := 0; i < 300; i++ {
+			access := PageAccess{PageID: fmt.Sprintf("D%d", rng.Intn(2+trial%8)), Type: "D"}
+			s.accesses = append(s.accesses, access)
+			s.distinctPages[access.PageID] = true
+		}
+		budget := len(s.distinctPages) + rng.Intn(150)
+		for _, name := range []string{"optimal", "clock"} {
+			want := 0
+			for c := 1; c <= len(s.distinctPages) && want == 0; c++ {
+				stepper, _ := s.NewStepper(s.accesses, c, name)
+				for {
+					if _, more := stepper.Step(); !more {
+						break
+					}
+				}
+				if stepper.Summary().Faults <= budget {
+					want = c
+				}
+			}
+			if rec, err := s.recommendFrames(name, budget); err != nil || rec.Frames != want {
+				t.Errorf("-target-faults %d, %s: %d frames, esperado %d (%v)", budget, name, rec.Frames, want, err)
+			}
+		}
+	}
+	for _, c := range []struct {
+		example, name  string
+		budget, frames int
+	}{
+		{"loop.txt", "optimal", 11, 4}, {"loop.txt", "optimal", 10, 5}, {"loop.txt", "clock", 29, 5},
+		{"belady.txt", "clock", 9, 3}, {"belady.txt", "optimal", 4, 0}, {"belady.txt", "fifo", 9, 3},
+	} {
+		data, _ := exampleTraces.ReadFile("examples/" + c.example)
+		example := NewSimulator(PAGE_SIZE)
+		example.LoadAccesses(bytes.NewReader(data))
+		if rec, err := example.recommendFrames(c.name, c.budget); err != nil || rec.Frames != c.frames {
+			t.Errorf("-target-faults %d em %s, %s: %d frames, esperado %d (%v)", c.budget, c.example, c.name, rec.Frames, c.frames, err)
+		}
+	}
+}
+
+// Observadores: dois contadores recebem os mesmos eventos, que batem
+// com o resultado; um erro interrompe a execução naquele acesso
+func TestObservers(t *testing.T) {
+	stationary := stationaryTrace()
+	observed := NewSimulator(4 * PAGE_SIZE)
+	observed.accesses = stationary[:1000]
+	a, b := &countingObserver{}, &countingObserver{}
+	if r := observed.runPolicy(observed.newClock(), a, b); *a != *b || a.faults != r.Faults ||
+		a.hits != r.Hits() || a.evictions != r.Final.Evictions || a.completes != 1 || r.Err != nil {
+		t.Errorf("observadores: %+v e %+v para %d faltas e %d hits", *a, *b, r.Faults, r.Hits())
+	}
+	if r := observed.runPolicy(observed.newClock(), &countingObserver{stopAt: 300}); r.Err == nil || r.Accesses != 300 || r.Partial {
+		t.Errorf("observador com erro: %d acessos, erro %v", r.Accesses, r.Err)
+	}
+}
+
+// Ctrl-C e -timeout: cancelado no meio, o algoritmo para na consulta
+// seguinte com as faltas do trecho já simulado; Run mostra o resumo
+// parcial, não executa os seguintes e guarda o motivo para a saída
+func TestCancel(t *testing.T) {
+	stationary := stationaryTrace()
+	long := NewSimulator(8 * PAGE_SIZE)
+	long.accesses = stationary[:3*cancelCheckEvery]
+	ctx, cancel := context.WithCancelCause(context.Background())
+	long.ctx = ctx
+	const cancelAt = cancelCheckEvery + 100
+	r := long.runPolicy(long.newClock(), &cancellingObserver{at: cancelAt, cancel: func() { cancel(errInterrupted) }})
+	prefix, _ := long.stepperFaults(long.accesses[:r.Accesses], 8, "clock")
+	if r.Err != errInterrupted || r.Accesses <= cancelAt || r.Accesses > cancelAt+cancelCheckEvery || r.Faults != prefix {
+		t.Errorf("interrupção: %d acessos e %d faltas (%d no trecho), erro %v", r.Accesses, r.Faults, prefix, r.Err)
+	}
+	long.algorithms = []string{"clock", "secondchance"}
+	long.noEstimate = true
+	ctx, cancel = context.WithCancelCause(context.Background())
+	cancel(errTimeout)
+	long.ctx = ctx
+	out := captureStdout(long.Run)
+	if long.stopped != errTimeout || !strings.Contains(out, "RESULTADOS PARCIAIS") ||
+		!strings.Contains(out, "Não executados: FIFO 2ª chance") || strings.Contains(out, "=== ALGORITMO FIFO") {
+		t.Errorf("-timeout: motivo %v, saída:\n%s", long.stopped, out)
+	}
+}
+
+// Tela do -tui desenhada num buffer: contadores, ponteiro e página atual
+func TestTUIScreen(t *testing.T) {
+	tuiPolicy := newClockPolicy(2)
+	st := tuiState{Title: "Relógio", Total: 3, Frames: tuiPolicy.Frames(), Rate: 5}
+	for _, access := range []PageAccess{{PageID: "D1"}, {PageID: "D2"}, {PageID: "D1"}} {
+		st.Last, st.Current = step(tuiPolicy, access), access
+		st.Access++
+		if !st.Last.Hit {
+			st.Faults++
+		}
+	}
+	st.Hand, st.Recent = tuiPolicy.Hand(), []float64{0.5, 1}
+	for _, ansi := range []bool{false, true} {
+		var screen bytes.Buffer
+		renderTUI(&screen, st, ansi)
+		text := screen.String()
+		if !strings.Contains(text, "Faltas: 2  Hits: 1") || !strings.Contains(text, "-> ") ||
+			strings.Contains(text, "\x1b[") != ansi {
+			t.Errorf("tela do -tui (ansi=%v):\n%s", ansi, text)
+		}
+	}
+}
+
+// -selftest: FWF num exemplo feito à mão e o autoteste completo sem falhas
+func TestSelfTest(t *testing.T) {
+	// 2 frames: D1 D2 D1 | D3 (esvazia) D1 | D2 (esvazia) D1 -> 6 faltas
+	trace := []PageAccess{{PageID: "D1"}, {PageID: "D2"}, {PageID: "D1"}, {PageID: "D3"}, {PageID: "D1"}, {PageID: "D2"}, {PageID: "D1"}}
+	if faults := fwfFaults(trace, 2); faults != 6 {
+		t.Errorf("FWF: %d faltas, esperado 6", faults)
+	}
+	var ok bool
+	out := captureStdout(func() { ok = runSelfTest(1) })
+	if !ok || !strings.HasPrefix(out, "Autoteste: OK") {
+		t.Errorf("-selftest:\n%s", out)
+	}
+}
+
+// Traces aleatórios: propriedades que todo algoritmo deve respeitar e as
+// vítimas das políticas que têm uma referência mais simples
+func TestPolicyProperties(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	mfuScore, _ := compileExpr("accesses*1000000 + idle")
+	for trial := 0; trial < propertyTrials; trial++ {
+		pages := 2 + rng.Intn(14)
+		accesses := make([]PageAccess, propertyLength)
+		distinct := make(map[string]bool)
+		for i := range accesses {
+			pageID := fmt.Sprintf("D%d", rng.Intn(pages))
+			if i > 0 && rng.Intn(4) == 0 {
+				pageID = accesses[i-1].PageID // sequências para o atalho de -rle
+			}
+			accesses[i] = PageAccess{PageID: pageID, Type: "D", Write: rng.Intn(4) == 0}
+			distinct[pageID] = true
+		}
+
+		// As invariantes do -selftest
+		for _, violation := range checkInvariants(accesses, 8) {
+			t.Errorf("trace %d: %s", trial, violation)
+		}
+
+		curve := optimalMissCurve(accesses)
+		for frames := 1; frames <= 8; frames++ {
+			s := NewSimulator(frames * PAGE_SIZE)
+			s.accesses = accesses
+			s.seed = int64(trial)
+
+			optimal := checkedRun(t, newOptimalPolicy(frames, s.BuildNextUseIndex()), accesses, frames)
+			if want := curve[min(frames, len(curve))-1]; want != optimal.Faults {
+				t.Errorf("trace %d, %d frames: curva do Ótimo em uma passada deu %d faltas, execução deu %d",
+					trial, frames, want, optimal.Faults)
+			}
+
+			// O Stepper percorre o trace inteiro com os mesmos totais
+			stepAll := func(name string) Result {
+				stepper, err := s.NewStepper(accesses, frames, name)
+				if err != nil {
+					t.Errorf("Stepper de %s: %v", name, err)
+					return Result{}
+				}
+				for stepper.Remaining() > 0 {
+					stepper.Step()
+				}
+				return stepper.Summary()
+			}
+			if r := stepAll("optimal"); r.Faults != optimal.Faults || r.Accesses != len(accesses) {
+				t.Errorf("trace %d, %d frames: Ótimo fez %d faltas e, no Stepper, %d", trial, frames, optimal.Faults, r.Faults)
+			}
+
+			victims := make(map[string][]string)
+			faults := make(map[string]int)
+			for _, info := range streamingPolicies {
+				r := checkedRun(t, info.New(s), accesses, frames)
+				victims[info.Name] = r.Victims
+				faults[info.Name] = r.Faults
+				plain := s.runPolicy(info.New(s))
+				s.runs = buildRuns(accesses)
+				compact := s.runPolicy(info.New(s))
+				s.runs = nil
+				if plain.Faults != compact.Faults || plain.WriteBacks != compact.WriteBacks {
+					t.Errorf("trace %d, %d frames: %s fez %d faltas e %d gravações, e com -rle %d e %d",
+						trial, frames, info.Name, plain.Faults, plain.WriteBacks, compact.Faults, compact.WriteBacks)
+				}
+				if stepped := stepAll(info.Name); stepped.Faults != plain.Faults || stepped.WriteBacks != plain.WriteBacks {
+					t.Errorf("trace %d, %d frames: %s fez %d faltas e %d gravações, e no Stepper %d e %d",
+						trial, frames, info.Name, plain.Faults, plain.WriteBacks, stepped.Faults, stepped.WriteBacks)
+				}
+			}
+			// Execuções repetidas e simultâneas sobre o mesmo trace carregado
+			// precisam dar o mesmo resultado
+			first := s.runPolicy(newOptimalPolicy(frames, s.BuildNextUseIndex())).Faults
+			var wg sync.WaitGroup
+			concurrent := make([]int, len(streamingPolicies))
+			for k, info := range streamingPolicies {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					concurrent[k] = s.runPolicy(info.New(s)).Faults
+				}()
+			}
+			wg.Wait()
+			if again := s.runPolicy(newOptimalPolicy(frames, s.BuildNextUseIndex())).Faults; again != first {
+				t.Errorf("trace %d, %d frames: Ótimo fez %d faltas e, repetido, %d", trial, frames, first, again)
+			}
+			for k, info := range streamingPolicies {
+				if concurrent[k] != faults[info.Name] {
+					t.Errorf("trace %d, %d frames: %s fez %d faltas em paralelo", trial, frames, info.Name, concurrent[k])
+				}
+			}
+			// MFU é a expressão com a maior contagem de usos, desempatada pelo LRU
+			mfu := checkedRun(t, newExprPolicy(frames, mfuScore), accesses, frames)
+			if strings.Join(victims["mfu"], ",") != strings.Join(mfu.Victims, ",") {
+				t.Errorf("trace %d, %d frames: MFU substituiu %v, esperado %v", trial, frames, victims["mfu"], mfu.Victims)
+			}
+			// Com frames para todas as páginas, o Working Set é o modelo de Denning
+			if frames >= len(distinct) {
+				ws := checkedRun(t, newWorkingSetPolicy(frames, 5), accesses, frames)
+				if want := workingSetModel(accesses, 5, frames).Faults; ws.Faults != want {
+					t.Errorf("trace %d, %d frames: Working Set fez %d faltas, o modelo %d", trial, frames, ws.Faults, want)
+				}
+			}
+			for _, kind := range []string{"fifo", "lru"} {
+				if want := referenceRun(kind, accesses, frames); strings.Join(victims[kind], ",") != strings.Join(want.Victims, ",") {
+					t.Errorf("trace %d, %d frames: %s substituiu %v, esperado %v", trial, frames, kind, victims[kind], want.Victims)
+				}
+			}
+		}
+	}
+}
+
+// API do servidor: validação da requisição, resultado igual ao do
+// simulador, tempo limite que cancela a simulação e libera a vaga e pânico
+// que marca o job como falho
+func TestServer(t *testing.T) {
+	srv := newServer("", 1, 0)
+	ts := httptest.NewServer(srv.routes())
+	defer ts.Close()
+
+	post := func(body string) (int, map[string]any) {
+		t.Helper()
+		resp, err := http.Post(ts.URL+"/simulate", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var out map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, out
+	}
+
+	for _, body := range []string{
+		`{"trace":"D1\nD2\n","memory":4611686018427387904}`,
+		`{"trace":"D1\nD2\n","memory":100}`,
+		`{"trace":"D1\nD2\n","memory":8192,"algorithms":["belady"]}`,
+		`{"memory":8192}`,
+		`{"trace":"D1","memory":8192,"extra":1}`,
+	} {
+		if status, out := post(body); status != http.StatusBadRequest || out["error"] == "" {
+			t.Errorf("%s: status %d, %v; esperado 400 com erro", body, status, out)
+		}
+	}
+
+	trace := "D1\nD2\nD3\nD1\nD4\nD2\nD1\nD3\n"
+	status, out := post(`{"trace":` + strconv.Quote(trace) + `,"memory":8192,"algorithms":["optimal","fifo"]}`)
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %v", status, out)
+	}
+	s := NewSimulator(2 * PAGE_SIZE)
+	if _, err := s.LoadAccesses(strings.NewReader(trace)); err != nil {
+		t.Fatal(err)
+	}
+	results := out["results"].([]any)
+	for i, name := range []string{"optimal", "fifo"} {
+		got := int(results[i].(map[string]any)["faults"].(float64))
+		if want, _ := s.stepperFaults(s.accesses, 2, name); got != want {
+			t.Errorf("%s: %d faltas pela API, %d pelo simulador", name, got, want)
+		}
+	}
+
+	// Assíncrono: o job aparece em /jobs/{id} e termina
+	status, out = post(`{"trace":"D1\nD2\n","memory":8192,"async":true}`)
+	if status != http.StatusAccepted {
+		t.Fatalf("async: status %d: %v", status, out)
+	}
+	srv.mu.Lock()
+	j := srv.jobs[out["id"].(string)]
+	srv.mu.Unlock()
+	srv.wait(j)
+	resp, err := http.Get(ts.URL + "/jobs/" + j.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snap job
+	json.NewDecoder(resp.Body).Decode(&snap)
+	resp.Body.Close()
+	if snap.Status != "done" || snap.Result == nil {
+		t.Errorf("job assíncrono: %+v", snap)
+	}
+
+	long := NewSimulator(4 * PAGE_SIZE)
+	long.algorithms = []string{"fifo"}
+	stationary := stationaryTrace()
+	for range 20 {
+		long.accesses = append(long.accesses, stationary...)
+	}
+	srv.jobTimeout = 20 * time.Millisecond
+	j = srv.start(long)
+	srv.wait(j)
+	if snap := srv.snapshot(j); snap.Status != "timeout" {
+		t.Errorf("status %q, esperado timeout", snap.Status)
+	}
+	if n := len(srv.slots); n != 0 {
+		t.Errorf("%d vagas ocupadas depois do tempo limite", n)
+	}
+	srv.jobTimeout = 0
+
+	broken := NewSimulator(PAGE_SIZE)
+	broken.LoadAccesses(strings.NewReader("D1\n"))
+	broken.totalFrames = -1
+	j = srv.start(broken)
+	srv.wait(j)
+	if snap := srv.snapshot(j); snap.Status != "failed" || !strings.Contains(snap.Error, "erro interno") {
+		t.Errorf("pânico: %+v", snap)
+	}
+	if status, out := post(`{"trace":"D1\nD2\n","memory":8192}`); status != http.StatusOK {
+		t.Errorf("depois do pânico: status %d: %v", status, out)
+	}
+}
+
+// Cliente WebSocket mínimo para os testes de /stream: quadros mascarados
+// como a RFC exige dos clientes
+type testWebsocket struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialStream(t *testing.T, base, query string, header http.Header) (*testWebsocket, *http.Response) {
+	t.Helper()
+	u, _ := url.Parse(base)
+	conn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", base+"/stream?"+query, nil)
+	req.Header = header.Clone()
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, resp
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept %q", got)
+	}
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	return &testWebsocket{conn: conn, r: r}, resp
+}
+
+func (c *testWebsocket) send(opcode byte, payload []byte) {
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	c.conn.Write(frame)
+}
+
+func (c *testWebsocket) read() (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	n := int(header[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(c.r, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(c.r, ext[:])
+		n = int(binary.BigEndian.Uint64(ext[:]))
+	}
+	payload := make([]byte, n)
+	_, err := io.ReadFull(c.r, payload)
+	return header[0] & 0x0F, payload, err
+}
+
+// /stream: eventos iguais à simulação, ping e fechamento pelo cliente,
+// origem de outro site, rate inválido e a vaga de -max-jobs com o tempo
+// limite
+func TestStream(t *testing.T) {
+	srv := newServer("", 1, 0)
+	ts := httptest.NewServer(srv.routes())
+	defer ts.Close()
+	trace := "D1\nD2\nD3\nD1\nD4\nD2\nD1\nD3\n"
+	base := "memory=8192&algorithm=fifo&trace=" + url.QueryEscape(trace)
+	query := base + "&rate=1e9"
+
+	ws, resp := dialStream(t, ts.URL, query, http.Header{"Origin": {ts.URL}})
+	if ws == nil {
+		t.Fatalf("handshake: status %d", resp.StatusCode)
+	}
+	faults, last := 0, 0
+	var kinds []string
+	for {
+		opcode, payload, err := ws.read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opcode == 8 {
+			if code := binary.BigEndian.Uint16(payload); code != 1000 {
+				t.Errorf("fechamento com código %d", code)
+			}
+			break
+		}
+		var event streamEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			t.Fatal(err)
+		}
+		if !event.Hit {
+			faults++
+			kinds = append(kinds, fmt.Sprintf("%s:%d:%t", event.PageID, event.Load, event.Cold))
+		} else if event.Load != 0 || event.Cold {
+			t.Errorf("acerto %d com marcador de carga", event.Index)
+		}
+		if event.Coalesced != 0 {
+			t.Errorf("acesso %d: %d eventos agrupados com rate alto", event.Index, event.Coalesced)
+		}
+		last = event.Index
+	}
+	ws.conn.Close()
+	s := NewSimulator(2 * PAGE_SIZE)
+	s.LoadAccesses(strings.NewReader(trace))
+	if want, _ := s.stepperFaults(s.accesses, 2, "fifo"); faults != want || last != len(s.accesses) {
+		t.Errorf("stream: %d faltas até o acesso %d, esperado %d até %d", faults, last, want, len(s.accesses))
+	}
+	// FIFO com 2 quadros: só a primeira carga de cada página é fria
+	wantKinds := "[D1:1:true D2:1:true D3:1:true D1:2:false D4:1:true D2:2:false D1:3:false D3:2:false]"
+	if fmt.Sprint(kinds) != wantKinds {
+		t.Errorf("marcadores de carga: %v, esperado %v", kinds, wantKinds)
+	}
+
+	// Com pace baixo o cliente pinga, recebe o pong e fecha no meio
+	ws, _ = dialStream(t, ts.URL, query+"&pace=20", http.Header{})
+	ws.send(9, []byte("oi"))
+	for {
+		opcode, payload, err := ws.read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opcode == 10 {
+			if string(payload) != "oi" {
+				t.Errorf("pong %q", payload)
+			}
+			break
+		}
+	}
+	ws.send(8, binary.BigEndian.AppendUint16(nil, 1000))
+	for {
+		opcode, _, err := ws.read()
+		if err != nil {
+			t.Fatalf("sem quadro de fechamento: %v", err)
+		}
+		if opcode == 8 {
+			break
+		}
+	}
+	ws.conn.Close()
+
+	for _, c := range []struct {
+		query  string
+		origin string
+		status int
+	}{
+		{query, "http://outro.example", http.StatusForbidden},
+		{base + "&rate=abc", "", http.StatusBadRequest},
+		{base + "&pace=-1", "", http.StatusBadRequest},
+		{base + "&rate=0", "", http.StatusBadRequest},
+		{base + "&rate=Inf", "", http.StatusBadRequest},
+		{base + "&pace=NaN", "", http.StatusBadRequest},
+	} {
+		header := http.Header{}
+		if c.origin != "" {
+			header.Set("Origin", c.origin)
+		}
+		ws, resp := dialStream(t, ts.URL, c.query, header)
+		if ws != nil || resp.StatusCode != c.status {
+			t.Errorf("%s (Origin %q): status %d, esperado %d", c.query, c.origin, resp.StatusCode, c.status)
+		}
+	}
+
+	// Sem vaga livre, o stream espera até o tempo limite
+	srv.jobTimeout = 20 * time.Millisecond
+	srv.slots <- struct{}{}
+	if ws, resp := dialStream(t, ts.URL, query, http.Header{}); ws != nil || resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("sem vaga: status %d, esperado 504", resp.StatusCode)
+	}
+	<-srv.slots
+
+	// O tempo limite encerra um stream em andamento com o código 1011
+	ws, _ = dialStream(t, ts.URL, query+"&pace=5", http.Header{})
+	for {
+		opcode, payload, err := ws.read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opcode == 8 {
+			if code := binary.BigEndian.Uint16(payload); code != 1011 {
+				t.Errorf("tempo limite: fechamento com código %d", code)
+			}
+			break
+		}
+	}
+	ws.conn.Close()
+}
+
+// Codificação protobuf feita à mão conferida com os tipos gerados de
+// proto/trace.proto: mesmos bytes e tamanho do proto.Marshal, leitura nos
+// dois sentidos e o trace delimitado do convert
+func TestProtoBindings(t *testing.T) {
+	accesses := []PageAccess{
+		{PageID: "I0", Type: "I"},
+		{PageID: "D42", Type: "D"},
+		{PageID: "D7", Type: "D", Write: true},
+		{PageID: "D8", Type: "D", Write: true, Zero: true},
+		{PageID: "I1f", Type: "I", Delay: 1500},
+		{PageID: "D9", Type: "D", Delay: math.MaxInt64},
+	}
+	for _, a := range accesses {
+		hand := a.MarshalProto()
+		var msg paging.Access
+		if err := proto.Unmarshal(hand, &msg); err != nil {
+			t.Fatalf("%+v: %v", a, err)
+		}
+		wantType := paging.AccessType_INSTRUCTION
+		if a.Type == "D" {
+			wantType = paging.AccessType_DATA
+		}
+		if msg.GetType() != wantType || msg.GetPage() != a.PageID || msg.GetWrite() != a.Write ||
+			msg.GetZeroFill() != a.Zero || msg.GetDelay() != uint64(a.Delay) {
+			t.Errorf("%+v lido como %v", a, &msg)
+		}
+		generated, err := proto.MarshalOptions{Deterministic: true}.Marshal(&msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(hand, generated) || proto.Size(&msg) != len(hand) {
+			t.Errorf("%+v: %x à mão, %x gerado (tamanho %d)", a, hand, generated, proto.Size(&msg))
+		}
+		back, err := unmarshalAccessProto(generated)
+		if err != nil || back != a {
+			t.Errorf("%+v voltou como %+v %v", a, back, err)
+		}
+	}
+
+	// Campos que o simulador ignora (pid, timestamp) não atrapalham a leitura
+	extra, _ := proto.Marshal(&paging.Access{Type: paging.AccessType_DATA, Page: "D3", Pid: 12, Timestamp: 99})
+	if back, err := unmarshalAccessProto(extra); err != nil || back != (PageAccess{PageID: "D3", Type: "D"}) {
+		t.Errorf("com pid e timestamp: %+v %v", back, err)
+	}
+
+	dir := t.TempDir()
+	text, binary := filepath.Join(dir, "trace.txt"), filepath.Join(dir, "trace.pb")
+	var lines strings.Builder
+	for _, a := range accesses {
+		fmt.Fprintln(&lines, a)
+	}
+	os.WriteFile(text, []byte(lines.String()), 0o644)
+	var err error
+	captureStdout(func() { err = convertTrace("text", "proto", text, binary, nil) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(binary)
+	reader := bufio.NewReader(bytes.NewReader(data))
+	for i := 0; ; i++ {
+		var msg paging.Access
+		err := protodelim.UnmarshalFrom(reader, &msg)
+		if err == io.EOF {
+			if i != len(accesses) {
+				t.Errorf("%d mensagens no trace, esperado %d", i, len(accesses))
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("mensagem %d: %v", i+1, err)
+		}
+		if i < len(accesses) && msg.GetPage() != accesses[i].PageID {
+			t.Errorf("mensagem %d: página %s, esperado %s", i+1, msg.GetPage(), accesses[i].PageID)
+		}
+	}
+
+	results := []Result{
+		{Algorithm: "Ótimo", Faults: 7},
+		{Algorithm: "Relógio", Faults: 9, WriteBacks: 3, ZeroFills: 1},
+		{Algorithm: "FIFO", Faults: 40, Partial: true, Accesses: 1000},
+	}
+	hand := marshalResultsProto(100000, 16, 4, results)
+	var msg paging.Results
+	if err := proto.Unmarshal(hand, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.GetAccesses() != 100000 || msg.GetDistinct() != 16 || msg.GetFrames() != 4 || len(msg.GetResults()) != len(results) ||
+		msg.GetSchemaVersion() != resultsSchemaVersion {
+		t.Fatalf("Results lido como %v", &msg)
+	}
+	for i, r := range results {
+		got := msg.GetResults()[i]
+		if got.GetAlgorithm() != r.Algorithm || got.GetFaults() != uint64(r.Faults) || got.GetWriteBacks() != uint64(r.WriteBacks) ||
+			got.GetZeroFills() != uint64(r.ZeroFills) || got.GetEstimate() != r.Partial || (r.Partial && got.GetSimulated() != uint64(r.Accesses)) {
+			t.Errorf("%+v lido como %v", r, got)
+		}
+	}
+	generated, _ := proto.MarshalOptions{Deterministic: true}.Marshal(&msg)
+	if !bytes.Equal(hand, generated) || proto.Size(&msg) != len(hand) {
+		t.Errorf("Results: %x à mão, %x gerado (tamanho %d)", hand, generated, proto.Size(&msg))
+	}
+}
+
+// Trace de laços curtos para os benchmarks: cada página se repete de 1 a
+// 16 vezes seguidas, sobre 64 páginas
+func benchTrace(n int) []PageAccess {
+	rng := rand.New(rand.NewSource(1))
+	trace := make([]PageAccess, 0, n)
+	for len(trace) < n {
+		access := PageAccess{PageID: fmt.Sprintf("D%d", rng.Intn(64)), Type: "D", Write: rng.Intn(8) == 0}
+		for repeat := 1 + rng.Intn(16); repeat > 0 && len(trace) < n; repeat-- {
+			trace = append(trace, access)
+		}
+	}
+	return trace
+}
+
+// -rle contra o laço acesso a acesso, com as mesmas faltas
+func BenchmarkRLE(b *testing.B) {
+	trace := benchTrace(1 << 20)
+	for _, name := range []string{"clock", "lru", "fifo"} {
+		info, _ := findPolicy(name)
+		for _, rle := range []bool{false, true} {
+			b.Run(fmt.Sprintf("%s/rle=%v", name, rle), func(b *testing.B) {
+				s := NewSimulator(16 * PAGE_SIZE)
+				s.accesses = trace
+				if rle {
+					s.runs = buildRuns(trace)
+				}
+				for b.Loop() {
+					s.runPolicy(info.New(s))
+				}
+				b.ReportMetric(float64(len(trace))*float64(b.N)/b.Elapsed().Seconds(), "acessos/s")
+			})
+		}
+	}
+}
+
+// Varredura de 8 tamanhos de memória no Ótimo: com o índice de próximo uso
+// guardado (BuildNextUseIndex) e refeito a cada ponto, como antes
+func BenchmarkOptimalSweep(b *testing.B) {
+	trace := benchTrace(1 << 19)
+	for _, shared := range []bool{false, true} {
+		b.Run(fmt.Sprintf("shared=%v", shared), func(b *testing.B) {
+			for b.Loop() {
+				s := NewSimulator(PAGE_SIZE)
+				s.accesses = trace
+				if !shared {
+					s.nextUse = nil
+				}
+				for frames := 4; frames <= 32; frames += 4 {
+					s.totalFrames = frames
+					s.runOptimal()
+				}
+			}
+		})
+	}
+}
+
+// Custo por passo do Stepper contra o laço em lote de runPolicy
+func BenchmarkStepper(b *testing.B) {
+	trace := benchTrace(1 << 20)
+	s := NewSimulator(16 * PAGE_SIZE)
+	s.accesses = trace
+	info, _ := findPolicy("clock")
+	b.Run("batch", func(b *testing.B) {
+		for b.Loop() {
+			s.runPolicy(info.New(s))
+		}
+		b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(trace)), "ns/acesso")
+	})
+	b.Run("stepper", func(b *testing.B) {
+		for b.Loop() {
+			stepper, _ := s.NewStepper(trace, 16, "clock")
+			for stepper.Remaining() > 0 {
+				stepper.Step()
+			}
+		}
+		b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(trace)), "ns/acesso")
+	})
+}
+
+// Laço sem observadores (caminho rápido) contra um e três observadores
+func BenchmarkObservers(b *testing.B) {
+	trace := benchTrace(1 << 20)
+	info, _ := findPolicy("clock")
+	for _, n := range []int{0, 1, 3} {
+		b.Run(fmt.Sprintf("observers=%d", n), func(b *testing.B) {
+			s := NewSimulator(16 * PAGE_SIZE)
+			s.accesses = trace
+			observers := make([]Observer, n)
+			for k := range observers {
+				observers[k] = &countingObserver{}
+			}
+			for b.Loop() {
+				s.runPolicy(info.New(s), observers...)
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(trace)), "ns/acesso")
+		})
+	}
+}
+
+// Trace texto grande para os benchmarks de leitura, com escritas e atrasos
+func benchTraceText(n int) []byte {
+	var b bytes.Buffer
+	for i, access := range benchTrace(n) {
+		if i%3 == 0 {
+			access.Delay = int64(i % 500)
+		}
+		fmt.Fprintln(&b, access)
+	}
+	return b.Bytes()
+}
+
+// Leitura de um arquivo de trace: bufio linha a linha contra o arquivo
+// inteiro na memória (-reader slice), os dois num único trecho
+func BenchmarkReader(b *testing.B) {
+	data := benchTraceText(1 << 20)
+	path := filepath.Join(b.TempDir(), "trace.txt")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		b.Fatal(err)
+	}
+	for _, reader := range []string{"buffered", "slice"} {
+		b.Run(reader, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for b.Loop() {
+				s := NewSimulator(16 * PAGE_SIZE)
+				s.traceReader, s.parseWorkers = reader, 1
+				if _, err := s.LoadAccessFile(path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Interpretação em paralelo de um trace grande já na memória, por número
+// de trechos (-parse-workers)
+func BenchmarkParseWorkers(b *testing.B) {
+	data := benchTraceText(1 << 21)
+	counts := []int{1, 2, 4}
+	if n := runtime.NumCPU(); n > 4 {
+		counts = append(counts, n)
+	}
+	for _, workers := range counts {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for b.Loop() {
+				s := NewSimulator(16 * PAGE_SIZE)
+				s.parseWorkers = workers
+				if _, err := s.LoadAccessBytes(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,416 @@
+// Formatos protobuf do simulador de paginação.
+//
+// Trace: sequência de mensagens Access, cada uma precedida pelo seu tamanho
+// em bytes codificado como varint (o formato "delimited" das bibliotecas
+// protobuf). Lido com -format proto e gerado por "convert -to proto".
+//
+// Resultados: uma única mensagem Results, gravada com -proto-out.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: proto/trace.proto
+
+package paging
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type AccessType int32
+
+const (
+	AccessType_INSTRUCTION AccessType = 0
+	AccessType_DATA        AccessType = 1
+)
+
+// Enum value maps for AccessType.
+var (
+	AccessType_name = map[int32]string{
+		0: "INSTRUCTION",
+		1: "DATA",
+	}
+	AccessType_value = map[string]int32{
+		"INSTRUCTION": 0,
+		"DATA":        1,
+	}
+)
+
+func (x AccessType) Enum() *AccessType {
+	p := new(AccessType)
+	*p = x
+	return p
+}
+
+func (x AccessType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AccessType) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_trace_proto_enumTypes[0].Descriptor()
+}
+
+func (AccessType) Type() protoreflect.EnumType {
+	return &file_proto_trace_proto_enumTypes[0]
+}
+
+func (x AccessType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AccessType.Descriptor instead.
+func (AccessType) EnumDescriptor() ([]byte, []int) {
+	return file_proto_trace_proto_rawDescGZIP(), []int{0}
+}
+
+type Access struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          AccessType             `protobuf:"varint,1,opt,name=type,proto3,enum=paging.AccessType" json:"type,omitempty"`
+	Page          string                 `protobuf:"bytes,2,opt,name=page,proto3" json:"page,omitempty"` // identificador completo, com a letra do tipo (D42)
+	Write         bool                   `protobuf:"varint,3,opt,name=write,proto3" json:"write,omitempty"`
+	Pid           uint32                 `protobuf:"varint,4,opt,name=pid,proto3" json:"pid,omitempty"`                           // ignorado: o simulador executa um único processo
+	Timestamp     uint64                 `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`               // ignorado
+	ZeroFill      bool                   `protobuf:"varint,6,opt,name=zero_fill,json=zeroFill,proto3" json:"zero_fill,omitempty"` // escrita em página de demanda-zero
+	Delay         uint64                 `protobuf:"varint,7,opt,name=delay,proto3" json:"delay,omitempty"`                       // ns até o próximo acesso (coluna +N do texto)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Access) Reset() {
+	*x = Access{}
+	mi := &file_proto_trace_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Access) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Access) ProtoMessage() {}
+
+func (x *Access) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trace_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Access.ProtoReflect.Descriptor instead.
+func (*Access) Descriptor() ([]byte, []int) {
+	return file_proto_trace_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Access) GetType() AccessType {
+	if x != nil {
+		return x.Type
+	}
+	return AccessType_INSTRUCTION
+}
+
+func (x *Access) GetPage() string {
+	if x != nil {
+		return x.Page
+	}
+	return ""
+}
+
+func (x *Access) GetWrite() bool {
+	if x != nil {
+		return x.Write
+	}
+	return false
+}
+
+func (x *Access) GetPid() uint32 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *Access) GetTimestamp() uint64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *Access) GetZeroFill() bool {
+	if x != nil {
+		return x.ZeroFill
+	}
+	return false
+}
+
+func (x *Access) GetDelay() uint64 {
+	if x != nil {
+		return x.Delay
+	}
+	return 0
+}
+
+type AlgorithmResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Algorithm     string                 `protobuf:"bytes,1,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
+	Faults        uint64                 `protobuf:"varint,2,opt,name=faults,proto3" json:"faults,omitempty"`
+	WriteBacks    uint64                 `protobuf:"varint,3,opt,name=write_backs,json=writeBacks,proto3" json:"write_backs,omitempty"`
+	ZeroFills     uint64                 `protobuf:"varint,4,opt,name=zero_fills,json=zeroFills,proto3" json:"zero_fills,omitempty"`
+	Simulated     uint64                 `protobuf:"varint,5,opt,name=simulated,proto3" json:"simulated,omitempty"` // acessos simulados quando estimate é verdadeiro
+	Estimate      bool                   `protobuf:"varint,6,opt,name=estimate,proto3" json:"estimate,omitempty"`   // interrompido por -converge: faltas do trecho simulado
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AlgorithmResult) Reset() {
+	*x = AlgorithmResult{}
+	mi := &file_proto_trace_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AlgorithmResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AlgorithmResult) ProtoMessage() {}
+
+func (x *AlgorithmResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trace_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AlgorithmResult.ProtoReflect.Descriptor instead.
+func (*AlgorithmResult) Descriptor() ([]byte, []int) {
+	return file_proto_trace_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AlgorithmResult) GetAlgorithm() string {
+	if x != nil {
+		return x.Algorithm
+	}
+	return ""
+}
+
+func (x *AlgorithmResult) GetFaults() uint64 {
+	if x != nil {
+		return x.Faults
+	}
+	return 0
+}
+
+func (x *AlgorithmResult) GetWriteBacks() uint64 {
+	if x != nil {
+		return x.WriteBacks
+	}
+	return 0
+}
+
+func (x *AlgorithmResult) GetZeroFills() uint64 {
+	if x != nil {
+		return x.ZeroFills
+	}
+	return 0
+}
+
+func (x *AlgorithmResult) GetSimulated() uint64 {
+	if x != nil {
+		return x.Simulated
+	}
+	return 0
+}
+
+func (x *AlgorithmResult) GetEstimate() bool {
+	if x != nil {
+		return x.Estimate
+	}
+	return false
+}
+
+type Results struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Accesses      uint64                 `protobuf:"varint,1,opt,name=accesses,proto3" json:"accesses,omitempty"`
+	Distinct      uint64                 `protobuf:"varint,2,opt,name=distinct,proto3" json:"distinct,omitempty"`
+	Frames        uint32                 `protobuf:"varint,3,opt,name=frames,proto3" json:"frames,omitempty"`
+	Results       []*AlgorithmResult     `protobuf:"bytes,4,rep,name=results,proto3" json:"results,omitempty"`
+	SchemaVersion uint32                 `protobuf:"varint,5,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"` // versão do esquema dos resultados, a mesma do JSON
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Results) Reset() {
+	*x = Results{}
+	mi := &file_proto_trace_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Results) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Results) ProtoMessage() {}
+
+func (x *Results) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trace_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Results.ProtoReflect.Descriptor instead.
+func (*Results) Descriptor() ([]byte, []int) {
+	return file_proto_trace_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Results) GetAccesses() uint64 {
+	if x != nil {
+		return x.Accesses
+	}
+	return 0
+}
+
+func (x *Results) GetDistinct() uint64 {
+	if x != nil {
+		return x.Distinct
+	}
+	return 0
+}
+
+func (x *Results) GetFrames() uint32 {
+	if x != nil {
+		return x.Frames
+	}
+	return 0
+}
+
+func (x *Results) GetResults() []*AlgorithmResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *Results) GetSchemaVersion() uint32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+var File_proto_trace_proto protoreflect.FileDescriptor
+
+const file_proto_trace_proto_rawDesc = "" +
+	"\n" +
+	"\x11proto/trace.proto\x12\x06paging\"\xbd\x01\n" +
+	"\x06Access\x12&\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x12.paging.AccessTypeR\x04type\x12\x12\n" +
+	"\x04page\x18\x02 \x01(\tR\x04page\x12\x14\n" +
+	"\x05write\x18\x03 \x01(\bR\x05write\x12\x10\n" +
+	"\x03pid\x18\x04 \x01(\rR\x03pid\x12\x1c\n" +
+	"\ttimestamp\x18\x05 \x01(\x04R\ttimestamp\x12\x1b\n" +
+	"\tzero_fill\x18\x06 \x01(\bR\bzeroFill\x12\x14\n" +
+	"\x05delay\x18\a \x01(\x04R\x05delay\"\xc1\x01\n" +
+	"\x0fAlgorithmResult\x12\x1c\n" +
+	"\talgorithm\x18\x01 \x01(\tR\talgorithm\x12\x16\n" +
+	"\x06faults\x18\x02 \x01(\x04R\x06faults\x12\x1f\n" +
+	"\vwrite_backs\x18\x03 \x01(\x04R\n" +
+	"writeBacks\x12\x1d\n" +
+	"\n" +
+	"zero_fills\x18\x04 \x01(\x04R\tzeroFills\x12\x1c\n" +
+	"\tsimulated\x18\x05 \x01(\x04R\tsimulated\x12\x1a\n" +
+	"\bestimate\x18\x06 \x01(\bR\bestimate\"\xb3\x01\n" +
+	"\aResults\x12\x1a\n" +
+	"\baccesses\x18\x01 \x01(\x04R\baccesses\x12\x1a\n" +
+	"\bdistinct\x18\x02 \x01(\x04R\bdistinct\x12\x16\n" +
+	"\x06frames\x18\x03 \x01(\rR\x06frames\x121\n" +
+	"\aresults\x18\x04 \x03(\v2\x17.paging.AlgorithmResultR\aresults\x12%\n" +
+	"\x0eschema_version\x18\x05 \x01(\rR\rschemaVersion*'\n" +
+	"\n" +
+	"AccessType\x12\x0f\n" +
+	"\vINSTRUCTION\x10\x00\x12\b\n" +
+	"\x04DATA\x10\x01B9Z7github.com/GabrielVGS/memory-management-go/proto;pagingb\x06proto3"
+
+var (
+	file_proto_trace_proto_rawDescOnce sync.Once
+	file_proto_trace_proto_rawDescData []byte
+)
+
+func file_proto_trace_proto_rawDescGZIP() []byte {
+	file_proto_trace_proto_rawDescOnce.Do(func() {
+		file_proto_trace_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_trace_proto_rawDesc), len(file_proto_trace_proto_rawDesc)))
+	})
+	return file_proto_trace_proto_rawDescData
+}
+
+var file_proto_trace_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proto_trace_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_proto_trace_proto_goTypes = []any{
+	(AccessType)(0),         // 0: paging.AccessType
+	(*Access)(nil),          // 1: paging.Access
+	(*AlgorithmResult)(nil), // 2: paging.AlgorithmResult
+	(*Results)(nil),         // 3: paging.Results
+}
+var file_proto_trace_proto_depIdxs = []int32{
+	0, // 0: paging.Access.type:type_name -> paging.AccessType
+	2, // 1: paging.Results.results:type_name -> paging.AlgorithmResult
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_proto_trace_proto_init() }
+func file_proto_trace_proto_init() {
+	if File_proto_trace_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_trace_proto_rawDesc), len(file_proto_trace_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_proto_trace_proto_goTypes,
+		DependencyIndexes: file_proto_trace_proto_depIdxs,
+		EnumInfos:         file_proto_trace_proto_enumTypes,
+		MessageInfos:      file_proto_trace_proto_msgTypes,
+	}.Build()
+	File_proto_trace_proto = out.File
+	file_proto_trace_proto_goTypes = nil
+	file_proto_trace_proto_depIdxs = nil
+}